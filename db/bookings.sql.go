@@ -11,34 +11,98 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countBookingReschedules = `-- name: CountBookingReschedules :one
+SELECT
+  COUNT(*)
+FROM
+  booking_history
+WHERE
+  booking_id = $1
+  AND status = 'rescheduled'
+`
+
+func (q *Queries) CountBookingReschedules(ctx context.Context, bookingID int32) (int64, error) {
+	row := q.db.QueryRow(ctx, countBookingReschedules, bookingID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countBookingTypeUses = `-- name: CountBookingTypeUses :one
+SELECT
+  (
+    SELECT
+      COUNT(*)
+    FROM
+      availability_types
+    WHERE
+      availability_types.type_id = $1
+  ) + (
+    SELECT
+      COUNT(*)
+    FROM
+      bookings
+    WHERE
+      bookings.type_id = $1
+  ) AS uses
+`
+
+func (q *Queries) CountBookingTypeUses(ctx context.Context, typeID int32) (int64, error) {
+	row := q.db.QueryRow(ctx, countBookingTypeUses, typeID)
+	var uses int64
+	err := row.Scan(&uses)
+	return uses, err
+}
+
 const createAvailabilitySlot = `-- name: CreateAvailabilitySlot :one
 INSERT INTO
-  availability (employee_id, datetime, type_id)
+  availability (employee_id, datetime, unit_minutes, capacity)
 VALUES
-  ($1, $2, $3)
+  ($1, $2, $3, $4)
 RETURNING
   id
 `
 
 type CreateAvailabilitySlotParams struct {
-	EmployeeID int32            `json:"employee_id"`
-	Datetime   pgtype.Timestamp `json:"datetime"`
-	TypeID     int32            `json:"type_id"`
+	EmployeeID  int32              `json:"employee_id"`
+	Datetime    pgtype.Timestamptz `json:"datetime"`
+	UnitMinutes int32              `json:"unit_minutes"`
+	Capacity    int32              `json:"capacity"`
 }
 
 func (q *Queries) CreateAvailabilitySlot(ctx context.Context, arg CreateAvailabilitySlotParams) (int32, error) {
-	row := q.db.QueryRow(ctx, createAvailabilitySlot, arg.EmployeeID, arg.Datetime, arg.TypeID)
+	row := q.db.QueryRow(ctx, createAvailabilitySlot,
+		arg.EmployeeID,
+		arg.Datetime,
+		arg.UnitMinutes,
+		arg.Capacity,
+	)
 	var id int32
 	err := row.Scan(&id)
 	return id, err
 }
 
+const createAvailabilityTypes = `-- name: CreateAvailabilityTypes :exec
+INSERT INTO
+  availability_types (availability_id, type_id)
+SELECT
+  $1,
+  UNNEST($2::int[])
+ON CONFLICT DO NOTHING
+`
+
+type CreateAvailabilityTypesParams struct {
+	AvailabilityID int32   `json:"availability_id"`
+	Column2        []int32 `json:"column_2"`
+}
+
+func (q *Queries) CreateAvailabilityTypes(ctx context.Context, arg CreateAvailabilityTypesParams) error {
+	_, err := q.db.Exec(ctx, createAvailabilityTypes, arg.AvailabilityID, arg.Column2)
+	return err
+}
+
 const createBooking = `-- name: CreateBooking :one
 WITH
-  unit AS (
-    SELECT
-      $7::integer AS minutes
-  ),
   new_booking as (
     INSERT INTO
       bookings (
@@ -47,7 +111,10 @@ WITH
         paid,
         cost,
         notes,
-        status_updated_by
+        status_updated_by,
+        free_cancellation_hours,
+        late_cancellation_percent,
+        no_show_percent
       )
     VALUES
       (
@@ -63,6 +130,39 @@ WITH
             users
           WHERE
             users.id = $1
+        ),
+        COALESCE(
+          (
+            SELECT
+              free_cancellation_hours
+            FROM
+              booking_types
+            WHERE
+              booking_types.id = $2
+          ),
+          0
+        ),
+        COALESCE(
+          (
+            SELECT
+              late_cancellation_percent
+            FROM
+              booking_types
+            WHERE
+              booking_types.id = $2
+          ),
+          0
+        ),
+        COALESCE(
+          (
+            SELECT
+              no_show_percent
+            FROM
+              booking_types
+            WHERE
+              booking_types.id = $2
+          ),
+          0
         )
       )
     RETURNING
@@ -84,17 +184,8 @@ WITH
     SELECT
       s.booking_id,
       a.employee_id,
-      MIN(a.datetime)::timestamp AS start_time,
-      (
-        MAX(a.datetime) + (
-          (
-            SELECT
-              minutes
-            from
-              unit
-          ) * INTERVAL '1 minute'
-        )
-      )::timestamp AS end_time
+      MIN(a.datetime)::timestamptz AS start_time,
+      MAX(a.datetime + a.unit_minutes * INTERVAL '1 minute')::timestamptz AS end_time
     FROM
       slot_insert s
       JOIN availability a ON s.availability_slot_id = a.id
@@ -123,17 +214,16 @@ type CreateBookingParams struct {
 	Cost    int32       `json:"cost"`
 	Notes   pgtype.Text `json:"notes"`
 	Column6 []int32     `json:"column_6"`
-	Column7 int32       `json:"column_7"`
 }
 
 type CreateBookingRow struct {
-	BookingID       int32            `json:"booking_id"`
-	StartTime       pgtype.Timestamp `json:"start_time"`
-	EndTime         pgtype.Timestamp `json:"end_time"`
-	EmployeeID      int32            `json:"employee_id"`
-	EmployeeName    string           `json:"employee_name"`
-	EmployeeSurname string           `json:"employee_surname"`
-	EmployeeEmail   string           `json:"employee_email"`
+	BookingID       int32              `json:"booking_id"`
+	StartTime       pgtype.Timestamptz `json:"start_time"`
+	EndTime         pgtype.Timestamptz `json:"end_time"`
+	EmployeeID      int32              `json:"employee_id"`
+	EmployeeName    string             `json:"employee_name"`
+	EmployeeSurname string             `json:"employee_surname"`
+	EmployeeEmail   string             `json:"employee_email"`
 }
 
 func (q *Queries) CreateBooking(ctx context.Context, arg CreateBookingParams) (CreateBookingRow, error) {
@@ -144,7 +234,6 @@ func (q *Queries) CreateBooking(ctx context.Context, arg CreateBookingParams) (C
 		arg.Cost,
 		arg.Notes,
 		arg.Column6,
-		arg.Column7,
 	)
 	var i CreateBookingRow
 	err := row.Scan(
@@ -170,22 +259,28 @@ INSERT INTO
     start_time,
     end_time,
     status,
-    changed_by_email
+    changed_by_email,
+    previous_start_time,
+    previous_end_time,
+    reason
   )
 VALUES
-  ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+  ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 `
 
 type CreateBookingHistoryParams struct {
-	BookingID       int32            `json:"booking_id"`
-	EmployeeID      int32            `json:"employee_id"`
-	EmployeeName    string           `json:"employee_name"`
-	EmployeeSurname string           `json:"employee_surname"`
-	EmployeeEmail   string           `json:"employee_email"`
-	StartTime       pgtype.Timestamp `json:"start_time"`
-	EndTime         pgtype.Timestamp `json:"end_time"`
-	Status          BookingStatus    `json:"status"`
-	ChangedByEmail  string           `json:"changed_by_email"`
+	BookingID         int32              `json:"booking_id"`
+	EmployeeID        int32              `json:"employee_id"`
+	EmployeeName      string             `json:"employee_name"`
+	EmployeeSurname   string             `json:"employee_surname"`
+	EmployeeEmail     string             `json:"employee_email"`
+	StartTime         pgtype.Timestamptz `json:"start_time"`
+	EndTime           pgtype.Timestamptz `json:"end_time"`
+	Status            BookingStatus      `json:"status"`
+	ChangedByEmail    string             `json:"changed_by_email"`
+	PreviousStartTime pgtype.Timestamptz `json:"previous_start_time"`
+	PreviousEndTime   pgtype.Timestamptz `json:"previous_end_time"`
+	Reason            pgtype.Text        `json:"reason"`
 }
 
 func (q *Queries) CreateBookingHistory(ctx context.Context, arg CreateBookingHistoryParams) error {
@@ -199,6 +294,9 @@ func (q *Queries) CreateBookingHistory(ctx context.Context, arg CreateBookingHis
 		arg.EndTime,
 		arg.Status,
 		arg.ChangedByEmail,
+		arg.PreviousStartTime,
+		arg.PreviousEndTime,
+		arg.Reason,
 	)
 	return err
 }
@@ -222,19 +320,64 @@ func (q *Queries) CreateBookingSlot(ctx context.Context, arg CreateBookingSlotPa
 
 const createBookingType = `-- name: CreateBookingType :one
 INSERT INTO
-  booking_types (title, description, fixed, cost, duration)
+  booking_types (
+    title,
+    description,
+    fixed,
+    cost,
+    duration,
+    unit_minutes,
+    buffer_before_minutes,
+    buffer_after_minutes,
+    min_notice_minutes,
+    max_advance_days,
+    free_cancellation_hours,
+    late_cancellation_percent,
+    no_show_percent,
+    deposit_percent,
+    deposit_amount,
+    deposit_window_hours
+  )
 VALUES
-  ($1, $2, $3, $4, $5)
+  (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13,
+    $14,
+    $15,
+    $16
+  )
 RETURNING
   id
 `
 
 type CreateBookingTypeParams struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Fixed       bool   `json:"fixed"`
-	Cost        int32  `json:"cost"`
-	Duration    int32  `json:"duration"`
+	Title                   string `json:"title"`
+	Description             string `json:"description"`
+	Fixed                   bool   `json:"fixed"`
+	Cost                    int32  `json:"cost"`
+	Duration                int32  `json:"duration"`
+	UnitMinutes             int32  `json:"unit_minutes"`
+	BufferBeforeMinutes     int32  `json:"buffer_before_minutes"`
+	BufferAfterMinutes      int32  `json:"buffer_after_minutes"`
+	MinNoticeMinutes        int32  `json:"min_notice_minutes"`
+	MaxAdvanceDays          int32  `json:"max_advance_days"`
+	FreeCancellationHours   int32  `json:"free_cancellation_hours"`
+	LateCancellationPercent int32  `json:"late_cancellation_percent"`
+	NoShowPercent           int32  `json:"no_show_percent"`
+	DepositPercent          int32  `json:"deposit_percent"`
+	DepositAmount           int32  `json:"deposit_amount"`
+	DepositWindowHours      int32  `json:"deposit_window_hours"`
 }
 
 func (q *Queries) CreateBookingType(ctx context.Context, arg CreateBookingTypeParams) (int32, error) {
@@ -244,6 +387,17 @@ func (q *Queries) CreateBookingType(ctx context.Context, arg CreateBookingTypePa
 		arg.Fixed,
 		arg.Cost,
 		arg.Duration,
+		arg.UnitMinutes,
+		arg.BufferBeforeMinutes,
+		arg.BufferAfterMinutes,
+		arg.MinNoticeMinutes,
+		arg.MaxAdvanceDays,
+		arg.FreeCancellationHours,
+		arg.LateCancellationPercent,
+		arg.NoShowPercent,
+		arg.DepositPercent,
+		arg.DepositAmount,
+		arg.DepositWindowHours,
 	)
 	var id int32
 	err := row.Scan(&id)
@@ -252,9 +406,9 @@ func (q *Queries) CreateBookingType(ctx context.Context, arg CreateBookingTypePa
 
 const createEmployee = `-- name: CreateEmployee :one
 INSERT INTO
-  employees (name, surname, email, title, description)
+  employees (name, surname, email, title, description, time_zone)
 VALUES
-  ($1, $2, $3, $4, $5)
+  ($1, $2, $3, $4, $5, $6)
 RETURNING
   id
 `
@@ -265,6 +419,7 @@ type CreateEmployeeParams struct {
 	Email       string `json:"email"`
 	Title       string `json:"title"`
 	Description string `json:"description"`
+	TimeZone    string `json:"time_zone"`
 }
 
 func (q *Queries) CreateEmployee(ctx context.Context, arg CreateEmployeeParams) (int32, error) {
@@ -274,6 +429,7 @@ func (q *Queries) CreateEmployee(ctx context.Context, arg CreateEmployeeParams)
 		arg.Email,
 		arg.Title,
 		arg.Description,
+		arg.TimeZone,
 	)
 	var id int32
 	err := row.Scan(&id)
@@ -294,6 +450,17 @@ func (q *Queries) DeleteAvailabilitySlot(ctx context.Context, id int32) (int32,
 	return id, err
 }
 
+const deleteAvailabilityTypes = `-- name: DeleteAvailabilityTypes :exec
+DELETE FROM availability_types
+WHERE
+  availability_id = ANY ($1::int[])
+`
+
+func (q *Queries) DeleteAvailabilityTypes(ctx context.Context, dollar_1 []int32) error {
+	_, err := q.db.Exec(ctx, deleteAvailabilityTypes, dollar_1)
+	return err
+}
+
 const deleteBooking = `-- name: DeleteBooking :one
 DELETE FROM bookings
 WHERE
@@ -366,7 +533,7 @@ func (q *Queries) FreeAvailabilitySlot(ctx context.Context, bookingID int32) err
 
 const getAllAvailabilitySlots = `-- name: GetAllAvailabilitySlots :many
 SELECT
-  id, employee_id, datetime, type_id, created_at, last_edited
+  id, employee_id, datetime, created_at, last_edited, rule_id, unit_minutes, capacity
 FROM
   availability
 `
@@ -384,9 +551,11 @@ func (q *Queries) GetAllAvailabilitySlots(ctx context.Context) ([]Availability,
 			&i.ID,
 			&i.EmployeeID,
 			&i.Datetime,
-			&i.TypeID,
 			&i.CreatedAt,
 			&i.LastEdited,
+			&i.RuleID,
+			&i.UnitMinutes,
+			&i.Capacity,
 		); err != nil {
 			return nil, err
 		}
@@ -400,7 +569,7 @@ func (q *Queries) GetAllAvailabilitySlots(ctx context.Context) ([]Availability,
 
 const getAllBookingTypes = `-- name: GetAllBookingTypes :many
 SELECT
-  id, title, description, fixed, cost, duration, created_at, last_edited
+  id, title, description, fixed, cost, duration, created_at, last_edited, unit_minutes, buffer_before_minutes, buffer_after_minutes, min_notice_minutes, max_advance_days, free_cancellation_hours, late_cancellation_percent, no_show_percent, deposit_percent, deposit_amount, deposit_window_hours
 FROM
   booking_types
 `
@@ -423,6 +592,17 @@ func (q *Queries) GetAllBookingTypes(ctx context.Context) ([]BookingType, error)
 			&i.Duration,
 			&i.CreatedAt,
 			&i.LastEdited,
+			&i.UnitMinutes,
+			&i.BufferBeforeMinutes,
+			&i.BufferAfterMinutes,
+			&i.MinNoticeMinutes,
+			&i.MaxAdvanceDays,
+			&i.FreeCancellationHours,
+			&i.LateCancellationPercent,
+			&i.NoShowPercent,
+			&i.DepositPercent,
+			&i.DepositAmount,
+			&i.DepositWindowHours,
 		); err != nil {
 			return nil, err
 		}
@@ -436,7 +616,7 @@ func (q *Queries) GetAllBookingTypes(ctx context.Context) ([]BookingType, error)
 
 const getAllBookings = `-- name: GetAllBookings :many
 SELECT
-  id, user_id, type_id, paid, cost, status, status_updated_at, status_updated_by, notes, created_at, last_edited
+  id, user_id, type_id, paid, cost, status, status_updated_at, status_updated_by, notes, created_at, last_edited, series_id, series_occurrence, free_cancellation_hours, late_cancellation_percent, no_show_percent, deposit, deposit_due_at
 FROM
   bookings
 `
@@ -462,6 +642,13 @@ func (q *Queries) GetAllBookings(ctx context.Context) ([]Booking, error) {
 			&i.Notes,
 			&i.CreatedAt,
 			&i.LastEdited,
+			&i.SeriesID,
+			&i.SeriesOccurrence,
+			&i.FreeCancellationHours,
+			&i.LateCancellationPercent,
+			&i.NoShowPercent,
+			&i.Deposit,
+			&i.DepositDueAt,
 		); err != nil {
 			return nil, err
 		}
@@ -475,10 +662,6 @@ func (q *Queries) GetAllBookings(ctx context.Context) ([]Booking, error) {
 
 const getAllBookingsWithJoin = `-- name: GetAllBookingsWithJoin :many
 WITH
-  unit AS (
-    SELECT
-      $1::integer AS minutes
-  ),
   cancelled_history AS (
     SELECT
       h.booking_id,
@@ -549,18 +732,13 @@ SELECT
   b.last_edited,
   CASE
     WHEN b.status = 'cancelled' THEN ch.cancelled_start_time
-    ELSE MIN(a.datetime)::timestamp
+    ELSE MIN(a.datetime)::timestamptz
   END AS start_time,
   CASE
     WHEN b.status = 'cancelled' THEN ch.cancelled_end_time
     ELSE (
-      MAX(a.datetime) + (
-        SELECT
-          minutes
-        FROM
-          unit
-      ) * INTERVAL '1 minute'
-    )::timestamp
+      MAX(a.datetime + a.unit_minutes * INTERVAL '1 minute')
+    )::timestamptz
   END AS end_time,
   CASE
     WHEN b.status = 'cancelled' THEN ch.cancelled_employee_id::int
@@ -619,33 +797,33 @@ ORDER BY
 `
 
 type GetAllBookingsWithJoinRow struct {
-	ID              int32            `json:"id"`
-	UserID          int32            `json:"user_id"`
-	UserName        string           `json:"user_name"`
-	UserSurname     string           `json:"user_surname"`
-	UserEmail       string           `json:"user_email"`
-	UserLastLogin   pgtype.Timestamp `json:"user_last_login"`
-	TypeID          int32            `json:"type_id"`
-	TypeTitle       string           `json:"type_title"`
-	TypeDuration    int32            `json:"type_duration"`
-	Paid            bool             `json:"paid"`
-	Cost            int32            `json:"cost"`
-	Status          BookingStatus    `json:"status"`
-	StatusUpdatedAt pgtype.Timestamp `json:"status_updated_at"`
-	StatusUpdatedBy string           `json:"status_updated_by"`
-	Notes           pgtype.Text      `json:"notes"`
-	CreatedAt       pgtype.Timestamp `json:"created_at"`
-	LastEdited      pgtype.Timestamp `json:"last_edited"`
-	StartTime       pgtype.Timestamp `json:"start_time"`
-	EndTime         pgtype.Timestamp `json:"end_time"`
-	EmployeeID      int32            `json:"employee_id"`
-	EmployeeName    string           `json:"employee_name"`
-	EmployeeSurname string           `json:"employee_surname"`
-	EmployeeEmail   string           `json:"employee_email"`
-}
-
-func (q *Queries) GetAllBookingsWithJoin(ctx context.Context, dollar_1 int32) ([]GetAllBookingsWithJoinRow, error) {
-	rows, err := q.db.Query(ctx, getAllBookingsWithJoin, dollar_1)
+	ID              int32              `json:"id"`
+	UserID          int32              `json:"user_id"`
+	UserName        string             `json:"user_name"`
+	UserSurname     string             `json:"user_surname"`
+	UserEmail       string             `json:"user_email"`
+	UserLastLogin   pgtype.Timestamptz `json:"user_last_login"`
+	TypeID          int32              `json:"type_id"`
+	TypeTitle       string             `json:"type_title"`
+	TypeDuration    int32              `json:"type_duration"`
+	Paid            bool               `json:"paid"`
+	Cost            int32              `json:"cost"`
+	Status          BookingStatus      `json:"status"`
+	StatusUpdatedAt pgtype.Timestamptz `json:"status_updated_at"`
+	StatusUpdatedBy string             `json:"status_updated_by"`
+	Notes           pgtype.Text        `json:"notes"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	LastEdited      pgtype.Timestamptz `json:"last_edited"`
+	StartTime       pgtype.Timestamptz `json:"start_time"`
+	EndTime         pgtype.Timestamptz `json:"end_time"`
+	EmployeeID      int32              `json:"employee_id"`
+	EmployeeName    string             `json:"employee_name"`
+	EmployeeSurname string             `json:"employee_surname"`
+	EmployeeEmail   string             `json:"employee_email"`
+}
+
+func (q *Queries) GetAllBookingsWithJoin(ctx context.Context) ([]GetAllBookingsWithJoinRow, error) {
+	rows, err := q.db.Query(ctx, getAllBookingsWithJoin)
 	if err != nil {
 		return nil, err
 	}
@@ -690,10 +868,6 @@ func (q *Queries) GetAllBookingsWithJoin(ctx context.Context, dollar_1 int32) ([
 
 const getAllBookingsWithJoinByID = `-- name: GetAllBookingsWithJoinByID :many
 WITH
-  unit AS (
-    SELECT
-      $2::integer AS minutes
-  ),
   cancelled_history AS (
     SELECT
       h.booking_id,
@@ -764,18 +938,13 @@ SELECT
   b.last_edited,
   CASE
     WHEN b.status = 'cancelled' THEN ch.cancelled_start_time
-    ELSE MIN(a.datetime)::timestamp
+    ELSE MIN(a.datetime)::timestamptz
   END AS start_time,
   CASE
     WHEN b.status = 'cancelled' THEN ch.cancelled_end_time
     ELSE (
-      MAX(a.datetime) + (
-        SELECT
-          minutes
-        FROM
-          unit
-      ) * INTERVAL '1 minute'
-    )::timestamp
+      MAX(a.datetime + a.unit_minutes * INTERVAL '1 minute')
+    )::timestamptz
   END AS end_time,
   CASE
     WHEN b.status = 'cancelled' THEN ch.cancelled_employee_id::int
@@ -835,39 +1004,34 @@ ORDER BY
   b.created_at DESC
 `
 
-type GetAllBookingsWithJoinByIDParams struct {
-	UserID  int32 `json:"user_id"`
-	Column2 int32 `json:"column_2"`
+type GetAllBookingsWithJoinByIDRow struct {
+	ID              int32              `json:"id"`
+	UserID          int32              `json:"user_id"`
+	UserName        string             `json:"user_name"`
+	UserSurname     string             `json:"user_surname"`
+	UserEmail       string             `json:"user_email"`
+	UserLastLogin   pgtype.Timestamptz `json:"user_last_login"`
+	TypeID          int32              `json:"type_id"`
+	TypeTitle       string             `json:"type_title"`
+	TypeDuration    int32              `json:"type_duration"`
+	Paid            bool               `json:"paid"`
+	Cost            int32              `json:"cost"`
+	Status          BookingStatus      `json:"status"`
+	StatusUpdatedAt pgtype.Timestamptz `json:"status_updated_at"`
+	StatusUpdatedBy string             `json:"status_updated_by"`
+	Notes           pgtype.Text        `json:"notes"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	LastEdited      pgtype.Timestamptz `json:"last_edited"`
+	StartTime       pgtype.Timestamptz `json:"start_time"`
+	EndTime         pgtype.Timestamptz `json:"end_time"`
+	EmployeeID      int32              `json:"employee_id"`
+	EmployeeName    string             `json:"employee_name"`
+	EmployeeSurname string             `json:"employee_surname"`
+	EmployeeEmail   string             `json:"employee_email"`
 }
 
-type GetAllBookingsWithJoinByIDRow struct {
-	ID              int32            `json:"id"`
-	UserID          int32            `json:"user_id"`
-	UserName        string           `json:"user_name"`
-	UserSurname     string           `json:"user_surname"`
-	UserEmail       string           `json:"user_email"`
-	UserLastLogin   pgtype.Timestamp `json:"user_last_login"`
-	TypeID          int32            `json:"type_id"`
-	TypeTitle       string           `json:"type_title"`
-	TypeDuration    int32            `json:"type_duration"`
-	Paid            bool             `json:"paid"`
-	Cost            int32            `json:"cost"`
-	Status          BookingStatus    `json:"status"`
-	StatusUpdatedAt pgtype.Timestamp `json:"status_updated_at"`
-	StatusUpdatedBy string           `json:"status_updated_by"`
-	Notes           pgtype.Text      `json:"notes"`
-	CreatedAt       pgtype.Timestamp `json:"created_at"`
-	LastEdited      pgtype.Timestamp `json:"last_edited"`
-	StartTime       pgtype.Timestamp `json:"start_time"`
-	EndTime         pgtype.Timestamp `json:"end_time"`
-	EmployeeID      int32            `json:"employee_id"`
-	EmployeeName    string           `json:"employee_name"`
-	EmployeeSurname string           `json:"employee_surname"`
-	EmployeeEmail   string           `json:"employee_email"`
-}
-
-func (q *Queries) GetAllBookingsWithJoinByID(ctx context.Context, arg GetAllBookingsWithJoinByIDParams) ([]GetAllBookingsWithJoinByIDRow, error) {
-	rows, err := q.db.Query(ctx, getAllBookingsWithJoinByID, arg.UserID, arg.Column2)
+func (q *Queries) GetAllBookingsWithJoinByID(ctx context.Context, userID int32) ([]GetAllBookingsWithJoinByIDRow, error) {
+	rows, err := q.db.Query(ctx, getAllBookingsWithJoinByID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -912,17 +1076,86 @@ func (q *Queries) GetAllBookingsWithJoinByID(ctx context.Context, arg GetAllBook
 
 const getAllFreeAvailabilitySlots = `-- name: GetAllFreeAvailabilitySlots :many
 SELECT
-  a.id, a.employee_id, a.datetime, a.type_id, a.created_at, a.last_edited
+  a.id, a.employee_id, a.datetime, a.created_at, a.last_edited, a.rule_id, a.unit_minutes, a.capacity
 FROM
   availability a
 WHERE
-  NOT EXISTS (
+  -- there is a seat that isnt booked or held
+  (
     SELECT
-      1
+      COUNT(*)
     FROM
       booking_slots bs
     WHERE
       bs.availability_slot_id = a.id
+  ) + (
+    SELECT
+      COUNT(*)
+    FROM
+      slot_hold_slots hs
+      JOIN slot_holds h ON h.id = hs.hold_id
+    WHERE
+      hs.availability_slot_id = a.id
+      AND h.expires_at > CURRENT_TIMESTAMP
+  ) < a.capacity
+  -- within the buffers of one of the employee's other bookings, bookings on
+  -- this slot are taking the other seats
+  AND NOT EXISTS (
+    SELECT
+      1
+    FROM
+      booking_slots bs
+      JOIN availability ba ON ba.id = bs.availability_slot_id
+      JOIN bookings b ON b.id = bs.booking_id
+      JOIN booking_types bt ON bt.id = b.type_id
+    WHERE
+      ba.employee_id = a.employee_id
+      AND a.datetime < ba.datetime + (ba.unit_minutes + bt.buffer_after_minutes) * INTERVAL '1 minute'
+      AND a.datetime + a.unit_minutes * INTERVAL '1 minute' > ba.datetime - bt.buffer_before_minutes * INTERVAL '1 minute'
+      AND NOT EXISTS (
+        SELECT
+          1
+        FROM
+          booking_slots own
+        WHERE
+          own.booking_id = b.id
+          AND own.availability_slot_id = a.id
+      )
+  )
+  -- bookable now for at least one of the types it is offered for
+  AND EXISTS (
+    SELECT
+      1
+    FROM
+      availability_types t
+      JOIN booking_types bt ON bt.id = t.type_id
+    WHERE
+      t.availability_id = a.id
+      AND a.datetime >= CURRENT_TIMESTAMP + bt.min_notice_minutes * INTERVAL '1 minute'
+      AND (
+        bt.max_advance_days = 0
+        OR a.datetime <= CURRENT_TIMESTAMP + bt.max_advance_days * INTERVAL '1 day'
+      )
+  )
+  -- the employee is on leave or the business is closed
+  AND NOT EXISTS (
+    SELECT
+      1
+    FROM
+      employee_time_off o
+    WHERE
+      o.employee_id = a.employee_id
+      AND o.start_time < a.datetime + a.unit_minutes * INTERVAL '1 minute'
+      AND o.end_time > a.datetime
+  )
+  AND NOT EXISTS (
+    SELECT
+      1
+    FROM
+      closures c
+      JOIN employees e ON e.id = a.employee_id
+    WHERE
+      (a.datetime AT TIME ZONE e.time_zone)::date BETWEEN c.start_date AND c.end_date
   )
 `
 
@@ -939,9 +1172,11 @@ func (q *Queries) GetAllFreeAvailabilitySlots(ctx context.Context) ([]Availabili
 			&i.ID,
 			&i.EmployeeID,
 			&i.Datetime,
-			&i.TypeID,
 			&i.CreatedAt,
 			&i.LastEdited,
+			&i.RuleID,
+			&i.UnitMinutes,
+			&i.Capacity,
 		); err != nil {
 			return nil, err
 		}
@@ -955,7 +1190,7 @@ func (q *Queries) GetAllFreeAvailabilitySlots(ctx context.Context) ([]Availabili
 
 const getAvailabilitySlotById = `-- name: GetAvailabilitySlotById :one
 SELECT
-  id, employee_id, datetime, type_id, created_at, last_edited
+  id, employee_id, datetime, created_at, last_edited, rule_id, unit_minutes, capacity
 FROM
   availability
 WHERE
@@ -971,20 +1206,24 @@ func (q *Queries) GetAvailabilitySlotById(ctx context.Context, id int32) (Availa
 		&i.ID,
 		&i.EmployeeID,
 		&i.Datetime,
-		&i.TypeID,
 		&i.CreatedAt,
 		&i.LastEdited,
+		&i.RuleID,
+		&i.UnitMinutes,
+		&i.Capacity,
 	)
 	return i, err
 }
 
 const getAvailabilitySlotByIds = `-- name: GetAvailabilitySlotByIds :many
 SELECT
-  id, employee_id, datetime, type_id, created_at, last_edited
+  id, employee_id, datetime, created_at, last_edited, rule_id, unit_minutes, capacity
 FROM
   availability
 WHERE
   id = ANY ($1::int[])
+ORDER BY
+  datetime
 `
 
 func (q *Queries) GetAvailabilitySlotByIds(ctx context.Context, dollar_1 []int32) ([]Availability, error) {
@@ -1000,9 +1239,153 @@ func (q *Queries) GetAvailabilitySlotByIds(ctx context.Context, dollar_1 []int32
 			&i.ID,
 			&i.EmployeeID,
 			&i.Datetime,
+			&i.CreatedAt,
+			&i.LastEdited,
+			&i.RuleID,
+			&i.UnitMinutes,
+			&i.Capacity,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAvailabilityTypes = `-- name: GetAvailabilityTypes :many
+SELECT
+  availability_id, type_id, rule_id
+FROM
+  availability_types
+WHERE
+  availability_id = ANY ($1::int[])
+ORDER BY
+  availability_id,
+  type_id
+`
+
+func (q *Queries) GetAvailabilityTypes(ctx context.Context, dollar_1 []int32) ([]AvailabilityType, error) {
+	rows, err := q.db.Query(ctx, getAvailabilityTypes, dollar_1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AvailabilityType
+	for rows.Next() {
+		var i AvailabilityType
+		if err := rows.Scan(&i.AvailabilityID, &i.TypeID, &i.RuleID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBookedTimes = `-- name: GetBookedTimes :many
+SELECT
+  a.employee_id,
+  b.type_id,
+  MIN(a.datetime)::timestamptz AS start_time,
+  MAX(a.datetime + a.unit_minutes * INTERVAL '1 minute')::timestamptz AS end_time,
+  bt.buffer_before_minutes,
+  bt.buffer_after_minutes
+FROM
+  bookings b
+  JOIN booking_slots bs ON bs.booking_id = b.id
+  JOIN availability a ON a.id = bs.availability_slot_id
+  JOIN booking_types bt ON bt.id = b.type_id
+WHERE
+  a.employee_id = ANY ($1::int[])
+GROUP BY
+  b.id,
+  a.employee_id,
+  bt.buffer_before_minutes,
+  bt.buffer_after_minutes
+HAVING
+  MIN(a.datetime) < $2::timestamptz
+  AND MAX(a.datetime + a.unit_minutes * INTERVAL '1 minute') > $3::timestamptz
+ORDER BY
+  start_time
+`
+
+type GetBookedTimesParams struct {
+	EmployeeIds []int32            `json:"employee_ids"`
+	ToTime      pgtype.Timestamptz `json:"to_time"`
+	FromTime    pgtype.Timestamptz `json:"from_time"`
+}
+
+type GetBookedTimesRow struct {
+	EmployeeID          int32              `json:"employee_id"`
+	TypeID              int32              `json:"type_id"`
+	StartTime           pgtype.Timestamptz `json:"start_time"`
+	EndTime             pgtype.Timestamptz `json:"end_time"`
+	BufferBeforeMinutes int32              `json:"buffer_before_minutes"`
+	BufferAfterMinutes  int32              `json:"buffer_after_minutes"`
+}
+
+func (q *Queries) GetBookedTimes(ctx context.Context, arg GetBookedTimesParams) ([]GetBookedTimesRow, error) {
+	rows, err := q.db.Query(ctx, getBookedTimes, arg.EmployeeIds, arg.ToTime, arg.FromTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBookedTimesRow
+	for rows.Next() {
+		var i GetBookedTimesRow
+		if err := rows.Scan(
+			&i.EmployeeID,
 			&i.TypeID,
+			&i.StartTime,
+			&i.EndTime,
+			&i.BufferBeforeMinutes,
+			&i.BufferAfterMinutes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBookingAvailabilitySlots = `-- name: GetBookingAvailabilitySlots :many
+SELECT
+  a.id, a.employee_id, a.datetime, a.created_at, a.last_edited, a.rule_id, a.unit_minutes, a.capacity
+FROM
+  availability a
+  JOIN booking_slots bs ON bs.availability_slot_id = a.id
+WHERE
+  bs.booking_id = $1
+ORDER BY
+  a.datetime
+`
+
+func (q *Queries) GetBookingAvailabilitySlots(ctx context.Context, bookingID int32) ([]Availability, error) {
+	rows, err := q.db.Query(ctx, getBookingAvailabilitySlots, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Availability
+	for rows.Next() {
+		var i Availability
+		if err := rows.Scan(
+			&i.ID,
+			&i.EmployeeID,
+			&i.Datetime,
 			&i.CreatedAt,
 			&i.LastEdited,
+			&i.RuleID,
+			&i.UnitMinutes,
+			&i.Capacity,
 		); err != nil {
 			return nil, err
 		}
@@ -1027,6 +1410,13 @@ SELECT
   b.notes,
   b.created_at,
   b.last_edited,
+  b.series_id,
+  b.series_occurrence,
+  b.free_cancellation_hours,
+  b.late_cancellation_percent,
+  b.no_show_percent,
+  b.deposit,
+  b.deposit_due_at,
   array_agg(
     bs.availability_slot_id
     ORDER BY
@@ -1044,18 +1434,25 @@ LIMIT
 `
 
 type GetBookingByIdRow struct {
-	ID              int32            `json:"id"`
-	UserID          int32            `json:"user_id"`
-	TypeID          int32            `json:"type_id"`
-	Paid            bool             `json:"paid"`
-	Cost            int32            `json:"cost"`
-	Status          BookingStatus    `json:"status"`
-	StatusUpdatedAt pgtype.Timestamp `json:"status_updated_at"`
-	StatusUpdatedBy string           `json:"status_updated_by"`
-	Notes           pgtype.Text      `json:"notes"`
-	CreatedAt       pgtype.Timestamp `json:"created_at"`
-	LastEdited      pgtype.Timestamp `json:"last_edited"`
-	SlotIds         []int32          `json:"slot_ids"`
+	ID                      int32              `json:"id"`
+	UserID                  int32              `json:"user_id"`
+	TypeID                  int32              `json:"type_id"`
+	Paid                    bool               `json:"paid"`
+	Cost                    int32              `json:"cost"`
+	Status                  BookingStatus      `json:"status"`
+	StatusUpdatedAt         pgtype.Timestamptz `json:"status_updated_at"`
+	StatusUpdatedBy         string             `json:"status_updated_by"`
+	Notes                   pgtype.Text        `json:"notes"`
+	CreatedAt               pgtype.Timestamptz `json:"created_at"`
+	LastEdited              pgtype.Timestamptz `json:"last_edited"`
+	SeriesID                pgtype.Int4        `json:"series_id"`
+	SeriesOccurrence        pgtype.Int4        `json:"series_occurrence"`
+	FreeCancellationHours   int32              `json:"free_cancellation_hours"`
+	LateCancellationPercent int32              `json:"late_cancellation_percent"`
+	NoShowPercent           int32              `json:"no_show_percent"`
+	Deposit                 int32              `json:"deposit"`
+	DepositDueAt            pgtype.Timestamptz `json:"deposit_due_at"`
+	SlotIds                 []int32            `json:"slot_ids"`
 }
 
 func (q *Queries) GetBookingById(ctx context.Context, id int32) (GetBookingByIdRow, error) {
@@ -1073,11 +1470,49 @@ func (q *Queries) GetBookingById(ctx context.Context, id int32) (GetBookingByIdR
 		&i.Notes,
 		&i.CreatedAt,
 		&i.LastEdited,
+		&i.SeriesID,
+		&i.SeriesOccurrence,
+		&i.FreeCancellationHours,
+		&i.LateCancellationPercent,
+		&i.NoShowPercent,
+		&i.Deposit,
+		&i.DepositDueAt,
 		&i.SlotIds,
 	)
 	return i, err
 }
 
+const getBookingSlotIds = `-- name: GetBookingSlotIds :many
+SELECT
+  availability_slot_id
+FROM
+  booking_slots
+WHERE
+  booking_id = $1
+ORDER BY
+  availability_slot_id
+`
+
+func (q *Queries) GetBookingSlotIds(ctx context.Context, bookingID int32) ([]int32, error) {
+	rows, err := q.db.Query(ctx, getBookingSlotIds, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var availability_slot_id int32
+		if err := rows.Scan(&availability_slot_id); err != nil {
+			return nil, err
+		}
+		items = append(items, availability_slot_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getBookingSlotsFromAvailability = `-- name: GetBookingSlotsFromAvailability :many
 SELECT DISTINCT
   booking_id
@@ -1109,7 +1544,7 @@ func (q *Queries) GetBookingSlotsFromAvailability(ctx context.Context, dollar_1
 
 const getBookingTypeById = `-- name: GetBookingTypeById :one
 SELECT
-  id, title, description, fixed, cost, duration, created_at, last_edited
+  id, title, description, fixed, cost, duration, created_at, last_edited, unit_minutes, buffer_before_minutes, buffer_after_minutes, min_notice_minutes, max_advance_days, free_cancellation_hours, late_cancellation_percent, no_show_percent, deposit_percent, deposit_amount, deposit_window_hours
 FROM
   booking_types
 WHERE
@@ -1130,16 +1565,23 @@ func (q *Queries) GetBookingTypeById(ctx context.Context, id int32) (BookingType
 		&i.Duration,
 		&i.CreatedAt,
 		&i.LastEdited,
+		&i.UnitMinutes,
+		&i.BufferBeforeMinutes,
+		&i.BufferAfterMinutes,
+		&i.MinNoticeMinutes,
+		&i.MaxAdvanceDays,
+		&i.FreeCancellationHours,
+		&i.LateCancellationPercent,
+		&i.NoShowPercent,
+		&i.DepositPercent,
+		&i.DepositAmount,
+		&i.DepositWindowHours,
 	)
 	return i, err
 }
 
 const getBookingWithJoin = `-- name: GetBookingWithJoin :one
 WITH
-  unit AS (
-    SELECT
-      $1::integer AS minutes
-  ),
   cancelled_history AS (
     SELECT
       h.booking_id,
@@ -1210,18 +1652,13 @@ SELECT
   b.last_edited,
   CASE
     WHEN b.status = 'cancelled' THEN ch.cancelled_start_time
-    ELSE MIN(a.datetime)::timestamp
+    ELSE MIN(a.datetime)::timestamptz
   END AS start_time,
   CASE
     WHEN b.status = 'cancelled' THEN ch.cancelled_end_time
     ELSE (
-      MAX(a.datetime) + (
-        SELECT
-          minutes
-        FROM
-          unit
-      ) * INTERVAL '1 minute'
-    )::timestamp
+      MAX(a.datetime + a.unit_minutes * INTERVAL '1 minute')
+    )::timestamptz
   END AS end_time,
   CASE
     WHEN b.status = 'cancelled' THEN ch.cancelled_employee_id::int
@@ -1248,7 +1685,7 @@ FROM
   LEFT JOIN cancelled_history ch ON b.id = ch.booking_id
   LEFT JOIN employees e ON e.id = a.employee_id
 WHERE
-  b.id = $2
+  b.id = $1
 GROUP BY
   b.id,
   b.user_id,
@@ -1281,39 +1718,34 @@ ORDER BY
   b.created_at DESC
 `
 
-type GetBookingWithJoinParams struct {
-	Column1 int32 `json:"column_1"`
-	ID      int32 `json:"id"`
+type GetBookingWithJoinRow struct {
+	ID              int32              `json:"id"`
+	UserID          int32              `json:"user_id"`
+	UserName        string             `json:"user_name"`
+	UserSurname     string             `json:"user_surname"`
+	UserEmail       string             `json:"user_email"`
+	UserLastLogin   pgtype.Timestamptz `json:"user_last_login"`
+	TypeID          int32              `json:"type_id"`
+	TypeTitle       string             `json:"type_title"`
+	TypeDuration    int32              `json:"type_duration"`
+	Paid            bool               `json:"paid"`
+	Cost            int32              `json:"cost"`
+	Status          BookingStatus      `json:"status"`
+	StatusUpdatedAt pgtype.Timestamptz `json:"status_updated_at"`
+	StatusUpdatedBy string             `json:"status_updated_by"`
+	Notes           pgtype.Text        `json:"notes"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	LastEdited      pgtype.Timestamptz `json:"last_edited"`
+	StartTime       pgtype.Timestamptz `json:"start_time"`
+	EndTime         pgtype.Timestamptz `json:"end_time"`
+	EmployeeID      int32              `json:"employee_id"`
+	EmployeeName    string             `json:"employee_name"`
+	EmployeeSurname string             `json:"employee_surname"`
+	EmployeeEmail   string             `json:"employee_email"`
 }
 
-type GetBookingWithJoinRow struct {
-	ID              int32            `json:"id"`
-	UserID          int32            `json:"user_id"`
-	UserName        string           `json:"user_name"`
-	UserSurname     string           `json:"user_surname"`
-	UserEmail       string           `json:"user_email"`
-	UserLastLogin   pgtype.Timestamp `json:"user_last_login"`
-	TypeID          int32            `json:"type_id"`
-	TypeTitle       string           `json:"type_title"`
-	TypeDuration    int32            `json:"type_duration"`
-	Paid            bool             `json:"paid"`
-	Cost            int32            `json:"cost"`
-	Status          BookingStatus    `json:"status"`
-	StatusUpdatedAt pgtype.Timestamp `json:"status_updated_at"`
-	StatusUpdatedBy string           `json:"status_updated_by"`
-	Notes           pgtype.Text      `json:"notes"`
-	CreatedAt       pgtype.Timestamp `json:"created_at"`
-	LastEdited      pgtype.Timestamp `json:"last_edited"`
-	StartTime       pgtype.Timestamp `json:"start_time"`
-	EndTime         pgtype.Timestamp `json:"end_time"`
-	EmployeeID      int32            `json:"employee_id"`
-	EmployeeName    string           `json:"employee_name"`
-	EmployeeSurname string           `json:"employee_surname"`
-	EmployeeEmail   string           `json:"employee_email"`
-}
-
-func (q *Queries) GetBookingWithJoin(ctx context.Context, arg GetBookingWithJoinParams) (GetBookingWithJoinRow, error) {
-	row := q.db.QueryRow(ctx, getBookingWithJoin, arg.Column1, arg.ID)
+func (q *Queries) GetBookingWithJoin(ctx context.Context, id int32) (GetBookingWithJoinRow, error) {
+	row := q.db.QueryRow(ctx, getBookingWithJoin, id)
 	var i GetBookingWithJoinRow
 	err := row.Scan(
 		&i.ID,
@@ -1343,16 +1775,330 @@ func (q *Queries) GetBookingWithJoin(ctx context.Context, arg GetBookingWithJoin
 	return i, err
 }
 
-const postManualPayment = `-- name: PostManualPayment :exec
+const getOverlappingAvailabilitySlots = `-- name: GetOverlappingAvailabilitySlots :many
+SELECT
+  a.id, a.employee_id, a.datetime, a.created_at, a.last_edited, a.rule_id, a.unit_minutes, a.capacity
+FROM
+  availability a
+WHERE
+  a.employee_id = $1
+  AND a.datetime < $2::timestamptz
+  AND a.datetime + a.unit_minutes * INTERVAL '1 minute' > $3::timestamptz
+  AND NOT a.id = ANY ($4::int[])
+ORDER BY
+  a.datetime
+`
+
+type GetOverlappingAvailabilitySlotsParams struct {
+	EmployeeID int32              `json:"employee_id"`
+	EndTime    pgtype.Timestamptz `json:"end_time"`
+	StartTime  pgtype.Timestamptz `json:"start_time"`
+	ExcludeIds []int32            `json:"exclude_ids"`
+}
+
+func (q *Queries) GetOverlappingAvailabilitySlots(ctx context.Context, arg GetOverlappingAvailabilitySlotsParams) ([]Availability, error) {
+	rows, err := q.db.Query(ctx, getOverlappingAvailabilitySlots,
+		arg.EmployeeID,
+		arg.EndTime,
+		arg.StartTime,
+		arg.ExcludeIds,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Availability
+	for rows.Next() {
+		var i Availability
+		if err := rows.Scan(
+			&i.ID,
+			&i.EmployeeID,
+			&i.Datetime,
+			&i.CreatedAt,
+			&i.LastEdited,
+			&i.RuleID,
+			&i.UnitMinutes,
+			&i.Capacity,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUnpaidDepositBookings = `-- name: GetUnpaidDepositBookings :many
+SELECT
+  b.id
+FROM
+  bookings b
+WHERE
+  b.status IN ('created', 'rescheduled')
+  AND b.deposit > 0
+  AND b.deposit_due_at <= CURRENT_TIMESTAMP
+  AND (
+    SELECT
+      COALESCE(
+        SUM(l.amount) FILTER (
+          WHERE
+            l.kind = 'payment'
+        ),
+        0
+      ) - COALESCE(
+        SUM(l.amount) FILTER (
+          WHERE
+            l.kind = 'refund'
+        ),
+        0
+      )
+    FROM
+      booking_ledger_entries l
+    WHERE
+      l.booking_id = b.id
+  ) < b.deposit
+ORDER BY
+  b.deposit_due_at
+FOR UPDATE OF
+  b SKIP LOCKED
+`
+
+func (q *Queries) GetUnpaidDepositBookings(ctx context.Context) ([]int32, error) {
+	rows, err := q.db.Query(ctx, getUnpaidDepositBookings)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockBooking = `-- name: LockBooking :one
+SELECT
+  id
+FROM
+  bookings
+WHERE
+  id = $1
+FOR UPDATE
+`
+
+func (q *Queries) LockBooking(ctx context.Context, id int32) (int32, error) {
+	row := q.db.QueryRow(ctx, lockBooking, id)
+	err := row.Scan(&id)
+	return id, err
+}
+
+const lockEmployee = `-- name: LockEmployee :one
+SELECT
+  id
+FROM
+  employees
+WHERE
+  id = $1
+FOR UPDATE
+`
+
+func (q *Queries) LockEmployee(ctx context.Context, id int32) (int32, error) {
+	row := q.db.QueryRow(ctx, lockEmployee, id)
+	err := row.Scan(&id)
+	return id, err
+}
+
+const rescheduleBooking = `-- name: RescheduleBooking :exec
 UPDATE bookings
 SET
-  paid = true
+  cost = $2,
+  status = 'rescheduled',
+  status_updated_by = $3,
+  status_updated_at = DEFAULT,
+  last_edited = DEFAULT
 WHERE
   id = $1
 `
 
-func (q *Queries) PostManualPayment(ctx context.Context, id int32) error {
-	_, err := q.db.Exec(ctx, postManualPayment, id)
+type RescheduleBookingParams struct {
+	ID              int32  `json:"id"`
+	Cost            int32  `json:"cost"`
+	StatusUpdatedBy string `json:"status_updated_by"`
+}
+
+func (q *Queries) RescheduleBooking(ctx context.Context, arg RescheduleBookingParams) error {
+	_, err := q.db.Exec(ctx, rescheduleBooking, arg.ID, arg.Cost, arg.StatusUpdatedBy)
+	return err
+}
+
+const searchFreeAvailabilitySlots = `-- name: SearchFreeAvailabilitySlots :many
+SELECT
+  a.id, a.employee_id, a.datetime, a.created_at, a.last_edited, a.rule_id, a.unit_minutes, a.capacity
+FROM
+  availability a
+WHERE
+  EXISTS (
+    SELECT
+      1
+    FROM
+      availability_types t
+    WHERE
+      t.availability_id = a.id
+      AND t.type_id = $1
+  )
+  AND a.datetime >= GREATEST($2::timestamptz, CURRENT_TIMESTAMP)
+  AND a.datetime < $3::timestamptz
+  AND (
+    $4::int IS NULL
+    OR a.employee_id = $4::int
+  )
+  -- there is a seat that isnt booked or held
+  AND (
+    SELECT
+      COUNT(*)
+    FROM
+      booking_slots bs
+    WHERE
+      bs.availability_slot_id = a.id
+  ) + (
+    SELECT
+      COUNT(*)
+    FROM
+      slot_hold_slots hs
+      JOIN slot_holds h ON h.id = hs.hold_id
+    WHERE
+      hs.availability_slot_id = a.id
+      AND h.expires_at > CURRENT_TIMESTAMP
+  ) < a.capacity
+  -- the employee is on leave or the business is closed
+  AND NOT EXISTS (
+    SELECT
+      1
+    FROM
+      employee_time_off o
+    WHERE
+      o.employee_id = a.employee_id
+      AND o.start_time < a.datetime + a.unit_minutes * INTERVAL '1 minute'
+      AND o.end_time > a.datetime
+  )
+  AND NOT EXISTS (
+    SELECT
+      1
+    FROM
+      closures c
+      JOIN employees e ON e.id = a.employee_id
+    WHERE
+      (a.datetime AT TIME ZONE e.time_zone)::date BETWEEN c.start_date AND c.end_date
+  )
+ORDER BY
+  a.employee_id,
+  a.datetime
+`
+
+type SearchFreeAvailabilitySlotsParams struct {
+	TypeID     int32              `json:"type_id"`
+	FromTime   pgtype.Timestamptz `json:"from_time"`
+	ToTime     pgtype.Timestamptz `json:"to_time"`
+	EmployeeID pgtype.Int4        `json:"employee_id"`
+}
+
+func (q *Queries) SearchFreeAvailabilitySlots(ctx context.Context, arg SearchFreeAvailabilitySlotsParams) ([]Availability, error) {
+	rows, err := q.db.Query(ctx, searchFreeAvailabilitySlots,
+		arg.TypeID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.EmployeeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Availability
+	for rows.Next() {
+		var i Availability
+		if err := rows.Scan(
+			&i.ID,
+			&i.EmployeeID,
+			&i.Datetime,
+			&i.CreatedAt,
+			&i.LastEdited,
+			&i.RuleID,
+			&i.UnitMinutes,
+			&i.Capacity,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setBookingDeposit = `-- name: SetBookingDeposit :exec
+UPDATE bookings
+SET
+  deposit = $2,
+  deposit_due_at = $3
+WHERE
+  id = $1
+`
+
+type SetBookingDepositParams struct {
+	ID           int32              `json:"id"`
+	Deposit      int32              `json:"deposit"`
+	DepositDueAt pgtype.Timestamptz `json:"deposit_due_at"`
+}
+
+func (q *Queries) SetBookingDeposit(ctx context.Context, arg SetBookingDepositParams) error {
+	_, err := q.db.Exec(ctx, setBookingDeposit, arg.ID, arg.Deposit, arg.DepositDueAt)
+	return err
+}
+
+const setBookingPaid = `-- name: SetBookingPaid :exec
+UPDATE bookings
+SET
+  paid = $2
+WHERE
+  id = $1
+`
+
+type SetBookingPaidParams struct {
+	ID   int32 `json:"id"`
+	Paid bool  `json:"paid"`
+}
+
+func (q *Queries) SetBookingPaid(ctx context.Context, arg SetBookingPaidParams) error {
+	_, err := q.db.Exec(ctx, setBookingPaid, arg.ID, arg.Paid)
+	return err
+}
+
+const updateAvailabilityCapacity = `-- name: UpdateAvailabilityCapacity :exec
+UPDATE availability
+SET
+  capacity = $2,
+  last_edited = DEFAULT
+WHERE
+  id = ANY ($1::int[])
+`
+
+type UpdateAvailabilityCapacityParams struct {
+	Column1  []int32 `json:"column_1"`
+	Capacity int32   `json:"capacity"`
+}
+
+func (q *Queries) UpdateAvailabilityCapacity(ctx context.Context, arg UpdateAvailabilityCapacityParams) error {
+	_, err := q.db.Exec(ctx, updateAvailabilityCapacity, arg.Column1, arg.Capacity)
 	return err
 }
 
@@ -1362,7 +2108,6 @@ SET
   id = $1,
   employee_id = $2,
   datetime = $3,
-  type_id = $4,
   created_at = DEFAULT,
   last_edited = DEFAULT
 WHERE
@@ -1372,19 +2117,13 @@ RETURNING
 `
 
 type UpdateAvailabilitySlotParams struct {
-	ID         int32            `json:"id"`
-	EmployeeID int32            `json:"employee_id"`
-	Datetime   pgtype.Timestamp `json:"datetime"`
-	TypeID     int32            `json:"type_id"`
+	ID         int32              `json:"id"`
+	EmployeeID int32              `json:"employee_id"`
+	Datetime   pgtype.Timestamptz `json:"datetime"`
 }
 
 func (q *Queries) UpdateAvailabilitySlot(ctx context.Context, arg UpdateAvailabilitySlotParams) (int32, error) {
-	row := q.db.QueryRow(ctx, updateAvailabilitySlot,
-		arg.ID,
-		arg.EmployeeID,
-		arg.Datetime,
-		arg.TypeID,
-	)
+	row := q.db.QueryRow(ctx, updateAvailabilitySlot, arg.ID, arg.EmployeeID, arg.Datetime)
 	var id int32
 	err := row.Scan(&id)
 	return id, err
@@ -1395,10 +2134,9 @@ UPDATE bookings
 SET
   user_id = $2,
   type_id = $3,
-  paid = $4,
-  cost = $5,
-  notes = $6,
-  status_updated_by = $7,
+  cost = $4,
+  notes = $5,
+  status_updated_by = $6,
   last_edited = DEFAULT
 WHERE
   id = $1
@@ -1410,7 +2148,6 @@ type UpdateBookingParams struct {
 	ID              int32       `json:"id"`
 	UserID          int32       `json:"user_id"`
 	TypeID          int32       `json:"type_id"`
-	Paid            bool        `json:"paid"`
 	Cost            int32       `json:"cost"`
 	Notes           pgtype.Text `json:"notes"`
 	StatusUpdatedBy string      `json:"status_updated_by"`
@@ -1421,7 +2158,6 @@ func (q *Queries) UpdateBooking(ctx context.Context, arg UpdateBookingParams) (i
 		arg.ID,
 		arg.UserID,
 		arg.TypeID,
-		arg.Paid,
 		arg.Cost,
 		arg.Notes,
 		arg.StatusUpdatedBy,
@@ -1488,6 +2224,17 @@ SET
   fixed = $4,
   cost = $5,
   duration = $6,
+  unit_minutes = $7,
+  buffer_before_minutes = $8,
+  buffer_after_minutes = $9,
+  min_notice_minutes = $10,
+  max_advance_days = $11,
+  free_cancellation_hours = $12,
+  late_cancellation_percent = $13,
+  no_show_percent = $14,
+  deposit_percent = $15,
+  deposit_amount = $16,
+  deposit_window_hours = $17,
   created_at = DEFAULT,
   last_edited = DEFAULT
 WHERE
@@ -1497,12 +2244,23 @@ RETURNING
 `
 
 type UpdateBookingTypeParams struct {
-	ID          int32  `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Fixed       bool   `json:"fixed"`
-	Cost        int32  `json:"cost"`
-	Duration    int32  `json:"duration"`
+	ID                      int32  `json:"id"`
+	Title                   string `json:"title"`
+	Description             string `json:"description"`
+	Fixed                   bool   `json:"fixed"`
+	Cost                    int32  `json:"cost"`
+	Duration                int32  `json:"duration"`
+	UnitMinutes             int32  `json:"unit_minutes"`
+	BufferBeforeMinutes     int32  `json:"buffer_before_minutes"`
+	BufferAfterMinutes      int32  `json:"buffer_after_minutes"`
+	MinNoticeMinutes        int32  `json:"min_notice_minutes"`
+	MaxAdvanceDays          int32  `json:"max_advance_days"`
+	FreeCancellationHours   int32  `json:"free_cancellation_hours"`
+	LateCancellationPercent int32  `json:"late_cancellation_percent"`
+	NoShowPercent           int32  `json:"no_show_percent"`
+	DepositPercent          int32  `json:"deposit_percent"`
+	DepositAmount           int32  `json:"deposit_amount"`
+	DepositWindowHours      int32  `json:"deposit_window_hours"`
 }
 
 func (q *Queries) UpdateBookingType(ctx context.Context, arg UpdateBookingTypeParams) (int32, error) {
@@ -1513,6 +2271,17 @@ func (q *Queries) UpdateBookingType(ctx context.Context, arg UpdateBookingTypePa
 		arg.Fixed,
 		arg.Cost,
 		arg.Duration,
+		arg.UnitMinutes,
+		arg.BufferBeforeMinutes,
+		arg.BufferAfterMinutes,
+		arg.MinNoticeMinutes,
+		arg.MaxAdvanceDays,
+		arg.FreeCancellationHours,
+		arg.LateCancellationPercent,
+		arg.NoShowPercent,
+		arg.DepositPercent,
+		arg.DepositAmount,
+		arg.DepositWindowHours,
 	)
 	var id int32
 	err := row.Scan(&id)
@@ -1528,6 +2297,7 @@ SET
   email = $4,
   title = $5,
   description = $6,
+  time_zone = $7,
   created_at = DEFAULT,
   last_login = DEFAULT
 WHERE
@@ -1543,6 +2313,7 @@ type UpdateEmployeeParams struct {
 	Email       string `json:"email"`
 	Title       string `json:"title"`
 	Description string `json:"description"`
+	TimeZone    string `json:"time_zone"`
 }
 
 func (q *Queries) UpdateEmployee(ctx context.Context, arg UpdateEmployeeParams) (int32, error) {
@@ -1553,6 +2324,7 @@ func (q *Queries) UpdateEmployee(ctx context.Context, arg UpdateEmployeeParams)
 		arg.Email,
 		arg.Title,
 		arg.Description,
+		arg.TimeZone,
 	)
 	var id int32
 	err := row.Scan(&id)
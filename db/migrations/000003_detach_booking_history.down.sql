@@ -0,0 +1,11 @@
+DELETE FROM booking_history
+WHERE
+  booking_id NOT IN (
+    SELECT
+      id
+    FROM
+      bookings
+  );
+
+ALTER TABLE booking_history
+ADD CONSTRAINT booking_history_booking_id_fkey FOREIGN KEY (booking_id) REFERENCES bookings (id);
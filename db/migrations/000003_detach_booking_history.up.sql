@@ -0,0 +1,4 @@
+-- booking_history is an audit trail so it has to outlive the bookings it
+-- describes, otherwise a booking could never be deleted
+ALTER TABLE booking_history
+DROP CONSTRAINT IF EXISTS booking_history_booking_id_fkey;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS slot_hold_slots;
+
+DROP TABLE IF EXISTS slot_holds;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS slot_holds (
+  id serial PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  expires_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS slot_hold_slots (
+  hold_id INT NOT NULL REFERENCES slot_holds (id) ON DELETE CASCADE,
+  availability_slot_id INT NOT NULL REFERENCES availability (id) ON DELETE CASCADE,
+  PRIMARY KEY (hold_id, availability_slot_id)
+);
+
+CREATE INDEX IF NOT EXISTS slot_hold_slots_availability_slot_id_idx ON slot_hold_slots (availability_slot_id);
+
+CREATE INDEX IF NOT EXISTS slot_holds_expires_at_idx ON slot_holds (expires_at);
@@ -0,0 +1,3 @@
+ALTER TABLE booking_history
+DROP COLUMN IF EXISTS previous_start_time,
+DROP COLUMN IF EXISTS previous_end_time;
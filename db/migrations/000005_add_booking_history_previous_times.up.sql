@@ -0,0 +1,4 @@
+-- set when a booking is rescheduled so the history keeps both the old and new times
+ALTER TABLE booking_history
+ADD COLUMN IF NOT EXISTS previous_start_time TIMESTAMP NULL,
+ADD COLUMN IF NOT EXISTS previous_end_time TIMESTAMP NULL;
@@ -0,0 +1,6 @@
+ALTER TABLE availability
+DROP COLUMN IF EXISTS rule_id;
+
+DROP TABLE IF EXISTS availability_rule_exceptions;
+
+DROP TABLE IF EXISTS availability_rules;
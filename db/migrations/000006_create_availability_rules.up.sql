@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS availability_rules (
+  id serial PRIMARY KEY,
+  employee_id INT NOT NULL REFERENCES employees (id) ON DELETE CASCADE,
+  type_id INT NOT NULL REFERENCES booking_types (id) ON DELETE CASCADE,
+  -- 0 is Sunday through to 6 Saturday
+  weekdays INT[] NOT NULL,
+  -- minutes after midnight
+  start_minute INT NOT NULL,
+  end_minute INT NOT NULL,
+  valid_from DATE NOT NULL,
+  valid_until DATE NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  last_edited TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  CHECK (start_minute < end_minute),
+  CHECK (
+    valid_until IS NULL
+    OR valid_from <= valid_until
+  )
+);
+
+CREATE TABLE IF NOT EXISTS availability_rule_exceptions (
+  id serial PRIMARY KEY,
+  rule_id INT NOT NULL REFERENCES availability_rules (id) ON DELETE CASCADE,
+  date DATE NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (rule_id, date)
+);
+
+-- slots materialised from a rule point back at it so the rule can replace them.
+-- slots that were booked are kept if the rule is deleted.
+ALTER TABLE availability
+ADD COLUMN IF NOT EXISTS rule_id INT NULL REFERENCES availability_rules (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS availability_rule_id_idx ON availability (rule_id);
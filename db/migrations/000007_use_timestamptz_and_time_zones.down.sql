@@ -0,0 +1,55 @@
+ALTER TABLE employees
+DROP COLUMN IF EXISTS time_zone;
+
+ALTER TABLE employees
+ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN last_login TYPE TIMESTAMP USING last_login AT TIME ZONE 'UTC';
+
+ALTER TABLE users
+ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN last_login TYPE TIMESTAMP USING last_login AT TIME ZONE 'UTC';
+
+ALTER TABLE sessions
+ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN expires_at TYPE TIMESTAMP USING expires_at AT TIME ZONE 'UTC';
+
+ALTER TABLE role_requests
+ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN approved_at TYPE TIMESTAMP USING approved_at AT TIME ZONE 'UTC';
+
+ALTER TABLE booking_types
+ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN last_edited TYPE TIMESTAMP USING last_edited AT TIME ZONE 'UTC';
+
+ALTER TABLE availability
+ALTER COLUMN datetime TYPE TIMESTAMP USING datetime AT TIME ZONE 'UTC',
+ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN last_edited TYPE TIMESTAMP USING last_edited AT TIME ZONE 'UTC';
+
+ALTER TABLE bookings
+ALTER COLUMN status_updated_at TYPE TIMESTAMP USING status_updated_at AT TIME ZONE 'UTC',
+ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN last_edited TYPE TIMESTAMP USING last_edited AT TIME ZONE 'UTC';
+
+ALTER TABLE booking_history
+ALTER COLUMN start_time TYPE TIMESTAMP USING start_time AT TIME ZONE 'UTC',
+ALTER COLUMN end_time TYPE TIMESTAMP USING end_time AT TIME ZONE 'UTC',
+ALTER COLUMN changed_at TYPE TIMESTAMP USING changed_at AT TIME ZONE 'UTC',
+ALTER COLUMN previous_start_time TYPE TIMESTAMP USING previous_start_time AT TIME ZONE 'UTC',
+ALTER COLUMN previous_end_time TYPE TIMESTAMP USING previous_end_time AT TIME ZONE 'UTC';
+
+ALTER TABLE password_resets
+ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN expires_at TYPE TIMESTAMP USING expires_at AT TIME ZONE 'UTC',
+ALTER COLUMN used_at TYPE TIMESTAMP USING used_at AT TIME ZONE 'UTC';
+
+ALTER TABLE slot_holds
+ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN expires_at TYPE TIMESTAMP USING expires_at AT TIME ZONE 'UTC';
+
+ALTER TABLE availability_rules
+ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN last_edited TYPE TIMESTAMP USING last_edited AT TIME ZONE 'UTC';
+
+ALTER TABLE availability_rule_exceptions
+ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC';
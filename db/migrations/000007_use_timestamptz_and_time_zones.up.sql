@@ -0,0 +1,58 @@
+-- store instants rather than wall clock times, existing values were written as UTC
+
+ALTER TABLE employees
+ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN last_login TYPE TIMESTAMPTZ USING last_login AT TIME ZONE 'UTC';
+
+ALTER TABLE users
+ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN last_login TYPE TIMESTAMPTZ USING last_login AT TIME ZONE 'UTC';
+
+ALTER TABLE sessions
+ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN expires_at TYPE TIMESTAMPTZ USING expires_at AT TIME ZONE 'UTC';
+
+ALTER TABLE role_requests
+ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN approved_at TYPE TIMESTAMPTZ USING approved_at AT TIME ZONE 'UTC';
+
+ALTER TABLE booking_types
+ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN last_edited TYPE TIMESTAMPTZ USING last_edited AT TIME ZONE 'UTC';
+
+ALTER TABLE availability
+ALTER COLUMN datetime TYPE TIMESTAMPTZ USING datetime AT TIME ZONE 'UTC',
+ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN last_edited TYPE TIMESTAMPTZ USING last_edited AT TIME ZONE 'UTC';
+
+ALTER TABLE bookings
+ALTER COLUMN status_updated_at TYPE TIMESTAMPTZ USING status_updated_at AT TIME ZONE 'UTC',
+ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN last_edited TYPE TIMESTAMPTZ USING last_edited AT TIME ZONE 'UTC';
+
+ALTER TABLE booking_history
+ALTER COLUMN start_time TYPE TIMESTAMPTZ USING start_time AT TIME ZONE 'UTC',
+ALTER COLUMN end_time TYPE TIMESTAMPTZ USING end_time AT TIME ZONE 'UTC',
+ALTER COLUMN changed_at TYPE TIMESTAMPTZ USING changed_at AT TIME ZONE 'UTC',
+ALTER COLUMN previous_start_time TYPE TIMESTAMPTZ USING previous_start_time AT TIME ZONE 'UTC',
+ALTER COLUMN previous_end_time TYPE TIMESTAMPTZ USING previous_end_time AT TIME ZONE 'UTC';
+
+ALTER TABLE password_resets
+ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN expires_at TYPE TIMESTAMPTZ USING expires_at AT TIME ZONE 'UTC',
+ALTER COLUMN used_at TYPE TIMESTAMPTZ USING used_at AT TIME ZONE 'UTC';
+
+ALTER TABLE slot_holds
+ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN expires_at TYPE TIMESTAMPTZ USING expires_at AT TIME ZONE 'UTC';
+
+ALTER TABLE availability_rules
+ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN last_edited TYPE TIMESTAMPTZ USING last_edited AT TIME ZONE 'UTC';
+
+ALTER TABLE availability_rule_exceptions
+ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC';
+
+-- the IANA time zone an employee works in, their recurring availability is in this zone
+ALTER TABLE employees
+ADD COLUMN IF NOT EXISTS time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC';
@@ -0,0 +1,5 @@
+ALTER TABLE availability
+DROP COLUMN IF EXISTS unit_minutes;
+
+ALTER TABLE booking_types
+DROP COLUMN IF EXISTS unit_minutes;
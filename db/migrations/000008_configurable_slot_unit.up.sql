@@ -0,0 +1,12 @@
+-- slots used to be a fixed 30 minutes, existing rows keep that length
+
+-- the minutes per unit a booking type's duration and cost are given in
+ALTER TABLE booking_types
+ADD COLUMN unit_minutes INT NOT NULL DEFAULT 30 CHECK (
+  unit_minutes > 0
+  AND 60 % unit_minutes = 0
+);
+
+-- the length of each slot, taken from its booking type when it is created
+ALTER TABLE availability
+ADD COLUMN unit_minutes INT NOT NULL DEFAULT 30 CHECK (unit_minutes > 0);
@@ -0,0 +1,49 @@
+ALTER TABLE availability
+DROP CONSTRAINT IF EXISTS availability_employee_id_datetime_key;
+
+ALTER TABLE availability
+ADD COLUMN IF NOT EXISTS type_id INT NULL REFERENCES booking_types (id) ON DELETE CASCADE;
+
+UPDATE availability a
+SET
+  type_id = (
+    SELECT
+      MIN(t.type_id)
+    FROM
+      availability_types t
+    WHERE
+      t.availability_id = a.id
+  );
+
+-- every other type a slot was offered for gets its own unbooked slot again
+INSERT INTO
+  availability (
+    employee_id,
+    datetime,
+    type_id,
+    rule_id,
+    unit_minutes
+  )
+SELECT
+  a.employee_id,
+  a.datetime,
+  t.type_id,
+  a.rule_id,
+  a.unit_minutes
+FROM
+  availability a
+  JOIN availability_types t ON t.availability_id = a.id
+WHERE
+  t.type_id <> a.type_id;
+
+DELETE FROM availability
+WHERE
+  type_id IS NULL;
+
+ALTER TABLE availability
+ALTER COLUMN type_id SET NOT NULL;
+
+ALTER TABLE availability
+ADD CONSTRAINT availability_employee_id_datetime_type_id_key UNIQUE (employee_id, datetime, type_id);
+
+DROP TABLE IF EXISTS availability_types;
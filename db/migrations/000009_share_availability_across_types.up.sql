@@ -0,0 +1,71 @@
+-- an availability slot is now an employee's time, which can be offered for any
+-- number of booking types and booked once
+CREATE TABLE IF NOT EXISTS availability_types (
+  availability_id INT NOT NULL REFERENCES availability (id) ON DELETE CASCADE,
+  type_id INT NOT NULL REFERENCES booking_types (id) ON DELETE CASCADE,
+  PRIMARY KEY (availability_id, type_id)
+);
+
+CREATE INDEX IF NOT EXISTS availability_types_type_id_idx ON availability_types (type_id);
+
+-- slots offered for several types at the same time are merged into one, keeping
+-- the booked slot if there is one. Times booked more than once need sorting out
+-- by hand first.
+DO $$
+BEGIN
+  IF EXISTS (
+    SELECT
+      1
+    FROM
+      availability a
+      JOIN booking_slots bs ON bs.availability_slot_id = a.id
+    GROUP BY
+      a.employee_id,
+      a.datetime
+    HAVING
+      COUNT(*) > 1
+  ) THEN
+    RAISE EXCEPTION 'some employees are booked more than once at the same time, cancel the extra bookings before migrating';
+  END IF;
+END
+$$;
+
+CREATE TEMPORARY TABLE availability_merge AS
+SELECT
+  a.id,
+  a.type_id,
+  FIRST_VALUE(a.id) OVER (
+    PARTITION BY
+      a.employee_id,
+      a.datetime
+    ORDER BY
+      bs.booking_id IS NULL,
+      a.id
+  ) AS keep_id
+FROM
+  availability a
+  LEFT JOIN booking_slots bs ON bs.availability_slot_id = a.id;
+
+INSERT INTO
+  availability_types (availability_id, type_id)
+SELECT DISTINCT
+  keep_id,
+  type_id
+FROM
+  availability_merge;
+
+DELETE FROM availability a USING availability_merge m
+WHERE
+  a.id = m.id
+  AND m.id <> m.keep_id;
+
+DROP TABLE availability_merge;
+
+ALTER TABLE availability
+DROP CONSTRAINT IF EXISTS availability_employee_id_datetime_type_id_key;
+
+ALTER TABLE availability
+DROP COLUMN IF EXISTS type_id;
+
+ALTER TABLE availability
+ADD CONSTRAINT availability_employee_id_datetime_key UNIQUE (employee_id, datetime);
@@ -0,0 +1,5 @@
+ALTER TABLE booking_types
+DROP COLUMN IF EXISTS max_advance_days,
+DROP COLUMN IF EXISTS min_notice_minutes,
+DROP COLUMN IF EXISTS buffer_after_minutes,
+DROP COLUMN IF EXISTS buffer_before_minutes;
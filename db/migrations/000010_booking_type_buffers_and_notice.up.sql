@@ -0,0 +1,14 @@
+-- time kept free either side of a booking, e.g. for cleaning up
+ALTER TABLE booking_types
+ADD COLUMN buffer_before_minutes INT NOT NULL DEFAULT 0 CHECK (
+  buffer_before_minutes BETWEEN 0 AND 1440
+),
+ADD COLUMN buffer_after_minutes INT NOT NULL DEFAULT 0 CHECK (
+  buffer_after_minutes BETWEEN 0 AND 1440
+);
+
+-- how soon and how far ahead a booking can be made, a max_advance_days of 0 has
+-- no limit
+ALTER TABLE booking_types
+ADD COLUMN min_notice_minutes INT NOT NULL DEFAULT 0 CHECK (min_notice_minutes >= 0),
+ADD COLUMN max_advance_days INT NOT NULL DEFAULT 0 CHECK (max_advance_days >= 0);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS closures;
+
+DROP TABLE IF EXISTS employee_time_off;
@@ -0,0 +1,25 @@
+-- leave for one employee, their slots in [start_time, end_time) cant be booked
+CREATE TABLE IF NOT EXISTS employee_time_off (
+  id serial PRIMARY KEY,
+  employee_id INT NOT NULL REFERENCES employees (id) ON DELETE CASCADE,
+  start_time TIMESTAMPTZ NOT NULL,
+  end_time TIMESTAMPTZ NOT NULL,
+  reason TEXT NOT NULL DEFAULT '',
+  created_by VARCHAR(255) NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+  CHECK (start_time < end_time)
+);
+
+CREATE INDEX IF NOT EXISTS employee_time_off_employee_id_idx ON employee_time_off (employee_id, start_time);
+
+-- the whole business is shut from start_date to end_date inclusive. the dates
+-- are calendar dates in each employee's own time zone.
+CREATE TABLE IF NOT EXISTS closures (
+  id serial PRIMARY KEY,
+  start_date DATE NOT NULL,
+  end_date DATE NOT NULL,
+  reason TEXT NOT NULL DEFAULT '',
+  created_by VARCHAR(255) NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+  CHECK (start_date <= end_date)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE booking_history
+DROP COLUMN IF EXISTS reason;
@@ -0,0 +1,4 @@
+-- why a change was made to a booking when it wasnt made by the customer, e.g. the
+-- availability it was on being removed
+ALTER TABLE booking_history
+ADD COLUMN IF NOT EXISTS reason TEXT NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS booking_type_resources;
+
+DROP TABLE IF EXISTS resources;
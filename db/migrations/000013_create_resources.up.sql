@@ -0,0 +1,19 @@
+-- rooms and equipment that some booking types need as well as an employee
+CREATE TABLE IF NOT EXISTS resources (
+  id serial PRIMARY KEY,
+  name VARCHAR(80) NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+  last_edited TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (name)
+);
+
+-- every booking of a type takes all of its resources for the whole booking, so a
+-- resource is in use whenever a booking of one of its types has slots
+CREATE TABLE IF NOT EXISTS booking_type_resources (
+  type_id INT NOT NULL REFERENCES booking_types (id) ON DELETE CASCADE,
+  resource_id INT NOT NULL REFERENCES resources (id) ON DELETE RESTRICT,
+  PRIMARY KEY (type_id, resource_id)
+);
+
+CREATE INDEX IF NOT EXISTS booking_type_resources_resource_id_idx ON booking_type_resources (resource_id);
@@ -0,0 +1,27 @@
+DO $$
+BEGIN
+  IF EXISTS (
+    SELECT
+      1
+    FROM
+      booking_slots
+    GROUP BY
+      availability_slot_id
+    HAVING
+      COUNT(*) > 1
+  ) THEN
+    RAISE EXCEPTION 'some slots are booked more than once, cancel the extra bookings before migrating';
+  END IF;
+END
+$$;
+
+DROP INDEX IF EXISTS booking_slots_availability_slot_id_idx;
+
+ALTER TABLE booking_slots
+ADD CONSTRAINT booking_slots_availability_slot_id_key UNIQUE (availability_slot_id);
+
+ALTER TABLE availability_rules
+DROP COLUMN IF EXISTS capacity;
+
+ALTER TABLE availability
+DROP COLUMN IF EXISTS capacity;
@@ -0,0 +1,11 @@
+-- a slot can be booked by up to capacity bookings at once, e.g. seats in a class
+ALTER TABLE availability
+ADD COLUMN capacity INT NOT NULL DEFAULT 1 CHECK (capacity >= 1);
+
+ALTER TABLE availability_rules
+ADD COLUMN capacity INT NOT NULL DEFAULT 1 CHECK (capacity >= 1);
+
+ALTER TABLE booking_slots
+DROP CONSTRAINT IF EXISTS booking_slots_availability_slot_id_key;
+
+CREATE INDEX IF NOT EXISTS booking_slots_availability_slot_id_idx ON booking_slots (availability_slot_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS waitlist_entries;
+
+DROP TYPE IF EXISTS waitlist_status;
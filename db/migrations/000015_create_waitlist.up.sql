@@ -0,0 +1,25 @@
+CREATE TYPE waitlist_status AS ENUM('waiting', 'offered', 'booked', 'expired');
+
+-- a customer waiting for a booking of type_id that fits in [start_time,
+-- end_time), with employee_id or with anyone when it is null. when a booking is
+-- cancelled the oldest entry that fits is offered the freed slots through a
+-- hold, or booked into them straight away.
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+  id serial PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  type_id INT NOT NULL REFERENCES booking_types (id) ON DELETE CASCADE,
+  employee_id INT REFERENCES employees (id) ON DELETE CASCADE,
+  start_time TIMESTAMPTZ NOT NULL,
+  end_time TIMESTAMPTZ NOT NULL,
+  status waitlist_status NOT NULL DEFAULT 'waiting',
+  hold_id INT REFERENCES slot_holds (id) ON DELETE SET NULL,
+  offered_slot_ids INT[] NOT NULL DEFAULT '{}',
+  offer_expires_at TIMESTAMPTZ,
+  booking_id INT REFERENCES bookings (id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+  CHECK (start_time < end_time)
+);
+
+CREATE INDEX IF NOT EXISTS waitlist_entries_status_idx ON waitlist_entries (status, created_at);
+
+CREATE INDEX IF NOT EXISTS waitlist_entries_user_id_idx ON waitlist_entries (user_id);
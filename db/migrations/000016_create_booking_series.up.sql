@@ -0,0 +1,19 @@
+-- bookings made together every interval_weeks weeks at the same time. each
+-- occurrence is a normal booking with series_id set, occurrences that couldnt
+-- be placed when the series was made have no booking.
+CREATE TABLE IF NOT EXISTS booking_series (
+  id serial PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  type_id INT NOT NULL REFERENCES booking_types (id) ON DELETE CASCADE,
+  employee_id INT NOT NULL REFERENCES employees (id) ON DELETE CASCADE,
+  interval_weeks INT NOT NULL CHECK (interval_weeks IN (1, 2)),
+  occurrences INT NOT NULL CHECK (occurrences >= 1),
+  created_by VARCHAR(255) NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE bookings
+ADD COLUMN series_id INT REFERENCES booking_series (id) ON DELETE SET NULL,
+ADD COLUMN series_occurrence INT;
+
+CREATE INDEX IF NOT EXISTS bookings_series_id_idx ON bookings (series_id);
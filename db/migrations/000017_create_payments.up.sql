@@ -0,0 +1,25 @@
+CREATE TYPE payment_status AS ENUM('pending', 'captured', 'failed', 'refunded');
+
+-- money taken for a booking through provider, provider_ref is the providers id
+-- for the payment and is null for manual payments. amounts are in the minor
+-- unit of currency, a payment is only refunded once all of it is given back.
+CREATE TABLE IF NOT EXISTS payments (
+  id serial PRIMARY KEY,
+  booking_id INT NOT NULL REFERENCES bookings (id) ON DELETE CASCADE,
+  provider VARCHAR(50) NOT NULL,
+  provider_ref VARCHAR(255),
+  amount INT NOT NULL CHECK (amount >= 0),
+  refunded_amount INT NOT NULL DEFAULT 0,
+  currency VARCHAR(3) NOT NULL,
+  status payment_status NOT NULL DEFAULT 'pending',
+  created_by VARCHAR(255) NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (provider, provider_ref),
+  CHECK (
+    refunded_amount >= 0
+    AND refunded_amount <= amount
+  )
+);
+
+CREATE INDEX IF NOT EXISTS payments_booking_id_idx ON payments (booking_id);
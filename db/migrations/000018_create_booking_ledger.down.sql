@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS booking_ledger_entries;
+
+DROP TYPE IF EXISTS ledger_entry_kind;
@@ -0,0 +1,63 @@
+CREATE TYPE ledger_entry_kind AS ENUM('charge', 'payment', 'refund', 'adjustment');
+
+-- what a customer owes and has paid for a booking. charges and adjustments add
+-- to what is owed and payments take away from it while refunds give it back, so
+-- the outstanding balance is charges + adjustments - payments + refunds.
+-- adjustments can be negative, every other amount is positive. bookings.paid is
+-- kept in step with the balance and isnt set by hand.
+CREATE TABLE IF NOT EXISTS booking_ledger_entries (
+  id serial PRIMARY KEY,
+  booking_id INT NOT NULL REFERENCES bookings (id) ON DELETE CASCADE,
+  kind ledger_entry_kind NOT NULL,
+  amount INT NOT NULL,
+  payment_id INT REFERENCES payments (id) ON DELETE SET NULL,
+  reason TEXT,
+  created_by VARCHAR(255) NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+  CHECK (
+    kind = 'adjustment'
+    OR amount >= 0
+  )
+);
+
+CREATE INDEX IF NOT EXISTS booking_ledger_entries_booking_id_idx ON booking_ledger_entries (booking_id);
+
+-- bookings made before the ledger open with their cost charged, and paid in
+-- full if they were marked paid. cancelled bookings had nothing refunded so
+-- only their charge is taken back.
+INSERT INTO
+  booking_ledger_entries (booking_id, kind, amount, reason, created_by)
+SELECT
+  id,
+  'charge',
+  cost,
+  'opening balance',
+  'system'
+FROM
+  bookings;
+
+INSERT INTO
+  booking_ledger_entries (booking_id, kind, amount, reason, created_by)
+SELECT
+  id,
+  'payment',
+  cost,
+  'opening balance',
+  'system'
+FROM
+  bookings
+WHERE
+  paid;
+
+INSERT INTO
+  booking_ledger_entries (booking_id, kind, amount, reason, created_by)
+SELECT
+  id,
+  'adjustment',
+  - cost,
+  'opening balance of a cancelled booking',
+  'system'
+FROM
+  bookings
+WHERE
+  status = 'cancelled';
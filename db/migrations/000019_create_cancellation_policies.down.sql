@@ -0,0 +1,44 @@
+ALTER TABLE bookings
+DROP COLUMN IF EXISTS no_show_percent,
+DROP COLUMN IF EXISTS late_cancellation_percent,
+DROP COLUMN IF EXISTS free_cancellation_hours;
+
+ALTER TABLE booking_types
+DROP COLUMN IF EXISTS no_show_percent,
+DROP COLUMN IF EXISTS late_cancellation_percent,
+DROP COLUMN IF EXISTS free_cancellation_hours;
+
+-- postgres cant drop an enum value so the type is rebuilt without no_show, no
+-- shows are kept as completed
+UPDATE bookings
+SET
+  status = 'completed'
+WHERE
+  status = 'no_show';
+
+UPDATE booking_history
+SET
+  status = 'completed'
+WHERE
+  status = 'no_show';
+
+ALTER TYPE booking_status
+RENAME TO booking_status_old;
+
+CREATE TYPE booking_status AS ENUM(
+  'created',
+  'confirmed',
+  'rescheduled',
+  'cancelled',
+  'completed'
+);
+
+ALTER TABLE bookings
+ALTER COLUMN status DROP DEFAULT,
+ALTER COLUMN status TYPE booking_status USING status::text::booking_status,
+ALTER COLUMN status SET DEFAULT 'created';
+
+ALTER TABLE booking_history
+ALTER COLUMN status TYPE booking_status USING status::text::booking_status;
+
+DROP TYPE booking_status_old;
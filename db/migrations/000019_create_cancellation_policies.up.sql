@@ -0,0 +1,19 @@
+ALTER TYPE booking_status
+ADD VALUE IF NOT EXISTS 'no_show';
+
+-- what it costs to cancel a booking of the type, cancelling within
+-- free_cancellation_hours of the start keeps late_cancellation_percent of the
+-- charge and not turning up keeps no_show_percent
+ALTER TABLE booking_types
+ADD COLUMN free_cancellation_hours INT NOT NULL DEFAULT 0 CHECK (free_cancellation_hours >= 0),
+ADD COLUMN late_cancellation_percent INT NOT NULL DEFAULT 0 CHECK (
+  late_cancellation_percent BETWEEN 0 AND 100
+),
+ADD COLUMN no_show_percent INT NOT NULL DEFAULT 0 CHECK (no_show_percent BETWEEN 0 AND 100);
+
+-- the policy of the booking type when the booking was made, so editing the type
+-- doesnt change what existing bookings agreed to
+ALTER TABLE bookings
+ADD COLUMN free_cancellation_hours INT NOT NULL DEFAULT 0,
+ADD COLUMN late_cancellation_percent INT NOT NULL DEFAULT 0,
+ADD COLUMN no_show_percent INT NOT NULL DEFAULT 0;
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS bookings_deposit_due_at_idx;
+
+ALTER TABLE bookings
+DROP COLUMN IF EXISTS deposit_due_at,
+DROP COLUMN IF EXISTS deposit;
+
+ALTER TABLE booking_types
+DROP CONSTRAINT IF EXISTS booking_types_single_deposit,
+DROP COLUMN IF EXISTS deposit_window_hours,
+DROP COLUMN IF EXISTS deposit_amount,
+DROP COLUMN IF EXISTS deposit_percent;
@@ -0,0 +1,20 @@
+-- a deposit taken when a booking of the type is made, either deposit_percent of
+-- the cost or a fixed deposit_amount, that has to be paid within
+-- deposit_window_hours or the booking is cancelled
+ALTER TABLE booking_types
+ADD COLUMN deposit_percent INT NOT NULL DEFAULT 0 CHECK (deposit_percent BETWEEN 0 AND 100),
+ADD COLUMN deposit_amount INT NOT NULL DEFAULT 0 CHECK (deposit_amount >= 0),
+ADD COLUMN deposit_window_hours INT NOT NULL DEFAULT 24 CHECK (deposit_window_hours > 0),
+ADD CONSTRAINT booking_types_single_deposit CHECK (
+  deposit_percent = 0
+  OR deposit_amount = 0
+);
+
+-- the deposit a booking needs paid by deposit_due_at, set when it is made
+ALTER TABLE bookings
+ADD COLUMN deposit INT NOT NULL DEFAULT 0 CHECK (deposit >= 0),
+ADD COLUMN deposit_due_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS bookings_deposit_due_at_idx ON bookings (deposit_due_at)
+WHERE
+  deposit > 0;
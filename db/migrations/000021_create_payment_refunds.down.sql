@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS payment_refunds_unsent_idx;
+
+DROP TABLE IF EXISTS payment_refunds;
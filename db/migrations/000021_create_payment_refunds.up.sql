@@ -0,0 +1,16 @@
+-- refunds owed on provider payments. they are recorded with the ledger and sent
+-- to the provider once that is committed, sent_at is set when the provider has
+-- taken the refund and unsent refunds are retried
+CREATE TABLE IF NOT EXISTS payment_refunds (
+  id serial PRIMARY KEY,
+  payment_id INT NOT NULL REFERENCES payments (id) ON DELETE CASCADE,
+  amount INT NOT NULL CHECK (amount > 0),
+  attempts INT NOT NULL DEFAULT 0,
+  last_error TEXT,
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+  sent_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS payment_refunds_unsent_idx ON payment_refunds (id)
+WHERE
+  sent_at IS NULL;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS availability_types_rule_id_idx;
+
+ALTER TABLE availability_types
+DROP COLUMN IF EXISTS rule_id;
@@ -0,0 +1,6 @@
+-- the rule that offered a slot for a type, so the type is taken off the slot
+-- again when the rule releases it. it is null for types added by hand
+ALTER TABLE availability_types
+ADD COLUMN IF NOT EXISTS rule_id INT NULL REFERENCES availability_rules (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS availability_types_rule_id_idx ON availability_types (rule_id);
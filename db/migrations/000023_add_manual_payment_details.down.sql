@@ -0,0 +1,3 @@
+ALTER TABLE payments
+DROP COLUMN IF EXISTS reference,
+DROP COLUMN IF EXISTS method;
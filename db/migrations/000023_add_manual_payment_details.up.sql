@@ -0,0 +1,5 @@
+-- how a manual payment was taken, such as cash or a bank transfer, and any
+-- reference for it like a receipt number. both are null for provider payments
+ALTER TABLE payments
+ADD COLUMN IF NOT EXISTS method VARCHAR(50),
+ADD COLUMN IF NOT EXISTS reference VARCHAR(255);
@@ -0,0 +1 @@
+-- the backfilled rule ids are dropped with the column by 000022
@@ -0,0 +1,12 @@
+-- types rules offered on their slots before availability_types.rule_id was
+-- tracked are given the rule, so they are taken off again when it releases them
+UPDATE availability_types t
+SET
+  rule_id = a.rule_id
+FROM
+  availability a
+  JOIN availability_rules r ON r.id = a.rule_id
+WHERE
+  t.availability_id = a.id
+  AND t.type_id = r.type_id
+  AND t.rule_id IS NULL;
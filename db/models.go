@@ -19,6 +19,7 @@ const (
 	BookingStatusRescheduled BookingStatus = "rescheduled"
 	BookingStatusCancelled   BookingStatus = "cancelled"
 	BookingStatusCompleted   BookingStatus = "completed"
+	BookingStatusNoShow      BookingStatus = "no_show"
 )
 
 func (e *BookingStatus) Scan(src interface{}) error {
@@ -56,6 +57,94 @@ func (ns NullBookingStatus) Value() (driver.Value, error) {
 	return string(ns.BookingStatus), nil
 }
 
+type LedgerEntryKind string
+
+const (
+	LedgerEntryKindCharge     LedgerEntryKind = "charge"
+	LedgerEntryKindPayment    LedgerEntryKind = "payment"
+	LedgerEntryKindRefund     LedgerEntryKind = "refund"
+	LedgerEntryKindAdjustment LedgerEntryKind = "adjustment"
+)
+
+func (e *LedgerEntryKind) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = LedgerEntryKind(s)
+	case string:
+		*e = LedgerEntryKind(s)
+	default:
+		return fmt.Errorf("unsupported scan type for LedgerEntryKind: %T", src)
+	}
+	return nil
+}
+
+type NullLedgerEntryKind struct {
+	LedgerEntryKind LedgerEntryKind `json:"ledger_entry_kind"`
+	Valid           bool            `json:"valid"` // Valid is true if LedgerEntryKind is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullLedgerEntryKind) Scan(value interface{}) error {
+	if value == nil {
+		ns.LedgerEntryKind, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.LedgerEntryKind.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullLedgerEntryKind) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.LedgerEntryKind), nil
+}
+
+type PaymentStatus string
+
+const (
+	PaymentStatusPending  PaymentStatus = "pending"
+	PaymentStatusCaptured PaymentStatus = "captured"
+	PaymentStatusFailed   PaymentStatus = "failed"
+	PaymentStatusRefunded PaymentStatus = "refunded"
+)
+
+func (e *PaymentStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = PaymentStatus(s)
+	case string:
+		*e = PaymentStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for PaymentStatus: %T", src)
+	}
+	return nil
+}
+
+type NullPaymentStatus struct {
+	PaymentStatus PaymentStatus `json:"payment_status"`
+	Valid         bool          `json:"valid"` // Valid is true if PaymentStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullPaymentStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.PaymentStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.PaymentStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullPaymentStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.PaymentStatus), nil
+}
+
 type RoleRequestStatus string
 
 const (
@@ -99,41 +188,146 @@ func (ns NullRoleRequestStatus) Value() (driver.Value, error) {
 	return string(ns.RoleRequestStatus), nil
 }
 
+type WaitlistStatus string
+
+const (
+	WaitlistStatusWaiting WaitlistStatus = "waiting"
+	WaitlistStatusOffered WaitlistStatus = "offered"
+	WaitlistStatusBooked  WaitlistStatus = "booked"
+	WaitlistStatusExpired WaitlistStatus = "expired"
+)
+
+func (e *WaitlistStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = WaitlistStatus(s)
+	case string:
+		*e = WaitlistStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for WaitlistStatus: %T", src)
+	}
+	return nil
+}
+
+type NullWaitlistStatus struct {
+	WaitlistStatus WaitlistStatus `json:"waitlist_status"`
+	Valid          bool           `json:"valid"` // Valid is true if WaitlistStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullWaitlistStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.WaitlistStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.WaitlistStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullWaitlistStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.WaitlistStatus), nil
+}
+
 type Availability struct {
-	ID         int32            `json:"id"`
-	EmployeeID int32            `json:"employee_id"`
-	Datetime   pgtype.Timestamp `json:"datetime"`
-	TypeID     int32            `json:"type_id"`
-	CreatedAt  pgtype.Timestamp `json:"created_at"`
-	LastEdited pgtype.Timestamp `json:"last_edited"`
+	ID          int32              `json:"id"`
+	EmployeeID  int32              `json:"employee_id"`
+	Datetime    pgtype.Timestamptz `json:"datetime"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	LastEdited  pgtype.Timestamptz `json:"last_edited"`
+	RuleID      pgtype.Int4        `json:"rule_id"`
+	UnitMinutes int32              `json:"unit_minutes"`
+	Capacity    int32              `json:"capacity"`
+}
+
+type AvailabilityType struct {
+	AvailabilityID int32       `json:"availability_id"`
+	TypeID         int32       `json:"type_id"`
+	RuleID         pgtype.Int4 `json:"rule_id"`
+}
+
+type AvailabilityRule struct {
+	ID          int32              `json:"id"`
+	EmployeeID  int32              `json:"employee_id"`
+	TypeID      int32              `json:"type_id"`
+	Weekdays    []int32            `json:"weekdays"`
+	StartMinute int32              `json:"start_minute"`
+	EndMinute   int32              `json:"end_minute"`
+	ValidFrom   pgtype.Date        `json:"valid_from"`
+	ValidUntil  pgtype.Date        `json:"valid_until"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	LastEdited  pgtype.Timestamptz `json:"last_edited"`
+	Capacity    int32              `json:"capacity"`
+}
+
+type AvailabilityRuleException struct {
+	ID        int32              `json:"id"`
+	RuleID    int32              `json:"rule_id"`
+	Date      pgtype.Date        `json:"date"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
 }
 
 type Booking struct {
-	ID              int32            `json:"id"`
-	UserID          int32            `json:"user_id"`
-	TypeID          int32            `json:"type_id"`
-	Paid            bool             `json:"paid"`
-	Cost            int32            `json:"cost"`
-	Status          BookingStatus    `json:"status"`
-	StatusUpdatedAt pgtype.Timestamp `json:"status_updated_at"`
-	StatusUpdatedBy string           `json:"status_updated_by"`
-	Notes           pgtype.Text      `json:"notes"`
-	CreatedAt       pgtype.Timestamp `json:"created_at"`
-	LastEdited      pgtype.Timestamp `json:"last_edited"`
+	ID                      int32              `json:"id"`
+	UserID                  int32              `json:"user_id"`
+	TypeID                  int32              `json:"type_id"`
+	Paid                    bool               `json:"paid"`
+	Cost                    int32              `json:"cost"`
+	Status                  BookingStatus      `json:"status"`
+	StatusUpdatedAt         pgtype.Timestamptz `json:"status_updated_at"`
+	StatusUpdatedBy         string             `json:"status_updated_by"`
+	Notes                   pgtype.Text        `json:"notes"`
+	CreatedAt               pgtype.Timestamptz `json:"created_at"`
+	LastEdited              pgtype.Timestamptz `json:"last_edited"`
+	SeriesID                pgtype.Int4        `json:"series_id"`
+	SeriesOccurrence        pgtype.Int4        `json:"series_occurrence"`
+	FreeCancellationHours   int32              `json:"free_cancellation_hours"`
+	LateCancellationPercent int32              `json:"late_cancellation_percent"`
+	NoShowPercent           int32              `json:"no_show_percent"`
+	Deposit                 int32              `json:"deposit"`
+	DepositDueAt            pgtype.Timestamptz `json:"deposit_due_at"`
 }
 
 type BookingHistory struct {
-	ID              int32            `json:"id"`
-	BookingID       int32            `json:"booking_id"`
-	EmployeeID      int32            `json:"employee_id"`
-	EmployeeName    string           `json:"employee_name"`
-	EmployeeSurname string           `json:"employee_surname"`
-	EmployeeEmail   string           `json:"employee_email"`
-	Status          BookingStatus    `json:"status"`
-	StartTime       pgtype.Timestamp `json:"start_time"`
-	EndTime         pgtype.Timestamp `json:"end_time"`
-	ChangedAt       pgtype.Timestamp `json:"changed_at"`
-	ChangedByEmail  string           `json:"changed_by_email"`
+	ID                int32              `json:"id"`
+	BookingID         int32              `json:"booking_id"`
+	EmployeeID        int32              `json:"employee_id"`
+	EmployeeName      string             `json:"employee_name"`
+	EmployeeSurname   string             `json:"employee_surname"`
+	EmployeeEmail     string             `json:"employee_email"`
+	Status            BookingStatus      `json:"status"`
+	StartTime         pgtype.Timestamptz `json:"start_time"`
+	EndTime           pgtype.Timestamptz `json:"end_time"`
+	ChangedAt         pgtype.Timestamptz `json:"changed_at"`
+	ChangedByEmail    string             `json:"changed_by_email"`
+	PreviousStartTime pgtype.Timestamptz `json:"previous_start_time"`
+	PreviousEndTime   pgtype.Timestamptz `json:"previous_end_time"`
+	Reason            pgtype.Text        `json:"reason"`
+}
+
+type BookingLedgerEntry struct {
+	ID        int32              `json:"id"`
+	BookingID int32              `json:"booking_id"`
+	Kind      LedgerEntryKind    `json:"kind"`
+	Amount    int32              `json:"amount"`
+	PaymentID pgtype.Int4        `json:"payment_id"`
+	Reason    pgtype.Text        `json:"reason"`
+	CreatedBy string             `json:"created_by"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type BookingSeries struct {
+	ID            int32              `json:"id"`
+	UserID        int32              `json:"user_id"`
+	TypeID        int32              `json:"type_id"`
+	EmployeeID    int32              `json:"employee_id"`
+	IntervalWeeks int32              `json:"interval_weeks"`
+	Occurrences   int32              `json:"occurrences"`
+	CreatedBy     string             `json:"created_by"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
 }
 
 type BookingSlot struct {
@@ -142,25 +336,104 @@ type BookingSlot struct {
 }
 
 type BookingType struct {
-	ID          int32            `json:"id"`
-	Title       string           `json:"title"`
-	Description string           `json:"description"`
-	Fixed       bool             `json:"fixed"`
-	Cost        int32            `json:"cost"`
-	Duration    int32            `json:"duration"`
-	CreatedAt   pgtype.Timestamp `json:"created_at"`
-	LastEdited  pgtype.Timestamp `json:"last_edited"`
+	ID                      int32              `json:"id"`
+	Title                   string             `json:"title"`
+	Description             string             `json:"description"`
+	Fixed                   bool               `json:"fixed"`
+	Cost                    int32              `json:"cost"`
+	Duration                int32              `json:"duration"`
+	CreatedAt               pgtype.Timestamptz `json:"created_at"`
+	LastEdited              pgtype.Timestamptz `json:"last_edited"`
+	UnitMinutes             int32              `json:"unit_minutes"`
+	BufferBeforeMinutes     int32              `json:"buffer_before_minutes"`
+	BufferAfterMinutes      int32              `json:"buffer_after_minutes"`
+	MinNoticeMinutes        int32              `json:"min_notice_minutes"`
+	MaxAdvanceDays          int32              `json:"max_advance_days"`
+	FreeCancellationHours   int32              `json:"free_cancellation_hours"`
+	LateCancellationPercent int32              `json:"late_cancellation_percent"`
+	NoShowPercent           int32              `json:"no_show_percent"`
+	DepositPercent          int32              `json:"deposit_percent"`
+	DepositAmount           int32              `json:"deposit_amount"`
+	DepositWindowHours      int32              `json:"deposit_window_hours"`
+}
+
+type BookingTypeResource struct {
+	TypeID     int32 `json:"type_id"`
+	ResourceID int32 `json:"resource_id"`
+}
+
+type Closure struct {
+	ID        int32              `json:"id"`
+	StartDate pgtype.Date        `json:"start_date"`
+	EndDate   pgtype.Date        `json:"end_date"`
+	Reason    string             `json:"reason"`
+	CreatedBy string             `json:"created_by"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
 }
 
 type Employee struct {
-	ID          int32            `json:"id"`
-	Name        string           `json:"name"`
-	Surname     string           `json:"surname"`
-	Email       string           `json:"email"`
-	Title       string           `json:"title"`
-	Description string           `json:"description"`
-	CreatedAt   pgtype.Timestamp `json:"created_at"`
-	LastLogin   pgtype.Timestamp `json:"last_login"`
+	ID          int32              `json:"id"`
+	Name        string             `json:"name"`
+	Surname     string             `json:"surname"`
+	Email       string             `json:"email"`
+	Title       string             `json:"title"`
+	Description string             `json:"description"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	LastLogin   pgtype.Timestamptz `json:"last_login"`
+	TimeZone    string             `json:"time_zone"`
+}
+
+type EmployeeTimeOff struct {
+	ID         int32              `json:"id"`
+	EmployeeID int32              `json:"employee_id"`
+	StartTime  pgtype.Timestamptz `json:"start_time"`
+	EndTime    pgtype.Timestamptz `json:"end_time"`
+	Reason     string             `json:"reason"`
+	CreatedBy  string             `json:"created_by"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type PasswordReset struct {
+	ID        int32              `json:"id"`
+	UserID    int32              `json:"user_id"`
+	TokenHash string             `json:"token_hash"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+}
+
+type Payment struct {
+	ID             int32              `json:"id"`
+	BookingID      int32              `json:"booking_id"`
+	Provider       string             `json:"provider"`
+	ProviderRef    pgtype.Text        `json:"provider_ref"`
+	Amount         int32              `json:"amount"`
+	RefundedAmount int32              `json:"refunded_amount"`
+	Currency       string             `json:"currency"`
+	Status         PaymentStatus      `json:"status"`
+	CreatedBy      string             `json:"created_by"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
+	Method         pgtype.Text        `json:"method"`
+	Reference      pgtype.Text        `json:"reference"`
+}
+
+type PaymentRefund struct {
+	ID        int32              `json:"id"`
+	PaymentID int32              `json:"payment_id"`
+	Amount    int32              `json:"amount"`
+	Attempts  int32              `json:"attempts"`
+	LastError pgtype.Text        `json:"last_error"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	SentAt    pgtype.Timestamptz `json:"sent_at"`
+}
+
+type Resource struct {
+	ID          int32              `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	LastEdited  pgtype.Timestamptz `json:"last_edited"`
 }
 
 type Role struct {
@@ -170,35 +443,62 @@ type Role struct {
 }
 
 type RoleRequest struct {
-	ID              int32             `json:"id"`
-	UserID          int32             `json:"user_id"`
-	RequestedRoleID int32             `json:"requested_role_id"`
-	Status          RoleRequestStatus `json:"status"`
-	Comment         pgtype.Text       `json:"comment"`
-	ApprovedBy      pgtype.Int4       `json:"approved_by"`
-	CreatedAt       pgtype.Timestamp  `json:"created_at"`
-	ApprovedAt      pgtype.Timestamp  `json:"approved_at"`
+	ID              int32              `json:"id"`
+	UserID          int32              `json:"user_id"`
+	RequestedRoleID int32              `json:"requested_role_id"`
+	Status          RoleRequestStatus  `json:"status"`
+	Comment         pgtype.Text        `json:"comment"`
+	ApprovedBy      pgtype.Int4        `json:"approved_by"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	ApprovedAt      pgtype.Timestamptz `json:"approved_at"`
 }
 
 type Session struct {
-	ID           int32            `json:"id"`
-	UserID       int32            `json:"user_id"`
-	SessionToken string           `json:"session_token"`
-	CreatedAt    pgtype.Timestamp `json:"created_at"`
-	ExpiresAt    pgtype.Timestamp `json:"expires_at"`
+	ID           int32              `json:"id"`
+	UserID       int32              `json:"user_id"`
+	SessionToken string             `json:"session_token"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	ExpiresAt    pgtype.Timestamptz `json:"expires_at"`
+}
+
+type SlotHold struct {
+	ID        int32              `json:"id"`
+	UserID    int32              `json:"user_id"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+}
+
+type SlotHoldSlot struct {
+	HoldID             int32 `json:"hold_id"`
+	AvailabilitySlotID int32 `json:"availability_slot_id"`
 }
 
 type User struct {
-	ID             int32            `json:"id"`
-	Name           string           `json:"name"`
-	Surname        string           `json:"surname"`
-	Email          string           `json:"email"`
-	HashedPassword string           `json:"hashed_password"`
-	CreatedAt      pgtype.Timestamp `json:"created_at"`
-	LastLogin      pgtype.Timestamp `json:"last_login"`
+	ID             int32              `json:"id"`
+	Name           string             `json:"name"`
+	Surname        string             `json:"surname"`
+	Email          string             `json:"email"`
+	HashedPassword string             `json:"-"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	LastLogin      pgtype.Timestamptz `json:"last_login"`
 }
 
 type UserRole struct {
 	UserID int32 `json:"user_id"`
 	RoleID int32 `json:"role_id"`
 }
+
+type WaitlistEntry struct {
+	ID             int32              `json:"id"`
+	UserID         int32              `json:"user_id"`
+	TypeID         int32              `json:"type_id"`
+	EmployeeID     pgtype.Int4        `json:"employee_id"`
+	StartTime      pgtype.Timestamptz `json:"start_time"`
+	EndTime        pgtype.Timestamptz `json:"end_time"`
+	Status         WaitlistStatus     `json:"status"`
+	HoldID         pgtype.Int4        `json:"hold_id"`
+	OfferedSlotIds []int32            `json:"offered_slot_ids"`
+	OfferExpiresAt pgtype.Timestamptz `json:"offer_expires_at"`
+	BookingID      pgtype.Int4        `json:"booking_id"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+}
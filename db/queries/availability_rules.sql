@@ -0,0 +1,203 @@
+-- name: CreateAvailabilityRule :one
+INSERT INTO
+  availability_rules (
+    employee_id,
+    type_id,
+    weekdays,
+    start_minute,
+    end_minute,
+    valid_from,
+    valid_until,
+    capacity
+  )
+VALUES
+  ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING
+  *;
+
+-- name: GetAvailabilityRuleById :one
+SELECT
+  *
+FROM
+  availability_rules
+WHERE
+  id = $1
+LIMIT
+  1;
+
+-- name: GetAllAvailabilityRules :many
+SELECT
+  *
+FROM
+  availability_rules
+ORDER BY
+  id;
+
+-- name: GetActiveAvailabilityRules :many
+SELECT
+  *
+FROM
+  availability_rules
+WHERE
+  valid_until IS NULL
+  OR valid_until >= $1
+ORDER BY
+  id;
+
+-- name: UpdateAvailabilityRule :one
+UPDATE availability_rules
+SET
+  employee_id = $2,
+  type_id = $3,
+  weekdays = $4,
+  start_minute = $5,
+  end_minute = $6,
+  valid_from = $7,
+  valid_until = $8,
+  capacity = $9,
+  last_edited = DEFAULT
+WHERE
+  id = $1
+RETURNING
+  *;
+
+-- name: DeleteAvailabilityRule :one
+DELETE FROM availability_rules
+WHERE
+  id = $1
+RETURNING
+  id;
+
+-- name: CreateAvailabilityRuleException :one
+INSERT INTO
+  availability_rule_exceptions (rule_id, date)
+VALUES
+  ($1, $2)
+RETURNING
+  id;
+
+-- name: GetAvailabilityRuleExceptions :many
+SELECT
+  *
+FROM
+  availability_rule_exceptions
+WHERE
+  rule_id = $1
+ORDER BY
+  date;
+
+-- name: DeleteAvailabilityRuleException :one
+DELETE FROM availability_rule_exceptions
+WHERE
+  rule_id = $1
+  AND date = $2
+RETURNING
+  id;
+
+-- name: CreateRuleAvailabilitySlot :execrows
+INSERT INTO
+  availability (
+    employee_id,
+    datetime,
+    rule_id,
+    unit_minutes,
+    capacity
+  )
+SELECT
+  sqlc.arg('employee_id')::int,
+  sqlc.arg('datetime')::timestamptz,
+  sqlc.narg('rule_id')::int,
+  sqlc.arg('unit_minutes')::int,
+  sqlc.arg('capacity')::int
+WHERE
+  NOT EXISTS (
+    SELECT
+      1
+    FROM
+      availability a
+    WHERE
+      a.employee_id = sqlc.arg('employee_id')::int
+      AND a.datetime < sqlc.arg('datetime')::timestamptz + sqlc.arg('unit_minutes')::int * INTERVAL '1 minute'
+      AND a.datetime + a.unit_minutes * INTERVAL '1 minute' > sqlc.arg('datetime')::timestamptz
+  )
+ON CONFLICT (employee_id, datetime) DO NOTHING;
+
+-- name: CreateRuleAvailabilitySlotType :exec
+INSERT INTO
+  availability_types (availability_id, type_id, rule_id)
+SELECT
+  a.id,
+  sqlc.arg('type_id')::int,
+  sqlc.arg('rule_id')::int
+FROM
+  availability a
+WHERE
+  a.employee_id = sqlc.arg('employee_id')::int
+  AND a.datetime = sqlc.arg('datetime')::timestamptz
+  AND a.unit_minutes = sqlc.arg('unit_minutes')::int
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteUnbookedRuleSlots :execrows
+DELETE FROM availability a
+WHERE
+  a.rule_id = $1
+  AND a.datetime >= $2
+  AND (
+    $3::timestamptz IS NULL
+    OR a.datetime < $3::timestamptz
+  )
+  AND NOT EXISTS (
+    SELECT
+      1
+    FROM
+      booking_slots bs
+    WHERE
+      bs.availability_slot_id = a.id
+  )
+  AND NOT EXISTS (
+    SELECT
+      1
+    FROM
+      slot_hold_slots hs
+      JOIN slot_holds h ON h.id = hs.hold_id
+    WHERE
+      hs.availability_slot_id = a.id
+      AND h.expires_at > CURRENT_TIMESTAMP
+  )
+  AND NOT EXISTS (
+    SELECT
+      1
+    FROM
+      availability_types t
+    WHERE
+      t.availability_id = a.id
+  );
+
+-- name: DeleteUnbookedRuleSlotTypes :execrows
+DELETE FROM availability_types t USING availability a
+WHERE
+  t.availability_id = a.id
+  AND t.rule_id = $1
+  AND a.datetime >= $2
+  AND (
+    $3::timestamptz IS NULL
+    OR a.datetime < $3::timestamptz
+  )
+  AND NOT EXISTS (
+    SELECT
+      1
+    FROM
+      booking_slots bs
+    WHERE
+      bs.availability_slot_id = a.id
+  )
+  AND NOT EXISTS (
+    SELECT
+      1
+    FROM
+      slot_hold_slots hs
+      JOIN slot_holds h ON h.id = hs.hold_id
+    WHERE
+      hs.availability_slot_id = a.id
+      AND h.expires_at > CURRENT_TIMESTAMP
+  );
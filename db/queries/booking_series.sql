@@ -0,0 +1,61 @@
+-- name: CreateBookingSeries :one
+INSERT INTO
+  booking_series (
+    user_id,
+    type_id,
+    employee_id,
+    interval_weeks,
+    occurrences,
+    created_by
+  )
+VALUES
+  ($1, $2, $3, $4, $5, $6)
+RETURNING
+  *;
+
+-- name: GetBookingSeriesById :one
+SELECT
+  *
+FROM
+  booking_series
+WHERE
+  id = $1
+LIMIT
+  1;
+
+-- name: SetBookingSeries :exec
+UPDATE bookings
+SET
+  series_id = $2,
+  series_occurrence = $3
+WHERE
+  id = $1;
+
+-- name: GetBookingSeriesBookings :many
+SELECT
+  b.id,
+  b.series_occurrence,
+  b.status,
+  MIN(a.datetime)::timestamptz AS start_time,
+  MAX(a.datetime + a.unit_minutes * INTERVAL '1 minute')::timestamptz AS end_time
+FROM
+  bookings b
+  LEFT JOIN booking_slots bs ON bs.booking_id = b.id
+  LEFT JOIN availability a ON a.id = bs.availability_slot_id
+WHERE
+  b.series_id = $1
+GROUP BY
+  b.id
+ORDER BY
+  b.series_occurrence;
+
+-- name: GetEmployeeSlotsAt :many
+SELECT
+  *
+FROM
+  availability
+WHERE
+  employee_id = $1
+  AND datetime = ANY ($2::timestamptz[])
+ORDER BY
+  datetime;
@@ -6,10 +6,6 @@ FROM
 
 -- name: GetAllBookingsWithJoinByID :many
 WITH
-  unit AS (
-    SELECT
-      $2::integer AS minutes
-  ),
   cancelled_history AS (
     SELECT
       h.booking_id,
@@ -80,18 +76,13 @@ SELECT
   b.last_edited,
   CASE
     WHEN b.status = 'cancelled' THEN ch.cancelled_start_time
-    ELSE MIN(a.datetime)::timestamp
+    ELSE MIN(a.datetime)::timestamptz
   END AS start_time,
   CASE
     WHEN b.status = 'cancelled' THEN ch.cancelled_end_time
     ELSE (
-      MAX(a.datetime) + (
-        SELECT
-          minutes
-        FROM
-          unit
-      ) * INTERVAL '1 minute'
-    )::timestamp
+      MAX(a.datetime + a.unit_minutes * INTERVAL '1 minute')
+    )::timestamptz
   END AS end_time,
   CASE
     WHEN b.status = 'cancelled' THEN ch.cancelled_employee_id::int
@@ -152,10 +143,6 @@ ORDER BY
 
 -- name: GetAllBookingsWithJoin :many
 WITH
-  unit AS (
-    SELECT
-      $1::integer AS minutes
-  ),
   cancelled_history AS (
     SELECT
       h.booking_id,
@@ -226,18 +213,13 @@ SELECT
   b.last_edited,
   CASE
     WHEN b.status = 'cancelled' THEN ch.cancelled_start_time
-    ELSE MIN(a.datetime)::timestamp
+    ELSE MIN(a.datetime)::timestamptz
   END AS start_time,
   CASE
     WHEN b.status = 'cancelled' THEN ch.cancelled_end_time
     ELSE (
-      MAX(a.datetime) + (
-        SELECT
-          minutes
-        FROM
-          unit
-      ) * INTERVAL '1 minute'
-    )::timestamp
+      MAX(a.datetime + a.unit_minutes * INTERVAL '1 minute')
+    )::timestamptz
   END AS end_time,
   CASE
     WHEN b.status = 'cancelled' THEN ch.cancelled_employee_id::int
@@ -296,10 +278,6 @@ ORDER BY
 
 -- name: GetBookingWithJoin :one
 WITH
-  unit AS (
-    SELECT
-      $1::integer AS minutes
-  ),
   cancelled_history AS (
     SELECT
       h.booking_id,
@@ -370,18 +348,13 @@ SELECT
   b.last_edited,
   CASE
     WHEN b.status = 'cancelled' THEN ch.cancelled_start_time
-    ELSE MIN(a.datetime)::timestamp
+    ELSE MIN(a.datetime)::timestamptz
   END AS start_time,
   CASE
     WHEN b.status = 'cancelled' THEN ch.cancelled_end_time
     ELSE (
-      MAX(a.datetime) + (
-        SELECT
-          minutes
-        FROM
-          unit
-      ) * INTERVAL '1 minute'
-    )::timestamp
+      MAX(a.datetime + a.unit_minutes * INTERVAL '1 minute')
+    )::timestamptz
   END AS end_time,
   CASE
     WHEN b.status = 'cancelled' THEN ch.cancelled_employee_id::int
@@ -408,7 +381,7 @@ FROM
   LEFT JOIN cancelled_history ch ON b.id = ch.booking_id
   LEFT JOIN employees e ON e.id = a.employee_id
 WHERE
-  b.id = $2
+  b.id = $1
 GROUP BY
   b.id,
   b.user_id,
@@ -453,6 +426,13 @@ SELECT
   b.notes,
   b.created_at,
   b.last_edited,
+  b.series_id,
+  b.series_occurrence,
+  b.free_cancellation_hours,
+  b.late_cancellation_percent,
+  b.no_show_percent,
+  b.deposit,
+  b.deposit_due_at,
   array_agg(
     bs.availability_slot_id
     ORDER BY
@@ -468,10 +448,52 @@ GROUP BY
 LIMIT
   1;
 
--- name: PostManualPayment :exec 
+-- name: SetBookingDeposit :exec
+UPDATE bookings
+SET
+  deposit = $2,
+  deposit_due_at = $3
+WHERE
+  id = $1;
+
+-- name: GetUnpaidDepositBookings :many
+SELECT
+  b.id
+FROM
+  bookings b
+WHERE
+  b.status IN ('created', 'rescheduled')
+  AND b.deposit > 0
+  AND b.deposit_due_at <= CURRENT_TIMESTAMP
+  AND (
+    SELECT
+      COALESCE(
+        SUM(l.amount) FILTER (
+          WHERE
+            l.kind = 'payment'
+        ),
+        0
+      ) - COALESCE(
+        SUM(l.amount) FILTER (
+          WHERE
+            l.kind = 'refund'
+        ),
+        0
+      )
+    FROM
+      booking_ledger_entries l
+    WHERE
+      l.booking_id = b.id
+  ) < b.deposit
+ORDER BY
+  b.deposit_due_at
+FOR UPDATE OF
+  b SKIP LOCKED;
+
+-- name: SetBookingPaid :exec
 UPDATE bookings
 SET
-  paid = true
+  paid = $2
 WHERE
   id = $1;
 
@@ -491,7 +513,9 @@ SELECT
 FROM
   availability
 WHERE
-  id = ANY ($1::int[]);
+  id = ANY ($1::int[])
+ORDER BY
+  datetime;
 
 -- name: GetAllAvailabilitySlots :many
 SELECT
@@ -505,15 +529,173 @@ SELECT
 FROM
   availability a
 WHERE
-  NOT EXISTS (
+  -- there is a seat that isnt booked or held
+  (
     SELECT
-      1
+      COUNT(*)
     FROM
       booking_slots bs
     WHERE
       bs.availability_slot_id = a.id
+  ) + (
+    SELECT
+      COUNT(*)
+    FROM
+      slot_hold_slots hs
+      JOIN slot_holds h ON h.id = hs.hold_id
+    WHERE
+      hs.availability_slot_id = a.id
+      AND h.expires_at > CURRENT_TIMESTAMP
+  ) < a.capacity
+  -- within the buffers of one of the employee's other bookings, bookings on
+  -- this slot are taking the other seats
+  AND NOT EXISTS (
+    SELECT
+      1
+    FROM
+      booking_slots bs
+      JOIN availability ba ON ba.id = bs.availability_slot_id
+      JOIN bookings b ON b.id = bs.booking_id
+      JOIN booking_types bt ON bt.id = b.type_id
+    WHERE
+      ba.employee_id = a.employee_id
+      AND a.datetime < ba.datetime + (ba.unit_minutes + bt.buffer_after_minutes) * INTERVAL '1 minute'
+      AND a.datetime + a.unit_minutes * INTERVAL '1 minute' > ba.datetime - bt.buffer_before_minutes * INTERVAL '1 minute'
+      AND NOT EXISTS (
+        SELECT
+          1
+        FROM
+          booking_slots own
+        WHERE
+          own.booking_id = b.id
+          AND own.availability_slot_id = a.id
+      )
+  )
+  -- bookable now for at least one of the types it is offered for
+  AND EXISTS (
+    SELECT
+      1
+    FROM
+      availability_types t
+      JOIN booking_types bt ON bt.id = t.type_id
+    WHERE
+      t.availability_id = a.id
+      AND a.datetime >= CURRENT_TIMESTAMP + bt.min_notice_minutes * INTERVAL '1 minute'
+      AND (
+        bt.max_advance_days = 0
+        OR a.datetime <= CURRENT_TIMESTAMP + bt.max_advance_days * INTERVAL '1 day'
+      )
+  )
+  -- the employee is on leave or the business is closed
+  AND NOT EXISTS (
+    SELECT
+      1
+    FROM
+      employee_time_off o
+    WHERE
+      o.employee_id = a.employee_id
+      AND o.start_time < a.datetime + a.unit_minutes * INTERVAL '1 minute'
+      AND o.end_time > a.datetime
+  )
+  AND NOT EXISTS (
+    SELECT
+      1
+    FROM
+      closures c
+      JOIN employees e ON e.id = a.employee_id
+    WHERE
+      (a.datetime AT TIME ZONE e.time_zone)::date BETWEEN c.start_date AND c.end_date
   );
 
+-- name: SearchFreeAvailabilitySlots :many
+SELECT
+  a.*
+FROM
+  availability a
+WHERE
+  EXISTS (
+    SELECT
+      1
+    FROM
+      availability_types t
+    WHERE
+      t.availability_id = a.id
+      AND t.type_id = sqlc.arg('type_id')
+  )
+  AND a.datetime >= GREATEST(sqlc.arg('from_time')::timestamptz, CURRENT_TIMESTAMP)
+  AND a.datetime < sqlc.arg('to_time')::timestamptz
+  AND (
+    sqlc.narg('employee_id')::int IS NULL
+    OR a.employee_id = sqlc.narg('employee_id')::int
+  )
+  -- there is a seat that isnt booked or held
+  AND (
+    SELECT
+      COUNT(*)
+    FROM
+      booking_slots bs
+    WHERE
+      bs.availability_slot_id = a.id
+  ) + (
+    SELECT
+      COUNT(*)
+    FROM
+      slot_hold_slots hs
+      JOIN slot_holds h ON h.id = hs.hold_id
+    WHERE
+      hs.availability_slot_id = a.id
+      AND h.expires_at > CURRENT_TIMESTAMP
+  ) < a.capacity
+  -- the employee is on leave or the business is closed
+  AND NOT EXISTS (
+    SELECT
+      1
+    FROM
+      employee_time_off o
+    WHERE
+      o.employee_id = a.employee_id
+      AND o.start_time < a.datetime + a.unit_minutes * INTERVAL '1 minute'
+      AND o.end_time > a.datetime
+  )
+  AND NOT EXISTS (
+    SELECT
+      1
+    FROM
+      closures c
+      JOIN employees e ON e.id = a.employee_id
+    WHERE
+      (a.datetime AT TIME ZONE e.time_zone)::date BETWEEN c.start_date AND c.end_date
+  )
+ORDER BY
+  a.employee_id,
+  a.datetime;
+
+-- name: GetBookedTimes :many
+SELECT
+  a.employee_id,
+  b.type_id,
+  MIN(a.datetime)::timestamptz AS start_time,
+  MAX(a.datetime + a.unit_minutes * INTERVAL '1 minute')::timestamptz AS end_time,
+  bt.buffer_before_minutes,
+  bt.buffer_after_minutes
+FROM
+  bookings b
+  JOIN booking_slots bs ON bs.booking_id = b.id
+  JOIN availability a ON a.id = bs.availability_slot_id
+  JOIN booking_types bt ON bt.id = b.type_id
+WHERE
+  a.employee_id = ANY (sqlc.arg('employee_ids')::int[])
+GROUP BY
+  b.id,
+  a.employee_id,
+  bt.buffer_before_minutes,
+  bt.buffer_after_minutes
+HAVING
+  MIN(a.datetime) < sqlc.arg('to_time')::timestamptz
+  AND MAX(a.datetime + a.unit_minutes * INTERVAL '1 minute') > sqlc.arg('from_time')::timestamptz
+ORDER BY
+  start_time;
+
 -- name: GetAllBookingTypes :many
 SELECT
   *
@@ -532,10 +714,6 @@ LIMIT
 
 -- name: CreateBooking :one 
 WITH
-  unit AS (
-    SELECT
-      $7::integer AS minutes
-  ),
   new_booking as (
     INSERT INTO
       bookings (
@@ -544,7 +722,10 @@ WITH
         paid,
         cost,
         notes,
-        status_updated_by
+        status_updated_by,
+        free_cancellation_hours,
+        late_cancellation_percent,
+        no_show_percent
       )
     VALUES
       (
@@ -560,6 +741,39 @@ WITH
             users
           WHERE
             users.id = $1
+        ),
+        COALESCE(
+          (
+            SELECT
+              free_cancellation_hours
+            FROM
+              booking_types
+            WHERE
+              booking_types.id = $2
+          ),
+          0
+        ),
+        COALESCE(
+          (
+            SELECT
+              late_cancellation_percent
+            FROM
+              booking_types
+            WHERE
+              booking_types.id = $2
+          ),
+          0
+        ),
+        COALESCE(
+          (
+            SELECT
+              no_show_percent
+            FROM
+              booking_types
+            WHERE
+              booking_types.id = $2
+          ),
+          0
         )
       )
     RETURNING
@@ -581,17 +795,8 @@ WITH
     SELECT
       s.booking_id,
       a.employee_id,
-      MIN(a.datetime)::timestamp AS start_time,
-      (
-        MAX(a.datetime) + (
-          (
-            SELECT
-              minutes
-            from
-              unit
-          ) * INTERVAL '1 minute'
-        )
-      )::timestamp AS end_time
+      MIN(a.datetime)::timestamptz AS start_time,
+      MAX(a.datetime + a.unit_minutes * INTERVAL '1 minute')::timestamptz AS end_time
     FROM
       slot_insert s
       JOIN availability a ON s.availability_slot_id = a.id
@@ -617,10 +822,9 @@ UPDATE bookings
 SET
   user_id = $2,
   type_id = $3,
-  paid = $4,
-  cost = $5,
-  notes = $6,
-  status_updated_by = $7,
+  cost = $4,
+  notes = $5,
+  status_updated_by = $6,
   last_edited = DEFAULT
 WHERE
   id = $1
@@ -636,6 +840,17 @@ SET
 WHERE
   id = $1;
 
+-- name: RescheduleBooking :exec
+UPDATE bookings
+SET
+  cost = $2,
+  status = 'rescheduled',
+  status_updated_by = $3,
+  status_updated_at = DEFAULT,
+  last_edited = DEFAULT
+WHERE
+  id = $1;
+
 -- name: DeleteBooking :one
 DELETE FROM bookings
 WHERE
@@ -649,6 +864,17 @@ INSERT INTO
 VALUES
   ($1, $2);
 
+-- name: GetBookingAvailabilitySlots :many
+SELECT
+  a.*
+FROM
+  availability a
+  JOIN booking_slots bs ON bs.availability_slot_id = a.id
+WHERE
+  bs.booking_id = $1
+ORDER BY
+  a.datetime;
+
 -- name: GetBookingSlotsFromAvailability :many
 SELECT DISTINCT
   booking_id
@@ -683,10 +909,50 @@ INSERT INTO
     start_time,
     end_time,
     status,
-    changed_by_email
+    changed_by_email,
+    previous_start_time,
+    previous_end_time,
+    reason
   )
 VALUES
-  ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+  ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);
+
+-- name: CountBookingReschedules :one
+SELECT
+  COUNT(*)
+FROM
+  booking_history
+WHERE
+  booking_id = $1
+  AND status = 'rescheduled';
+
+-- name: CountBookingTypeUses :one
+SELECT
+  (
+    SELECT
+      COUNT(*)
+    FROM
+      availability_types
+    WHERE
+      availability_types.type_id = $1
+  ) + (
+    SELECT
+      COUNT(*)
+    FROM
+      bookings
+    WHERE
+      bookings.type_id = $1
+  ) AS uses;
+
+-- name: GetBookingSlotIds :many
+SELECT
+  availability_slot_id
+FROM
+  booking_slots
+WHERE
+  booking_id = $1
+ORDER BY
+  availability_slot_id;
 
 -- name: FreeAvailabilitySlot :exec
 DELETE FROM booking_slots
@@ -695,9 +961,9 @@ WHERE
 
 -- name: CreateEmployee :one 
 INSERT INTO
-  employees (name, surname, email, title, description)
+  employees (name, surname, email, title, description, time_zone)
 VALUES
-  ($1, $2, $3, $4, $5)
+  ($1, $2, $3, $4, $5, $6)
 RETURNING
   id;
 
@@ -710,6 +976,7 @@ SET
   email = $4,
   title = $5,
   description = $6,
+  time_zone = $7,
   created_at = DEFAULT,
   last_login = DEFAULT
 WHERE
@@ -726,19 +993,73 @@ RETURNING
 
 -- name: CreateAvailabilitySlot :one 
 INSERT INTO
-  availability (employee_id, datetime, type_id)
+  availability (employee_id, datetime, unit_minutes, capacity)
 VALUES
-  ($1, $2, $3)
+  ($1, $2, $3, $4)
 RETURNING
   id;
 
+-- name: CreateAvailabilityTypes :exec
+INSERT INTO
+  availability_types (availability_id, type_id)
+SELECT
+  $1,
+  UNNEST($2::int[])
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteAvailabilityTypes :exec
+DELETE FROM availability_types
+WHERE
+  availability_id = ANY ($1::int[]);
+
+-- name: GetAvailabilityTypes :many
+SELECT
+  *
+FROM
+  availability_types
+WHERE
+  availability_id = ANY ($1::int[])
+ORDER BY
+  availability_id,
+  type_id;
+
+-- name: LockBooking :one
+SELECT
+  id
+FROM
+  bookings
+WHERE
+  id = $1
+FOR UPDATE;
+
+-- name: LockEmployee :one
+SELECT
+  id
+FROM
+  employees
+WHERE
+  id = $1
+FOR UPDATE;
+
+-- name: GetOverlappingAvailabilitySlots :many
+SELECT
+  *
+FROM
+  availability a
+WHERE
+  a.employee_id = sqlc.arg('employee_id')
+  AND a.datetime < sqlc.arg('end_time')::timestamptz
+  AND a.datetime + a.unit_minutes * INTERVAL '1 minute' > sqlc.arg('start_time')::timestamptz
+  AND NOT a.id = ANY (sqlc.arg('exclude_ids')::int[])
+ORDER BY
+  a.datetime;
+
 -- name: UpdateAvailabilitySlot :one
 UPDATE availability
 SET
   id = $1,
   employee_id = $2,
   datetime = $3,
-  type_id = $4,
   created_at = DEFAULT,
   last_edited = DEFAULT
 WHERE
@@ -746,6 +1067,14 @@ WHERE
 RETURNING
   id;
 
+-- name: UpdateAvailabilityCapacity :exec
+UPDATE availability
+SET
+  capacity = $2,
+  last_edited = DEFAULT
+WHERE
+  id = ANY ($1::int[]);
+
 -- name: DeleteAvailabilitySlot :one
 DELETE FROM availability
 WHERE
@@ -755,9 +1084,43 @@ RETURNING
 
 -- name: CreateBookingType :one 
 INSERT INTO
-  booking_types (title, description, fixed, cost, duration)
+  booking_types (
+    title,
+    description,
+    fixed,
+    cost,
+    duration,
+    unit_minutes,
+    buffer_before_minutes,
+    buffer_after_minutes,
+    min_notice_minutes,
+    max_advance_days,
+    free_cancellation_hours,
+    late_cancellation_percent,
+    no_show_percent,
+    deposit_percent,
+    deposit_amount,
+    deposit_window_hours
+  )
 VALUES
-  ($1, $2, $3, $4, $5)
+  (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13,
+    $14,
+    $15,
+    $16
+  )
 RETURNING
   id;
 
@@ -770,6 +1133,17 @@ SET
   fixed = $4,
   cost = $5,
   duration = $6,
+  unit_minutes = $7,
+  buffer_before_minutes = $8,
+  buffer_after_minutes = $9,
+  min_notice_minutes = $10,
+  max_advance_days = $11,
+  free_cancellation_hours = $12,
+  late_cancellation_percent = $13,
+  no_show_percent = $14,
+  deposit_percent = $15,
+  deposit_amount = $16,
+  deposit_window_hours = $17,
   created_at = DEFAULT,
   last_edited = DEFAULT
 WHERE
@@ -0,0 +1,163 @@
+-- name: CreateSlotHold :one
+INSERT INTO
+  slot_holds (user_id, expires_at)
+VALUES
+  ($1, $2)
+RETURNING
+  *;
+
+-- name: CreateSlotHoldSlots :exec
+INSERT INTO
+  slot_hold_slots (hold_id, availability_slot_id)
+SELECT
+  $1::int,
+  unnest($2::int[]);
+
+-- name: GetSlotHoldById :one
+SELECT
+  *
+FROM
+  slot_holds
+WHERE
+  id = $1
+LIMIT
+  1;
+
+-- name: GetSlotHoldSlotIds :many
+SELECT
+  availability_slot_id
+FROM
+  slot_hold_slots
+WHERE
+  hold_id = $1
+ORDER BY
+  availability_slot_id;
+
+-- name: DeleteSlotHold :one
+DELETE FROM slot_holds
+WHERE
+  id = $1
+RETURNING
+  id;
+
+-- name: DeleteExpiredSlotHolds :execrows
+DELETE FROM slot_holds
+WHERE
+  expires_at <= CURRENT_TIMESTAMP;
+
+-- name: LockAvailabilitySlots :many
+SELECT
+  id
+FROM
+  availability
+WHERE
+  id = ANY ($1::int[])
+ORDER BY
+  id
+FOR UPDATE;
+
+-- name: LockSlotEmployees :many
+-- the employees with slots in $1, locked before the slots themselves
+SELECT
+  id
+FROM
+  employees
+WHERE
+  id IN (
+    SELECT
+      employee_id
+    FROM
+      availability
+    WHERE
+      availability.id = ANY ($1::int[])
+  )
+ORDER BY
+  id
+FOR UPDATE;
+
+-- name: GetTakenSlotIds :many
+-- slots with every seat booked or held by another hold
+SELECT
+  a.id AS availability_slot_id
+FROM
+  availability a
+WHERE
+  a.id = ANY ($1::int[])
+  AND (
+    SELECT
+      COUNT(*)
+    FROM
+      booking_slots bs
+    WHERE
+      bs.availability_slot_id = a.id
+  ) + (
+    SELECT
+      COUNT(*)
+    FROM
+      slot_hold_slots hs
+      JOIN slot_holds h ON h.id = hs.hold_id
+    WHERE
+      hs.availability_slot_id = a.id
+      AND h.id <> $2
+      AND h.expires_at > CURRENT_TIMESTAMP
+  ) >= a.capacity
+UNION
+-- slots during the employee's time off or a closure
+SELECT
+  a.id
+FROM
+  availability a
+  JOIN employees e ON e.id = a.employee_id
+WHERE
+  a.id = ANY ($1::int[])
+  AND (
+    EXISTS (
+      SELECT
+        1
+      FROM
+        employee_time_off o
+      WHERE
+        o.employee_id = a.employee_id
+        AND o.start_time < a.datetime + a.unit_minutes * INTERVAL '1 minute'
+        AND o.end_time > a.datetime
+    )
+    OR EXISTS (
+      SELECT
+        1
+      FROM
+        closures c
+      WHERE
+        (a.datetime AT TIME ZONE e.time_zone)::date BETWEEN c.start_date AND c.end_date
+    )
+  )
+ORDER BY
+  availability_slot_id;
+
+-- name: GetSlotSeats :many
+SELECT
+  a.id,
+  a.capacity,
+  (
+    SELECT
+      COUNT(*)
+    FROM
+      booking_slots bs
+    WHERE
+      bs.availability_slot_id = a.id
+  ) AS booked,
+  (
+    SELECT
+      COUNT(*)
+    FROM
+      slot_hold_slots hs
+      JOIN slot_holds h ON h.id = hs.hold_id
+    WHERE
+      hs.availability_slot_id = a.id
+      AND h.expires_at > CURRENT_TIMESTAMP
+  ) AS held
+FROM
+  availability a
+WHERE
+  a.id = ANY ($1::int[])
+ORDER BY
+  a.id;
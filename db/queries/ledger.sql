@@ -0,0 +1,53 @@
+-- name: CreateLedgerEntry :one
+INSERT INTO
+  booking_ledger_entries (
+    booking_id,
+    kind,
+    amount,
+    payment_id,
+    reason,
+    created_by
+  )
+VALUES
+  ($1, $2, $3, $4, $5, $6)
+RETURNING
+  *;
+
+-- name: GetBookingLedger :many
+SELECT
+  *
+FROM
+  booking_ledger_entries
+WHERE
+  booking_id = $1
+ORDER BY
+  created_at,
+  id;
+
+-- name: GetBookingBalance :one
+SELECT
+  COALESCE(
+    SUM(amount) FILTER (
+      WHERE
+        kind IN ('charge', 'adjustment')
+    ),
+    0
+  )::int AS charged,
+  COALESCE(
+    SUM(amount) FILTER (
+      WHERE
+        kind = 'payment'
+    ),
+    0
+  )::int AS paid,
+  COALESCE(
+    SUM(amount) FILTER (
+      WHERE
+        kind = 'refund'
+    ),
+    0
+  )::int AS refunded
+FROM
+  booking_ledger_entries
+WHERE
+  booking_id = $1;
@@ -0,0 +1,129 @@
+-- name: CreatePayment :one
+INSERT INTO
+  payments (
+    booking_id,
+    provider,
+    provider_ref,
+    amount,
+    currency,
+    status,
+    created_by
+  )
+VALUES
+  ($1, $2, $3, $4, $5, $6, $7)
+RETURNING
+  *;
+
+-- name: CreateManualPayment :one
+INSERT INTO
+  payments (
+    booking_id,
+    provider,
+    amount,
+    currency,
+    status,
+    created_by,
+    method,
+    reference
+  )
+VALUES
+  ($1, 'manual', $2, $3, 'captured', $4, $5, $6)
+RETURNING
+  *;
+
+-- name: GetPaymentById :one
+SELECT
+  *
+FROM
+  payments
+WHERE
+  id = $1;
+
+-- name: LockPayment :one
+SELECT
+  *
+FROM
+  payments
+WHERE
+  id = $1
+FOR UPDATE;
+
+-- name: LockPaymentByProviderRef :one
+SELECT
+  *
+FROM
+  payments
+WHERE
+  provider = $1
+  AND provider_ref = $2
+FOR UPDATE;
+
+-- name: GetBookingPayments :many
+SELECT
+  *
+FROM
+  payments
+WHERE
+  booking_id = $1
+ORDER BY
+  created_at,
+  id;
+
+-- name: UpdatePaymentStatus :exec
+UPDATE payments
+SET
+  status = $2,
+  refunded_amount = $3,
+  updated_at = CURRENT_TIMESTAMP
+WHERE
+  id = $1;
+
+-- name: CreatePaymentRefund :one
+INSERT INTO
+  payment_refunds (payment_id, amount)
+VALUES
+  ($1, $2)
+RETURNING
+  *;
+
+-- name: GetUnsentPaymentRefunds :many
+SELECT
+  id
+FROM
+  payment_refunds
+WHERE
+  sent_at IS NULL
+ORDER BY
+  id;
+
+-- name: LockUnsentPaymentRefund :one
+SELECT
+  r.id,
+  r.amount,
+  p.provider,
+  p.provider_ref
+FROM
+  payment_refunds r
+  JOIN payments p ON p.id = r.payment_id
+WHERE
+  r.id = $1
+  AND r.sent_at IS NULL
+FOR UPDATE OF
+  r SKIP LOCKED;
+
+-- name: MarkPaymentRefundSent :exec
+UPDATE payment_refunds
+SET
+  attempts = attempts + 1,
+  last_error = NULL,
+  sent_at = CURRENT_TIMESTAMP
+WHERE
+  id = $1;
+
+-- name: MarkPaymentRefundFailed :exec
+UPDATE payment_refunds
+SET
+  attempts = attempts + 1,
+  last_error = $2
+WHERE
+  id = $1;
@@ -0,0 +1,103 @@
+-- name: CreateResource :one
+INSERT INTO
+  resources (name, description)
+VALUES
+  ($1, $2)
+RETURNING
+  id;
+
+-- name: GetResourceById :one
+SELECT
+  *
+FROM
+  resources
+WHERE
+  id = $1
+LIMIT
+  1;
+
+-- name: GetAllResources :many
+SELECT
+  *
+FROM
+  resources
+ORDER BY
+  id;
+
+-- name: UpdateResource :one
+UPDATE resources
+SET
+  name = $2,
+  description = $3,
+  last_edited = DEFAULT
+WHERE
+  id = $1
+RETURNING
+  id;
+
+-- name: DeleteResource :one
+DELETE FROM resources
+WHERE
+  id = $1
+RETURNING
+  id;
+
+-- name: LockResources :many
+SELECT
+  id
+FROM
+  resources
+WHERE
+  id = ANY ($1::int[])
+ORDER BY
+  id
+FOR UPDATE;
+
+-- name: CreateBookingTypeResources :exec
+INSERT INTO
+  booking_type_resources (type_id, resource_id)
+SELECT
+  $1,
+  UNNEST($2::int[])
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteBookingTypeResources :exec
+DELETE FROM booking_type_resources
+WHERE
+  type_id = $1;
+
+-- name: GetBookingTypeResources :many
+SELECT
+  *
+FROM
+  booking_type_resources
+WHERE
+  type_id = ANY ($1::int[])
+ORDER BY
+  type_id,
+  resource_id;
+
+-- name: GetResourceBookedTimes :many
+SELECT
+  btr.resource_id,
+  b.id AS booking_id,
+  b.type_id,
+  a.employee_id,
+  MIN(a.datetime)::timestamptz AS start_time,
+  MAX(a.datetime + a.unit_minutes * INTERVAL '1 minute')::timestamptz AS end_time
+FROM
+  bookings b
+  JOIN booking_type_resources btr ON btr.type_id = b.type_id
+  JOIN booking_slots bs ON bs.booking_id = b.id
+  JOIN availability a ON a.id = bs.availability_slot_id
+WHERE
+  btr.resource_id = ANY (sqlc.arg('resource_ids')::int[])
+GROUP BY
+  btr.resource_id,
+  b.id,
+  a.employee_id
+HAVING
+  MIN(a.datetime) < sqlc.arg('to_time')::timestamptz
+  AND MAX(a.datetime + a.unit_minutes * INTERVAL '1 minute') > sqlc.arg('from_time')::timestamptz
+ORDER BY
+  start_time;
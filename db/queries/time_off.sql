@@ -0,0 +1,97 @@
+-- name: CreateEmployeeTimeOff :one
+INSERT INTO
+  employee_time_off (employee_id, start_time, end_time, reason, created_by)
+VALUES
+  ($1, $2, $3, $4, $5)
+RETURNING
+  *;
+
+-- name: GetEmployeeTimeOffById :one
+SELECT
+  *
+FROM
+  employee_time_off
+WHERE
+  id = $1
+LIMIT
+  1;
+
+-- name: GetAllEmployeeTimeOff :many
+SELECT
+  *
+FROM
+  employee_time_off
+WHERE
+  sqlc.narg('employee_id')::int IS NULL
+  OR employee_id = sqlc.narg('employee_id')::int
+ORDER BY
+  start_time;
+
+-- name: DeleteEmployeeTimeOff :one
+DELETE FROM employee_time_off
+WHERE
+  id = $1
+RETURNING
+  id;
+
+-- name: CreateClosure :one
+INSERT INTO
+  closures (start_date, end_date, reason, created_by)
+VALUES
+  ($1, $2, $3, $4)
+RETURNING
+  *;
+
+-- name: GetClosureById :one
+SELECT
+  *
+FROM
+  closures
+WHERE
+  id = $1
+LIMIT
+  1;
+
+-- name: GetAllClosures :many
+SELECT
+  *
+FROM
+  closures
+ORDER BY
+  start_date;
+
+-- name: DeleteClosure :one
+DELETE FROM closures
+WHERE
+  id = $1
+RETURNING
+  id;
+
+-- name: GetTimeOffConflicts :many
+SELECT DISTINCT
+  b.id
+FROM
+  bookings b
+  JOIN booking_slots bs ON bs.booking_id = b.id
+  JOIN availability a ON a.id = bs.availability_slot_id
+WHERE
+  a.employee_id = sqlc.arg('employee_id')::int
+  AND a.datetime < sqlc.arg('end_time')::timestamptz
+  AND a.datetime + a.unit_minutes * INTERVAL '1 minute' > sqlc.arg('start_time')::timestamptz
+  AND b.status IN ('created', 'confirmed', 'rescheduled')
+ORDER BY
+  b.id;
+
+-- name: GetClosureConflicts :many
+SELECT DISTINCT
+  b.id
+FROM
+  bookings b
+  JOIN booking_slots bs ON bs.booking_id = b.id
+  JOIN availability a ON a.id = bs.availability_slot_id
+  JOIN employees e ON e.id = a.employee_id
+WHERE
+  (a.datetime AT TIME ZONE e.time_zone)::date BETWEEN sqlc.arg('start_date')::date AND sqlc.arg('end_date')::date
+  AND b.status IN ('created', 'confirmed', 'rescheduled')
+ORDER BY
+  b.id;
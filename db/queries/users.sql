@@ -13,7 +13,6 @@ SET
   name = $2,
   surname = $3,
   email = $4,
-  hashed_password = $5,
   created_at = DEFAULT,
   last_login = DEFAULT
 WHERE
@@ -269,3 +268,41 @@ WHERE
   id = $1
 RETURNING
   *;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET
+  hashed_password = $2
+WHERE
+  id = $1;
+
+-- name: CreatePasswordReset :one
+INSERT INTO
+  password_resets (user_id, token_hash, expires_at)
+VALUES
+  ($1, $2, $3)
+RETURNING
+  id;
+
+-- name: GetPasswordResetByTokenHash :one
+SELECT
+  *
+FROM
+  password_resets
+WHERE
+  token_hash = $1;
+
+-- name: UsePasswordReset :one
+UPDATE password_resets
+SET
+  used_at = CURRENT_TIMESTAMP
+WHERE
+  id = $1
+  AND used_at IS NULL
+RETURNING
+  user_id;
+
+-- name: DeletePasswordResetsByUserID :exec
+DELETE FROM password_resets
+WHERE
+  user_id = $1;
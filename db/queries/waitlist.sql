@@ -0,0 +1,109 @@
+-- name: CreateWaitlistEntry :one
+INSERT INTO
+  waitlist_entries (user_id, type_id, employee_id, start_time, end_time)
+VALUES
+  ($1, $2, $3, $4, $5)
+RETURNING
+  *;
+
+-- name: GetWaitlistEntryById :one
+SELECT
+  *
+FROM
+  waitlist_entries
+WHERE
+  id = $1
+LIMIT
+  1;
+
+-- name: GetAllWaitlistEntries :many
+SELECT
+  *
+FROM
+  waitlist_entries
+WHERE
+  sqlc.narg('user_id')::int IS NULL
+  OR user_id = sqlc.narg('user_id')::int
+ORDER BY
+  created_at,
+  id;
+
+-- name: DeleteWaitlistEntry :one
+DELETE FROM waitlist_entries
+WHERE
+  id = $1
+RETURNING
+  id;
+
+-- name: GetWaitingWaitlistEntries :many
+SELECT
+  *
+FROM
+  waitlist_entries
+WHERE
+  status = 'waiting'
+  AND (
+    employee_id IS NULL
+    OR employee_id = sqlc.arg('employee_id')::int
+  )
+  AND start_time <= sqlc.arg('start_time')::timestamptz
+  AND end_time >= sqlc.arg('end_time')::timestamptz
+ORDER BY
+  created_at,
+  id
+FOR UPDATE;
+
+-- name: OfferWaitlistEntry :exec
+UPDATE waitlist_entries
+SET
+  status = 'offered',
+  hold_id = $2,
+  offered_slot_ids = $3,
+  offer_expires_at = $4
+WHERE
+  id = $1;
+
+-- name: GetWaitlistEntryByHoldId :one
+SELECT
+  *
+FROM
+  waitlist_entries
+WHERE
+  hold_id = $1
+  AND status = 'offered'
+LIMIT
+  1
+FOR UPDATE;
+
+-- name: BookWaitlistEntry :exec
+UPDATE waitlist_entries
+SET
+  status = 'booked',
+  booking_id = $2,
+  hold_id = NULL
+WHERE
+  id = $1;
+
+-- name: GetLapsedWaitlistOffers :many
+SELECT
+  *
+FROM
+  waitlist_entries
+WHERE
+  status = 'offered'
+  AND (
+    hold_id IS NULL
+    OR offer_expires_at <= CURRENT_TIMESTAMP
+  )
+ORDER BY
+  offer_expires_at
+FOR UPDATE
+  SKIP LOCKED;
+
+-- name: ExpireWaitlistEntry :exec
+UPDATE waitlist_entries
+SET
+  status = 'expired',
+  hold_id = NULL
+WHERE
+  id = $1;
@@ -50,6 +50,28 @@ func (q *Queries) CreateNewRoleRequest(ctx context.Context, arg CreateNewRoleReq
 	return id, err
 }
 
+const createPasswordReset = `-- name: CreatePasswordReset :one
+INSERT INTO
+  password_resets (user_id, token_hash, expires_at)
+VALUES
+  ($1, $2, $3)
+RETURNING
+  id
+`
+
+type CreatePasswordResetParams struct {
+	UserID    int32              `json:"user_id"`
+	TokenHash string             `json:"token_hash"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+}
+
+func (q *Queries) CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (int32, error) {
+	row := q.db.QueryRow(ctx, createPasswordReset, arg.UserID, arg.TokenHash, arg.ExpiresAt)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
 const createSession = `-- name: CreateSession :one
 INSERT INTO
   sessions (user_id, session_token, expires_at)
@@ -60,9 +82,9 @@ RETURNING
 `
 
 type CreateSessionParams struct {
-	UserID       int32            `json:"user_id"`
-	SessionToken string           `json:"session_token"`
-	ExpiresAt    pgtype.Timestamp `json:"expires_at"`
+	UserID       int32              `json:"user_id"`
+	SessionToken string             `json:"session_token"`
+	ExpiresAt    pgtype.Timestamptz `json:"expires_at"`
 }
 
 func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (int32, error) {
@@ -111,6 +133,17 @@ func (q *Queries) DeleteExpiredSessions(ctx context.Context) error {
 	return err
 }
 
+const deletePasswordResetsByUserID = `-- name: DeletePasswordResetsByUserID :exec
+DELETE FROM password_resets
+WHERE
+  user_id = $1
+`
+
+func (q *Queries) DeletePasswordResetsByUserID(ctx context.Context, userID int32) error {
+	_, err := q.db.Exec(ctx, deletePasswordResetsByUserID, userID)
+	return err
+}
+
 const deleteSessionByToken = `-- name: DeleteSessionByToken :exec
 DELETE FROM sessions
 WHERE
@@ -149,7 +182,7 @@ func (q *Queries) DeleteUser(ctx context.Context, id int32) (int32, error) {
 
 const getAllEmployees = `-- name: GetAllEmployees :many
 SELECT
-  id, name, surname, email, title, description, created_at, last_login
+  id, name, surname, email, title, description, created_at, last_login, time_zone
 FROM
   employees
 `
@@ -172,6 +205,7 @@ func (q *Queries) GetAllEmployees(ctx context.Context) ([]Employee, error) {
 			&i.Description,
 			&i.CreatedAt,
 			&i.LastLogin,
+			&i.TimeZone,
 		); err != nil {
 			return nil, err
 		}
@@ -252,26 +286,26 @@ FROM
 `
 
 type GetAllRoleRequestsWithJoinRow struct {
-	ID                       int32             `json:"id"`
-	RequestingUserID         int32             `json:"requesting_user_id"`
-	RequestedRoleID          int32             `json:"requested_role_id"`
-	Status                   RoleRequestStatus `json:"status"`
-	Comment                  pgtype.Text       `json:"comment"`
-	ApprovingUserID          pgtype.Int4       `json:"approving_user_id"`
-	CreatedAt                pgtype.Timestamp  `json:"created_at"`
-	ApprovedAt               pgtype.Timestamp  `json:"approved_at"`
-	RequestingUserName       pgtype.Text       `json:"requesting_user_name"`
-	RequestingUserSurname    pgtype.Text       `json:"requesting_user_surname"`
-	RequestingUserEmail      pgtype.Text       `json:"requesting_user_email"`
-	RequestingUserCreatedAt  pgtype.Timestamp  `json:"requesting_user_created_at"`
-	RequestingUserLastLogin  pgtype.Timestamp  `json:"requesting_user_last_login"`
-	RequestedRoleName        pgtype.Text       `json:"requested_role_name"`
-	RequestedRoleDescription pgtype.Text       `json:"requested_role_description"`
-	ApprovingUserName        pgtype.Text       `json:"approving_user_name"`
-	ApprovingUserSurname     pgtype.Text       `json:"approving_user_surname"`
-	ApprovingUserEmail       pgtype.Text       `json:"approving_user_email"`
-	ApprovingUserCreatedAt   pgtype.Timestamp  `json:"approving_user_created_at"`
-	ApprovingUserLastLogin   pgtype.Timestamp  `json:"approving_user_last_login"`
+	ID                       int32              `json:"id"`
+	RequestingUserID         int32              `json:"requesting_user_id"`
+	RequestedRoleID          int32              `json:"requested_role_id"`
+	Status                   RoleRequestStatus  `json:"status"`
+	Comment                  pgtype.Text        `json:"comment"`
+	ApprovingUserID          pgtype.Int4        `json:"approving_user_id"`
+	CreatedAt                pgtype.Timestamptz `json:"created_at"`
+	ApprovedAt               pgtype.Timestamptz `json:"approved_at"`
+	RequestingUserName       pgtype.Text        `json:"requesting_user_name"`
+	RequestingUserSurname    pgtype.Text        `json:"requesting_user_surname"`
+	RequestingUserEmail      pgtype.Text        `json:"requesting_user_email"`
+	RequestingUserCreatedAt  pgtype.Timestamptz `json:"requesting_user_created_at"`
+	RequestingUserLastLogin  pgtype.Timestamptz `json:"requesting_user_last_login"`
+	RequestedRoleName        pgtype.Text        `json:"requested_role_name"`
+	RequestedRoleDescription pgtype.Text        `json:"requested_role_description"`
+	ApprovingUserName        pgtype.Text        `json:"approving_user_name"`
+	ApprovingUserSurname     pgtype.Text        `json:"approving_user_surname"`
+	ApprovingUserEmail       pgtype.Text        `json:"approving_user_email"`
+	ApprovingUserCreatedAt   pgtype.Timestamptz `json:"approving_user_created_at"`
+	ApprovingUserLastLogin   pgtype.Timestamptz `json:"approving_user_last_login"`
 }
 
 func (q *Queries) GetAllRoleRequestsWithJoin(ctx context.Context) ([]GetAllRoleRequestsWithJoinRow, error) {
@@ -317,7 +351,7 @@ func (q *Queries) GetAllRoleRequestsWithJoin(ctx context.Context) ([]GetAllRoleR
 
 const getEmployeeById = `-- name: GetEmployeeById :one
 SELECT
-  id, name, surname, email, title, description, created_at, last_login
+  id, name, surname, email, title, description, created_at, last_login, time_zone
 FROM
   employees
 WHERE
@@ -338,6 +372,7 @@ func (q *Queries) GetEmployeeById(ctx context.Context, id int32) (Employee, erro
 		&i.Description,
 		&i.CreatedAt,
 		&i.LastLogin,
+		&i.TimeZone,
 	)
 	return i, err
 }
@@ -368,6 +403,29 @@ func (q *Queries) GetLatestSession(ctx context.Context, userID int32) (Session,
 	return i, err
 }
 
+const getPasswordResetByTokenHash = `-- name: GetPasswordResetByTokenHash :one
+SELECT
+  id, user_id, token_hash, created_at, expires_at, used_at
+FROM
+  password_resets
+WHERE
+  token_hash = $1
+`
+
+func (q *Queries) GetPasswordResetByTokenHash(ctx context.Context, tokenHash string) (PasswordReset, error) {
+	row := q.db.QueryRow(ctx, getPasswordResetByTokenHash, tokenHash)
+	var i PasswordReset
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TokenHash,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.UsedAt,
+	)
+	return i, err
+}
+
 const getRoleByName = `-- name: GetRoleByName :one
 SELECT
   id
@@ -479,26 +537,26 @@ WHERE
 `
 
 type GetRoleRequestsWithJoinByIDRow struct {
-	ID                       int32             `json:"id"`
-	RequestingUserID         int32             `json:"requesting_user_id"`
-	RequestedRoleID          int32             `json:"requested_role_id"`
-	Status                   RoleRequestStatus `json:"status"`
-	Comment                  pgtype.Text       `json:"comment"`
-	ApprovingUserID          pgtype.Int4       `json:"approving_user_id"`
-	CreatedAt                pgtype.Timestamp  `json:"created_at"`
-	ApprovedAt               pgtype.Timestamp  `json:"approved_at"`
-	RequestingUserName       pgtype.Text       `json:"requesting_user_name"`
-	RequestingUserSurname    pgtype.Text       `json:"requesting_user_surname"`
-	RequestingUserEmail      pgtype.Text       `json:"requesting_user_email"`
-	RequestingUserCreatedAt  pgtype.Timestamp  `json:"requesting_user_created_at"`
-	RequestingUserLastLogin  pgtype.Timestamp  `json:"requesting_user_last_login"`
-	RequestedRoleName        pgtype.Text       `json:"requested_role_name"`
-	RequestedRoleDescription pgtype.Text       `json:"requested_role_description"`
-	ApprovingUserName        pgtype.Text       `json:"approving_user_name"`
-	ApprovingUserSurname     pgtype.Text       `json:"approving_user_surname"`
-	ApprovingUserEmail       pgtype.Text       `json:"approving_user_email"`
-	ApprovingUserCreatedAt   pgtype.Timestamp  `json:"approving_user_created_at"`
-	ApprovingUserLastLogin   pgtype.Timestamp  `json:"approving_user_last_login"`
+	ID                       int32              `json:"id"`
+	RequestingUserID         int32              `json:"requesting_user_id"`
+	RequestedRoleID          int32              `json:"requested_role_id"`
+	Status                   RoleRequestStatus  `json:"status"`
+	Comment                  pgtype.Text        `json:"comment"`
+	ApprovingUserID          pgtype.Int4        `json:"approving_user_id"`
+	CreatedAt                pgtype.Timestamptz `json:"created_at"`
+	ApprovedAt               pgtype.Timestamptz `json:"approved_at"`
+	RequestingUserName       pgtype.Text        `json:"requesting_user_name"`
+	RequestingUserSurname    pgtype.Text        `json:"requesting_user_surname"`
+	RequestingUserEmail      pgtype.Text        `json:"requesting_user_email"`
+	RequestingUserCreatedAt  pgtype.Timestamptz `json:"requesting_user_created_at"`
+	RequestingUserLastLogin  pgtype.Timestamptz `json:"requesting_user_last_login"`
+	RequestedRoleName        pgtype.Text        `json:"requested_role_name"`
+	RequestedRoleDescription pgtype.Text        `json:"requested_role_description"`
+	ApprovingUserName        pgtype.Text        `json:"approving_user_name"`
+	ApprovingUserSurname     pgtype.Text        `json:"approving_user_surname"`
+	ApprovingUserEmail       pgtype.Text        `json:"approving_user_email"`
+	ApprovingUserCreatedAt   pgtype.Timestamptz `json:"approving_user_created_at"`
+	ApprovingUserLastLogin   pgtype.Timestamptz `json:"approving_user_last_login"`
 }
 
 func (q *Queries) GetRoleRequestsWithJoinByID(ctx context.Context, id int32) (GetRoleRequestsWithJoinByIDRow, error) {
@@ -648,14 +706,14 @@ GROUP BY
 `
 
 type GetUserByIdWithRolesRow struct {
-	ID             int32            `json:"id"`
-	Name           string           `json:"name"`
-	Surname        string           `json:"surname"`
-	Email          string           `json:"email"`
-	HashedPassword string           `json:"hashed_password"`
-	CreatedAt      pgtype.Timestamp `json:"created_at"`
-	LastLogin      pgtype.Timestamp `json:"last_login"`
-	RoleNames      []string         `json:"role_names"`
+	ID             int32              `json:"id"`
+	Name           string             `json:"name"`
+	Surname        string             `json:"surname"`
+	Email          string             `json:"email"`
+	HashedPassword string             `json:"-"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	LastLogin      pgtype.Timestamptz `json:"last_login"`
+	RoleNames      []string           `json:"role_names"`
 }
 
 func (q *Queries) GetUserByIdWithRoles(ctx context.Context, id int32) (GetUserByIdWithRolesRow, error) {
@@ -738,10 +796,10 @@ RETURNING
 `
 
 type UpdateRoleRequestParams struct {
-	ID         int32             `json:"id"`
-	Status     RoleRequestStatus `json:"status"`
-	ApprovedAt pgtype.Timestamp  `json:"approved_at"`
-	Comment    pgtype.Text       `json:"comment"`
+	ID         int32              `json:"id"`
+	Status     RoleRequestStatus  `json:"status"`
+	ApprovedAt pgtype.Timestamptz `json:"approved_at"`
+	Comment    pgtype.Text        `json:"comment"`
 }
 
 func (q *Queries) UpdateRoleRequest(ctx context.Context, arg UpdateRoleRequestParams) (int32, error) {
@@ -763,7 +821,6 @@ SET
   name = $2,
   surname = $3,
   email = $4,
-  hashed_password = $5,
   created_at = DEFAULT,
   last_login = DEFAULT
 WHERE
@@ -773,11 +830,10 @@ RETURNING
 `
 
 type UpdateUserParams struct {
-	ID             int32  `json:"id"`
-	Name           string `json:"name"`
-	Surname        string `json:"surname"`
-	Email          string `json:"email"`
-	HashedPassword string `json:"hashed_password"`
+	ID      int32  `json:"id"`
+	Name    string `json:"name"`
+	Surname string `json:"surname"`
+	Email   string `json:"email"`
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (int32, error) {
@@ -786,9 +842,44 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (int32,
 		arg.Name,
 		arg.Surname,
 		arg.Email,
-		arg.HashedPassword,
 	)
 	var id int32
 	err := row.Scan(&id)
 	return id, err
 }
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET
+  hashed_password = $2
+WHERE
+  id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID             int32  `json:"id"`
+	HashedPassword string `json:"hashed_password"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.Exec(ctx, updateUserPassword, arg.ID, arg.HashedPassword)
+	return err
+}
+
+const usePasswordReset = `-- name: UsePasswordReset :one
+UPDATE password_resets
+SET
+  used_at = CURRENT_TIMESTAMP
+WHERE
+  id = $1
+  AND used_at IS NULL
+RETURNING
+  user_id
+`
+
+func (q *Queries) UsePasswordReset(ctx context.Context, id int32) (int32, error) {
+	row := q.db.QueryRow(ctx, usePasswordReset, id)
+	var user_id int32
+	err := row.Scan(&user_id)
+	return user_id, err
+}
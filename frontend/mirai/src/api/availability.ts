@@ -13,6 +13,7 @@ export async function postAvailabilitySlot(
     method: "POST",
     headers: { "Content-Type": "application/json" },
     body: JSON.stringify(postRequest),
+    credentials: "include",
   });
 
   if (!res.ok) {
@@ -28,6 +29,7 @@ export async function putAvailabilitySlot(
     method: "PUT",
     headers: { "Content-Type": "application/json" },
     body: JSON.stringify(putRequest),
+    credentials: "include",
   });
 
   if (!res.ok) {
@@ -11,6 +11,7 @@ export async function postBookingType(postRequest: {
     method: "POST",
     headers: { "Content-Type": "application/json" },
     body: JSON.stringify(postRequest),
+    credentials: "include",
   });
 
   if (!res.ok) {
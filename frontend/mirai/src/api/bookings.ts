@@ -10,6 +10,7 @@ export async function postBooking(postRequest: {
     method: "POST",
     headers: { "Content-Type": "application/json" },
     body: JSON.stringify(postRequest),
+    credentials: "include",
   });
 
   if (!res.ok) {
@@ -26,6 +27,7 @@ export async function getBooking(getRequest: { booking_id: number }) {
   const res = await fetch(`${apiUrl}/booking/${getRequest.booking_id}`, {
     method: "GET",
     headers: { "Content-Type": "application/json" },
+    credentials: "include",
   });
 
   if (!res.ok) {
@@ -50,7 +52,9 @@ export type GetBookingResponse = {
 };
 
 export async function getAllBookings() {
-  const res = await fetch(`${apiUrl}/booking`);
+  const res = await fetch(`${apiUrl}/booking`, {
+    credentials: "include",
+  });
 
   if (!res.ok) {
     throw new Error(`get all bookings failed with ${res.status}`);
@@ -99,11 +103,12 @@ export async function getAllBookingsUser() {
   return res.json();
 }
 
-export async function postManualPayment(id: number) {
+export async function postManualPayment(id: number, method = "cash") {
   const res = await fetch(`${apiUrl}/booking/${id}/payment/manual`, {
     method: "POST",
     credentials: "include",
     headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ method }),
   });
 
   if (!res.ok) {
@@ -0,0 +1,15 @@
+const apiUrl = import.meta.env.VITE_API_URL;
+
+export type GetConfigResponse = {
+  unit_minutes: number;
+};
+
+// get the server config, unit_minutes is the length of an availability slot
+export async function getConfig(): Promise<GetConfigResponse> {
+  const res = await fetch(`${apiUrl}/config`);
+
+  if (!res.ok) {
+    throw new Error(`Get config failed with ${res.status}`);
+  }
+  return res.json();
+}
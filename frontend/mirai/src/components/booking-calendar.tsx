@@ -20,8 +20,6 @@ import { getAllEmployees } from "@/api/employee"
 
 export default function BookingCalendar() {
 
-        const unit = 30
-
         const { startTime, endTime } = loadWorkingDayTimes()
         const { user } = useAuth();
         const today = new Date()
@@ -52,7 +50,7 @@ export default function BookingCalendar() {
                 const slots = generateOptionsFromSlots(
                         selectedTimes.startTime ? selectedTimes.startTime : startTime,
                         selectedTimes.endTime ? selectedTimes.endTime : endTime,
-                        availabilitySlots.filter((a) => a.type_id === selectedBookingType?.type_id), date);
+                        availabilitySlots.filter((a) => a.type_ids.includes(selectedBookingType?.type_id ?? 0)), date);
                 return slots.filter((s) => s.duration >= (selectedBookingType?.duration ?? 0));
         }, [selectedTimes, availabilitySlots, date, selectedBookingType])
 
@@ -61,9 +59,9 @@ export default function BookingCalendar() {
                 const slots = generateOptionsFromSlots(
                         selectedTimes.startTime ? selectedTimes.startTime : startTime,
                         selectedTimes.endTime ? selectedTimes.endTime : endTime,
-                        availabilitySlots.filter((a) => a.type_id === selectedBookingType?.type_id), date);
+                        availabilitySlots.filter((a) => a.type_ids.includes(selectedBookingType?.type_id ?? 0)), date);
                 const filteredSlots = slots.filter((s) => s.duration >= (selectedBookingType?.duration ?? 0)).map((a) => a.id);
-                const aSlots = availabilitySlots.filter((a) => filteredSlots.includes(a.availability_slot_id)).filter((a) => a.type_id === selectedBookingType?.type_id).filter((a) => {
+                const aSlots = availabilitySlots.filter((a) => filteredSlots.includes(a.availability_slot_id)).filter((a) => a.type_ids.includes(selectedBookingType?.type_id ?? 0)).filter((a) => {
                         const opt = datetimeToTime(a.datetime)
                         const start = selectedTimes.startTime
                         const end = selectedTimes.endTime
@@ -214,6 +212,7 @@ export default function BookingCalendar() {
                                                         defaultStart={startTime}
                                                         defaultEnd={endTime}
                                                         selectedTimes={selectedTimes}
+                                                        unitMinutes={selectedBookingType?.unit_minutes}
                                                         onChange={handleChange}
                                                 />
                                         </div>
@@ -330,7 +329,7 @@ export default function BookingCalendar() {
                                                         <div className="flex justify-between text-sm">
                                                                 <span className="text-muted-foreground">Duration</span>
                                                                 <span className="font-medium">
-                                                                        {(selectedBookingType?.duration ?? 0) * unit} minutes
+                                                                        {(selectedBookingType?.duration ?? 0) * (selectedBookingType?.unit_minutes ?? 0)} minutes
                                                                 </span >
                                                         </div>
 
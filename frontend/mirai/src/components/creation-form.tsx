@@ -15,35 +15,33 @@ import {
         TabsList,
         TabsTrigger,
 } from "@/components/ui/tabs"
-import { useState } from "react"
+import { useEffect, useState } from "react"
 import { Textarea } from "./ui/textarea"
 import { Checkbox } from "@/components/ui/checkbox"
 import { postBookingType } from "@/api/booking-type"
 import { toast } from "sonner"
 import { postEmployee } from "@/api/employee"
 import { DurationSelector } from "./ui/duration-selector"
+import { useUnitMinutes } from "@/hooks/use-unit-minutes"
 
 export function BookingTypeFormCard() {
-        const rawUnit = import.meta.env.VITE_TIME_SLOT_DURATION;
-        const unit = rawUnit ? Number(rawUnit) : NaN;
-
-        if (
-                !Number.isInteger(unit)
-        ) {
-                throw new Error(
-                        `invalid config vars unit ${rawUnit}`,
-                );
-        }
-
+        const unit = useUnitMinutes();
 
         const [formData, setFormData] = useState({
                 title: "",
                 description: "",
                 cost: 0,
                 fixed: false,
-                duration: unit,
+                duration: 0,
         });
 
+        // the duration starts at one unit once the unit has loaded
+        useEffect(() => {
+                if (unit) {
+                        setFormData((prev) => (prev.duration === 0 ? { ...prev, duration: unit } : prev));
+                }
+        }, [unit]);
+
         const handleChange = (field: string, value: any) => {
                 setFormData((prev) => ({ ...prev, [field]: value }));
         };
@@ -64,7 +62,7 @@ export function BookingTypeFormCard() {
                                 description: "",
                                 cost: 0,
                                 fixed: false,
-                                duration: unit,
+                                duration: unit ?? 0,
                         })
                 } catch (err) {
                         toast("creation failed")
@@ -100,7 +98,7 @@ export function BookingTypeFormCard() {
                                         </div>
                                         <div className="grid gap-3">
                                                 <Label htmlFor="cost">Duration</Label>
-                                                <DurationSelector formData={formData} handleChange={handleChange} unit={unit} />
+                                                {unit && <DurationSelector formData={formData} handleChange={handleChange} unit={unit} />}
                                         </div>
                                         <div className="grid gap-3">
                                                 <Label htmlFor="cost">Cost</Label>
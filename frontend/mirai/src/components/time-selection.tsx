@@ -2,11 +2,14 @@ import { Select, SelectTrigger, SelectContent, SelectGroup, SelectItem, SelectVa
 import type { TimeOfDay, SelectedTimes } from "@/types/booking";
 import { timeToValue, valueToTime } from "@/types/booking";
 import { generateOptions, loadWorkingDayTimes } from "@/lib/utils";
+import { useUnitMinutes } from "@/hooks/use-unit-minutes";
 
 type Props = {
         defaultStart: TimeOfDay,
         defaultEnd: TimeOfDay,
         selectedTimes: SelectedTimes;
+        // the selected booking type's unit, the configured unit is used without one
+        unitMinutes?: number;
         onChange: (times: {
                 startTime: TimeOfDay | null;
                 endTime: TimeOfDay | null;
@@ -15,8 +18,10 @@ type Props = {
 
 
 
-export default function TimeSelection({ defaultStart, defaultEnd, selectedTimes, onChange }: Props) {
-        const { startTime, endTime, slotDuration } = loadWorkingDayTimes()
+export default function TimeSelection({ defaultStart, defaultEnd, selectedTimes, unitMinutes, onChange }: Props) {
+        const { startTime, endTime } = loadWorkingDayTimes()
+        const configUnitMinutes = useUnitMinutes()
+        const slotDuration = unitMinutes ?? configUnitMinutes
 
         const handleStartChange = (val: string) => {
                 if (val === "__clear__") {
@@ -43,7 +48,7 @@ export default function TimeSelection({ defaultStart, defaultEnd, selectedTimes,
                         })
                 }
         }
-        const options = generateOptions(startTime, endTime, slotDuration)
+        const options = slotDuration ? generateOptions(startTime, endTime, slotDuration) : []
 
         return (
                 <div className="flex items-center gap-2">
@@ -0,0 +1,37 @@
+import * as React from "react"
+import { getConfig } from "@/api/config"
+
+// the config only changes when the server restarts so it is fetched once
+let unitMinutes: Promise<number> | undefined
+
+function loadUnitMinutes() {
+  if (!unitMinutes) {
+    unitMinutes = getConfig()
+      .then((config) => config.unit_minutes)
+      .catch((err) => {
+        unitMinutes = undefined
+        throw err
+      })
+  }
+  return unitMinutes
+}
+
+// useUnitMinutes is the length of an availability slot in minutes from
+// GET /config, undefined until it has loaded
+export function useUnitMinutes() {
+  const [unit, setUnit] = React.useState<number | undefined>(undefined)
+
+  React.useEffect(() => {
+    let active = true
+    loadUnitMinutes()
+      .then((minutes) => {
+        if (active) setUnit(minutes)
+      })
+      .catch((err) => console.error(`loading the slot unit failed: ${err}`))
+    return () => {
+      active = false
+    }
+  }, [])
+
+  return unit
+}
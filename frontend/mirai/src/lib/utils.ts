@@ -58,29 +58,26 @@ export function loadWorkingDayTimes() {
   const rawStartMinute = import.meta.env.VITE_START_MINUTE;
   const rawEndTime = import.meta.env.VITE_END_TIME;
   const rawEndMinute = import.meta.env.VITE_END_MINUTE;
-  const rawDuration = import.meta.env.VITE_TIME_SLOT_DURATION;
 
   const startHour = rawStartTime ? Number(rawStartTime) : NaN;
   const endHour = rawEndTime ? Number(rawEndTime) : NaN;
   const startMinute = rawStartMinute ? Number(rawStartMinute) : NaN;
   const endMinute = rawEndMinute ? Number(rawEndMinute) : NaN;
-  const slotDuration = rawDuration ? Number(rawDuration) : NaN;
 
   if (
     !Number.isInteger(startHour) ||
     !Number.isInteger(endHour) ||
-    !Number.isInteger(slotDuration) ||
     !Number.isInteger(startMinute) ||
     !Number.isInteger(endMinute)
   ) {
     throw new Error(
-      `invalid config vars st ${rawStartTime} et ${rawEndTime} duration ${rawDuration} sm ${startMinute} em ${endMinute}`,
+      `invalid config vars st ${rawStartTime} et ${rawEndTime} sm ${startMinute} em ${endMinute}`,
     );
   }
 
   const startTime = { hour: startHour, minute: startMinute };
   const endTime = { hour: endHour, minute: endMinute };
-  return { startTime, endTime, slotDuration };
+  return { startTime, endTime };
 }
 
 export function generateOptionsFromSlots(
@@ -195,7 +192,7 @@ export function availabilitySlotsToEvents(
       startDate: startDate,
       endDate: endDate,
       employeeId: slot.employee_id,
-      typeId: slot.type_id,
+      typeId: slot.type_ids[0],
       isBooking: false,
       bookingId: null,
       availability_slot_ids: [slot.availability_slot_id],
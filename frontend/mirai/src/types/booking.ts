@@ -23,6 +23,7 @@ export type BookingType = {
   fixed: boolean;
   cost: number;
   duration: number;
+  unit_minutes: number;
   created_at: string;
   last_edited: string;
 };
@@ -42,7 +43,7 @@ export type AvailabilitySlot = {
   availability_slot_id: number;
   employee_id: number;
   datetime: string;
-  type_id: number;
+  type_ids: number[];
   created_at: string;
   last_edited: string;
 };
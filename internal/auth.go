@@ -11,7 +11,6 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -19,6 +18,7 @@ import (
 	"github.com/jack-cordery/mirai/db"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/crypto/argon2"
 )
 
@@ -27,6 +27,23 @@ const (
 	RoleUser  = "USER"
 )
 
+// the lengths a password has to be between when it is set
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 128
+)
+
+// validatePassword checks a new password, it is used wherever one is set
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	if len(password) > maxPasswordLength {
+		return fmt.Errorf("password must be at most %d characters", maxPasswordLength)
+	}
+	return nil
+}
+
 type Permissions struct {
 	Roles []string `json:"role"`
 }
@@ -52,13 +69,15 @@ type CookieParams struct {
 }
 
 type AuthParams struct {
-	SessionDuration uint8
-	TokenLength     uint32
-	SecretKey       []byte
-	RedirectPath    string
-	LoginPath       string
-	CParams         CookieParams
-	HParams         HashParams
+	SessionDuration    uint8
+	TokenLength        uint32
+	SecretKey          []byte
+	RedirectPath       string
+	LoginPath          string
+	ResetTokenDuration time.Duration
+	ResetSender        ResetTokenSender
+	CParams            CookieParams
+	HParams            HashParams
 }
 
 type RegisterRequest struct {
@@ -89,6 +108,15 @@ type StatusResponse struct {
 	Perms  Permissions `json:"permissions"`
 }
 
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type ChangePasswordResponse struct {
+	Message string `json:"message"`
+}
+
 type ErrorResponse struct {
 	Message string `json:"message"`
 }
@@ -128,10 +156,14 @@ func HandleLogout(w http.ResponseWriter, r *http.Request, ctx context.Context, q
 }
 
 func HandleRegister(w http.ResponseWriter, ctx context.Context, queries *db.Queries, creds RegisterRequest, a *AuthParams) error {
-	// TODO: Add validation step
+	err := validatePassword(creds.Password)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
 
 	// 1. Check DB to see they are new
-	_, err := queries.GetUserByEmail(ctx, creds.Email)
+	_, err = queries.GetUserByEmail(ctx, creds.Email)
 	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return err
@@ -349,184 +381,150 @@ func HandleSessionStatus(w http.ResponseWriter, r *http.Request, ctx context.Con
 	}
 }
 
-func HandleRaise(w http.ResponseWriter, r *http.Request, ctx context.Context, queries *db.Queries, a *AuthParams) error {
+func HandleRaise(w http.ResponseWriter, r *http.Request, ctx context.Context, queries *db.Queries) error {
 	initialAdminEmail := os.Getenv("INITIAL_ADMIN_EMAIL")
-	token, err := ReadEncryptedCookie(r, a.CParams.Name, a.SecretKey)
-	if err != nil {
-		log.Printf("The token provided failed: %v", token)
-		w.WriteHeader(http.StatusUnauthorized)
-		err = json.NewEncoder(w).Encode(ErrorResponse{Message: "Invalid session"})
-		if err != nil {
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-			return err
-		}
-		return err
+	principal, ok := requestPrincipal(w, r)
+	if !ok {
+		return ErrInvalidSession
 	}
 
-	valid, err := VerifySession(ctx, queries, token)
+	role, err := queries.GetRoleByName(ctx, RoleAdmin)
 	if err != nil {
-		log.Printf("verifying session in HandleRaise failed with %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		log.Printf("error getting role by name in HandleRaise with %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
 		return err
 	}
-
-	if !valid {
-		w.WriteHeader(http.StatusUnauthorized)
-		err = json.NewEncoder(w).Encode(ErrorResponse{Message: "Invalid session"})
+	if principal.Email == initialAdminEmail {
+		err := queries.AssignRoleToUser(ctx, db.AssignRoleToUserParams{
+			UserID: principal.UserID,
+			RoleID: role,
+		})
 		if err != nil {
-			log.Printf("writing json in HandleRaise failed with %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			log.Printf("error assigning role by name in HandleRaise with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
 			return err
 		}
+		w.WriteHeader(http.StatusCreated)
 		return nil
 	} else {
-		session, err := queries.GetSessionByToken(ctx, token)
-		if err != nil {
-			log.Printf("getting session by token in HandleRaise failed with %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-			return err
-		}
-
-		user, err := queries.GetUserById(ctx, session.UserID)
-		if err != nil {
-			log.Printf("getting employee by id for user in HandleSessionStatus failed with %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-			return err
-		}
-
-		role, err := queries.GetRoleByName(ctx, RoleAdmin)
+		_, err := queries.CreateNewRoleRequest(ctx, db.CreateNewRoleRequestParams{
+			UserID:          principal.UserID,
+			RequestedRoleID: role,
+		})
 		if err != nil {
-			log.Printf("error getting role by name in HandleRaise with %v", err)
+			log.Printf("error creating new role request in HandleRaise with %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return err
 		}
-		if user.Email == initialAdminEmail {
-			err := queries.AssignRoleToUser(ctx, db.AssignRoleToUserParams{
-				UserID: user.ID,
-				RoleID: role,
-			})
-			if err != nil {
-				log.Printf("error assigning role by name in HandleRaise with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return err
-			}
-			w.WriteHeader(http.StatusCreated)
-			return nil
-		} else {
-			_, err := queries.CreateNewRoleRequest(ctx, db.CreateNewRoleRequestParams{
-				UserID:          user.ID,
-				RequestedRoleID: role,
-			})
-			if err != nil {
-				log.Printf("error creating new role request in HandleRaise with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return err
-			}
-			w.WriteHeader(http.StatusAccepted)
-			return nil
-		}
+		w.WriteHeader(http.StatusAccepted)
+		return nil
 	}
 }
 
-func HandleGetAllRequests(w http.ResponseWriter, r *http.Request, ctx context.Context, queries *db.Queries, a *AuthParams) error {
-	token, err := ReadEncryptedCookie(r, a.CParams.Name, a.SecretKey)
+func HandleGetAllRequests(w http.ResponseWriter, r *http.Request, ctx context.Context, queries *db.Queries) error {
+	data, err := queries.GetAllRoleRequestsWithJoin(ctx)
 	if err != nil {
-		log.Printf("The token provided failed: %v", token)
-		w.WriteHeader(http.StatusUnauthorized)
-		err = json.NewEncoder(w).Encode(ErrorResponse{Message: "Invalid session"})
-		if err != nil {
-			log.Printf("encoding response in HandleGetAllRequests failed with %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return err
-		}
+		log.Printf("getting all role requests with join in HandleGetAllRequests failed with %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
 		return err
 	}
-
-	valid, err := VerifySession(ctx, queries, token)
+	err = json.NewEncoder(w).Encode(data)
 	if err != nil {
-		log.Printf("verifying session in HandleGetAllRequests failed with %v", err)
+		log.Printf("encoding all role requests with join in HandleGetAllRequests failed with %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return err
 	}
+	return nil
+}
 
-	if !valid {
-		w.WriteHeader(http.StatusUnauthorized)
-		err = json.NewEncoder(w).Encode(ErrorResponse{Message: "Invalid session"})
-		if err != nil {
-			log.Printf("writing json in HandleGetAllRequests failed with %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return err
-		}
-		return nil
-	} else {
-		session, err := queries.GetSessionByToken(ctx, token)
-		if err != nil {
-			log.Printf("getting session by token in HandleRaise failed with %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return err
-		}
+func HandleRequestReview(w http.ResponseWriter, r *http.Request, ctx context.Context, queries *db.Queries, review db.RoleRequestStatus) error {
 
-		user, err := queries.GetUserById(ctx, session.UserID)
-		if err != nil {
-			log.Printf("getting user by id for user in HandleGetAllRequests failed with %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return err
-		}
+	id := r.PathValue("request_id")
+	if id == "" {
+		log.Printf("request_id is empty")
+		w.WriteHeader(http.StatusBadRequest)
+		return errors.New("request_id is empty")
+	}
+	request_id, err := strconv.ParseInt(id, 10, 32)
+	if err != nil {
+		log.Printf("request_id is not an integer")
+		w.WriteHeader(http.StatusBadRequest)
+		return err
+	}
 
-		userRoles, err := queries.GetRolesForUser(ctx, user.ID)
-		if err != nil {
-			log.Printf("getting user roles by id for user in HandleGetAllRequests failed with %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return err
-		}
+	approver, ok := requestPrincipal(w, r)
+	if !ok {
+		return ErrInvalidSession
+	}
 
-		isAdmin := slices.Contains(userRoles, RoleAdmin)
+	new_row, err := queries.ReviewRequest(ctx, db.ReviewRequestParams{
+		ID:     int32(request_id),
+		Status: review,
+		ApprovedBy: pgtype.Int4{
+			Int32: int32(approver.UserID),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		log.Printf("updating row for request in HandleRequestReview failed with %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return err
+	}
 
-		if !isAdmin {
-			log.Printf("user %d has requests HandleGetAllRequests and doesnt have permission to", user.ID)
-			w.WriteHeader(http.StatusUnauthorized)
-			return errors.New("unauthorised request")
-		}
+	adminID, err := queries.GetRoleByName(ctx, RoleAdmin)
+	if err != nil {
+		log.Printf("getting adminId in HandleRequestReview failed with %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return err
+	}
 
-		data, err := queries.GetAllRoleRequestsWithJoin(ctx)
+	if review == db.RoleRequestStatusAPPROVED {
+		err = queries.AssignRoleToUser(ctx, db.AssignRoleToUserParams{
+			UserID: new_row.UserID,
+			RoleID: adminID,
+		})
 		if err != nil {
-			log.Printf("getting all role requests with join in HandleGetAllRequests failed with %v", err)
+			log.Printf("error assigning role by name in HandleRequestReview with %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return err
 		}
-		err = json.NewEncoder(w).Encode(data)
+	}
+	if review == db.RoleRequestStatusREJECTED {
+		err = queries.RemoveRoleToUser(ctx, db.RemoveRoleToUserParams{
+			UserID: new_row.UserID,
+			RoleID: adminID,
+		})
 		if err != nil {
-			log.Printf("encoding all role requests with join in HandleGetAllRequests failed with %v", err)
+			log.Printf("error removing role by name in HandleRequestReview with %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return err
 		}
-		return nil
 	}
-}
 
-func HandleRequestReview(w http.ResponseWriter, r *http.Request, ctx context.Context, queries *db.Queries, a *AuthParams, review db.RoleRequestStatus) error {
-
-	id := r.PathValue("request_id")
-	if id == "" {
-		log.Printf("request_id is empty")
-		w.WriteHeader(http.StatusBadRequest)
-		return errors.New("request_id is empty")
+	new_row_with_join, err := queries.GetRoleRequestsWithJoinByID(ctx, int32(request_id))
+	if err != nil {
+		log.Printf("getting new row with join in HandleRequestReview failed with %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return err
 	}
-	request_id, err := strconv.ParseInt(id, 10, 32)
+
+	err = json.NewEncoder(w).Encode(new_row_with_join)
+
 	if err != nil {
-		log.Printf("request_id is not an integer")
-		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("encoding new row in HandleRequestReview failed with %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
 		return err
 	}
+	return nil
+}
 
+func HandleSessionRefresh(w http.ResponseWriter, r *http.Request, ctx context.Context, queries *db.Queries, a *AuthParams) error {
 	token, err := ReadEncryptedCookie(r, a.CParams.Name, a.SecretKey)
 	if err != nil {
-		log.Printf("The token provided failed: %v", token)
 		w.WriteHeader(http.StatusUnauthorized)
 		err = json.NewEncoder(w).Encode(ErrorResponse{Message: "Invalid session"})
 		if err != nil {
-			log.Printf("encoding response in HandleRequestReview failed with %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return err
 		}
 		return err
@@ -534,8 +532,7 @@ func HandleRequestReview(w http.ResponseWriter, r *http.Request, ctx context.Con
 
 	valid, err := VerifySession(ctx, queries, token)
 	if err != nil {
-		log.Printf("verifying session in HandleRequestReview failed with %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return err
 	}
 
@@ -543,104 +540,38 @@ func HandleRequestReview(w http.ResponseWriter, r *http.Request, ctx context.Con
 		w.WriteHeader(http.StatusUnauthorized)
 		err = json.NewEncoder(w).Encode(ErrorResponse{Message: "Invalid session"})
 		if err != nil {
-			log.Printf("writing json in HandleRequestReview failed with %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return err
 		}
 		return nil
 	} else {
 		session, err := queries.GetSessionByToken(ctx, token)
 		if err != nil {
-			log.Printf("getting session by token in HandleRequestReview failed with %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return err
-		}
-
-		approver, err := queries.GetUserById(ctx, session.UserID)
-		if err != nil {
-			log.Printf("getting user by id for user in HandleRequestReview failed with %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return err
-		}
-
-		userRoles, err := queries.GetRolesForUser(ctx, approver.ID)
-		if err != nil {
-			log.Printf("getting user roles by id for user in HandleRequestReview failed with %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return err
-		}
-
-		isAdmin := slices.Contains(userRoles, RoleAdmin)
-
-		if !isAdmin {
-			log.Printf("user %d has requests HandleRequestReview and doesnt have permission to", approver.ID)
-			w.WriteHeader(http.StatusUnauthorized)
-			return errors.New("unauthorised request")
-		}
-
-		new_row, err := queries.ReviewRequest(ctx, db.ReviewRequestParams{
-			ID:     int32(request_id),
-			Status: review,
-			ApprovedBy: pgtype.Int4{
-				Int32: int32(approver.ID),
-				Valid: true,
-			},
-		})
-		if err != nil {
-			log.Printf("updating row for request in HandleRequestReview failed with %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return err
-		}
-
-		adminID, err := queries.GetRoleByName(ctx, RoleAdmin)
-		if err != nil {
-			log.Printf("getting adminId in HandleRequestReview failed with %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return err
 		}
-
-		if review == db.RoleRequestStatusAPPROVED {
-			err = queries.AssignRoleToUser(ctx, db.AssignRoleToUserParams{
-				UserID: new_row.UserID,
-				RoleID: adminID,
-			})
-			if err != nil {
-				log.Printf("error assigning role by name in HandleRequestReview with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return err
-			}
-		}
-		if review == db.RoleRequestStatusREJECTED {
-			err = queries.RemoveRoleToUser(ctx, db.RemoveRoleToUserParams{
-				UserID: new_row.UserID,
-				RoleID: adminID,
-			})
-			if err != nil {
-				log.Printf("error removing role by name in HandleRequestReview with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return err
-			}
-		}
-
-		new_row_with_join, err := queries.GetRoleRequestsWithJoinByID(ctx, int32(request_id))
+		err = HandleNewSession(w, ctx, queries, a, session.UserID)
 		if err != nil {
-			log.Printf("getting new row with join in HandleRequestReview failed with %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return err
 		}
+		// TODO: Potentially we need to expire the previous token from the DB
 
-		err = json.NewEncoder(w).Encode(new_row_with_join)
-
-		if err != nil {
-			log.Printf("encoding new row in HandleRequestReview failed with %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return err
-		}
 		return nil
 	}
 }
 
-func HandleSessionRefresh(w http.ResponseWriter, r *http.Request, ctx context.Context, queries *db.Queries, a *AuthParams) error {
+func HandleChangePassword(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgxpool.Conn, req ChangePasswordRequest, a *AuthParams) error {
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		http.Error(w, "Invalid body, expects current_password and new_password", http.StatusBadRequest)
+		return errors.New("current or new password is empty")
+	}
+	err := validatePassword(req.NewPassword)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+
 	token, err := ReadEncryptedCookie(r, a.CParams.Name, a.SecretKey)
 	if err != nil {
 		w.WriteHeader(http.StatusUnauthorized)
@@ -652,8 +583,26 @@ func HandleSessionRefresh(w http.ResponseWriter, r *http.Request, ctx context.Co
 		return err
 	}
 
-	valid, err := VerifySession(ctx, queries, token)
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		log.Printf("error beginning tx in HandleChangePassword: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			panic(err)
+		}
+	}()
+
+	queries := db.New(conn)
+	qtx := queries.WithTx(tx)
+
+	valid, err := VerifySession(ctx, qtx, token)
 	if err != nil {
+		log.Printf("verifying session in HandleChangePassword failed with %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return err
 	}
@@ -666,21 +615,79 @@ func HandleSessionRefresh(w http.ResponseWriter, r *http.Request, ctx context.Co
 			return err
 		}
 		return nil
-	} else {
-		session, err := queries.GetSessionByToken(ctx, token)
-		if err != nil {
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-			return err
-		}
-		err = HandleNewSession(w, ctx, queries, a, session.UserID)
-		if err != nil {
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-			return err
-		}
-		// TODO: Potentially we need to expire the previous token from the DB
+	}
 
-		return nil
+	session, err := qtx.GetSessionByToken(ctx, token)
+	if err != nil {
+		log.Printf("getting session by token in HandleChangePassword failed with %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+
+	user, err := qtx.GetUserById(ctx, session.UserID)
+	if err != nil {
+		log.Printf("getting user by id in HandleChangePassword failed with %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+
+	match, err := VerifyPassword(req.CurrentPassword, user.HashedPassword)
+	if err != nil {
+		log.Printf("verifying password in HandleChangePassword failed with %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+	if !match {
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return errors.New("current password does not match")
+	}
+
+	hashedPassword, err := HashPassword(req.NewPassword, &a.HParams)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+
+	err = qtx.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+		ID:             user.ID,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		log.Printf("updating password in HandleChangePassword failed with %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+
+	// drop every session, including the current one, so that a leaked cookie
+	// stops working and the caller carries on with a freshly issued token
+	err = qtx.DeleteSessionsByUserID(ctx, user.ID)
+	if err != nil {
+		log.Printf("revoking sessions in HandleChangePassword failed with %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
 	}
+
+	err = HandleNewSession(w, ctx, qtx, a, user.ID)
+	if err != nil {
+		log.Printf("creating new session in HandleChangePassword failed with %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Printf("error commiting tx in HandleChangePassword: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(ChangePasswordResponse{Message: "Password changed successfully"})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+	return nil
 }
 
 func HashPassword(password string, p *HashParams) (string, error) {
@@ -765,7 +772,7 @@ func GenerateSessionToken(n uint32) (string, error) {
 	return base64.RawURLEncoding.EncodeToString(b), nil
 }
 
-func IsTokenCurrent(expiry pgtype.Timestamp) (bool, error) {
+func IsTokenCurrent(expiry pgtype.Timestamptz) (bool, error) {
 	if !expiry.Valid {
 		return false, errors.New("invalid expiry timestamp")
 	}
@@ -836,7 +843,7 @@ func HandleNewSession(w http.ResponseWriter, ctx context.Context, queries *db.Qu
 	_, err = queries.CreateSession(ctx, db.CreateSessionParams{
 		UserID:       userId,
 		SessionToken: token,
-		ExpiresAt:    pgtype.Timestamp{Time: expiryTime, Valid: true},
+		ExpiresAt:    pgtype.Timestamptz{Time: expiryTime, Valid: true},
 	})
 	if err != nil {
 		return err
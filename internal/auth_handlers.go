@@ -102,7 +102,7 @@ func postSessionRefresh(pool *pgxpool.Pool, ctx context.Context, a *AuthParams)
 	}
 }
 
-func postRaise(pool *pgxpool.Pool, ctx context.Context, a *AuthParams) http.HandlerFunc {
+func postRaise(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		conn, err := pool.Acquire(ctx)
 		if err != nil {
@@ -112,11 +112,11 @@ func postRaise(pool *pgxpool.Pool, ctx context.Context, a *AuthParams) http.Hand
 		defer conn.Release()
 
 		queries := db.New(conn)
-		_ = HandleRaise(w, r, ctx, queries, a)
+		_ = HandleRaise(w, r, ctx, queries)
 	}
 }
 
-func getAllRequests(pool *pgxpool.Pool, ctx context.Context, a *AuthParams) http.HandlerFunc {
+func getAllRequests(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		conn, err := pool.Acquire(ctx)
 		if err != nil {
@@ -126,11 +126,11 @@ func getAllRequests(pool *pgxpool.Pool, ctx context.Context, a *AuthParams) http
 		defer conn.Release()
 
 		queries := db.New(conn)
-		_ = HandleGetAllRequests(w, r, ctx, queries, a)
+		_ = HandleGetAllRequests(w, r, ctx, queries)
 	}
 }
 
-func postApproveRequest(pool *pgxpool.Pool, ctx context.Context, a *AuthParams) http.HandlerFunc {
+func postApproveRequest(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		conn, err := pool.Acquire(ctx)
 		if err != nil {
@@ -140,12 +140,12 @@ func postApproveRequest(pool *pgxpool.Pool, ctx context.Context, a *AuthParams)
 		defer conn.Release()
 
 		queries := db.New(conn)
-		_ = HandleRequestReview(w, r, ctx, queries, a, db.RoleRequestStatusAPPROVED)
+		_ = HandleRequestReview(w, r, ctx, queries, db.RoleRequestStatusAPPROVED)
 
 	}
 }
 
-func postRejectRequest(pool *pgxpool.Pool, ctx context.Context, a *AuthParams) http.HandlerFunc {
+func postRejectRequest(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		conn, err := pool.Acquire(ctx)
 		if err != nil {
@@ -155,6 +155,70 @@ func postRejectRequest(pool *pgxpool.Pool, ctx context.Context, a *AuthParams) h
 		defer conn.Release()
 
 		queries := db.New(conn)
-		_ = HandleRequestReview(w, r, ctx, queries, a, db.RoleRequestStatusREJECTED)
+		_ = HandleRequestReview(w, r, ctx, queries, db.RoleRequestStatusREJECTED)
+	}
+}
+
+func postChangePassword(pool *pgxpool.Pool, ctx context.Context, a *AuthParams) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ChangePasswordRequest
+
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			http.Error(w, "Invalid body, expects current_password and new_password", http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		_ = HandleChangePassword(w, r, ctx, conn, req, a)
+	}
+}
+
+func postForgotPassword(pool *pgxpool.Pool, ctx context.Context, a *AuthParams) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ForgotPasswordRequest
+
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			http.Error(w, "Invalid body, expects email", http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		queries := db.New(conn)
+		_ = HandleForgotPassword(w, ctx, queries, req, a)
+	}
+}
+
+func postResetPassword(pool *pgxpool.Pool, ctx context.Context, a *AuthParams) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ResetPasswordRequest
+
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			http.Error(w, "Invalid body, expects token and password", http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		_ = HandleResetPassword(w, ctx, conn, req, a)
 	}
 }
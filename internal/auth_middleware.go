@@ -0,0 +1,158 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrInvalidSession = errors.New("invalid session")
+
+// Principal is the authenticated user behind a request, loaded once by withAuth
+type Principal struct {
+	UserID int32
+	Email  string
+	Roles  []string
+}
+
+func (p Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+func (p Principal) IsAdmin() bool {
+	return p.HasRole(RoleAdmin)
+}
+
+// CanAccessUser reports whether p may read or change the user record with userID
+func (p Principal) CanAccessUser(userID int32) bool {
+	return p.IsAdmin() || p.UserID == userID
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// authorize reports whether p holds at least one of roles. No roles means any logged in user.
+func authorize(p Principal, roles []string) bool {
+	if len(roles) == 0 {
+		return true
+	}
+	for _, role := range roles {
+		if p.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadPrincipal resolves the session cookie on r to a Principal. It returns
+// ErrInvalidSession when the caller is not logged in.
+func loadPrincipal(r *http.Request, ctx context.Context, queries *db.Queries, a *AuthParams) (Principal, error) {
+	token, err := ReadEncryptedCookie(r, a.CParams.Name, a.SecretKey)
+	if err != nil {
+		return Principal{}, ErrInvalidSession
+	}
+
+	valid, err := VerifySession(ctx, queries, token)
+	if err != nil {
+		return Principal{}, err
+	}
+	if !valid {
+		return Principal{}, ErrInvalidSession
+	}
+
+	session, err := queries.GetSessionByToken(ctx, token)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	user, err := queries.GetUserById(ctx, session.UserID)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	roles, err := queries.GetRolesForUser(ctx, user.ID)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	return Principal{
+		UserID: user.ID,
+		Email:  user.Email,
+		Roles:  roles,
+	}, nil
+}
+
+func writeUnauthorized(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusUnauthorized)
+	err := json.NewEncoder(w).Encode(ErrorResponse{Message: "Invalid session"})
+	if err != nil {
+		log.Printf("encoding unauthorized response failed with %v", err)
+	}
+}
+
+func writeForbidden(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusForbidden)
+	err := json.NewEncoder(w).Encode(ErrorResponse{Message: "Forbidden"})
+	if err != nil {
+		log.Printf("encoding forbidden response failed with %v", err)
+	}
+}
+
+// withAuth only calls next for a logged in user holding at least one of roles,
+// with the Principal available through PrincipalFromContext(r.Context()).
+// Callers that are not logged in get a 401 and callers without the role a 403.
+func withAuth(pool *pgxpool.Pool, ctx context.Context, a *AuthParams, next http.HandlerFunc, roles ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in withAuth: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		principal, err := loadPrincipal(r, ctx, db.New(conn), a)
+		conn.Release()
+		if errors.Is(err, ErrInvalidSession) {
+			writeUnauthorized(w)
+			return
+		}
+		if err != nil {
+			log.Printf("loading principal in withAuth failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if !authorize(principal, roles) {
+			log.Printf("user %d requested %s %s without any of the roles %v", principal.UserID, r.Method, r.URL.Path, roles)
+			writeForbidden(w)
+			return
+		}
+
+		next(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	}
+}
+
+// requestPrincipal returns the Principal set by withAuth, writing a 401 if the
+// handler was registered without it
+func requestPrincipal(w http.ResponseWriter, r *http.Request) (Principal, bool) {
+	p, ok := PrincipalFromContext(r.Context())
+	if !ok {
+		log.Printf("no principal on request for %s %s", r.Method, r.URL.Path)
+		writeUnauthorized(w)
+	}
+	return p, ok
+}
@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorize(t *testing.T) {
+	admin := Principal{UserID: 1, Email: "admin@example.com", Roles: []string{RoleUser, RoleAdmin}}
+	user := Principal{UserID: 2, Email: "user@example.com", Roles: []string{RoleUser}}
+	noRoles := Principal{UserID: 3, Email: "none@example.com"}
+
+	t.Run("any logged in user", func(t *testing.T) {
+		t.Parallel()
+		assert.True(t, authorize(admin, nil))
+		assert.True(t, authorize(user, nil))
+		assert.True(t, authorize(noRoles, nil))
+	})
+
+	t.Run("admin only", func(t *testing.T) {
+		t.Parallel()
+		assert.True(t, authorize(admin, []string{RoleAdmin}))
+		assert.False(t, authorize(user, []string{RoleAdmin}))
+		assert.False(t, authorize(noRoles, []string{RoleAdmin}))
+	})
+
+	t.Run("either role", func(t *testing.T) {
+		t.Parallel()
+		assert.True(t, authorize(admin, []string{RoleAdmin, RoleUser}))
+		assert.True(t, authorize(user, []string{RoleAdmin, RoleUser}))
+		assert.False(t, authorize(noRoles, []string{RoleAdmin, RoleUser}))
+	})
+}
+
+func TestCanAccessUser(t *testing.T) {
+	t.Run("self", func(t *testing.T) {
+		t.Parallel()
+		p := Principal{UserID: 2, Roles: []string{RoleUser}}
+		assert.True(t, p.CanAccessUser(2))
+		assert.False(t, p.CanAccessUser(3))
+	})
+
+	t.Run("admin", func(t *testing.T) {
+		t.Parallel()
+		p := Principal{UserID: 1, Roles: []string{RoleAdmin}}
+		assert.True(t, p.CanAccessUser(1))
+		assert.True(t, p.CanAccessUser(3))
+	})
+}
+
+func TestPrincipalContext(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		t.Parallel()
+		p := Principal{UserID: 7, Email: "jim@example.com", Roles: []string{RoleUser}}
+		actual, ok := PrincipalFromContext(WithPrincipal(context.Background(), p))
+		assert.True(t, ok)
+		assert.Equal(t, p, actual)
+		assert.True(t, actual.HasRole(RoleUser))
+		assert.False(t, actual.IsAdmin())
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		t.Parallel()
+		_, ok := PrincipalFromContext(context.Background())
+		assert.False(t, ok)
+	})
+}
+
+func TestRequestPrincipal(t *testing.T) {
+	t.Run("missing writes 401", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/booking/user", nil)
+
+		_, ok := requestPrincipal(w, r)
+		assert.False(t, ok)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("present", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		p := Principal{UserID: 7, Email: "jim@example.com", Roles: []string{RoleUser}}
+		r := httptest.NewRequest(http.MethodGet, "/booking/user", nil)
+		r = r.WithContext(WithPrincipal(r.Context(), p))
+
+		actual, ok := requestPrincipal(w, r)
+		assert.True(t, ok)
+		assert.Equal(t, p, actual)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
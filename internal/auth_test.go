@@ -1,12 +1,14 @@
 package internal
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/jack-cordery/mirai/db"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -141,7 +143,7 @@ func TestIsTokenCurrent(t *testing.T) {
 	t.Run("Valid Future Token", func(t *testing.T) {
 		t.Parallel()
 		futureTime := time.Now().UTC().Add(time.Hour)
-		expiryTimestamp := pgtype.Timestamp{
+		expiryTimestamp := pgtype.Timestamptz{
 			Time:  futureTime,
 			Valid: true,
 		}
@@ -154,7 +156,7 @@ func TestIsTokenCurrent(t *testing.T) {
 	t.Run("Valid Expired Token", func(t *testing.T) {
 		t.Parallel()
 		pastTime := time.Now().UTC().Add(-time.Hour)
-		expiryTimestamp := pgtype.Timestamp{
+		expiryTimestamp := pgtype.Timestamptz{
 			Time:  pastTime,
 			Valid: true,
 		}
@@ -166,7 +168,7 @@ func TestIsTokenCurrent(t *testing.T) {
 
 	t.Run("Invalid Timestamp", func(t *testing.T) {
 		t.Parallel()
-		invalidTimestamp := pgtype.Timestamp{
+		invalidTimestamp := pgtype.Timestamptz{
 			Valid: false,
 		}
 
@@ -255,3 +257,73 @@ func TestServeAuthCookie_ValidSecretKey(t *testing.T) {
 	assert.True(t, cookies[0].HttpOnly)
 	assert.True(t, cookies[0].Secure)
 }
+
+func TestHandleChangePassword_EarlyRejection(t *testing.T) {
+	authParams := &AuthParams{
+		CParams: CookieParams{
+			Name: "test_cookie",
+			Path: "/",
+		},
+		SecretKey: []byte("32_byte_valid_secret_key_1234567"),
+	}
+
+	t.Run("missing passwords", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/auth/change-password", nil)
+
+		err := HandleChangePassword(w, r, r.Context(), nil, ChangePasswordRequest{CurrentPassword: "old"}, authParams)
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("no session cookie", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/auth/change-password", nil)
+
+		_ = HandleChangePassword(w, r, r.Context(), nil, ChangePasswordRequest{CurrentPassword: "old", NewPassword: "a new password"}, authParams)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+	t.Run("new password too short", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/auth/change-password", nil)
+
+		err := HandleChangePassword(w, r, r.Context(), nil, ChangePasswordRequest{CurrentPassword: "old", NewPassword: "short"}, authParams)
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestValidatePassword(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+		assert.NoError(t, validatePassword("correct horse"))
+	})
+
+	t.Run("too short", func(t *testing.T) {
+		t.Parallel()
+		assert.Error(t, validatePassword("short"))
+	})
+
+	t.Run("too long", func(t *testing.T) {
+		t.Parallel()
+		assert.Error(t, validatePassword(strings.Repeat("a", maxPasswordLength+1)))
+	})
+}
+
+func TestUserJSONOmitsHashedPassword(t *testing.T) {
+	t.Parallel()
+	user := db.User{
+		ID:             1,
+		Name:           "Jim",
+		Email:          "jim@example.com",
+		HashedPassword: "$argon2id$v=19$m=65536,t=3,p=2$c2FsdA$aGFzaA",
+	}
+
+	b, err := json.Marshal(user)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "hashed_password")
+	assert.NotContains(t, string(b), "argon2id")
+}
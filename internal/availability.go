@@ -4,10 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jack-cordery/mirai/db"
@@ -17,25 +20,34 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-const Unit = 30 // number of minutes per unit i.e 1 duration unit in this case would be 30minutes.
-
 type GetAvailiabilitySlotResponse struct {
-	AvailabilitySlotID int32            `json:"availability_slot_id"`
-	EmployeeID         int32            `json:"employee_id"`
-	Datetime           pgtype.Timestamp `json:"datetime"`
-	TypeID             int32            `json:"type_id"`
-	CreatedAt          pgtype.Timestamp `json:"created_at"`
-	LastEdited         pgtype.Timestamp `json:"last_edited"`
+	AvailabilitySlotID int32              `json:"availability_slot_id"`
+	EmployeeID         int32              `json:"employee_id"`
+	Datetime           pgtype.Timestamptz `json:"datetime"`
+	TypeIDs            []int32            `json:"type_ids"`
+	CreatedAt          pgtype.Timestamptz `json:"created_at"`
+	LastEdited         pgtype.Timestamptz `json:"last_edited"`
+	RuleID             pgtype.Int4        `json:"rule_id"`
+	UnitMinutes        int32              `json:"unit_minutes"`
+	Capacity           int32              `json:"capacity"`
+	SeatsRemaining     int32              `json:"seats_remaining"`
 }
 
-func responseFromDBAvailability(availabilitySlot db.Availability) GetAvailiabilitySlotResponse {
+func responseFromDBAvailability(availabilitySlot db.Availability, typeIDs []int32, seatsRemaining int32, loc *time.Location) GetAvailiabilitySlotResponse {
+	if typeIDs == nil {
+		typeIDs = []int32{}
+	}
 	return GetAvailiabilitySlotResponse{
 		AvailabilitySlotID: availabilitySlot.ID,
 		EmployeeID:         availabilitySlot.EmployeeID,
-		Datetime:           availabilitySlot.Datetime,
-		TypeID:             availabilitySlot.TypeID,
-		CreatedAt:          availabilitySlot.CreatedAt,
-		LastEdited:         availabilitySlot.LastEdited,
+		Datetime:           inLocation(availabilitySlot.Datetime, loc),
+		TypeIDs:            typeIDs,
+		CreatedAt:          inLocation(availabilitySlot.CreatedAt, loc),
+		LastEdited:         inLocation(availabilitySlot.LastEdited, loc),
+		RuleID:             availabilitySlot.RuleID,
+		UnitMinutes:        availabilitySlot.UnitMinutes,
+		Capacity:           availabilitySlot.Capacity,
+		SeatsRemaining:     seatsRemaining,
 	}
 }
 
@@ -44,11 +56,19 @@ type PostAvailabilitySlotRequest struct {
 	StartTime  time.Time `json:"start_time"` // this expects RFC 3339 format, just need to sure it is encoded like this
 	EndTime    time.Time `json:"end_time"`   // this expects RFC 3339 format, just need to sure it is encoded like this
 	TypeID     int32     `json:"type_id"`
+	TypeIDs    []int32   `json:"type_ids"`
+	Capacity   int32     `json:"capacity"` // bookings each slot can take, defaults to 1
 }
 
-func (p PostAvailabilitySlotRequest) ToDBParams() ([]db.CreateAvailabilitySlotParams, error) {
+// ToDBParams splits the request into slots of unit minutes, the unit of the
+// requested booking types
+func (p PostAvailabilitySlotRequest) ToDBParams(unit int32) ([]db.CreateAvailabilitySlotParams, error) {
 	params := []db.CreateAvailabilitySlotParams{}
-	slots, err := spanToSlots(p.StartTime, p.EndTime, Unit)
+	capacity, err := slotCapacity(p.Capacity)
+	if err != nil {
+		return []db.CreateAvailabilitySlotParams{}, err
+	}
+	slots, err := spanToSlots(p.StartTime, p.EndTime, int(unit))
 	if err != nil {
 		return []db.CreateAvailabilitySlotParams{}, err
 	}
@@ -59,9 +79,10 @@ func (p PostAvailabilitySlotRequest) ToDBParams() ([]db.CreateAvailabilitySlotPa
 		}
 		params = append(params,
 			db.CreateAvailabilitySlotParams{
-				EmployeeID: p.EmployeeID,
-				Datetime:   slotTimeStamp,
-				TypeID:     p.TypeID,
+				EmployeeID:  p.EmployeeID,
+				Datetime:    slotTimeStamp,
+				UnitMinutes: unit,
+				Capacity:    capacity,
 			})
 	}
 	return params, nil
@@ -77,15 +98,21 @@ type PutAvailabilitySlotRequest struct {
 	StartTime           time.Time `json:"start_time"` // this expects RFC 3339 format, just need to sure it is encoded like this
 	EndTime             time.Time `json:"end_time"`   // this expects RFC 3339 format, just need to sure it is encoded like this
 	TypeID              int32     `json:"type_id"`
+	TypeIDs             []int32   `json:"type_ids"`
+	Capacity            int32     `json:"capacity"` // bookings each slot can take, defaults to 1
 }
 
 type PutAvailabilitySlotResponse struct {
 	AvailabilitySlotIDs []int32 `json:"availability_slot_ids"`
 }
 
-func (p PutAvailabilitySlotRequest) ToCreationParams() ([]db.CreateAvailabilitySlotParams, error) {
+func (p PutAvailabilitySlotRequest) ToCreationParams(unit int32) ([]db.CreateAvailabilitySlotParams, error) {
 	params := []db.CreateAvailabilitySlotParams{}
-	slots, err := spanToSlots(p.StartTime, p.EndTime, Unit)
+	capacity, err := slotCapacity(p.Capacity)
+	if err != nil {
+		return []db.CreateAvailabilitySlotParams{}, err
+	}
+	slots, err := spanToSlots(p.StartTime, p.EndTime, int(unit))
 	if err != nil {
 		return []db.CreateAvailabilitySlotParams{}, err
 	}
@@ -96,9 +123,10 @@ func (p PutAvailabilitySlotRequest) ToCreationParams() ([]db.CreateAvailabilityS
 		}
 		params = append(params,
 			db.CreateAvailabilitySlotParams{
-				EmployeeID: p.EmployeeID,
-				Datetime:   slotTimeStamp,
-				TypeID:     p.TypeID,
+				EmployeeID:  p.EmployeeID,
+				Datetime:    slotTimeStamp,
+				UnitMinutes: unit,
+				Capacity:    capacity,
 			})
 	}
 	return params, nil
@@ -108,13 +136,152 @@ type DeleteAvailabilityRequest struct {
 	AvailabilitySlotIDs []int32 `json:"availability_slot_ids"`
 }
 
-func handleCreation(params []db.CreateAvailabilitySlotParams, qtx *db.Queries, ctx context.Context) ([]int32, error) {
+type DeleteAvailabilityResponse struct {
+	CancelledBookingIDs     []int32 `json:"cancelled_booking_ids"`
+	SkippedBookingIDs       []int32 `json:"skipped_booking_ids"`        // already completed or no shows
+	KeptAvailabilitySlotIDs []int32 `json:"kept_availability_slot_ids"` // the slots of the skipped bookings
+}
+
+// AvailabilityConflictResponse is returned with a 409 when new availability
+// overlaps time the employee is already offering
+type AvailabilityConflictResponse struct {
+	Message            string  `json:"message"`
+	ConflictingSlotIDs []int32 `json:"conflicting_slot_ids"`
+}
+
+var (
+	ErrNoBookingTypes = errors.New("at least one booking type is required")
+	ErrMixedUnits     = errors.New("booking types offered together must have the same unit")
+)
+
+// requestTypeIDs merges the single type_id and the type_ids list of an
+// availability request, dropping zeros and duplicates
+func requestTypeIDs(typeID int32, typeIDs []int32) []int32 {
+	result := []int32{}
+	for _, id := range append([]int32{typeID}, typeIDs...) {
+		if id != 0 && !slices.Contains(result, id) {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// availabilityUnit returns the unit shared by typeIDs, which become the length
+// of the slots offered for them. It returns pgx.ErrNoRows if a type doesnt exist.
+func availabilityUnit(ctx context.Context, queries *db.Queries, typeIDs []int32) (int32, error) {
+	if len(typeIDs) == 0 {
+		return 0, ErrNoBookingTypes
+	}
+	var unit int32
+	for _, id := range typeIDs {
+		bookingType, err := queries.GetBookingTypeById(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		if unit != 0 && bookingType.UnitMinutes != unit {
+			return 0, ErrMixedUnits
+		}
+		unit = bookingType.UnitMinutes
+	}
+	return unit, nil
+}
+
+// slotCapacity is the number of bookings a slot can take, a capacity of 0 is
+// the default of one booking
+func slotCapacity(capacity int32) (int32, error) {
+	if capacity < 0 {
+		return 0, errors.New("capacity can not be negative")
+	}
+	return max(capacity, 1), nil
+}
+
+// slotSeatsRemaining maps each of slots to the number of bookings it can still
+// take, a live hold takes a seat until it expires
+func slotSeatsRemaining(ctx context.Context, queries *db.Queries, slots []db.Availability) (map[int32]int32, error) {
+	ids := []int32{}
+	for _, s := range slots {
+		ids = append(ids, s.ID)
+	}
+	rows, err := queries.GetSlotSeats(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	remaining := map[int32]int32{}
+	for _, row := range rows {
+		remaining[row.ID] = max(row.Capacity-int32(row.Booked+row.Held), 0)
+	}
+	return remaining, nil
+}
+
+// slotTypeIDs maps each of slots to the booking types it is offered for
+func slotTypeIDs(ctx context.Context, queries *db.Queries, slots []db.Availability) (map[int32][]int32, error) {
+	ids := []int32{}
+	for _, s := range slots {
+		ids = append(ids, s.ID)
+	}
+	rows, err := queries.GetAvailabilityTypes(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	typeIDs := map[int32][]int32{}
+	for _, row := range rows {
+		typeIDs[row.AvailabilityID] = append(typeIDs[row.AvailabilityID], row.TypeID)
+	}
+	return typeIDs, nil
+}
+
+// checkAvailabilityOverlap locks the employee and returns the ids of their slots,
+// other than exclude, that overlap [start, end). Locking the employee means
+// concurrent edits to their availability cant both pass the check.
+func checkAvailabilityOverlap(ctx context.Context, queries *db.Queries, employeeID int32, start time.Time, end time.Time, exclude []int32) ([]int32, error) {
+	_, err := queries.LockEmployee(ctx, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	if exclude == nil {
+		exclude = []int32{}
+	}
+	overlapping, err := queries.GetOverlappingAvailabilitySlots(ctx, db.GetOverlappingAvailabilitySlotsParams{
+		EmployeeID: employeeID,
+		StartTime:  pgtype.Timestamptz{Time: start, Valid: true},
+		EndTime:    pgtype.Timestamptz{Time: end, Valid: true},
+		ExcludeIds: exclude,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ids := []int32{}
+	for _, s := range overlapping {
+		ids = append(ids, s.ID)
+	}
+	return ids, nil
+}
+
+func writeAvailabilityConflict(w http.ResponseWriter, conflicting []int32) {
+	w.WriteHeader(http.StatusConflict)
+	err := json.NewEncoder(w).Encode(AvailabilityConflictResponse{
+		Message:            "The employee is already available at some of these times",
+		ConflictingSlotIDs: conflicting,
+	})
+	if err != nil {
+		log.Printf("encoding availability conflict response failed with %v", err)
+	}
+}
+
+func handleCreation(params []db.CreateAvailabilitySlotParams, typeIDs []int32, qtx *db.Queries, ctx context.Context) ([]int32, error) {
 	slotIDs := []int32{}
 	for _, param := range params {
 		availabilitySlotID, err := qtx.CreateAvailabilitySlot(ctx, param)
 		if err != nil {
 			return nil, err
 		}
+		err = qtx.CreateAvailabilityTypes(ctx, db.CreateAvailabilityTypesParams{
+			AvailabilityID: availabilitySlotID,
+			Column2:        typeIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
 		slotIDs = append(slotIDs, availabilitySlotID)
 	}
 
@@ -158,14 +325,53 @@ func postAvailabilitySlot(pool *pgxpool.Pool, ctx context.Context) http.HandlerF
 		queries := db.New(conn)
 		qtx := queries.WithTx(tx)
 
-		params, err := availabilitySlotRequest.ToDBParams()
+		typeIDs := requestTypeIDs(availabilitySlotRequest.TypeID, availabilitySlotRequest.TypeIDs)
+		unit, err := availabilityUnit(ctx, qtx, typeIDs)
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("booking types %v dont all exist in postAvailabilitySlot", typeIDs)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, ErrNoBookingTypes) || errors.Is(err, ErrMixedUnits) {
+			log.Printf("invalid booking types %v in postAvailabilitySlot: %v", typeIDs, err)
+			w.WriteHeader(http.StatusBadRequest)
+			err = json.NewEncoder(w).Encode(ErrorResponse{Message: err.Error()})
+			if err != nil {
+				log.Printf("error encoding json in postAvailabilitySlot: %v", err)
+			}
+			return
+		}
+		if err != nil {
+			log.Printf("getting booking types in postAvailabilitySlot failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		params, err := availabilitySlotRequest.ToDBParams(unit)
 		if err != nil {
 			log.Printf("error creating params in postAvailabilitySlot: %v", err)
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
 
-		slotIDs, err := handleCreation(params, qtx, ctx)
+		conflicting, err := checkAvailabilityOverlap(ctx, qtx, availabilitySlotRequest.EmployeeID, availabilitySlotRequest.StartTime, availabilitySlotRequest.EndTime, nil)
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("employee id: %d does not exist in postAvailabilitySlot", availabilitySlotRequest.EmployeeID)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Printf("checking for overlapping slots in postAvailabilitySlot failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if len(conflicting) > 0 {
+			log.Printf("employee id: %d is already available in slots %v in postAvailabilitySlot", availabilitySlotRequest.EmployeeID, conflicting)
+			writeAvailabilityConflict(w, conflicting)
+			return
+		}
+
+		slotIDs, err := handleCreation(params, typeIDs, qtx, ctx)
 		if err != nil {
 			var pgErr *pgconn.PgError
 			if errors.As(err, &pgErr) {
@@ -178,8 +384,8 @@ func postAvailabilitySlot(pool *pgxpool.Pool, ctx context.Context) http.HandlerF
 					return
 				}
 				if pgErr.Code == "23503" {
-					log.Printf("either the booking type id: %d or employee id: %d does not exist",
-						availabilitySlotRequest.TypeID,
+					log.Printf("either the booking types %v or employee id: %d does not exist",
+						typeIDs,
 						availabilitySlotRequest.EmployeeID,
 					)
 					w.WriteHeader(http.StatusBadRequest)
@@ -214,6 +420,11 @@ func postAvailabilitySlot(pool *pgxpool.Pool, ctx context.Context) http.HandlerF
 
 func getAvailabilitySlot(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		loc, ok := requestLocation(w, r)
+		if !ok {
+			return
+		}
+
 		availabilitySlotID := r.PathValue("availability_slot_id")
 		conn, err := pool.Acquire(ctx)
 		if err != nil {
@@ -232,10 +443,24 @@ func getAvailabilitySlot(pool *pgxpool.Pool, ctx context.Context) http.HandlerFu
 				return
 			}
 
+			typeIDs, err := slotTypeIDs(ctx, queries, availabilitySlots)
+			if err != nil {
+				log.Printf("error getting slot booking types in getAllAvailabilitySlots: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			seats, err := slotSeatsRemaining(ctx, queries, availabilitySlots)
+			if err != nil {
+				log.Printf("error getting slot seats in getAllAvailabilitySlots: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
 			resp := []GetAvailiabilitySlotResponse{}
 
 			for _, a := range availabilitySlots {
-				resp = append(resp, responseFromDBAvailability(a))
+				resp = append(resp, responseFromDBAvailability(a, typeIDs[a.ID], seats[a.ID], loc))
 			}
 
 			err = json.NewEncoder(w).Encode(resp)
@@ -267,85 +492,64 @@ func getAvailabilitySlot(pool *pgxpool.Pool, ctx context.Context) http.HandlerFu
 			return
 		}
 
-		err = json.NewEncoder(w).Encode(responseFromDBAvailability(availabilitySlot))
+		typeIDs, err := slotTypeIDs(ctx, queries, []db.Availability{availabilitySlot})
 		if err != nil {
-			log.Printf("error encoding json in getAvailabilitySlot: %v", err)
+			log.Printf("error getting slot booking types in getAvailabilitySlot: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-	}
-}
 
-func getFreeAvailabilitySlots(pool *pgxpool.Pool, ctx context.Context, a *AuthParams) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// ok so we first want to check the user is a user and then we just
-		// need a query that gets all availability that doesnt have an entry
-		// in the join table
-		conn, err := pool.Acquire(ctx)
+		seats, err := slotSeatsRemaining(ctx, queries, []db.Availability{availabilitySlot})
 		if err != nil {
-			log.Printf("error aquiring pool in getFreeAvailabilitySlots: %v", err)
+			log.Printf("error getting slot seats in getAvailabilitySlot: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-		defer conn.Release()
-
-		queries := db.New(conn)
-
-		// get session token, validate token, and validate user role
-		token, err := ReadEncryptedCookie(r, a.CParams.Name, a.SecretKey)
-		if err != nil {
-			log.Printf("The token provided failed in getFreeAvailabilitySlots: %v with %v", token, err)
-			w.WriteHeader(http.StatusUnauthorized)
-			err = json.NewEncoder(w).Encode(ErrorResponse{Message: "Invalid session"})
-			if err != nil {
-				log.Printf("encoding response in getFreeAvailabilitySlots failed with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-			return
-		}
 
-		valid, err := VerifySession(ctx, queries, token)
+		err = json.NewEncoder(w).Encode(responseFromDBAvailability(availabilitySlot, typeIDs[availabilitySlot.ID], seats[availabilitySlot.ID], loc))
 		if err != nil {
-			log.Printf("error verfying session in getFreeAvailabilitySlots failed with %v", err)
+			log.Printf("error encoding json in getAvailabilitySlot: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+	}
+}
 
-		if !valid {
-			w.WriteHeader(http.StatusUnauthorized)
-			err = json.NewEncoder(w).Encode(ErrorResponse{Message: "Invalid session"})
-			if err != nil {
-				log.Printf("writing json in getFreeAvailabilitySlots failed with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
+func getFreeAvailabilitySlots(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// all availability that doesnt have an entry in the join table
+		loc, ok := requestLocation(w, r)
+		if !ok {
 			return
 		}
 
-		session, err := queries.GetSessionByToken(ctx, token)
+		conn, err := pool.Acquire(ctx)
 		if err != nil {
-			log.Printf("error getting session in getFreeAvailabilitySlots failed with %v", err)
+			log.Printf("error aquiring pool in getFreeAvailabilitySlots: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		defer conn.Release()
 
-		user, err := queries.GetUserByIdWithRoles(ctx, session.UserID)
-		if err != nil {
-			log.Printf("error getting user in getFreeAvailabilitySlots failed with %v", err)
+		queries := db.New(conn)
+
+		availabilitySlots, err := queries.GetAllFreeAvailabilitySlots(ctx)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("error querying availability table in getFreeAvailabilitySlots: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		if !(slices.Contains(user.RoleNames, RoleUser)) {
-			log.Printf("user %v has requested permission to getFreeAvailability without permissions USER", user.ID)
-			w.WriteHeader(http.StatusUnauthorized)
+		typeIDs, err := slotTypeIDs(ctx, queries, availabilitySlots)
+		if err != nil {
+			log.Printf("error getting slot booking types in getFreeAvailabilitySlots: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		availabilitySlots, err := queries.GetAllFreeAvailabilitySlots(ctx)
-		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-			log.Printf("error querying availability table in getFreeAvailabilitySlots: %v", err)
+		seats, err := slotSeatsRemaining(ctx, queries, availabilitySlots)
+		if err != nil {
+			log.Printf("error getting slot seats in getFreeAvailabilitySlots: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -353,7 +557,7 @@ func getFreeAvailabilitySlots(pool *pgxpool.Pool, ctx context.Context, a *AuthPa
 		resp := []GetAvailiabilitySlotResponse{}
 
 		for _, a := range availabilitySlots {
-			resp = append(resp, responseFromDBAvailability(a))
+			resp = append(resp, responseFromDBAvailability(a, typeIDs[a.ID], seats[a.ID], loc))
 		}
 
 		err = json.NewEncoder(w).Encode(resp)
@@ -408,25 +612,49 @@ func putAvailabilitySlot(pool *pgxpool.Pool, ctx context.Context) http.HandlerFu
 			return
 		}
 
-		sequentialCheckTimes := []time.Time{}
-		for _, s := range sequentialCheckSlots {
-			sequentialCheckTimes = append(sequentialCheckTimes, s.Datetime.Time)
-		}
-
-		if !isSequential(sequentialCheckTimes, Unit) {
+		if !slotsSequential(sequentialCheckSlots) {
 			log.Printf("slot request is not sequential in putAvailability")
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
-		params, err := availabilitySlotRequest.ToCreationParams()
+		typeIDs := requestTypeIDs(availabilitySlotRequest.TypeID, availabilitySlotRequest.TypeIDs)
+		unit, err := availabilityUnit(ctx, qtx, typeIDs)
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("booking types %v dont all exist in putAvailabilitySlot", typeIDs)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, ErrNoBookingTypes) || errors.Is(err, ErrMixedUnits) {
+			log.Printf("invalid booking types %v in putAvailabilitySlot: %v", typeIDs, err)
+			w.WriteHeader(http.StatusBadRequest)
+			err = json.NewEncoder(w).Encode(ErrorResponse{Message: err.Error()})
+			if err != nil {
+				log.Printf("error encoding json in putAvailabilitySlot: %v", err)
+			}
+			return
+		}
+		if err != nil {
+			log.Printf("getting booking types in putAvailabilitySlot failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		capacity, err := slotCapacity(availabilitySlotRequest.Capacity)
+		if err != nil {
+			log.Printf("invalid capacity in putAvailabilitySlot: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		params, err := availabilitySlotRequest.ToCreationParams(unit)
 		if err != nil {
 			log.Printf("error creating params in putAvailabilitySlot: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		currentDatetimes := []pgtype.Timestamp{}
-		timeToID := make(map[pgtype.Timestamp]int32)
+		currentDatetimes := []pgtype.Timestamptz{}
+		timeToID := make(map[pgtype.Timestamptz]int32)
 		for _, id := range availabilitySlotRequest.AvailabilitySlotIDs {
 			slot, err := qtx.GetAvailabilitySlotById(ctx, id)
 			if err != nil {
@@ -438,7 +666,7 @@ func putAvailabilitySlot(pool *pgxpool.Pool, ctx context.Context) http.HandlerFu
 			timeToID[slot.Datetime] = id
 		}
 
-		newDatetimes := []pgtype.Timestamp{}
+		newDatetimes := []pgtype.Timestamptz{}
 		for _, p := range params {
 			newDatetimes = append(newDatetimes, p.Datetime)
 		}
@@ -463,6 +691,45 @@ func putAvailabilitySlot(pool *pgxpool.Pool, ctx context.Context) http.HandlerFu
 			return
 		}
 
+		// the slots that are kept cant have fewer seats than they have bookings
+		keptIDs := []int32{}
+		for _, id := range availabilitySlotRequest.AvailabilitySlotIDs {
+			if !slices.Contains(idsToDel, id) {
+				keptIDs = append(keptIDs, id)
+			}
+		}
+		seats, err := qtx.GetSlotSeats(ctx, keptIDs)
+		if err != nil {
+			log.Printf("error getting slot seats in putAvailabilitySlot: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		for _, s := range seats {
+			if s.Booked > int64(capacity) {
+				log.Printf("slot %d has %d bookings and cant have its capacity lowered to %d in putAvailabilitySlot", s.ID, s.Booked, capacity)
+				http.Error(w, "This change would leave a slot with more bookings than seats", http.StatusBadRequest)
+				return
+			}
+		}
+
+		// the slots being edited can overlap the new times, anything else cant
+		conflicting, err := checkAvailabilityOverlap(ctx, qtx, availabilitySlotRequest.EmployeeID, availabilitySlotRequest.StartTime, availabilitySlotRequest.EndTime, availabilitySlotRequest.AvailabilitySlotIDs)
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("employee id: %d does not exist in putAvailabilitySlot", availabilitySlotRequest.EmployeeID)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Printf("checking for overlapping slots in putAvailabilitySlot failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if len(conflicting) > 0 {
+			log.Printf("employee id: %d is already available in slots %v in putAvailabilitySlot", availabilitySlotRequest.EmployeeID, conflicting)
+			writeAvailabilityConflict(w, conflicting)
+			return
+		}
+
 		for _, id := range idsToDel {
 			_, err = qtx.DeleteAvailabilitySlot(ctx, id)
 			if err != nil {
@@ -472,16 +739,46 @@ func putAvailabilitySlot(pool *pgxpool.Pool, ctx context.Context) http.HandlerFu
 			}
 		}
 
+		// the slots that are kept are offered for the requested types with the
+		// requested capacity from now on
+		err = qtx.UpdateAvailabilityCapacity(ctx, db.UpdateAvailabilityCapacityParams{
+			Column1:  keptIDs,
+			Capacity: capacity,
+		})
+		if err != nil {
+			log.Printf("error updating slot capacity in putAvailabilitySlot: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		err = qtx.DeleteAvailabilityTypes(ctx, keptIDs)
+		if err != nil {
+			log.Printf("error clearing slot booking types in putAvailabilitySlot: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		for _, id := range keptIDs {
+			err = qtx.CreateAvailabilityTypes(ctx, db.CreateAvailabilityTypesParams{
+				AvailabilityID: id,
+				Column2:        typeIDs,
+			})
+			if err != nil {
+				log.Printf("error setting booking types of slot %d in putAvailabilitySlot: %v", id, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
 		createParams := []db.CreateAvailabilitySlotParams{}
 		for _, s := range slotsToCreate {
 			createParams = append(createParams, db.CreateAvailabilitySlotParams{
-				EmployeeID: availabilitySlotRequest.EmployeeID,
-				TypeID:     availabilitySlotRequest.TypeID,
-				Datetime:   s,
+				EmployeeID:  availabilitySlotRequest.EmployeeID,
+				Datetime:    s,
+				UnitMinutes: unit,
+				Capacity:    capacity,
 			})
 		}
 
-		slotIDs, err := handleCreation(createParams, qtx, ctx)
+		slotIDs, err := handleCreation(createParams, typeIDs, qtx, ctx)
 		if err != nil {
 			var pgErr *pgconn.PgError
 			if errors.As(err, &pgErr) {
@@ -494,8 +791,8 @@ func putAvailabilitySlot(pool *pgxpool.Pool, ctx context.Context) http.HandlerFu
 					return
 				}
 				if pgErr.Code == "23503" {
-					log.Printf("either the booking type id: %d or employee id: %d does not exist",
-						availabilitySlotRequest.TypeID,
+					log.Printf("either the booking types %v or employee id: %d does not exist",
+						typeIDs,
 						availabilitySlotRequest.EmployeeID,
 					)
 					w.WriteHeader(http.StatusBadRequest)
@@ -529,7 +826,30 @@ func putAvailabilitySlot(pool *pgxpool.Pool, ctx context.Context) http.HandlerFu
 
 }
 
-func deleteAvailabilitySlot(pool *pgxpool.Pool, ctx context.Context, a *AuthParams, force bool) http.HandlerFunc {
+// parseForceDelete reads force and reason from the query string of a delete. A
+// forced delete needs a reason to give the customers whose bookings it cancels.
+func parseForceDelete(query url.Values) (bool, string, error) {
+	force := false
+	if f := query.Get("force"); f != "" {
+		var err error
+		force, err = strconv.ParseBool(f)
+		if err != nil {
+			return false, "", fmt.Errorf("force %q is not true or false", f)
+		}
+	}
+	reason := strings.TrimSpace(query.Get("reason"))
+	if force && reason == "" {
+		return false, "", errors.New("a reason is required to force delete availability")
+	}
+	return force, reason, nil
+}
+
+// deleteAvailabilitySlot deletes availability that isnt booked. Admins can pass
+// force=true and a reason in the query string to cancel the bookings on it
+// first, the customers are refunded and told the reason and the cancelled
+// bookings listed in the response. Bookings that have already finished cant be
+// cancelled, they are skipped and their slots kept as the record of them.
+func deleteAvailabilitySlot(pool *pgxpool.Pool, ctx context.Context, pp PaymentParams, notifier BookingNotifier) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var availabilitySlotIDs []int32
 		availabilitySlotId := r.PathValue("availability_slot_id")
@@ -553,6 +873,18 @@ func deleteAvailabilitySlot(pool *pgxpool.Pool, ctx context.Context, a *AuthPara
 			availabilitySlotIDs = deleteRequest.AvailabilitySlotIDs
 		}
 
+		force, reason, err := parseForceDelete(r.URL.Query())
+		if err != nil {
+			log.Printf("invalid force delete in deleteAvailabilitySlot: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		user, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
 		conn, err := pool.Acquire(ctx)
 		if err != nil {
 			log.Printf("error aquiring pool in deleteAvailabilitySlot: %v", err)
@@ -578,108 +910,72 @@ func deleteAvailabilitySlot(pool *pgxpool.Pool, ctx context.Context, a *AuthPara
 		queries := db.New(conn)
 		qtx := queries.WithTx(tx)
 
-		token, err := ReadEncryptedCookie(r, a.CParams.Name, a.SecretKey)
+		bookingIDs, err := qtx.GetBookingSlotsFromAvailability(ctx, availabilitySlotIDs)
 		if err != nil {
-			log.Printf("The token provided failed in deleteAvailabilitySlots: %v with %v", token, err)
-			w.WriteHeader(http.StatusUnauthorized)
-			err = json.NewEncoder(w).Encode(ErrorResponse{Message: "Invalid session"})
-			if err != nil {
-				log.Printf("encoding response in deleteAvailabilitySlots failed with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
+			log.Printf("error getting booking id deleteAvailabilitySlot: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		valid, err := VerifySession(ctx, queries, token)
-		if err != nil {
-			log.Printf("error verfying session in deleteAvailabilitySlots failed with %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
+		if len(bookingIDs) > 0 && !force {
+			http.Error(w, "deleting availability with bookings present", http.StatusBadRequest)
 			return
 		}
 
-		if !valid {
-			w.WriteHeader(http.StatusUnauthorized)
-			err = json.NewEncoder(w).Encode(ErrorResponse{Message: "Invalid session"})
+		cancelled := []db.GetBookingWithJoinRow{}
+		skipped := []int32{}
+		kept := map[int32]bool{}
+		for _, bookingID := range bookingIDs {
+			booking, err := qtx.GetBookingWithJoin(ctx, bookingID)
 			if err != nil {
-				log.Printf("writing json in deleteAvailabilitySlots failed with %v", err)
+				log.Printf("error getting booking with join in deleteAvailabilitySlot failed with %v", err)
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
-			return
-		}
-
-		session, err := queries.GetSessionByToken(ctx, token)
-		if err != nil {
-			log.Printf("error getting session in deleteAvailabilitySlots failed with %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-
-		user, err := queries.GetUserByIdWithRoles(ctx, session.UserID)
-		if err != nil {
-			log.Printf("error getting user in deleteAvailabilitySlots failed with %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-
-		if !(slices.Contains(user.RoleNames, RoleAdmin)) {
-			log.Printf("user %v has requested permission to deleteAvailability without permissions Admin", user.ID)
-			w.WriteHeader(http.StatusUnauthorized)
-			return
-		}
-
-		bookingIDs, err := qtx.GetBookingSlotsFromAvailability(ctx, availabilitySlotIDs)
-		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-			log.Printf("error getting booking id deleteAvailabilitySlot: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-
-		if len(bookingIDs) > 0 && !force {
-			http.Error(w, "deleting availability with bookings present", http.StatusBadRequest)
-			return
-		}
 
-		if err != nil && errors.Is(err, pgx.ErrNoRows) {
-			// no booking associated to it so no need to delete
-		} else {
-			for _, bookingID := range bookingIDs {
-				booking, err := qtx.GetBookingWithJoin(ctx, db.GetBookingWithJoinParams{
-					Column1: Unit,
-					ID:      bookingID,
-				})
+			err = checkTransition(user, booking.Status, db.BookingStatusCancelled, booking.UserID)
+			if errors.Is(err, ErrInvalidTransition) {
+				log.Printf("booking %d on deleted availability is %s, keeping its slots in deleteAvailabilitySlot", bookingID, booking.Status)
+				slotIDs, err := qtx.GetBookingSlotIds(ctx, bookingID)
 				if err != nil {
-					log.Printf("error getting booking with join in deleteAvailabilitySlots failed with %v", err)
+					log.Printf("error getting slots of booking %d in deleteAvailabilitySlot: %v", bookingID, err)
 					w.WriteHeader(http.StatusInternalServerError)
 					return
 				}
-
-				err = qtx.UpdateBookingStatus(ctx, db.UpdateBookingStatusParams{
-					ID:              bookingID,
-					Status:          db.BookingStatusCancelled,
-					StatusUpdatedBy: user.Email,
-				})
-				if err != nil {
-					log.Printf("error updating booking booking status in deleteAvailabilitySlot: %v", err)
-					w.WriteHeader(http.StatusInternalServerError)
-					return
+				for _, id := range slotIDs {
+					kept[id] = true
 				}
-				err = qtx.CreateBookingHistory(ctx, db.CreateBookingHistoryParams{
-					BookingID:       bookingID,
-					EmployeeID:      booking.EmployeeID,
-					EmployeeName:    booking.EmployeeName,
-					EmployeeSurname: booking.EmployeeSurname,
-					EmployeeEmail:   booking.EmployeeEmail,
-					StartTime:       booking.StartTime,
-					EndTime:         booking.EndTime,
-					Status:          db.BookingStatusCancelled,
-					ChangedByEmail:  user.Email,
-				})
+				skipped = append(skipped, bookingID)
+				continue
+			}
+			if err != nil {
+				log.Printf("booking %d on deleted availability cant be cancelled from %s in deleteAvailabilitySlot: %v", bookingID, booking.Status, err)
+				writeTransitionError(w, err, user, booking.Status, booking.UserID)
+				return
+			}
+
+			err = setBookingStatus(ctx, qtx, bookingID, db.BookingStatusCancelled, user.Email, reason)
+			if err != nil {
+				log.Printf("error cancelling booking %d in deleteAvailabilitySlot: %v", bookingID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			err = settleCancellation(ctx, qtx, pp, bookingID, 0, user.Email, cancelledReason)
+			if err != nil {
+				log.Printf("error settling booking %d in deleteAvailabilitySlot: %v", bookingID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
 			}
+			cancelled = append(cancelled, booking)
 		}
 
+		keptSlotIDs := []int32{}
 		for _, id := range availabilitySlotIDs {
+			if kept[id] {
+				keptSlotIDs = append(keptSlotIDs, id)
+				continue
+			}
 			_, err = qtx.DeleteAvailabilitySlot(ctx, int32(id))
 			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 				log.Printf("general error when trying to delete availabilitySlot id: %v in deleteAvailabilitySlot: %v", id, err)
@@ -700,7 +996,28 @@ func deleteAvailabilitySlot(pool *pgxpool.Pool, ctx context.Context, a *AuthPara
 			return
 		}
 
-		w.WriteHeader(http.StatusNoContent)
-	}
+		sendPaymentRefunds(ctx, pool, pp)
+
+		notifyCancelled(ctx, notifier, cancelled, reason)
+
+		if !force {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
 
+		response := DeleteAvailabilityResponse{
+			CancelledBookingIDs:     []int32{},
+			SkippedBookingIDs:       skipped,
+			KeptAvailabilitySlotIDs: keptSlotIDs,
+		}
+		for _, booking := range cancelled {
+			response.CancelledBookingIDs = append(response.CancelledBookingIDs, booking.ID)
+		}
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			log.Printf("error encoding json in deleteAvailabilitySlot: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
 }
@@ -0,0 +1,1051 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	clockLayout = "15:04"
+	dateLayout  = "2006-01-02"
+)
+
+// maxMaterialiseAhead is the furthest ahead a rule's slots can be created on
+// request
+const maxMaterialiseAhead = 366 * 24 * time.Hour
+
+// AvailabilityRuleRequest describes a weekly recurring span of availability,
+// e.g. Mon-Fri 09:00-17:00. Times are on a boundary of the booking type's unit,
+// dates are inclusive, an empty valid_from is today and an empty valid_until
+// never ends.
+type AvailabilityRuleRequest struct {
+	EmployeeID int32   `json:"employee_id"`
+	TypeID     int32   `json:"type_id"`
+	Weekdays   []int32 `json:"weekdays"`   // 0 is Sunday through to 6 Saturday
+	StartTime  string  `json:"start_time"` // 15:04
+	EndTime    string  `json:"end_time"`   // 15:04
+	ValidFrom  string  `json:"valid_from"` // 2006-01-02
+	ValidUntil string  `json:"valid_until"`
+	Capacity   int32   `json:"capacity"` // bookings each slot can take, defaults to 1
+}
+
+type AvailabilityRuleResponse struct {
+	RuleID     int32    `json:"rule_id"`
+	EmployeeID int32    `json:"employee_id"`
+	TypeID     int32    `json:"type_id"`
+	Weekdays   []int32  `json:"weekdays"`
+	StartTime  string   `json:"start_time"`
+	EndTime    string   `json:"end_time"`
+	ValidFrom  string   `json:"valid_from"`
+	ValidUntil string   `json:"valid_until"`
+	Capacity   int32    `json:"capacity"`
+	Exceptions []string `json:"exceptions"`
+}
+
+type PostAvailabilityRuleResponse struct {
+	RuleID       int32 `json:"rule_id"`
+	SlotsCreated int64 `json:"slots_created"`
+}
+
+type AvailabilityRuleExceptionRequest struct {
+	Date string `json:"date"` // 2006-01-02
+}
+
+type MaterialiseAvailabilityRuleRequest struct {
+	Until time.Time `json:"until"` // this expects RFC 3339 format
+}
+
+func (r MaterialiseAvailabilityRuleRequest) validate(now time.Time) error {
+	if !r.Until.After(now) {
+		return errors.New("until must be in the future")
+	}
+	if r.Until.After(now.Add(maxMaterialiseAhead)) {
+		return fmt.Errorf("until must be at most %d days ahead", maxMaterialiseAhead/(24*time.Hour))
+	}
+	return nil
+}
+
+type MaterialiseAvailabilityRuleResponse struct {
+	SlotsCreated int64 `json:"slots_created"`
+}
+
+// parseClock turns a 15:04 time of day into minutes after midnight
+func parseClock(clock string) (int32, error) {
+	t, err := time.Parse(clockLayout, clock)
+	if err != nil {
+		return 0, err
+	}
+	return int32(t.Hour()*60 + t.Minute()), nil
+}
+
+// alignedToUnit checks that a rule starts and ends on a unit boundary so that it
+// splits into whole slots
+func alignedToUnit(startMinute int32, endMinute int32, unit int32) error {
+	for _, minutes := range []int32{startMinute, endMinute} {
+		if minutes%unit != 0 {
+			return fmt.Errorf("%s is not on a %d minute boundary", formatClock(minutes), unit)
+		}
+	}
+	return nil
+}
+
+func formatClock(minutes int32) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// parseDate parses a 2006-01-02 date, an empty string is a null date
+func parseDate(date string) (pgtype.Date, error) {
+	if date == "" {
+		return pgtype.Date{}, nil
+	}
+	t, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return pgtype.Date{}, err
+	}
+	return pgtype.Date{Time: t, Valid: true}, nil
+}
+
+func formatDate(date pgtype.Date) string {
+	if !date.Valid {
+		return ""
+	}
+	return date.Time.Format(dateLayout)
+}
+
+// startOfDay returns midnight UTC on the calendar date of t in its own location,
+// which is how dates come back from the database
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// dayBounds returns the instants that date starts and ends at in loc. Around a
+// DST change the day can be 23 or 25 hours long.
+func dayBounds(date time.Time, loc *time.Location) (time.Time, time.Time) {
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+	return start, time.Date(date.Year(), date.Month(), date.Day()+1, 0, 0, 0, 0, loc)
+}
+
+func (r AvailabilityRuleRequest) ToDBParams(today time.Time) (db.CreateAvailabilityRuleParams, error) {
+	if len(r.Weekdays) == 0 {
+		return db.CreateAvailabilityRuleParams{}, errors.New("at least one weekday is required")
+	}
+	for _, d := range r.Weekdays {
+		if d < 0 || d > 6 {
+			return db.CreateAvailabilityRuleParams{}, fmt.Errorf("weekday %d is not between 0 and 6", d)
+		}
+	}
+	weekdays := slices.Clone(r.Weekdays)
+	slices.Sort(weekdays)
+	weekdays = slices.Compact(weekdays)
+
+	startMinute, err := parseClock(r.StartTime)
+	if err != nil {
+		return db.CreateAvailabilityRuleParams{}, err
+	}
+	endMinute, err := parseClock(r.EndTime)
+	if err != nil {
+		return db.CreateAvailabilityRuleParams{}, err
+	}
+	if startMinute >= endMinute {
+		return db.CreateAvailabilityRuleParams{}, errors.New("start_time must be before end_time")
+	}
+
+	validFrom, err := parseDate(r.ValidFrom)
+	if err != nil {
+		return db.CreateAvailabilityRuleParams{}, err
+	}
+	if !validFrom.Valid {
+		validFrom = pgtype.Date{Time: startOfDay(today), Valid: true}
+	}
+	validUntil, err := parseDate(r.ValidUntil)
+	if err != nil {
+		return db.CreateAvailabilityRuleParams{}, err
+	}
+	if validUntil.Valid && validUntil.Time.Before(validFrom.Time) {
+		return db.CreateAvailabilityRuleParams{}, errors.New("valid_until must not be before valid_from")
+	}
+	capacity, err := slotCapacity(r.Capacity)
+	if err != nil {
+		return db.CreateAvailabilityRuleParams{}, err
+	}
+
+	return db.CreateAvailabilityRuleParams{
+		EmployeeID:  r.EmployeeID,
+		TypeID:      r.TypeID,
+		Weekdays:    weekdays,
+		StartMinute: startMinute,
+		EndMinute:   endMinute,
+		ValidFrom:   validFrom,
+		ValidUntil:  validUntil,
+		Capacity:    capacity,
+	}, nil
+}
+
+func responseFromDBRule(rule db.AvailabilityRule, exceptions []db.AvailabilityRuleException) AvailabilityRuleResponse {
+	dates := []string{}
+	for _, e := range exceptions {
+		dates = append(dates, formatDate(e.Date))
+	}
+	return AvailabilityRuleResponse{
+		RuleID:     rule.ID,
+		EmployeeID: rule.EmployeeID,
+		TypeID:     rule.TypeID,
+		Weekdays:   rule.Weekdays,
+		StartTime:  formatClock(rule.StartMinute),
+		EndTime:    formatClock(rule.EndMinute),
+		ValidFrom:  formatDate(rule.ValidFrom),
+		ValidUntil: formatDate(rule.ValidUntil),
+		Capacity:   rule.Capacity,
+		Exceptions: dates,
+	}
+}
+
+// expandRule returns the start time of every unit minute slot rule covers in
+// [from, to), skipping the dates in exceptions. The rule's times are wall clock times in loc,
+// so a 09:00 slot stays at 09:00 local across DST changes. Slots that fall in the
+// hour skipped when the clocks go forward dont exist and are left out.
+func expandRule(rule db.AvailabilityRule, exceptions []db.AvailabilityRuleException, from time.Time, to time.Time, loc *time.Location, unit int32) []time.Time {
+	skip := map[time.Time]bool{}
+	for _, e := range exceptions {
+		skip[startOfDay(e.Date.Time)] = true
+	}
+
+	firstDay, _ := dayBounds(from.In(loc), loc)
+	if rule.ValidFrom.Valid && startOfDay(firstDay).Before(rule.ValidFrom.Time) {
+		firstDay, _ = dayBounds(rule.ValidFrom.Time, loc)
+	}
+
+	slots := []time.Time{}
+	for day := firstDay; day.Before(to); day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc) {
+		date := startOfDay(day)
+		if rule.ValidUntil.Valid && date.After(startOfDay(rule.ValidUntil.Time)) {
+			break
+		}
+		if skip[date] || !slices.Contains(rule.Weekdays, int32(day.Weekday())) {
+			continue
+		}
+		for m := rule.StartMinute; m < rule.EndMinute; m += unit {
+			hour, minute := int(m/60), int(m%60)
+			slot := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc)
+			if slot.Hour() != hour || slot.Minute() != minute {
+				continue
+			}
+			if slot.Before(from) || !slot.Before(to) {
+				continue
+			}
+			slots = append(slots, slot.UTC())
+		}
+	}
+	return slots
+}
+
+// ruleLocation returns the time zone of the employee rule belongs to
+func ruleLocation(ctx context.Context, queries *db.Queries, rule db.AvailabilityRule) (*time.Location, error) {
+	employee, err := queries.GetEmployeeById(ctx, rule.EmployeeID)
+	if err != nil {
+		return nil, err
+	}
+	return time.LoadLocation(employee.TimeZone)
+}
+
+// materialiseRule creates the slots for rule in [from, to). Slots that already
+// exist are left alone so this can be run repeatedly over the same window, and
+// times that overlap other availability of the employee are skipped.
+func materialiseRule(ctx context.Context, queries *db.Queries, rule db.AvailabilityRule, from time.Time, to time.Time) (int64, error) {
+	exceptions, err := queries.GetAvailabilityRuleExceptions(ctx, rule.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	loc, err := ruleLocation(ctx, queries, rule)
+	if err != nil {
+		return 0, err
+	}
+
+	bookingType, err := queries.GetBookingTypeById(ctx, rule.TypeID)
+	if err != nil {
+		return 0, err
+	}
+
+	var created int64
+	for _, slot := range expandRule(rule, exceptions, from, to, loc, bookingType.UnitMinutes) {
+		datetime := pgtype.Timestamptz{Time: slot, Valid: true}
+		n, err := queries.CreateRuleAvailabilitySlot(ctx, db.CreateRuleAvailabilitySlotParams{
+			EmployeeID:  rule.EmployeeID,
+			Datetime:    datetime,
+			RuleID:      pgtype.Int4{Int32: rule.ID, Valid: true},
+			UnitMinutes: bookingType.UnitMinutes,
+			Capacity:    rule.Capacity,
+		})
+		if err != nil {
+			return created, err
+		}
+		created += n
+
+		// the employee may already be offering this exact slot for another type,
+		// in which case it is offered for the rule's type as well
+		err = queries.CreateRuleAvailabilitySlotType(ctx, db.CreateRuleAvailabilitySlotTypeParams{
+			TypeID:      rule.TypeID,
+			RuleID:      rule.ID,
+			EmployeeID:  rule.EmployeeID,
+			Datetime:    datetime,
+			UnitMinutes: bookingType.UnitMinutes,
+		})
+		if err != nil {
+			return created, err
+		}
+	}
+	return created, nil
+}
+
+// releaseRuleSlots takes the rule's type off its slots in [from, to), or from
+// onwards if to is zero, and deletes the slots it made that are left with no
+// types. Slots that are booked or held, or that other rules or manual
+// availability still offer types on, are kept.
+func releaseRuleSlots(ctx context.Context, queries *db.Queries, ruleID int32, from time.Time, to time.Time) (int64, error) {
+	_, err := queries.DeleteUnbookedRuleSlotTypes(ctx, db.DeleteUnbookedRuleSlotTypesParams{
+		RuleID:   pgtype.Int4{Int32: ruleID, Valid: true},
+		Datetime: pgtype.Timestamptz{Time: from, Valid: true},
+		Column3:  pgtype.Timestamptz{Time: to, Valid: !to.IsZero()},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return queries.DeleteUnbookedRuleSlots(ctx, db.DeleteUnbookedRuleSlotsParams{
+		RuleID:   pgtype.Int4{Int32: ruleID, Valid: true},
+		Datetime: pgtype.Timestamptz{Time: from, Valid: true},
+		Column3:  pgtype.Timestamptz{Time: to, Valid: !to.IsZero()},
+	})
+}
+
+// materialiseAvailabilityRules keeps the slots for every active rule materialised
+// horizon ahead of now, running once straight away and then every interval
+func materialiseAvailabilityRules(ctx context.Context, pool *pgxpool.Pool, horizon time.Duration, interval time.Duration) {
+	run := func() {
+		queries := db.New(pool)
+		now := time.Now().UTC()
+		rules, err := queries.GetActiveAvailabilityRules(ctx, pgtype.Date{Time: startOfDay(now), Valid: true})
+		if err != nil {
+			log.Printf("getting active availability rules failed with %v", err)
+			return
+		}
+		for _, rule := range rules {
+			created, err := materialiseRule(ctx, queries, rule, now, now.Add(horizon))
+			if err != nil {
+				log.Printf("materialising availability rule %d failed with %v", rule.ID, err)
+				continue
+			}
+			if created > 0 {
+				log.Printf("materialised %d slots for availability rule %d", created, rule.ID)
+			}
+		}
+	}
+
+	run()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			run()
+		}
+	}
+}
+
+func postAvailabilityRule(pool *pgxpool.Pool, ctx context.Context, horizon time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var ruleRequest AvailabilityRuleRequest
+
+		err := json.NewDecoder(r.Body).Decode(&ruleRequest)
+		if err != nil {
+			log.Printf("error decoding body in postAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now().UTC()
+		params, err := ruleRequest.ToDBParams(now)
+		if err != nil {
+			log.Printf("invalid rule in postAvailabilityRule: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in postAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			log.Printf("error beginning tx in postAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		defer func() {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				panic(err)
+			}
+		}()
+
+		queries := db.New(conn)
+		qtx := queries.WithTx(tx)
+
+		bookingType, err := qtx.GetBookingTypeById(ctx, params.TypeID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("getting booking type in postAvailabilityRule failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("booking type id: %d does not exist in postAvailabilityRule", params.TypeID)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		err = alignedToUnit(params.StartMinute, params.EndMinute, bookingType.UnitMinutes)
+		if err != nil {
+			log.Printf("invalid rule in postAvailabilityRule: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		rule, err := qtx.CreateAvailabilityRule(ctx, params)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+				log.Printf("either the booking type id: %d or employee id: %d does not exist",
+					ruleRequest.TypeID,
+					ruleRequest.EmployeeID,
+				)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			log.Printf("general error when trying to create rule in postAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		created, err := materialiseRule(ctx, qtx, rule, now, now.Add(horizon))
+		if err != nil {
+			log.Printf("materialising rule in postAvailabilityRule failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Printf("error commiting tx in postAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := PostAvailabilityRuleResponse{
+			RuleID:       rule.ID,
+			SlotsCreated: created,
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			log.Printf("error encoding json in postAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func getAvailabilityRule(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ruleId := r.PathValue("rule_id")
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in getAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		queries := db.New(conn)
+
+		rules := []db.AvailabilityRule{}
+		if ruleId == "" {
+			rules, err = queries.GetAllAvailabilityRules(ctx)
+			if err != nil {
+				log.Printf("error querying availability rules in getAvailabilityRule: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		} else {
+			id, err := strconv.ParseInt(ruleId, 10, 32)
+			if err != nil {
+				log.Printf("error: %v converting rule id to int in getAvailabilityRule: %s", err, ruleId)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			rule, err := queries.GetAvailabilityRuleById(ctx, int32(id))
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				log.Printf("error querying availability rules in getAvailabilityRule: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if errors.Is(err, pgx.ErrNoRows) {
+				log.Printf("rule id: %d was requested in getAvailabilityRule and does not exist", id)
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			rules = append(rules, rule)
+		}
+
+		resp := []AvailabilityRuleResponse{}
+		for _, rule := range rules {
+			exceptions, err := queries.GetAvailabilityRuleExceptions(ctx, rule.ID)
+			if err != nil {
+				log.Printf("error querying rule exceptions in getAvailabilityRule: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			resp = append(resp, responseFromDBRule(rule, exceptions))
+		}
+
+		if ruleId != "" {
+			err = json.NewEncoder(w).Encode(resp[0])
+		} else {
+			err = json.NewEncoder(w).Encode(resp)
+		}
+		if err != nil {
+			log.Printf("error encoding json in getAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// putAvailabilityRule replaces a rule. Its future slots that arent booked or held
+// are replaced with the new ones, booked slots are left exactly as they were.
+func putAvailabilityRule(pool *pgxpool.Pool, ctx context.Context, horizon time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ruleId := r.PathValue("rule_id")
+		id, err := strconv.ParseInt(ruleId, 10, 32)
+		if err != nil {
+			log.Printf("error: %v converting rule id to int in putAvailabilityRule: %s", err, ruleId)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var ruleRequest AvailabilityRuleRequest
+
+		err = json.NewDecoder(r.Body).Decode(&ruleRequest)
+		if err != nil {
+			log.Printf("error decoding body in putAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now().UTC()
+		params, err := ruleRequest.ToDBParams(now)
+		if err != nil {
+			log.Printf("invalid rule in putAvailabilityRule: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in putAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			log.Printf("error beginning tx in putAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		defer func() {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				panic(err)
+			}
+		}()
+
+		queries := db.New(conn)
+		qtx := queries.WithTx(tx)
+
+		bookingType, err := qtx.GetBookingTypeById(ctx, params.TypeID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("getting booking type in putAvailabilityRule failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("booking type id: %d does not exist in putAvailabilityRule", params.TypeID)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		err = alignedToUnit(params.StartMinute, params.EndMinute, bookingType.UnitMinutes)
+		if err != nil {
+			log.Printf("invalid rule in putAvailabilityRule: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		rule, err := qtx.UpdateAvailabilityRule(ctx, db.UpdateAvailabilityRuleParams{
+			ID:          int32(id),
+			EmployeeID:  params.EmployeeID,
+			TypeID:      params.TypeID,
+			Weekdays:    params.Weekdays,
+			StartMinute: params.StartMinute,
+			EndMinute:   params.EndMinute,
+			ValidFrom:   params.ValidFrom,
+			ValidUntil:  params.ValidUntil,
+			Capacity:    params.Capacity,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				log.Printf("rule id: %d, which does not exist, was attemped to be updated by putAvailabilityRule", id)
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+				log.Printf("either the booking type id: %d or employee id: %d does not exist",
+					ruleRequest.TypeID,
+					ruleRequest.EmployeeID,
+				)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			log.Printf("general error when trying to update rule in putAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		_, err = releaseRuleSlots(ctx, qtx, rule.ID, now, time.Time{})
+		if err != nil {
+			log.Printf("releasing old slots in putAvailabilityRule failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		created, err := materialiseRule(ctx, qtx, rule, now, now.Add(horizon))
+		if err != nil {
+			log.Printf("materialising rule in putAvailabilityRule failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Printf("error commiting tx in putAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := PostAvailabilityRuleResponse{
+			RuleID:       rule.ID,
+			SlotsCreated: created,
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			log.Printf("error encoding json in putAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// deleteAvailabilityRule removes a rule along with its future unbooked slots.
+// Booked slots stay, they just no longer point at a rule.
+func deleteAvailabilityRule(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ruleId := r.PathValue("rule_id")
+		id, err := strconv.ParseInt(ruleId, 10, 32)
+		if err != nil {
+			log.Printf("error: %v converting rule id to int in deleteAvailabilityRule: %s", err, ruleId)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in deleteAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			log.Printf("error beginning tx in deleteAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		defer func() {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				panic(err)
+			}
+		}()
+
+		queries := db.New(conn)
+		qtx := queries.WithTx(tx)
+
+		_, err = releaseRuleSlots(ctx, qtx, int32(id), time.Now().UTC(), time.Time{})
+		if err != nil {
+			log.Printf("releasing slots in deleteAvailabilityRule failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		_, err = qtx.DeleteAvailabilityRule(ctx, int32(id))
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("general error when trying to delete rule in deleteAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("rule id: %d, which does not exist, was attemped to be deleted by deleteAvailabilityRule", id)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Printf("error commiting tx in deleteAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// postAvailabilityRuleException stops a rule applying on one date, releasing any
+// of its slots on that date that arent booked or held
+func postAvailabilityRuleException(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ruleId := r.PathValue("rule_id")
+		id, err := strconv.ParseInt(ruleId, 10, 32)
+		if err != nil {
+			log.Printf("error: %v converting rule id to int in postAvailabilityRuleException: %s", err, ruleId)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var exceptionRequest AvailabilityRuleExceptionRequest
+
+		err = json.NewDecoder(r.Body).Decode(&exceptionRequest)
+		if err != nil {
+			log.Printf("error decoding body in postAvailabilityRuleException: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		date, err := parseDate(exceptionRequest.Date)
+		if err != nil || !date.Valid {
+			log.Printf("invalid date %q in postAvailabilityRuleException", exceptionRequest.Date)
+			http.Error(w, "Invalid body, expects date as 2006-01-02", http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in postAvailabilityRuleException: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			log.Printf("error beginning tx in postAvailabilityRuleException: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		defer func() {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				panic(err)
+			}
+		}()
+
+		queries := db.New(conn)
+		qtx := queries.WithTx(tx)
+
+		_, err = qtx.CreateAvailabilityRuleException(ctx, db.CreateAvailabilityRuleExceptionParams{
+			RuleID: int32(id),
+			Date:   date,
+		})
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) {
+				if pgErr.Code == "23505" {
+					log.Printf("rule %d already has an exception on %s", id, exceptionRequest.Date)
+					w.WriteHeader(http.StatusConflict)
+					return
+				}
+				if pgErr.Code == "23503" {
+					log.Printf("rule id: %d does not exist in postAvailabilityRuleException", id)
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+			}
+			log.Printf("general error when trying to create exception in postAvailabilityRuleException: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		rule, err := qtx.GetAvailabilityRuleById(ctx, int32(id))
+		if err != nil {
+			log.Printf("getting rule in postAvailabilityRuleException failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		loc, err := ruleLocation(ctx, qtx, rule)
+		if err != nil {
+			log.Printf("getting rule time zone in postAvailabilityRuleException failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		dayStart, dayEnd := dayBounds(date.Time, loc)
+		_, err = releaseRuleSlots(ctx, qtx, int32(id), dayStart, dayEnd)
+		if err != nil {
+			log.Printf("releasing slots in postAvailabilityRuleException failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Printf("error commiting tx in postAvailabilityRuleException: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+// deleteAvailabilityRuleException lets a rule apply on a date again, putting its
+// slots back if the date is within the horizon
+func deleteAvailabilityRuleException(pool *pgxpool.Pool, ctx context.Context, horizon time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ruleId := r.PathValue("rule_id")
+		id, err := strconv.ParseInt(ruleId, 10, 32)
+		if err != nil {
+			log.Printf("error: %v converting rule id to int in deleteAvailabilityRuleException: %s", err, ruleId)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		date, err := parseDate(r.PathValue("date"))
+		if err != nil || !date.Valid {
+			log.Printf("invalid date %q in deleteAvailabilityRuleException", r.PathValue("date"))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in deleteAvailabilityRuleException: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			log.Printf("error beginning tx in deleteAvailabilityRuleException: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		defer func() {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				panic(err)
+			}
+		}()
+
+		queries := db.New(conn)
+		qtx := queries.WithTx(tx)
+
+		_, err = qtx.DeleteAvailabilityRuleException(ctx, db.DeleteAvailabilityRuleExceptionParams{
+			RuleID: int32(id),
+			Date:   date,
+		})
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("general error when trying to delete exception in deleteAvailabilityRuleException: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("rule %d has no exception on %s to delete", id, formatDate(date))
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		rule, err := qtx.GetAvailabilityRuleById(ctx, int32(id))
+		if err != nil {
+			log.Printf("getting rule in deleteAvailabilityRuleException failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		loc, err := ruleLocation(ctx, qtx, rule)
+		if err != nil {
+			log.Printf("getting rule time zone in deleteAvailabilityRuleException failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		now := time.Now().UTC()
+		from, to := dayBounds(date.Time, loc)
+		if from.Before(now) {
+			from = now
+		}
+		if to.After(now.Add(horizon)) {
+			to = now.Add(horizon)
+		}
+		if from.Before(to) {
+			_, err = materialiseRule(ctx, qtx, rule, from, to)
+			if err != nil {
+				log.Printf("materialising rule in deleteAvailabilityRuleException failed with %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Printf("error commiting tx in deleteAvailabilityRuleException: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// postMaterialiseAvailabilityRule creates a rule's slots from now until the
+// requested time, for when they are needed further ahead than the rolling job.
+// It goes at most maxMaterialiseAhead ahead.
+func postMaterialiseAvailabilityRule(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ruleId := r.PathValue("rule_id")
+		id, err := strconv.ParseInt(ruleId, 10, 32)
+		if err != nil {
+			log.Printf("error: %v converting rule id to int in postMaterialiseAvailabilityRule: %s", err, ruleId)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var materialiseRequest MaterialiseAvailabilityRuleRequest
+
+		err = json.NewDecoder(r.Body).Decode(&materialiseRequest)
+		if err != nil {
+			log.Printf("error decoding body in postMaterialiseAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now().UTC()
+		err = materialiseRequest.validate(now)
+		if err != nil {
+			log.Printf("invalid request in postMaterialiseAvailabilityRule: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in postMaterialiseAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			log.Printf("error beginning tx in postMaterialiseAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		defer func() {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				panic(err)
+			}
+		}()
+
+		queries := db.New(conn)
+		qtx := queries.WithTx(tx)
+
+		rule, err := qtx.GetAvailabilityRuleById(ctx, int32(id))
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("getting rule in postMaterialiseAvailabilityRule failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("rule id: %d was requested in postMaterialiseAvailabilityRule and does not exist", id)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		created, err := materialiseRule(ctx, qtx, rule, now, materialiseRequest.Until.UTC())
+		if err != nil {
+			log.Printf("materialising rule in postMaterialiseAvailabilityRule failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Printf("error commiting tx in postMaterialiseAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(MaterialiseAvailabilityRuleResponse{SlotsCreated: created})
+		if err != nil {
+			log.Printf("error encoding json in postMaterialiseAvailabilityRule: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustDate(t *testing.T, date string) pgtype.Date {
+	t.Helper()
+	d, err := parseDate(date)
+	require.NoError(t, err)
+	return d
+}
+
+func TestParseClock(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+		minutes, err := parseClock("09:30")
+		assert.NoError(t, err)
+		assert.Equal(t, int32(570), minutes)
+		assert.Equal(t, "09:30", formatClock(minutes))
+	})
+
+	t.Run("garbage", func(t *testing.T) {
+		t.Parallel()
+		_, err := parseClock("nine")
+		assert.Error(t, err)
+	})
+}
+
+func TestAlignedToUnit(t *testing.T) {
+	t.Run("on unit", func(t *testing.T) {
+		t.Parallel()
+		assert.NoError(t, alignedToUnit(540, 1020, 30))
+		assert.NoError(t, alignedToUnit(555, 1035, 15))
+	})
+
+	t.Run("off unit", func(t *testing.T) {
+		t.Parallel()
+		assert.Error(t, alignedToUnit(555, 1020, 30))
+		assert.Error(t, alignedToUnit(540, 1035, 30))
+	})
+}
+
+func TestAvailabilityRuleToDBParams(t *testing.T) {
+	today, _ := time.Parse(time.RFC3339, "2025-09-08T14:00:00Z")
+
+	t.Run("base", func(t *testing.T) {
+		t.Parallel()
+		r := AvailabilityRuleRequest{
+			EmployeeID: 3,
+			TypeID:     2,
+			Weekdays:   []int32{5, 1, 2, 3, 4, 1},
+			StartTime:  "09:00",
+			EndTime:    "17:00",
+			ValidUntil: "2027-01-01",
+		}
+		expected := db.CreateAvailabilityRuleParams{
+			EmployeeID:  3,
+			TypeID:      2,
+			Weekdays:    []int32{1, 2, 3, 4, 5},
+			StartMinute: 540,
+			EndMinute:   1020,
+			ValidFrom:   mustDate(t, "2025-09-08"),
+			ValidUntil:  mustDate(t, "2027-01-01"),
+			Capacity:    1,
+		}
+
+		params, err := r.ToDBParams(today)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, params)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		base := AvailabilityRuleRequest{Weekdays: []int32{1}, StartTime: "09:00", EndTime: "17:00"}
+
+		noDays := base
+		noDays.Weekdays = nil
+		badDay := base
+		badDay.Weekdays = []int32{7}
+		backwards := base
+		backwards.StartTime = "17:00"
+		backwards.EndTime = "09:00"
+		badUntil := base
+		badUntil.ValidFrom = "2025-10-01"
+		badUntil.ValidUntil = "2025-09-01"
+		badCapacity := base
+		badCapacity.Capacity = -2
+
+		for _, r := range []AvailabilityRuleRequest{noDays, badDay, backwards, badUntil, badCapacity} {
+			_, err := r.ToDBParams(today)
+			assert.Error(t, err)
+		}
+	})
+}
+
+func TestExpandRule(t *testing.T) {
+	// 2025-09-08 is a Monday
+	rule := db.AvailabilityRule{
+		ID:          1,
+		Weekdays:    []int32{1, 3},
+		StartMinute: 540,
+		EndMinute:   600,
+		ValidFrom:   mustDate(t, "2025-09-01"),
+		ValidUntil:  mustDate(t, "2025-09-15"),
+	}
+	slot := func(s string) time.Time {
+		ts, _ := time.Parse(time.RFC3339, s)
+		return ts
+	}
+
+	t.Run("base", func(t *testing.T) {
+		t.Parallel()
+		expected := []time.Time{
+			slot("2025-09-08T09:00:00Z"),
+			slot("2025-09-08T09:30:00Z"),
+			slot("2025-09-10T09:00:00Z"),
+			slot("2025-09-10T09:30:00Z"),
+			slot("2025-09-15T09:00:00Z"),
+			slot("2025-09-15T09:30:00Z"),
+		}
+		assert.Equal(t, expected, expandRule(rule, nil, slot("2025-09-08T00:00:00Z"), slot("2025-10-01T00:00:00Z"), time.UTC, 30))
+	})
+
+	t.Run("exceptions and partial days", func(t *testing.T) {
+		t.Parallel()
+		exceptions := []db.AvailabilityRuleException{{RuleID: 1, Date: mustDate(t, "2025-09-10")}}
+		expected := []time.Time{
+			slot("2025-09-08T09:30:00Z"),
+			slot("2025-09-15T09:00:00Z"),
+		}
+		assert.Equal(t, expected, expandRule(rule, exceptions, slot("2025-09-08T09:15:00Z"), slot("2025-09-15T09:30:00Z"), time.UTC, 30))
+	})
+
+	t.Run("before valid from", func(t *testing.T) {
+		t.Parallel()
+		assert.Empty(t, expandRule(rule, nil, slot("2025-08-01T00:00:00Z"), slot("2025-09-01T00:00:00Z"), time.UTC, 30))
+	})
+
+	t.Run("unit of the booking type", func(t *testing.T) {
+		t.Parallel()
+		expected := []time.Time{
+			slot("2025-09-08T09:00:00Z"),
+			slot("2025-09-08T09:15:00Z"),
+			slot("2025-09-08T09:30:00Z"),
+			slot("2025-09-08T09:45:00Z"),
+		}
+		assert.Equal(t, expected, expandRule(rule, nil, slot("2025-09-08T00:00:00Z"), slot("2025-09-09T00:00:00Z"), time.UTC, 15))
+	})
+
+	t.Run("wall clock kept across DST in London", func(t *testing.T) {
+		t.Parallel()
+		london, err := time.LoadLocation("Europe/London")
+		require.NoError(t, err)
+		// 2025-03-30 and 2025-10-26 are the Sundays the clocks change
+		sundays := db.AvailabilityRule{
+			Weekdays:    []int32{0},
+			StartMinute: 540,
+			EndMinute:   570,
+			ValidFrom:   mustDate(t, "2025-03-01"),
+		}
+		expected := []time.Time{
+			slot("2025-03-23T09:00:00Z"),
+			slot("2025-03-30T08:00:00Z"),
+			slot("2025-10-19T08:00:00Z"),
+			slot("2025-10-26T09:00:00Z"),
+		}
+		actual := expandRule(sundays, nil, slot("2025-03-22T00:00:00Z"), slot("2025-03-31T00:00:00Z"), london, 30)
+		actual = append(actual, expandRule(sundays, nil, slot("2025-10-18T00:00:00Z"), slot("2025-10-27T00:00:00Z"), london, 30)...)
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("skipped hour in London", func(t *testing.T) {
+		t.Parallel()
+		london, err := time.LoadLocation("Europe/London")
+		require.NoError(t, err)
+		// 01:00 to 02:00 doesnt exist on 2025-03-30
+		night := db.AvailabilityRule{
+			Weekdays:    []int32{0},
+			StartMinute: 30,
+			EndMinute:   150,
+			ValidFrom:   mustDate(t, "2025-03-01"),
+		}
+		expected := []time.Time{
+			slot("2025-03-30T00:30:00Z"),
+			slot("2025-03-30T01:00:00Z"),
+		}
+		assert.Equal(t, expected, expandRule(night, nil, slot("2025-03-29T00:00:00Z"), slot("2025-03-31T00:00:00Z"), london, 30))
+	})
+
+	t.Run("exception uses the local date", func(t *testing.T) {
+		t.Parallel()
+		tokyo, err := time.LoadLocation("Asia/Tokyo")
+		require.NoError(t, err)
+		// 08:00 on a Tuesday in Tokyo is 23:00 UTC on the Monday
+		early := db.AvailabilityRule{
+			ID:          2,
+			Weekdays:    []int32{2},
+			StartMinute: 480,
+			EndMinute:   510,
+			ValidFrom:   mustDate(t, "2025-09-01"),
+		}
+		exceptions := []db.AvailabilityRuleException{{RuleID: 2, Date: mustDate(t, "2025-09-09")}}
+		expected := []time.Time{
+			slot("2025-09-15T23:00:00Z"),
+		}
+		assert.Equal(t, expected, expandRule(early, exceptions, slot("2025-09-08T00:00:00Z"), slot("2025-09-17T00:00:00Z"), tokyo, 30))
+	})
+}
+
+func TestMaterialiseAvailabilityRuleRequestValidate(t *testing.T) {
+	now := time.Date(2025, 9, 8, 12, 0, 0, 0, time.UTC)
+
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+		assert.NoError(t, MaterialiseAvailabilityRuleRequest{Until: now.AddDate(0, 3, 0)}.validate(now))
+		assert.NoError(t, MaterialiseAvailabilityRuleRequest{Until: now.Add(maxMaterialiseAhead)}.validate(now))
+	})
+
+	t.Run("in the past", func(t *testing.T) {
+		t.Parallel()
+		assert.Error(t, MaterialiseAvailabilityRuleRequest{Until: now}.validate(now))
+		assert.Error(t, MaterialiseAvailabilityRuleRequest{}.validate(now))
+	})
+
+	t.Run("too far ahead", func(t *testing.T) {
+		t.Parallel()
+		assert.Error(t, MaterialiseAvailabilityRuleRequest{Until: now.Add(maxMaterialiseAhead + time.Hour)}.validate(now))
+	})
+}
@@ -0,0 +1,322 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	defaultSearchRange = 14 * 24 * time.Hour
+	maxSearchRange     = 62 * 24 * time.Hour
+	defaultSearchLimit = 50
+	maxSearchLimit     = 200
+)
+
+// AvailabilityWindow is a run of free slots long enough for a booking type,
+// AvailabilitySlots can be passed straight to POST /hold or POST /booking
+type AvailabilityWindow struct {
+	EmployeeID        int32     `json:"employee_id"`
+	StartTime         time.Time `json:"start_time"`
+	EndTime           time.Time `json:"end_time"`
+	AvailabilitySlots []int32   `json:"availability_slots"`
+	SeatsRemaining    int32     `json:"seats_remaining"`
+}
+
+type SearchAvailabilityResponse struct {
+	Windows    []AvailabilityWindow `json:"windows"`
+	Total      int                  `json:"total"`
+	NextOffset *int                 `json:"next_offset"` // null on the last page
+}
+
+type availabilitySearch struct {
+	TypeID     int32
+	EmployeeID pgtype.Int4
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
+
+// parseAvailabilitySearch reads the search from the query string. from defaults
+// to now and to defaults to defaultSearchRange after from.
+func parseAvailabilitySearch(query url.Values, now time.Time) (availabilitySearch, error) {
+	search := availabilitySearch{
+		From:  now,
+		Limit: defaultSearchLimit,
+	}
+
+	typeID, err := strconv.ParseInt(query.Get("type_id"), 10, 32)
+	if err != nil {
+		return availabilitySearch{}, errors.New("type_id is required")
+	}
+	search.TypeID = int32(typeID)
+
+	if employeeID := query.Get("employee_id"); employeeID != "" {
+		id, err := strconv.ParseInt(employeeID, 10, 32)
+		if err != nil {
+			return availabilitySearch{}, fmt.Errorf("employee_id %q is not an id", employeeID)
+		}
+		search.EmployeeID = pgtype.Int4{Int32: int32(id), Valid: true}
+	}
+
+	if from := query.Get("from"); from != "" {
+		search.From, err = time.Parse(time.RFC3339, from)
+		if err != nil {
+			return availabilitySearch{}, fmt.Errorf("from %q is not an RFC 3339 time", from)
+		}
+	}
+	search.To = search.From.Add(defaultSearchRange)
+	if to := query.Get("to"); to != "" {
+		search.To, err = time.Parse(time.RFC3339, to)
+		if err != nil {
+			return availabilitySearch{}, fmt.Errorf("to %q is not an RFC 3339 time", to)
+		}
+	}
+	if !search.From.Before(search.To) {
+		return availabilitySearch{}, errors.New("from must be before to")
+	}
+	if search.To.Sub(search.From) > maxSearchRange {
+		return availabilitySearch{}, fmt.Errorf("can not search more than %d days at once", int(maxSearchRange.Hours()/24))
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		search.Limit, err = strconv.Atoi(limit)
+		if err != nil || search.Limit < 1 || search.Limit > maxSearchLimit {
+			return availabilitySearch{}, fmt.Errorf("limit must be between 1 and %d", maxSearchLimit)
+		}
+	}
+	if offset := query.Get("offset"); offset != "" {
+		search.Offset, err = strconv.Atoi(offset)
+		if err != nil || search.Offset < 0 {
+			return availabilitySearch{}, errors.New("offset must not be negative")
+		}
+	}
+
+	return search, nil
+}
+
+// findWindows returns every run of units back to back slots for the same
+// employee, ordered by start time. slots must be ordered by employee and then
+// time. Windows overlap, any free slot with enough free time after it can start
+// one.
+func findWindows(slots []db.Availability, units int) []AvailabilityWindow {
+	windows := []AvailabilityWindow{}
+	runStart := 0
+	for i := range slots {
+		if i > 0 {
+			prev := slots[i-1]
+			prevEnd := prev.Datetime.Time.Add(time.Duration(prev.UnitMinutes) * time.Minute)
+			if slots[i].EmployeeID != prev.EmployeeID ||
+				slots[i].UnitMinutes != prev.UnitMinutes ||
+				!slots[i].Datetime.Time.Equal(prevEnd) {
+				runStart = i
+			}
+		}
+		if i-runStart+1 < units {
+			continue
+		}
+
+		run := slots[i-units+1 : i+1]
+		slotIDs := []int32{}
+		for _, s := range run {
+			slotIDs = append(slotIDs, s.ID)
+		}
+		windows = append(windows, AvailabilityWindow{
+			EmployeeID:        run[0].EmployeeID,
+			StartTime:         run[0].Datetime.Time,
+			EndTime:           slots[i].Datetime.Time.Add(time.Duration(slots[i].UnitMinutes) * time.Minute),
+			AvailabilitySlots: slotIDs,
+		})
+	}
+
+	slices.SortStableFunc(windows, func(a AvailabilityWindow, b AvailabilityWindow) int {
+		if c := a.StartTime.Compare(b.StartTime); c != 0 {
+			return c
+		}
+		return int(a.EmployeeID - b.EmployeeID)
+	})
+	return windows
+}
+
+// bookableWindows drops the windows that start outside [earliest, latest] or
+// that are too close to a booking in booked. A zero latest has no limit.
+func bookableWindows(windows []AvailabilityWindow, bookingType db.BookingType, booked []bookedTime, earliest time.Time, latest time.Time) []AvailabilityWindow {
+	result := []AvailabilityWindow{}
+	for _, window := range windows {
+		if window.StartTime.Before(earliest) || (!latest.IsZero() && window.StartTime.After(latest)) {
+			continue
+		}
+		if clashesWithBuffers(bookingType, window.EmployeeID, window.StartTime, window.EndTime, booked) {
+			continue
+		}
+		result = append(result, window)
+	}
+	return result
+}
+
+// freeResourceWindows drops the windows where one of resourceIDs is used by
+// another booking
+func freeResourceWindows(windows []AvailabilityWindow, bookingType db.BookingType, resourceIDs []int32, booked []resourceBooking) []AvailabilityWindow {
+	result := []AvailabilityWindow{}
+	for _, window := range windows {
+		if len(busyResources(resourceIDs, bookingType, window.EmployeeID, window.StartTime, window.EndTime, booked)) > 0 {
+			continue
+		}
+		result = append(result, window)
+	}
+	return result
+}
+
+// windowSeats is the number of bookings that can still be made on window, the
+// fewest seats left in any of its slots
+func windowSeats(window AvailabilityWindow, seats map[int32]int32) int32 {
+	remaining := int32(0)
+	for i, id := range window.AvailabilitySlots {
+		if i == 0 || seats[id] < remaining {
+			remaining = seats[id]
+		}
+	}
+	return remaining
+}
+
+// pageWindows returns the limit windows after offset and the offset of the next
+// page, which is nil when there isnt one
+func pageWindows(windows []AvailabilityWindow, limit int, offset int) ([]AvailabilityWindow, *int) {
+	if offset >= len(windows) {
+		return []AvailabilityWindow{}, nil
+	}
+	end := offset + limit
+	if end >= len(windows) {
+		return windows[offset:], nil
+	}
+	return windows[offset:end], &end
+}
+
+func getSearchAvailability(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loc, ok := requestLocation(w, r)
+		if !ok {
+			return
+		}
+
+		search, err := parseAvailabilitySearch(r.URL.Query(), time.Now().UTC())
+		if err != nil {
+			log.Printf("invalid search in getSearchAvailability: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			err = json.NewEncoder(w).Encode(ErrorResponse{Message: err.Error()})
+			if err != nil {
+				log.Printf("error encoding json in getSearchAvailability: %v", err)
+			}
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in getSearchAvailability: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		queries := db.New(conn)
+
+		bookingType, err := queries.GetBookingTypeById(ctx, search.TypeID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("getting booking type in getSearchAvailability failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("booking type id: %d does not exist in getSearchAvailability", search.TypeID)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		// slots before the notice of the type cant start a window or be part of one
+		earliest, latest := bookingWindow(bookingType, time.Now().UTC())
+		from := search.From
+		if from.Before(earliest) {
+			from = earliest
+		}
+
+		slots, err := queries.SearchFreeAvailabilitySlots(ctx, db.SearchFreeAvailabilitySlotsParams{
+			TypeID:     search.TypeID,
+			FromTime:   pgtype.Timestamptz{Time: from, Valid: true},
+			ToTime:     pgtype.Timestamptz{Time: search.To, Valid: true},
+			EmployeeID: search.EmployeeID,
+		})
+		if err != nil {
+			log.Printf("searching free slots in getSearchAvailability failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		employeeIDs := []int32{}
+		for _, s := range slots {
+			if !slices.Contains(employeeIDs, s.EmployeeID) {
+				employeeIDs = append(employeeIDs, s.EmployeeID)
+			}
+		}
+		booked, err := getBookedTimes(ctx, queries, employeeIDs, search.From, search.To)
+		if err != nil {
+			log.Printf("getting booked times in getSearchAvailability failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		resources, err := typeResourceIDs(ctx, queries, []int32{bookingType.ID})
+		if err != nil {
+			log.Printf("getting booking type resources in getSearchAvailability failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		resourceBooked, err := getResourceBookings(ctx, queries, resources[bookingType.ID], search.From, search.To)
+		if err != nil {
+			log.Printf("getting resource bookings in getSearchAvailability failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// every booking needs at least one slot, even for types without a duration
+		windows := findWindows(slots, max(int(bookingType.Duration), 1))
+		windows = bookableWindows(windows, bookingType, booked, earliest, latest)
+		windows = freeResourceWindows(windows, bookingType, resources[bookingType.ID], resourceBooked)
+		page, nextOffset := pageWindows(windows, search.Limit, search.Offset)
+
+		seats, err := slotSeatsRemaining(ctx, queries, slots)
+		if err != nil {
+			log.Printf("getting slot seats in getSearchAvailability failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		for i := range page {
+			page[i].SeatsRemaining = windowSeats(page[i], seats)
+			page[i].StartTime = page[i].StartTime.In(loc)
+			page[i].EndTime = page[i].EndTime.In(loc)
+		}
+
+		err = json.NewEncoder(w).Encode(SearchAvailabilityResponse{
+			Windows:    page,
+			Total:      len(windows),
+			NextOffset: nextOffset,
+		})
+		if err != nil {
+			log.Printf("error encoding json in getSearchAvailability: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package internal
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAvailabilitySearch(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2025-09-08T14:00:00Z")
+
+	t.Run("defaults", func(t *testing.T) {
+		t.Parallel()
+		search, err := parseAvailabilitySearch(url.Values{"type_id": {"2"}}, now)
+		assert.NoError(t, err)
+		assert.Equal(t, availabilitySearch{
+			TypeID: 2,
+			From:   now,
+			To:     now.Add(defaultSearchRange),
+			Limit:  defaultSearchLimit,
+		}, search)
+	})
+
+	t.Run("everything", func(t *testing.T) {
+		t.Parallel()
+		search, err := parseAvailabilitySearch(url.Values{
+			"type_id":     {"2"},
+			"employee_id": {"7"},
+			"from":        {"2025-09-10T00:00:00Z"},
+			"to":          {"2025-09-11T00:00:00+01:00"},
+			"limit":       {"10"},
+			"offset":      {"20"},
+		}, now)
+		assert.NoError(t, err)
+		assert.Equal(t, pgtype.Int4{Int32: 7, Valid: true}, search.EmployeeID)
+		assert.Equal(t, 23*time.Hour, search.To.Sub(search.From))
+		assert.Equal(t, 10, search.Limit)
+		assert.Equal(t, 20, search.Offset)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		for _, query := range []url.Values{
+			{},
+			{"type_id": {"haircut"}},
+			{"type_id": {"2"}, "employee_id": {"bob"}},
+			{"type_id": {"2"}, "from": {"tomorrow"}},
+			{"type_id": {"2"}, "from": {"2025-09-10T00:00:00Z"}, "to": {"2025-09-09T00:00:00Z"}},
+			{"type_id": {"2"}, "to": {"2026-09-09T00:00:00Z"}},
+			{"type_id": {"2"}, "limit": {"0"}},
+			{"type_id": {"2"}, "limit": {"1000"}},
+			{"type_id": {"2"}, "offset": {"-1"}},
+		} {
+			_, err := parseAvailabilitySearch(query, now)
+			assert.Error(t, err, query)
+		}
+	})
+}
+
+func TestFindWindows(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2025-09-08T09:00:00Z")
+	slot := func(id int32, employeeID int32, offset time.Duration) db.Availability {
+		return db.Availability{
+			ID:          id,
+			EmployeeID:  employeeID,
+			Datetime:    pgtype.Timestamptz{Time: start.Add(offset), Valid: true},
+			UnitMinutes: 30,
+		}
+	}
+	// employee 1 is free 09:00-10:30 then 11:00-11:30, employee 2 09:30-10:30
+	slots := []db.Availability{
+		slot(1, 1, 0),
+		slot(2, 1, 30*time.Minute),
+		slot(3, 1, 60*time.Minute),
+		slot(4, 1, 120*time.Minute),
+		slot(5, 2, 30*time.Minute),
+		slot(6, 2, 60*time.Minute),
+	}
+
+	t.Run("single unit", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, 6, len(findWindows(slots, 1)))
+	})
+
+	t.Run("contiguous runs", func(t *testing.T) {
+		t.Parallel()
+		expected := []AvailabilityWindow{
+			{EmployeeID: 1, StartTime: start, EndTime: start.Add(time.Hour), AvailabilitySlots: []int32{1, 2}},
+			{EmployeeID: 1, StartTime: start.Add(30 * time.Minute), EndTime: start.Add(90 * time.Minute), AvailabilitySlots: []int32{2, 3}},
+			{EmployeeID: 2, StartTime: start.Add(30 * time.Minute), EndTime: start.Add(90 * time.Minute), AvailabilitySlots: []int32{5, 6}},
+		}
+		assert.Equal(t, expected, findWindows(slots, 2))
+	})
+
+	t.Run("too long", func(t *testing.T) {
+		t.Parallel()
+		assert.Empty(t, findWindows(slots, 4))
+	})
+
+	t.Run("different units dont join", func(t *testing.T) {
+		t.Parallel()
+		mixed := []db.Availability{slot(1, 1, 0), slot(2, 1, 30*time.Minute)}
+		mixed[1].UnitMinutes = 15
+		assert.Empty(t, findWindows(mixed, 2))
+	})
+}
+
+func TestPageWindows(t *testing.T) {
+	windows := make([]AvailabilityWindow, 5)
+
+	t.Run("first page", func(t *testing.T) {
+		t.Parallel()
+		page, next := pageWindows(windows, 2, 0)
+		assert.Equal(t, 2, len(page))
+		assert.Equal(t, 2, *next)
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		t.Parallel()
+		page, next := pageWindows(windows, 2, 4)
+		assert.Equal(t, 1, len(page))
+		assert.Nil(t, next)
+	})
+
+	t.Run("past the end", func(t *testing.T) {
+		t.Parallel()
+		page, next := pageWindows(windows, 2, 10)
+		assert.Empty(t, page)
+		assert.Nil(t, next)
+	})
+}
+
+func TestBookableWindows(t *testing.T) {
+	start := time.Date(2025, 7, 26, 9, 0, 0, 0, time.UTC)
+	window := func(employeeID int32, offset time.Duration) AvailabilityWindow {
+		return AvailabilityWindow{
+			EmployeeID: employeeID,
+			StartTime:  start.Add(offset),
+			EndTime:    start.Add(offset + time.Hour),
+		}
+	}
+	windows := []AvailabilityWindow{window(1, 0), window(1, 2*time.Hour), window(2, 2*time.Hour), window(1, 48*time.Hour)}
+	booked := []bookedTime{{EmployeeID: 1, Start: start.Add(3 * time.Hour), End: start.Add(4 * time.Hour), Before: 30 * time.Minute}}
+
+	t.Run("no limits", func(t *testing.T) {
+		t.Parallel()
+		result := bookableWindows(windows, db.BookingType{}, []bookedTime{}, start, time.Time{})
+		assert.Equal(t, windows, result)
+	})
+
+	t.Run("notice and horizon", func(t *testing.T) {
+		t.Parallel()
+		result := bookableWindows(windows, db.BookingType{}, []bookedTime{}, start.Add(time.Hour), start.Add(24*time.Hour))
+		assert.Equal(t, []AvailabilityWindow{window(1, 2*time.Hour), window(2, 2*time.Hour)}, result)
+	})
+
+	t.Run("buffers", func(t *testing.T) {
+		t.Parallel()
+		result := bookableWindows(windows, db.BookingType{}, booked, start, time.Time{})
+		assert.Equal(t, []AvailabilityWindow{window(1, 0), window(2, 2*time.Hour), window(1, 48*time.Hour)}, result)
+	})
+}
+
+func TestFreeResourceWindows(t *testing.T) {
+	start := time.Date(2025, 7, 26, 9, 0, 0, 0, time.UTC)
+	window := func(employeeID int32, offset time.Duration) AvailabilityWindow {
+		return AvailabilityWindow{
+			EmployeeID: employeeID,
+			StartTime:  start.Add(offset),
+			EndTime:    start.Add(offset + time.Hour),
+		}
+	}
+	windows := []AvailabilityWindow{window(1, 0), window(2, 0), window(1, 2*time.Hour)}
+	booked := []resourceBooking{{ResourceID: 1, Start: start.Add(30 * time.Minute), End: start.Add(90 * time.Minute)}}
+
+	t.Run("no resources", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, windows, freeResourceWindows(windows, db.BookingType{}, []int32{}, booked))
+	})
+
+	t.Run("resource in use", func(t *testing.T) {
+		t.Parallel()
+		result := freeResourceWindows(windows, db.BookingType{}, []int32{1}, booked)
+		assert.Equal(t, []AvailabilityWindow{window(1, 2*time.Hour)}, result)
+	})
+}
+
+func TestWindowSeats(t *testing.T) {
+	seats := map[int32]int32{1: 8, 2: 3, 3: 0}
+
+	t.Run("fewest seats of the slots", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, int32(3), windowSeats(AvailabilityWindow{AvailabilitySlots: []int32{1, 2}}, seats))
+		assert.Equal(t, int32(8), windowSeats(AvailabilityWindow{AvailabilitySlots: []int32{1}}, seats))
+	})
+
+	t.Run("full slot", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, int32(0), windowSeats(AvailabilityWindow{AvailabilitySlots: []int32{2, 3}}, seats))
+	})
+}
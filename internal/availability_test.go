@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"net/url"
 	"testing"
 	"time"
 
@@ -23,20 +24,116 @@ func TestPostToDBParams(t *testing.T) {
 
 		expected := []db.CreateAvailabilitySlotParams{
 			{
-				EmployeeID: 1,
-				Datetime:   pgtype.Timestamp{Time: startTime, Valid: true},
-				TypeID:     2,
+				EmployeeID:  1,
+				Datetime:    pgtype.Timestamptz{Time: startTime, Valid: true},
+				UnitMinutes: 30,
+				Capacity:    1,
 			},
 			{
-				EmployeeID: 1,
-				Datetime:   pgtype.Timestamp{Time: startTime.Add(time.Duration(30 * time.Minute)), Valid: true},
-				TypeID:     2,
+				EmployeeID:  1,
+				Datetime:    pgtype.Timestamptz{Time: startTime.Add(time.Duration(30 * time.Minute)), Valid: true},
+				UnitMinutes: 30,
+				Capacity:    1,
 			},
 		}
 
-		params, err := obj.ToDBParams()
+		params, err := obj.ToDBParams(30)
 		assert.Nil(t, err)
 		assert.Equal(t, expected, params)
 
 	})
+
+	t.Run("class capacity", func(t *testing.T) {
+		t.Parallel()
+		startTime, _ := time.Parse(time.RFC3339, "2025-09-08T14:00:00Z")
+		obj := PostAvailabilitySlotRequest{
+			EmployeeID: 1,
+			StartTime:  startTime,
+			EndTime:    startTime.Add(time.Hour),
+			TypeID:     2,
+			Capacity:   12,
+		}
+
+		params, err := obj.ToDBParams(30)
+		assert.Nil(t, err)
+		for _, p := range params {
+			assert.Equal(t, int32(12), p.Capacity)
+		}
+
+		obj.Capacity = -1
+		_, err = obj.ToDBParams(30)
+		assert.Error(t, err)
+	})
+
+	t.Run("unit of the booking type", func(t *testing.T) {
+		t.Parallel()
+		startTime, _ := time.Parse(time.RFC3339, "2025-09-08T14:00:00Z")
+		endTime, _ := time.Parse(time.RFC3339, "2025-09-08T15:00:00Z")
+		obj := PostAvailabilitySlotRequest{
+			EmployeeID: 1,
+			StartTime:  startTime,
+			EndTime:    endTime,
+			TypeID:     2,
+		}
+
+		params, err := obj.ToDBParams(15)
+		assert.Nil(t, err)
+		assert.Equal(t, 4, len(params))
+		assert.Equal(t, int32(15), params[3].UnitMinutes)
+		assert.Equal(t, startTime.Add(45*time.Minute), params[3].Datetime.Time)
+
+		_, err = obj.ToDBParams(60)
+		assert.Nil(t, err)
+	})
+}
+
+func TestRequestTypeIDs(t *testing.T) {
+	t.Run("single type", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, []int32{2}, requestTypeIDs(2, nil))
+	})
+
+	t.Run("list of types", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, []int32{3, 4}, requestTypeIDs(0, []int32{3, 4}))
+	})
+
+	t.Run("both are merged without duplicates", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, []int32{2, 3}, requestTypeIDs(2, []int32{3, 2, 0}))
+	})
+
+	t.Run("no types", func(t *testing.T) {
+		t.Parallel()
+		assert.Empty(t, requestTypeIDs(0, nil))
+	})
+}
+
+func TestParseForceDelete(t *testing.T) {
+	t.Run("not forced", func(t *testing.T) {
+		t.Parallel()
+		force, _, err := parseForceDelete(url.Values{})
+		assert.NoError(t, err)
+		assert.False(t, force)
+	})
+
+	t.Run("forced with a reason", func(t *testing.T) {
+		t.Parallel()
+		force, reason, err := parseForceDelete(url.Values{"force": {"true"}, "reason": {" staff sickness "}})
+		assert.NoError(t, err)
+		assert.True(t, force)
+		assert.Equal(t, "staff sickness", reason)
+	})
+
+	t.Run("forced without a reason", func(t *testing.T) {
+		t.Parallel()
+		_, _, err := parseForceDelete(url.Values{"force": {"true"}, "reason": {"  "}})
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid force", func(t *testing.T) {
+		t.Parallel()
+		_, _, err := parseForceDelete(url.Values{"force": {"yes please"}})
+		assert.Error(t, err)
+	})
 }
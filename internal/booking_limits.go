@@ -0,0 +1,195 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// maxBufferMinutes caps the buffers of a booking type so that only bookings
+// within bufferMargin of a time need checking for clashes
+const (
+	maxBufferMinutes = 24 * 60
+	bufferMargin     = 2 * maxBufferMinutes * time.Minute
+)
+
+var (
+	ErrTooLittleNotice = errors.New("booking is too soon")
+	ErrTooFarAhead     = errors.New("booking is too far ahead")
+	ErrBufferClash     = errors.New("booking is too close to another booking")
+)
+
+// validBookingLimits checks the buffers, notice and horizon of a booking type
+func validBookingLimits(bufferBefore int32, bufferAfter int32, minNotice int32, maxAdvanceDays int32) error {
+	if bufferBefore < 0 || bufferBefore > maxBufferMinutes || bufferAfter < 0 || bufferAfter > maxBufferMinutes {
+		return fmt.Errorf("buffers must be between 0 and %d minutes", maxBufferMinutes)
+	}
+	if minNotice < 0 {
+		return errors.New("minimum notice can not be negative")
+	}
+	if maxAdvanceDays < 0 {
+		return errors.New("maximum advance can not be negative")
+	}
+	return nil
+}
+
+// checkBookingWindow returns an error if a booking of bookingType starting at
+// start can not be made at now
+func checkBookingWindow(bookingType db.BookingType, start time.Time, now time.Time) error {
+	if start.Before(now.Add(time.Duration(bookingType.MinNoticeMinutes) * time.Minute)) {
+		return fmt.Errorf("%w, %s needs %d minutes notice", ErrTooLittleNotice, bookingType.Title, bookingType.MinNoticeMinutes)
+	}
+	if bookingType.MaxAdvanceDays > 0 && start.After(now.AddDate(0, 0, int(bookingType.MaxAdvanceDays))) {
+		return fmt.Errorf("%w, %s can only be booked %d days ahead", ErrTooFarAhead, bookingType.Title, bookingType.MaxAdvanceDays)
+	}
+	return nil
+}
+
+// bookingWindow is the earliest and latest start at now for bookings of
+// bookingType, latest is zero when there is no limit
+func bookingWindow(bookingType db.BookingType, now time.Time) (time.Time, time.Time) {
+	earliest := now.Add(time.Duration(bookingType.MinNoticeMinutes) * time.Minute)
+	if bookingType.MaxAdvanceDays == 0 {
+		return earliest, time.Time{}
+	}
+	return earliest, now.AddDate(0, 0, int(bookingType.MaxAdvanceDays))
+}
+
+// bookedTime is the time an existing booking takes up with the buffers of its type
+type bookedTime struct {
+	EmployeeID int32
+	TypeID     int32
+	Start      time.Time
+	End        time.Time
+	Before     time.Duration
+	After      time.Duration
+}
+
+// getBookedTimes returns the bookings of employeeIDs that could clash with a
+// booking between from and to
+func getBookedTimes(ctx context.Context, queries *db.Queries, employeeIDs []int32, from time.Time, to time.Time) ([]bookedTime, error) {
+	rows, err := queries.GetBookedTimes(ctx, db.GetBookedTimesParams{
+		EmployeeIds: employeeIDs,
+		FromTime:    pgtype.Timestamptz{Time: from.Add(-bufferMargin), Valid: true},
+		ToTime:      pgtype.Timestamptz{Time: to.Add(bufferMargin), Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	booked := []bookedTime{}
+	for _, row := range rows {
+		booked = append(booked, bookedTime{
+			EmployeeID: row.EmployeeID,
+			TypeID:     row.TypeID,
+			Start:      row.StartTime.Time,
+			End:        row.EndTime.Time,
+			Before:     time.Duration(row.BufferBeforeMinutes) * time.Minute,
+			After:      time.Duration(row.BufferAfterMinutes) * time.Minute,
+		})
+	}
+	return booked, nil
+}
+
+// clashesWithBuffers reports whether a booking of bookingType with employeeID
+// from start to end is too close to one of booked. The gap between two bookings
+// has to cover the after buffer of the first and the before buffer of the
+// second, so the larger of the two is what counts. Bookings in the same class
+// dont clash with each other.
+func clashesWithBuffers(bookingType db.BookingType, employeeID int32, start time.Time, end time.Time, booked []bookedTime) bool {
+	before := time.Duration(bookingType.BufferBeforeMinutes) * time.Minute
+	after := time.Duration(bookingType.BufferAfterMinutes) * time.Minute
+	for _, b := range booked {
+		if b.EmployeeID != employeeID || sameClass(b.TypeID, b.Start, b.End, bookingType.ID, start, end) {
+			continue
+		}
+		if b.Start.Before(end.Add(after)) && b.End.After(start.Add(-before)) {
+			return true
+		}
+		if b.Start.Add(-b.Before).Before(end) && b.End.Add(b.After).After(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameClass reports whether two bookings with the same employee are seats in
+// the same class, which is a booking of the same type at exactly the same time
+func sameClass(typeID int32, start time.Time, end time.Time, otherTypeID int32, otherStart time.Time, otherEnd time.Time) bool {
+	return typeID == otherTypeID && start.Equal(otherStart) && end.Equal(otherEnd)
+}
+
+// slotsSpan returns when a booking of slots starts and ends, slots must be in
+// time order
+func slotsSpan(slots []db.Availability) (time.Time, time.Time) {
+	last := slots[len(slots)-1]
+	return slots[0].Datetime.Time, last.Datetime.Time.Add(time.Duration(last.UnitMinutes) * time.Minute)
+}
+
+// bookingLimitsError checks a booking of bookingType on slots, which must have
+// passed validateBookingSlots, against the notice and horizon of the type at now
+// if checkWindow is set, and against the buffers of the type. It returns an
+// error wrapping ErrTooLittleNotice or ErrTooFarAhead, ErrBufferClash, or the
+// error from the database.
+func bookingLimitsError(ctx context.Context, queries *db.Queries, bookingType db.BookingType, slots []db.Availability, checkWindow bool, now time.Time) error {
+	start, end := slotsSpan(slots)
+	if checkWindow {
+		err := checkBookingWindow(bookingType, start, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	// locking the employee stops two bookings either side of a buffer both
+	// passing the check, claimSlots has already locked them on the booking paths
+	employeeID := slots[0].EmployeeID
+	_, err := queries.LockEmployee(ctx, employeeID)
+	if err != nil {
+		return err
+	}
+	booked, err := getBookedTimes(ctx, queries, []int32{employeeID}, start, end)
+	if err != nil {
+		return err
+	}
+	if clashesWithBuffers(bookingType, employeeID, start, end, booked) {
+		return ErrBufferClash
+	}
+	return nil
+}
+
+// bookingProblem splits err from bookingLimitsError or bookingResourcesError
+// into the reason a booking cant be made, or the error from the database
+func bookingProblem(err error) (string, error) {
+	var busyErr *ResourcesBusyError
+	if errors.Is(err, ErrTooLittleNotice) || errors.Is(err, ErrTooFarAhead) || errors.Is(err, ErrBufferClash) || errors.As(err, &busyErr) {
+		return err.Error(), nil
+	}
+	return "", err
+}
+
+// checkBookingLimits checks a booking of bookingType on slots with
+// bookingLimitsError. It writes the response and returns false if the booking
+// cant be made. Admins can book outside the notice and horizon but not into a
+// buffer.
+func checkBookingLimits(w http.ResponseWriter, ctx context.Context, queries *db.Queries, p Principal, bookingType db.BookingType, slots []db.Availability, caller string) bool {
+	err := bookingLimitsError(ctx, queries, bookingType, slots, !p.IsAdmin(), time.Now().UTC())
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, ErrTooLittleNotice) || errors.Is(err, ErrTooFarAhead):
+		log.Printf("user %d cant book type %d at %s in %s: %v", p.UserID, bookingType.ID, slots[0].Datetime.Time, caller, err)
+		writeValidationErrors(w, []FieldError{{Field: "availability_slots", Message: err.Error()}})
+	case errors.Is(err, ErrBufferClash):
+		log.Printf("booking of type %d at %s in %s is too close to another booking", bookingType.ID, slots[0].Datetime.Time, caller)
+		writeSlotConflict(w, "The requested time is too close to another booking", nil)
+	default:
+		log.Printf("checking booking limits in %s failed with %v", caller, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidBookingLimits(t *testing.T) {
+	t.Run("no limits", func(t *testing.T) {
+		t.Parallel()
+		assert.NoError(t, validBookingLimits(0, 0, 0, 0))
+	})
+
+	t.Run("all limits", func(t *testing.T) {
+		t.Parallel()
+		assert.NoError(t, validBookingLimits(15, 30, 120, 90))
+	})
+
+	t.Run("buffer too long", func(t *testing.T) {
+		t.Parallel()
+		assert.Error(t, validBookingLimits(0, maxBufferMinutes+1, 0, 0))
+	})
+
+	t.Run("negative", func(t *testing.T) {
+		t.Parallel()
+		assert.Error(t, validBookingLimits(-5, 0, 0, 0))
+		assert.Error(t, validBookingLimits(0, 0, -1, 0))
+		assert.Error(t, validBookingLimits(0, 0, 0, -1))
+	})
+}
+
+func TestCheckBookingWindow(t *testing.T) {
+	now := time.Date(2025, 7, 26, 9, 0, 0, 0, time.UTC)
+	bookingType := db.BookingType{Title: "massage", MinNoticeMinutes: 120, MaxAdvanceDays: 30}
+
+	t.Run("inside the window", func(t *testing.T) {
+		t.Parallel()
+		assert.NoError(t, checkBookingWindow(bookingType, now.Add(2*time.Hour), now))
+		assert.NoError(t, checkBookingWindow(bookingType, now.AddDate(0, 0, 30), now))
+	})
+
+	t.Run("too soon", func(t *testing.T) {
+		t.Parallel()
+		err := checkBookingWindow(bookingType, now.Add(time.Hour), now)
+		assert.True(t, errors.Is(err, ErrTooLittleNotice))
+	})
+
+	t.Run("in the past without any notice", func(t *testing.T) {
+		t.Parallel()
+		err := checkBookingWindow(db.BookingType{}, now.Add(-time.Hour), now)
+		assert.True(t, errors.Is(err, ErrTooLittleNotice))
+	})
+
+	t.Run("too far ahead", func(t *testing.T) {
+		t.Parallel()
+		err := checkBookingWindow(bookingType, now.AddDate(0, 0, 31), now)
+		assert.True(t, errors.Is(err, ErrTooFarAhead))
+	})
+
+	t.Run("no horizon", func(t *testing.T) {
+		t.Parallel()
+		assert.NoError(t, checkBookingWindow(db.BookingType{}, now.AddDate(2, 0, 0), now))
+	})
+}
+
+func TestClashesWithBuffers(t *testing.T) {
+	at := func(hour int, minute int) time.Time {
+		return time.Date(2025, 7, 26, hour, minute, 0, 0, time.UTC)
+	}
+	// booked 10:00 to 11:00 with 30 minutes to clean up afterwards
+	booked := []bookedTime{{EmployeeID: 1, Start: at(10, 0), End: at(11, 0), After: 30 * time.Minute}}
+	noBuffers := db.BookingType{}
+	prep := db.BookingType{BufferBeforeMinutes: 45}
+	cleanup := db.BookingType{BufferAfterMinutes: 15}
+
+	t.Run("after the other booking's buffer", func(t *testing.T) {
+		t.Parallel()
+		assert.False(t, clashesWithBuffers(noBuffers, 1, at(11, 30), at(12, 0), booked))
+	})
+
+	t.Run("inside the other booking's buffer", func(t *testing.T) {
+		t.Parallel()
+		assert.True(t, clashesWithBuffers(noBuffers, 1, at(11, 0), at(11, 30), booked))
+	})
+
+	t.Run("own before buffer reaches the other booking", func(t *testing.T) {
+		t.Parallel()
+		assert.True(t, clashesWithBuffers(prep, 1, at(11, 30), at(12, 0), booked))
+		assert.False(t, clashesWithBuffers(prep, 1, at(12, 0), at(12, 30), booked))
+	})
+
+	t.Run("own after buffer reaches the other booking", func(t *testing.T) {
+		t.Parallel()
+		assert.True(t, clashesWithBuffers(cleanup, 1, at(9, 0), at(9, 50), booked))
+		assert.False(t, clashesWithBuffers(cleanup, 1, at(9, 0), at(9, 45), booked))
+	})
+
+	t.Run("another seat in the same class", func(t *testing.T) {
+		t.Parallel()
+		class := []bookedTime{{EmployeeID: 1, TypeID: 4, Start: at(10, 0), End: at(11, 0), After: 30 * time.Minute}}
+		yoga := db.BookingType{ID: 4, BufferBeforeMinutes: 15}
+		assert.False(t, clashesWithBuffers(yoga, 1, at(10, 0), at(11, 0), class))
+		assert.True(t, clashesWithBuffers(db.BookingType{ID: 5}, 1, at(10, 0), at(11, 0), class))
+	})
+
+	t.Run("other employees dont clash", func(t *testing.T) {
+		t.Parallel()
+		assert.False(t, clashesWithBuffers(prep, 2, at(11, 0), at(11, 30), booked))
+	})
+}
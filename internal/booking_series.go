@@ -0,0 +1,998 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxSeriesOccurrences caps how many bookings one series can make
+const maxSeriesOccurrences = 52
+
+var ErrNotInSeries = errors.New("booking is not part of this series")
+
+// SeriesScope is which occurrences of a series a change applies to
+type SeriesScope string
+
+const (
+	SeriesScopeOne    SeriesScope = "one"
+	SeriesScopeAll    SeriesScope = "all"
+	SeriesScopeFuture SeriesScope = "future"
+)
+
+// PostBookingSeriesRequest books Occurrences bookings of TypeID, one every
+// IntervalWeeks weeks at the same time of day as AvailabilitySlots, which are
+// the first occurrence
+type PostBookingSeriesRequest struct {
+	UserID            int32       `json:"user_id"`
+	TypeID            int32       `json:"type_id"`
+	AvailabilitySlots []int32     `json:"availability_slots"`
+	IntervalWeeks     int32       `json:"interval_weeks"` // 1 for weekly, 2 for fortnightly
+	Occurrences       int32       `json:"occurrences"`
+	Notes             pgtype.Text `json:"notes"`
+}
+
+func (r PostBookingSeriesRequest) validate() error {
+	if len(r.AvailabilitySlots) < 1 {
+		return errors.New("availability_slots are required for the first occurrence")
+	}
+	if r.IntervalWeeks != 1 && r.IntervalWeeks != 2 {
+		return errors.New("interval_weeks must be 1 or 2")
+	}
+	if r.Occurrences < 1 || r.Occurrences > maxSeriesOccurrences {
+		return fmt.Errorf("occurrences must be between 1 and %d", maxSeriesOccurrences)
+	}
+	return nil
+}
+
+// SeriesChangeRequest cancels or reschedules the occurrences of a series in
+// Scope. BookingID picks the occurrence for the one scope. A reschedule moves
+// the first occurrence in scope onto AvailabilitySlots and every other one by
+// the same number of days, to the same time of day.
+type SeriesChangeRequest struct {
+	Scope             SeriesScope `json:"scope"`
+	BookingID         int32       `json:"booking_id"`
+	AvailabilitySlots []int32     `json:"availability_slots"`
+}
+
+type SeriesOccurrence struct {
+	Occurrence int32              `json:"occurrence"`
+	BookingID  int32              `json:"booking_id"`
+	Status     db.BookingStatus   `json:"status"`
+	StartTime  pgtype.Timestamptz `json:"start_time"`
+	EndTime    pgtype.Timestamptz `json:"end_time"`
+}
+
+// SkippedOccurrence is an occurrence that a request couldnt place or change,
+// with the reason why. BookingID is 0 for occurrences that were never booked.
+type SkippedOccurrence struct {
+	Occurrence int32     `json:"occurrence"`
+	BookingID  int32     `json:"booking_id"`
+	StartTime  time.Time `json:"start_time"`
+	Reason     string    `json:"reason"`
+}
+
+type BookingSeriesResponse struct {
+	SeriesID      int32              `json:"series_id"`
+	UserID        int32              `json:"user_id"`
+	TypeID        int32              `json:"type_id"`
+	EmployeeID    int32              `json:"employee_id"`
+	IntervalWeeks int32              `json:"interval_weeks"`
+	Occurrences   int32              `json:"occurrences"`
+	CreatedBy     string             `json:"created_by"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	Bookings      []SeriesOccurrence `json:"bookings"`
+}
+
+type PostBookingSeriesResponse struct {
+	SeriesID int32               `json:"series_id"`
+	Bookings []SeriesOccurrence  `json:"bookings"`
+	Skipped  []SkippedOccurrence `json:"skipped"`
+}
+
+type SeriesChangeResponse struct {
+	SeriesID   int32               `json:"series_id"`
+	BookingIDs []int32             `json:"booking_ids"`
+	Skipped    []SkippedOccurrence `json:"skipped"`
+}
+
+func occurrenceFromDBRow(row db.GetBookingSeriesBookingsRow, loc *time.Location) SeriesOccurrence {
+	return SeriesOccurrence{
+		Occurrence: row.SeriesOccurrence.Int32,
+		BookingID:  row.ID,
+		Status:     row.Status,
+		StartTime:  inLocation(row.StartTime, loc),
+		EndTime:    inLocation(row.EndTime, loc),
+	}
+}
+
+func responseFromDBBookingSeries(series db.BookingSeries, bookings []db.GetBookingSeriesBookingsRow, loc *time.Location) BookingSeriesResponse {
+	occurrences := []SeriesOccurrence{}
+	for _, row := range bookings {
+		occurrences = append(occurrences, occurrenceFromDBRow(row, loc))
+	}
+	return BookingSeriesResponse{
+		SeriesID:      series.ID,
+		UserID:        series.UserID,
+		TypeID:        series.TypeID,
+		EmployeeID:    series.EmployeeID,
+		IntervalWeeks: series.IntervalWeeks,
+		Occurrences:   series.Occurrences,
+		CreatedBy:     series.CreatedBy,
+		CreatedAt:     inLocation(series.CreatedAt, loc),
+		Bookings:      occurrences,
+	}
+}
+
+// shiftDays moves each of times by days in loc, keeping the time of day the
+// same across changes to daylight saving
+func shiftDays(times []time.Time, loc *time.Location, days int) []time.Time {
+	shifted := []time.Time{}
+	for _, t := range times {
+		shifted = append(shifted, t.In(loc).AddDate(0, 0, days).UTC())
+	}
+	return shifted
+}
+
+// daysBetween is the number of calendar days in loc from the day of a to the day
+// of b
+func daysBetween(a time.Time, b time.Time, loc *time.Location) int {
+	ay, am, ad := a.In(loc).Date()
+	by, bm, bd := b.In(loc).Date()
+	from := time.Date(ay, am, ad, 0, 0, 0, 0, time.UTC)
+	to := time.Date(by, bm, bd, 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from).Hours() / 24)
+}
+
+func slotTimes(slots []db.Availability) []time.Time {
+	times := []time.Time{}
+	for _, s := range slots {
+		times = append(times, s.Datetime.Time)
+	}
+	return times
+}
+
+// slotsAt returns the ids of slots that start at each of times, and false if
+// any of the times doesnt have a slot
+func slotsAt(times []time.Time, slots []db.Availability) ([]int32, bool) {
+	ids := []int32{}
+	for _, t := range times {
+		i := slices.IndexFunc(slots, func(s db.Availability) bool {
+			return s.Datetime.Time.Equal(t)
+		})
+		if i < 0 {
+			return nil, false
+		}
+		ids = append(ids, slots[i].ID)
+	}
+	return ids, true
+}
+
+// findSlotsAt looks up the slots of employeeID that start at times
+func findSlotsAt(ctx context.Context, queries *db.Queries, employeeID int32, times []time.Time) ([]int32, bool, error) {
+	timestamps := []pgtype.Timestamptz{}
+	for _, t := range times {
+		timestamps = append(timestamps, pgtype.Timestamptz{Time: t, Valid: true})
+	}
+	slots, err := queries.GetEmployeeSlotsAt(ctx, db.GetEmployeeSlotsAtParams{
+		EmployeeID: employeeID,
+		Column2:    timestamps,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	ids, ok := slotsAt(times, slots)
+	return ids, ok, nil
+}
+
+// seriesScope picks the occurrences of bookings that request applies to at now.
+// Only the one scope can pick an occurrence without a time, which is one that
+// has been cancelled.
+func seriesScope(bookings []db.GetBookingSeriesBookingsRow, request SeriesChangeRequest, now time.Time) ([]db.GetBookingSeriesBookingsRow, error) {
+	picked := []db.GetBookingSeriesBookingsRow{}
+	switch request.Scope {
+	case SeriesScopeOne:
+		i := slices.IndexFunc(bookings, func(b db.GetBookingSeriesBookingsRow) bool {
+			return b.ID == request.BookingID
+		})
+		if i < 0 {
+			return nil, ErrNotInSeries
+		}
+		picked = append(picked, bookings[i])
+	case SeriesScopeAll, SeriesScopeFuture:
+		for _, b := range bookings {
+			if !b.StartTime.Valid {
+				continue
+			}
+			if request.Scope == SeriesScopeFuture && !b.StartTime.Time.After(now) {
+				continue
+			}
+			picked = append(picked, b)
+		}
+	default:
+		return nil, fmt.Errorf("scope must be %q, %q or %q", SeriesScopeOne, SeriesScopeAll, SeriesScopeFuture)
+	}
+	return picked, nil
+}
+
+// placeOccurrence books slotIDs for userID through the same checks as
+// postBooking. It returns the reason the booking cant be made, err is only set
+// when the database fails.
+func placeOccurrence(ctx context.Context, queries *db.Queries, p Principal, userID int32, bookingType db.BookingType, slotIDs []int32, notes pgtype.Text) (db.CreateBookingRow, string, error) {
+	taken, err := claimSlots(ctx, queries, slotIDs, 0)
+	if errors.Is(err, ErrUnknownSlots) {
+		return db.CreateBookingRow{}, err.Error(), nil
+	}
+	if err != nil {
+		return db.CreateBookingRow{}, "", err
+	}
+	if len(taken) > 0 {
+		return db.CreateBookingRow{}, fmt.Sprintf("slots %v are no longer available", taken), nil
+	}
+
+	slots, err := queries.GetAvailabilitySlotByIds(ctx, slotIDs)
+	if err != nil {
+		return db.CreateBookingRow{}, "", err
+	}
+	slotTypes, err := slotTypeIDs(ctx, queries, slots)
+	if err != nil {
+		return db.CreateBookingRow{}, "", err
+	}
+	if errs := validateBookingSlots(bookingType, slotIDs, slots, slotTypes); len(errs) > 0 {
+		return db.CreateBookingRow{}, errs[0].Message, nil
+	}
+	problem, err := bookingProblem(bookingLimitsError(ctx, queries, bookingType, slots, !p.IsAdmin(), time.Now().UTC()))
+	if err != nil || problem != "" {
+		return db.CreateBookingRow{}, problem, err
+	}
+	problem, err = bookingProblem(bookingResourcesError(ctx, queries, bookingType, slots))
+	if err != nil || problem != "" {
+		return db.CreateBookingRow{}, problem, err
+	}
+
+	cost := bookingCost(bookingType, int32(len(slotIDs)))
+	bookingRow, err := queries.CreateBooking(ctx, db.CreateBookingParams{
+		UserID:  userID,
+		TypeID:  bookingType.ID,
+		Notes:   notes,
+		Cost:    cost,
+		Column6: slotIDs,
+	})
+	if err != nil {
+		return db.CreateBookingRow{}, "", err
+	}
+	err = queries.CreateBookingHistory(ctx, createdBookingHistoryParams(bookingRow, p.Email))
+	if err != nil {
+		return db.CreateBookingRow{}, "", err
+	}
+	err = chargeBooking(ctx, queries, bookingType, bookingRow, cost, p.Email)
+	return bookingRow, "", err
+}
+
+// rescheduleOccurrence moves booking onto slotIDs through the same checks as
+// postRescheduleBooking. It works in a savepoint of tx so that an occurrence
+// that cant be moved is left as it was, and returns the reason it cant be moved.
+// err is only set when the database fails.
+func rescheduleOccurrence(ctx context.Context, tx pgx.Tx, queries *db.Queries, p Principal, rp RescheduleParams, booking db.GetBookingWithJoinRow, slotIDs []int32) (string, error) {
+	err := checkTransition(p, booking.Status, db.BookingStatusRescheduled, booking.UserID)
+	if err != nil {
+		return err.Error(), nil
+	}
+	if !p.IsAdmin() {
+		reschedules, err := queries.WithTx(tx).CountBookingReschedules(ctx, booking.ID)
+		if err != nil {
+			return "", err
+		}
+		err = rp.check(reschedules, booking.StartTime.Time, time.Now().UTC())
+		if err != nil {
+			return err.Error(), nil
+		}
+	}
+
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	defer func() {
+		err := savepoint.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			panic(err)
+		}
+	}()
+
+	qsp := queries.WithTx(savepoint)
+
+	// the old slots are released first so that the new set can overlap them
+	err = qsp.FreeAvailabilitySlot(ctx, booking.ID)
+	if err != nil {
+		return "", err
+	}
+
+	taken, err := claimSlots(ctx, qsp, slotIDs, 0)
+	if errors.Is(err, ErrUnknownSlots) {
+		return err.Error(), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(taken) > 0 {
+		return fmt.Sprintf("slots %v are no longer available", taken), nil
+	}
+
+	bookingType, err := qsp.GetBookingTypeById(ctx, booking.TypeID)
+	if err != nil {
+		return "", err
+	}
+	slots, err := qsp.GetAvailabilitySlotByIds(ctx, slotIDs)
+	if err != nil {
+		return "", err
+	}
+	slotTypes, err := slotTypeIDs(ctx, qsp, slots)
+	if err != nil {
+		return "", err
+	}
+	if errs := validateBookingSlots(bookingType, slotIDs, slots, slotTypes); len(errs) > 0 {
+		return errs[0].Message, nil
+	}
+	problem, err := bookingProblem(bookingLimitsError(ctx, qsp, bookingType, slots, !p.IsAdmin(), time.Now().UTC()))
+	if err != nil || problem != "" {
+		return problem, err
+	}
+	problem, err = bookingProblem(bookingResourcesError(ctx, qsp, bookingType, slots))
+	if err != nil || problem != "" {
+		return problem, err
+	}
+
+	for _, slotID := range slotIDs {
+		err = qsp.CreateBookingSlot(ctx, db.CreateBookingSlotParams{
+			BookingID:          booking.ID,
+			AvailabilitySlotID: slotID,
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	cost := bookingCost(bookingType, int32(len(slotIDs)))
+	err = qsp.RescheduleBooking(ctx, db.RescheduleBookingParams{
+		ID:              booking.ID,
+		Cost:            cost,
+		StatusUpdatedBy: p.Email,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	err = adjustBookingCharge(ctx, qsp, booking.ID, booking.Cost, cost, p.Email, rescheduledReason)
+	if err != nil {
+		return "", err
+	}
+
+	bookingRow, err := qsp.GetBookingWithJoin(ctx, booking.ID)
+	if err != nil {
+		return "", err
+	}
+	history := bookingHistoryParams(bookingRow, db.BookingStatusRescheduled, p.Email)
+	history.PreviousStartTime = booking.StartTime
+	history.PreviousEndTime = booking.EndTime
+	err = qsp.CreateBookingHistory(ctx, history)
+	if err != nil {
+		return "", err
+	}
+
+	return "", savepoint.Commit(ctx)
+}
+
+// getOwnedSeries loads series id for p, writing a 404 if it doesnt exist and a
+// 403 if p isnt an admin or the customer the series is for
+func getOwnedSeries(w http.ResponseWriter, ctx context.Context, queries *db.Queries, p Principal, id int32, caller string) (db.BookingSeries, bool) {
+	series, err := queries.GetBookingSeriesById(ctx, id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("error getting booking series in %s: %v", caller, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return series, false
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("booking series id: %d was requested in %s and does not exist", id, caller)
+		w.WriteHeader(http.StatusNotFound)
+		return series, false
+	}
+	if !p.CanAccessUser(series.UserID) {
+		log.Printf("user %d requested booking series %d in %s and doesnt have permission to", p.UserID, id, caller)
+		writeForbidden(w)
+		return series, false
+	}
+	return series, true
+}
+
+// postBookingSeries books every occurrence of a series that can be placed and
+// reports the ones that cant. Nothing is booked if none of them can be placed.
+func postBookingSeries(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
+		loc, ok := requestLocation(w, r)
+		if !ok {
+			return
+		}
+
+		var seriesRequest PostBookingSeriesRequest
+
+		err := json.NewDecoder(r.Body).Decode(&seriesRequest)
+		if err != nil {
+			log.Printf("error decoding body in postBookingSeries: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		err = seriesRequest.validate()
+		if err != nil {
+			log.Printf("invalid booking series in postBookingSeries: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in postBookingSeries: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			log.Printf("error beginning tx in postBookingSeries: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		defer func() {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				panic(err)
+			}
+		}()
+
+		queries := db.New(conn)
+		qtx := queries.WithTx(tx)
+		userID := bookingUserID(principal, seriesRequest.UserID)
+
+		bookingType, err := qtx.GetBookingTypeById(ctx, seriesRequest.TypeID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("error getting booking type in postBookingSeries: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("booking type id: %d was requested in postBookingSeries and does not exist", seriesRequest.TypeID)
+			writeValidationErrors(w, []FieldError{{
+				Field:   "type_id",
+				Message: fmt.Sprintf("booking type %d does not exist", seriesRequest.TypeID),
+			}})
+			return
+		}
+
+		first, err := qtx.GetAvailabilitySlotByIds(ctx, seriesRequest.AvailabilitySlots)
+		if err != nil {
+			log.Printf("getting first occurrence slots in postBookingSeries failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if len(first) != len(seriesRequest.AvailabilitySlots) {
+			log.Printf("booking series requested for slots %v in postBookingSeries that dont all exist", seriesRequest.AvailabilitySlots)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		employeeID := first[0].EmployeeID
+
+		employee, err := qtx.GetEmployeeById(ctx, employeeID)
+		if err != nil {
+			log.Printf("getting employee in postBookingSeries failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		employeeLoc, err := time.LoadLocation(employee.TimeZone)
+		if err != nil {
+			log.Printf("loading time zone of employee %d in postBookingSeries failed with %v", employeeID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		series, err := qtx.CreateBookingSeries(ctx, db.CreateBookingSeriesParams{
+			UserID:        userID,
+			TypeID:        bookingType.ID,
+			EmployeeID:    employeeID,
+			IntervalWeeks: seriesRequest.IntervalWeeks,
+			Occurrences:   seriesRequest.Occurrences,
+			CreatedBy:     principal.Email,
+		})
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+				log.Printf("user id: %d does not exist in postBookingSeries", userID)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			log.Printf("general error when trying to create booking series in postBookingSeries: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := PostBookingSeriesResponse{
+			SeriesID: series.ID,
+			Bookings: []SeriesOccurrence{},
+			Skipped:  []SkippedOccurrence{},
+		}
+		for i := range seriesRequest.Occurrences {
+			occurrence := i + 1
+			times := shiftDays(slotTimes(first), employeeLoc, int(7*seriesRequest.IntervalWeeks*i))
+			skip := SkippedOccurrence{Occurrence: occurrence, StartTime: times[0].In(loc)}
+
+			slotIDs, found, err := findSlotsAt(ctx, qtx, employeeID, times)
+			if err != nil {
+				log.Printf("finding slots for occurrence %d in postBookingSeries failed with %v", occurrence, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if !found {
+				skip.Reason = "no availability at this time"
+				response.Skipped = append(response.Skipped, skip)
+				continue
+			}
+
+			bookingRow, problem, err := placeOccurrence(ctx, qtx, principal, userID, bookingType, slotIDs, seriesRequest.Notes)
+			if err != nil {
+				log.Printf("placing occurrence %d in postBookingSeries failed with %v", occurrence, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if problem != "" {
+				skip.Reason = problem
+				response.Skipped = append(response.Skipped, skip)
+				continue
+			}
+
+			err = qtx.SetBookingSeries(ctx, db.SetBookingSeriesParams{
+				ID:               bookingRow.BookingID,
+				SeriesID:         pgtype.Int4{Int32: series.ID, Valid: true},
+				SeriesOccurrence: pgtype.Int4{Int32: occurrence, Valid: true},
+			})
+			if err != nil {
+				log.Printf("linking booking %d to series in postBookingSeries failed with %v", bookingRow.BookingID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			response.Bookings = append(response.Bookings, SeriesOccurrence{
+				Occurrence: occurrence,
+				BookingID:  bookingRow.BookingID,
+				Status:     db.BookingStatusCreated,
+				StartTime:  inLocation(bookingRow.StartTime, loc),
+				EndTime:    inLocation(bookingRow.EndTime, loc),
+			})
+		}
+
+		if len(response.Bookings) == 0 {
+			log.Printf("none of the occurrences of series for user %d could be placed in postBookingSeries", userID)
+			w.WriteHeader(http.StatusConflict)
+			err = json.NewEncoder(w).Encode(response)
+			if err != nil {
+				log.Printf("error encoding json in postBookingSeries: %v", err)
+			}
+			return
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Printf("error commiting tx in postBookingSeries: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			log.Printf("error encoding json in postBookingSeries: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func getBookingSeries(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
+		loc, ok := requestLocation(w, r)
+		if !ok {
+			return
+		}
+
+		seriesId := r.PathValue("series_id")
+		id, err := strconv.ParseInt(seriesId, 10, 32)
+		if err != nil {
+			log.Printf("error: %v converting series id to int in getBookingSeries: %s", err, seriesId)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in getBookingSeries: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		queries := db.New(conn)
+
+		series, ok := getOwnedSeries(w, ctx, queries, principal, int32(id), "getBookingSeries")
+		if !ok {
+			return
+		}
+
+		bookings, err := queries.GetBookingSeriesBookings(ctx, pgtype.Int4{Int32: series.ID, Valid: true})
+		if err != nil {
+			log.Printf("error querying series bookings in getBookingSeries: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(responseFromDBBookingSeries(series, bookings, loc))
+		if err != nil {
+			log.Printf("error encoding json in getBookingSeries: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// postCancelBookingSeries cancels the occurrences of a series in scope that can
+// be cancelled, their fees, refunds and slots are dealt with the same as a
+// single cancellation
+func postCancelBookingSeries(pool *pgxpool.Pool, ctx context.Context, wp WaitlistParams, pp PaymentParams, notifier BookingNotifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
+		loc, ok := requestLocation(w, r)
+		if !ok {
+			return
+		}
+
+		seriesId := r.PathValue("series_id")
+		id, err := strconv.ParseInt(seriesId, 10, 32)
+		if err != nil {
+			log.Printf("error: %v converting series id to int in postCancelBookingSeries: %s", err, seriesId)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var changeRequest SeriesChangeRequest
+
+		err = json.NewDecoder(r.Body).Decode(&changeRequest)
+		if err != nil {
+			log.Printf("error decoding body in postCancelBookingSeries: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in postCancelBookingSeries: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			log.Printf("error beginning tx in postCancelBookingSeries: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		defer func() {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				panic(err)
+			}
+		}()
+
+		queries := db.New(conn)
+		qtx := queries.WithTx(tx)
+
+		series, ok := getOwnedSeries(w, ctx, qtx, principal, int32(id), "postCancelBookingSeries")
+		if !ok {
+			return
+		}
+
+		bookings, err := qtx.GetBookingSeriesBookings(ctx, pgtype.Int4{Int32: series.ID, Valid: true})
+		if err != nil {
+			log.Printf("error querying series bookings in postCancelBookingSeries: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		picked, err := seriesScope(bookings, changeRequest, time.Now().UTC())
+		if err != nil {
+			log.Printf("invalid scope for series %d in postCancelBookingSeries: %v", series.ID, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		response := SeriesChangeResponse{
+			SeriesID:   series.ID,
+			BookingIDs: []int32{},
+			Skipped:    []SkippedOccurrence{},
+		}
+		promotions := []WaitlistPromotion{}
+		for _, occurrence := range picked {
+			err = checkTransition(principal, occurrence.Status, db.BookingStatusCancelled, series.UserID)
+			if err != nil {
+				response.Skipped = append(response.Skipped, SkippedOccurrence{
+					Occurrence: occurrence.SeriesOccurrence.Int32,
+					BookingID:  occurrence.ID,
+					StartTime:  occurrence.StartTime.Time.In(loc),
+					Reason:     err.Error(),
+				})
+				continue
+			}
+
+			freedSlots, err := qtx.GetBookingSlotIds(ctx, occurrence.ID)
+			if err != nil {
+				log.Printf("getting booking slots in postCancelBookingSeries failed with %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			err = setBookingStatus(ctx, qtx, occurrence.ID, db.BookingStatusCancelled, principal.Email, "")
+			if err != nil {
+				log.Printf("cancelling booking %d in postCancelBookingSeries failed with %v", occurrence.ID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			err = settleByPolicy(ctx, qtx, pp, occurrence.ID, db.BookingStatusCancelled, occurrence.StartTime.Time, principal.Email)
+			if err != nil {
+				log.Printf("settling booking %d in postCancelBookingSeries failed with %v", occurrence.ID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			response.BookingIDs = append(response.BookingIDs, occurrence.ID)
+
+			promotion, err := promoteWaitlist(ctx, qtx, freedSlots, wp, principal.Email)
+			if err != nil {
+				log.Printf("promoting the waitlist in postCancelBookingSeries failed with %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if promotion != nil {
+				promotions = append(promotions, *promotion)
+			}
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Printf("error commiting tx in postCancelBookingSeries: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		sendPaymentRefunds(ctx, pool, pp)
+
+		notifyWaitlist(ctx, notifier, promotions)
+
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			log.Printf("error encoding json in postCancelBookingSeries: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// postRescheduleBookingSeries moves the occurrences of a series in scope. The
+// first one moves onto the requested slots and the rest keep the same spacing,
+// each occurrence that cant be moved is left where it was and reported.
+func postRescheduleBookingSeries(pool *pgxpool.Pool, ctx context.Context, rp RescheduleParams) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
+		loc, ok := requestLocation(w, r)
+		if !ok {
+			return
+		}
+
+		seriesId := r.PathValue("series_id")
+		id, err := strconv.ParseInt(seriesId, 10, 32)
+		if err != nil {
+			log.Printf("error: %v converting series id to int in postRescheduleBookingSeries: %s", err, seriesId)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var changeRequest SeriesChangeRequest
+
+		err = json.NewDecoder(r.Body).Decode(&changeRequest)
+		if err != nil {
+			log.Printf("error decoding body in postRescheduleBookingSeries: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if len(changeRequest.AvailabilitySlots) < 1 {
+			log.Printf("requested a series reschedule with no slots")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in postRescheduleBookingSeries: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			log.Printf("error beginning tx in postRescheduleBookingSeries: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		defer func() {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				panic(err)
+			}
+		}()
+
+		queries := db.New(conn)
+		qtx := queries.WithTx(tx)
+
+		series, ok := getOwnedSeries(w, ctx, qtx, principal, int32(id), "postRescheduleBookingSeries")
+		if !ok {
+			return
+		}
+
+		bookings, err := qtx.GetBookingSeriesBookings(ctx, pgtype.Int4{Int32: series.ID, Valid: true})
+		if err != nil {
+			log.Printf("error querying series bookings in postRescheduleBookingSeries: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		picked, err := seriesScope(bookings, changeRequest, time.Now().UTC())
+		if err == nil && (len(picked) == 0 || !picked[0].StartTime.Valid) {
+			err = errors.New("there are no occurrences in scope that can be rescheduled")
+		}
+		if err != nil {
+			log.Printf("invalid scope for series %d in postRescheduleBookingSeries: %v", series.ID, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		target, err := qtx.GetAvailabilitySlotByIds(ctx, changeRequest.AvailabilitySlots)
+		if err != nil {
+			log.Printf("getting requested slots in postRescheduleBookingSeries failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if len(target) != len(changeRequest.AvailabilitySlots) {
+			log.Printf("series reschedule requested for slots %v that dont all exist", changeRequest.AvailabilitySlots)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		employeeID := target[0].EmployeeID
+
+		employee, err := qtx.GetEmployeeById(ctx, employeeID)
+		if err != nil {
+			log.Printf("getting employee in postRescheduleBookingSeries failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		employeeLoc, err := time.LoadLocation(employee.TimeZone)
+		if err != nil {
+			log.Printf("loading time zone of employee %d in postRescheduleBookingSeries failed with %v", employeeID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// moving later is done from the last occurrence back so that an
+		// occurrence can move into the time the next one is leaving
+		anchor := picked[0].StartTime.Time
+		if target[0].Datetime.Time.After(anchor) {
+			slices.Reverse(picked)
+		}
+
+		response := SeriesChangeResponse{
+			SeriesID:   series.ID,
+			BookingIDs: []int32{},
+			Skipped:    []SkippedOccurrence{},
+		}
+		for _, occurrence := range picked {
+			skip := SkippedOccurrence{
+				Occurrence: occurrence.SeriesOccurrence.Int32,
+				BookingID:  occurrence.ID,
+				StartTime:  occurrence.StartTime.Time.In(loc),
+			}
+			if !occurrence.StartTime.Valid {
+				skip.Reason = "booking is " + string(occurrence.Status)
+				response.Skipped = append(response.Skipped, skip)
+				continue
+			}
+
+			times := shiftDays(slotTimes(target), employeeLoc, daysBetween(anchor, occurrence.StartTime.Time, employeeLoc))
+			slotIDs, found, err := findSlotsAt(ctx, qtx, employeeID, times)
+			if err != nil {
+				log.Printf("finding slots for booking %d in postRescheduleBookingSeries failed with %v", occurrence.ID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if !found {
+				skip.Reason = "no availability at the new time"
+				response.Skipped = append(response.Skipped, skip)
+				continue
+			}
+
+			booking, err := qtx.GetBookingWithJoin(ctx, occurrence.ID)
+			if err != nil {
+				log.Printf("getting booking %d in postRescheduleBookingSeries failed with %v", occurrence.ID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			problem, err := rescheduleOccurrence(ctx, tx, queries, principal, rp, booking, slotIDs)
+			if err != nil {
+				log.Printf("rescheduling booking %d in postRescheduleBookingSeries failed with %v", occurrence.ID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if problem != "" {
+				skip.Reason = problem
+				response.Skipped = append(response.Skipped, skip)
+				continue
+			}
+			response.BookingIDs = append(response.BookingIDs, occurrence.ID)
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Printf("error commiting tx in postRescheduleBookingSeries: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			log.Printf("error encoding json in postRescheduleBookingSeries: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
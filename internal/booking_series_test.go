@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBookingSeriesValidate(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+		r := PostBookingSeriesRequest{TypeID: 1, AvailabilitySlots: []int32{4, 5}, IntervalWeeks: 2, Occurrences: 6}
+		assert.NoError(t, r.validate())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		for _, r := range []PostBookingSeriesRequest{
+			{AvailabilitySlots: []int32{}, IntervalWeeks: 1, Occurrences: 4},
+			{AvailabilitySlots: []int32{4}, IntervalWeeks: 3, Occurrences: 4},
+			{AvailabilitySlots: []int32{4}, IntervalWeeks: 1, Occurrences: 0},
+			{AvailabilitySlots: []int32{4}, IntervalWeeks: 1, Occurrences: maxSeriesOccurrences + 1},
+		} {
+			assert.Error(t, r.validate())
+		}
+	})
+}
+
+func TestShiftDays(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	assert.NoError(t, err)
+
+	t.Run("keeps the time of day across daylight saving", func(t *testing.T) {
+		t.Parallel()
+		// 09:00 in London before and after the clocks go forward on 2026-03-29
+		start := time.Date(2026, 3, 23, 9, 0, 0, 0, time.UTC)
+		shifted := shiftDays([]time.Time{start, start.Add(30 * time.Minute)}, london, 7)
+		assert.Equal(t, []time.Time{
+			time.Date(2026, 3, 30, 8, 0, 0, 0, time.UTC),
+			time.Date(2026, 3, 30, 8, 30, 0, 0, time.UTC),
+		}, shifted)
+	})
+
+	t.Run("days between undoes a shift", func(t *testing.T) {
+		t.Parallel()
+		start := time.Date(2026, 3, 23, 23, 30, 0, 0, time.UTC)
+		shifted := shiftDays([]time.Time{start}, london, 14)
+		assert.Equal(t, 14, daysBetween(start, shifted[0], london))
+		assert.Equal(t, -14, daysBetween(shifted[0], start, london))
+	})
+}
+
+func TestSlotsAt(t *testing.T) {
+	nine := time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC)
+	slots := []db.Availability{
+		{ID: 12, Datetime: pgtype.Timestamptz{Time: nine.Add(30 * time.Minute), Valid: true}},
+		{ID: 11, Datetime: pgtype.Timestamptz{Time: nine, Valid: true}},
+	}
+
+	t.Run("all found", func(t *testing.T) {
+		t.Parallel()
+		ids, ok := slotsAt([]time.Time{nine, nine.Add(30 * time.Minute)}, slots)
+		assert.True(t, ok)
+		assert.Equal(t, []int32{11, 12}, ids)
+	})
+
+	t.Run("missing time", func(t *testing.T) {
+		t.Parallel()
+		_, ok := slotsAt([]time.Time{nine, nine.Add(time.Hour)}, slots)
+		assert.False(t, ok)
+	})
+}
+
+func TestSeriesScope(t *testing.T) {
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	at := func(t time.Time) pgtype.Timestamptz {
+		return pgtype.Timestamptz{Time: t, Valid: true}
+	}
+	bookings := []db.GetBookingSeriesBookingsRow{
+		{ID: 1, Status: db.BookingStatusCompleted, StartTime: at(now.AddDate(0, 0, -7))},
+		{ID: 2, Status: db.BookingStatusCancelled},
+		{ID: 3, Status: db.BookingStatusCreated, StartTime: at(now.AddDate(0, 0, 7))},
+		{ID: 4, Status: db.BookingStatusCreated, StartTime: at(now.AddDate(0, 0, 14))},
+	}
+	ids := func(rows []db.GetBookingSeriesBookingsRow) []int32 {
+		picked := []int32{}
+		for _, row := range rows {
+			picked = append(picked, row.ID)
+		}
+		return picked
+	}
+
+	t.Run("one", func(t *testing.T) {
+		t.Parallel()
+		picked, err := seriesScope(bookings, SeriesChangeRequest{Scope: SeriesScopeOne, BookingID: 2}, now)
+		assert.NoError(t, err)
+		assert.Equal(t, []int32{2}, ids(picked))
+
+		_, err = seriesScope(bookings, SeriesChangeRequest{Scope: SeriesScopeOne, BookingID: 9}, now)
+		assert.ErrorIs(t, err, ErrNotInSeries)
+	})
+
+	t.Run("all", func(t *testing.T) {
+		t.Parallel()
+		picked, err := seriesScope(bookings, SeriesChangeRequest{Scope: SeriesScopeAll}, now)
+		assert.NoError(t, err)
+		assert.Equal(t, []int32{1, 3, 4}, ids(picked))
+	})
+
+	t.Run("future", func(t *testing.T) {
+		t.Parallel()
+		picked, err := seriesScope(bookings, SeriesChangeRequest{Scope: SeriesScopeFuture}, now)
+		assert.NoError(t, err)
+		assert.Equal(t, []int32{3, 4}, ids(picked))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		_, err := seriesScope(bookings, SeriesChangeRequest{Scope: "some"}, now)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/jack-cordery/mirai/db"
+)
+
+var (
+	ErrInvalidTransition   = errors.New("booking can not move between these statuses")
+	ErrTransitionForbidden = errors.New("not allowed to move the booking to this status")
+)
+
+// bookingStatusOrder is the order statuses are listed in responses
+var bookingStatusOrder = []db.BookingStatus{
+	db.BookingStatusCreated,
+	db.BookingStatusConfirmed,
+	db.BookingStatusRescheduled,
+	db.BookingStatusCancelled,
+	db.BookingStatusCompleted,
+	db.BookingStatusNoShow,
+}
+
+// bookingTransitions maps a status to the statuses it can move to, and the roles
+// that may make each move. A USER can only move their own bookings. Cancelled,
+// completed and no show are final.
+var bookingTransitions = map[db.BookingStatus]map[db.BookingStatus][]string{
+	db.BookingStatusCreated: {
+		db.BookingStatusConfirmed:   {RoleAdmin},
+		db.BookingStatusRescheduled: {RoleAdmin, RoleUser},
+		db.BookingStatusCancelled:   {RoleAdmin, RoleUser},
+	},
+	db.BookingStatusConfirmed: {
+		db.BookingStatusRescheduled: {RoleAdmin, RoleUser},
+		db.BookingStatusCancelled:   {RoleAdmin, RoleUser},
+		db.BookingStatusCompleted:   {RoleAdmin},
+		db.BookingStatusNoShow:      {RoleAdmin},
+	},
+	db.BookingStatusRescheduled: {
+		db.BookingStatusConfirmed:   {RoleAdmin},
+		db.BookingStatusRescheduled: {RoleAdmin, RoleUser},
+		db.BookingStatusCancelled:   {RoleAdmin, RoleUser},
+		db.BookingStatusCompleted:   {RoleAdmin},
+		db.BookingStatusNoShow:      {RoleAdmin},
+	},
+	db.BookingStatusCancelled: {},
+	db.BookingStatusCompleted: {},
+	db.BookingStatusNoShow:    {},
+}
+
+// canMakeTransition reports whether p may make a move open to roles on a
+// booking owned by ownerID
+func canMakeTransition(p Principal, roles []string, ownerID int32) bool {
+	if p.IsAdmin() && slices.Contains(roles, RoleAdmin) {
+		return true
+	}
+	return p.HasRole(RoleUser) && slices.Contains(roles, RoleUser) && p.UserID == ownerID
+}
+
+// allowedTransitions returns the statuses p can move a booking owned by ownerID
+// to from the status from
+func allowedTransitions(p Principal, from db.BookingStatus, ownerID int32) []db.BookingStatus {
+	allowed := []db.BookingStatus{}
+	for _, to := range bookingStatusOrder {
+		roles, ok := bookingTransitions[from][to]
+		if ok && canMakeTransition(p, roles, ownerID) {
+			allowed = append(allowed, to)
+		}
+	}
+	return allowed
+}
+
+// checkTransition returns ErrInvalidTransition if a booking can never move from
+// from to to, and ErrTransitionForbidden if it can but not by p
+func checkTransition(p Principal, from db.BookingStatus, to db.BookingStatus, ownerID int32) error {
+	roles, ok := bookingTransitions[from][to]
+	if !ok {
+		return ErrInvalidTransition
+	}
+	if !canMakeTransition(p, roles, ownerID) {
+		return ErrTransitionForbidden
+	}
+	return nil
+}
+
+type InvalidTransitionResponse struct {
+	Message         string             `json:"message"`
+	Status          db.BookingStatus   `json:"status"`
+	AllowedStatuses []db.BookingStatus `json:"allowed_statuses"`
+}
+
+// writeTransitionError writes the response for an error from checkTransition,
+// a 409 listing the statuses p could move to instead or a 403
+func writeTransitionError(w http.ResponseWriter, err error, p Principal, from db.BookingStatus, ownerID int32) {
+	if errors.Is(err, ErrTransitionForbidden) {
+		writeForbidden(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusConflict)
+	err = json.NewEncoder(w).Encode(InvalidTransitionResponse{
+		Message:         "Booking can not move to this status from " + string(from),
+		Status:          from,
+		AllowedStatuses: allowedTransitions(p, from, ownerID),
+	})
+	if err != nil {
+		log.Printf("encoding invalid transition response failed with %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTransition(t *testing.T) {
+	admin := Principal{UserID: 1, Roles: []string{RoleAdmin}}
+	owner := Principal{UserID: 2, Roles: []string{RoleUser}}
+	other := Principal{UserID: 3, Roles: []string{RoleUser}}
+
+	t.Run("admin", func(t *testing.T) {
+		t.Parallel()
+		assert.NoError(t, checkTransition(admin, db.BookingStatusCreated, db.BookingStatusConfirmed, 2))
+		assert.NoError(t, checkTransition(admin, db.BookingStatusConfirmed, db.BookingStatusCompleted, 2))
+		assert.NoError(t, checkTransition(admin, db.BookingStatusRescheduled, db.BookingStatusCancelled, 2))
+	})
+
+	t.Run("owner can only cancel", func(t *testing.T) {
+		t.Parallel()
+		assert.NoError(t, checkTransition(owner, db.BookingStatusCreated, db.BookingStatusCancelled, 2))
+		assert.ErrorIs(t, checkTransition(owner, db.BookingStatusCreated, db.BookingStatusConfirmed, 2), ErrTransitionForbidden)
+		assert.ErrorIs(t, checkTransition(owner, db.BookingStatusConfirmed, db.BookingStatusCompleted, 2), ErrTransitionForbidden)
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		t.Parallel()
+		assert.ErrorIs(t, checkTransition(other, db.BookingStatusCreated, db.BookingStatusCancelled, 2), ErrTransitionForbidden)
+	})
+
+	t.Run("final statuses", func(t *testing.T) {
+		t.Parallel()
+		assert.ErrorIs(t, checkTransition(admin, db.BookingStatusCompleted, db.BookingStatusConfirmed, 2), ErrInvalidTransition)
+		assert.ErrorIs(t, checkTransition(admin, db.BookingStatusCancelled, db.BookingStatusCompleted, 2), ErrInvalidTransition)
+		assert.ErrorIs(t, checkTransition(owner, db.BookingStatusCancelled, db.BookingStatusCancelled, 2), ErrInvalidTransition)
+	})
+
+	t.Run("created cant complete", func(t *testing.T) {
+		t.Parallel()
+		assert.ErrorIs(t, checkTransition(admin, db.BookingStatusCreated, db.BookingStatusCompleted, 2), ErrInvalidTransition)
+	})
+
+	t.Run("no show", func(t *testing.T) {
+		t.Parallel()
+		assert.NoError(t, checkTransition(admin, db.BookingStatusConfirmed, db.BookingStatusNoShow, 2))
+		assert.ErrorIs(t, checkTransition(owner, db.BookingStatusConfirmed, db.BookingStatusNoShow, 2), ErrTransitionForbidden)
+		assert.ErrorIs(t, checkTransition(admin, db.BookingStatusCreated, db.BookingStatusNoShow, 2), ErrInvalidTransition)
+		assert.ErrorIs(t, checkTransition(admin, db.BookingStatusNoShow, db.BookingStatusCancelled, 2), ErrInvalidTransition)
+	})
+}
+
+func TestAllowedTransitions(t *testing.T) {
+	t.Run("admin", func(t *testing.T) {
+		t.Parallel()
+		p := Principal{UserID: 1, Roles: []string{RoleAdmin}}
+		expected := []db.BookingStatus{
+			db.BookingStatusRescheduled,
+			db.BookingStatusCancelled,
+			db.BookingStatusCompleted,
+			db.BookingStatusNoShow,
+		}
+		assert.Equal(t, expected, allowedTransitions(p, db.BookingStatusConfirmed, 2))
+	})
+
+	t.Run("owner", func(t *testing.T) {
+		t.Parallel()
+		p := Principal{UserID: 2, Roles: []string{RoleUser}}
+		expected := []db.BookingStatus{
+			db.BookingStatusRescheduled,
+			db.BookingStatusCancelled,
+		}
+		assert.Equal(t, expected, allowedTransitions(p, db.BookingStatusConfirmed, 2))
+	})
+
+	t.Run("final", func(t *testing.T) {
+		t.Parallel()
+		p := Principal{UserID: 1, Roles: []string{RoleAdmin}}
+		assert.Empty(t, allowedTransitions(p, db.BookingStatusCompleted, 2))
+	})
+}
+
+func TestWriteTransitionError(t *testing.T) {
+	p := Principal{UserID: 1, Roles: []string{RoleAdmin}}
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		writeTransitionError(w, ErrInvalidTransition, p, db.BookingStatusCreated, 2)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		var response InvalidTransitionResponse
+		err := json.NewDecoder(w.Body).Decode(&response)
+		require.NoError(t, err)
+		assert.Equal(t, db.BookingStatusCreated, response.Status)
+		assert.Equal(t, []db.BookingStatus{
+			db.BookingStatusConfirmed,
+			db.BookingStatusRescheduled,
+			db.BookingStatusCancelled,
+		}, response.AllowedStatuses)
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		writeTransitionError(w, ErrTransitionForbidden, p, db.BookingStatusCreated, 2)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
@@ -16,46 +16,108 @@ import (
 )
 
 type GetBookingTypeResponse struct {
-	TypeID      int32            `json:"type_id"`
-	Title       string           `json:"title"`
-	Description string           `json:"description"`
-	Fixed       bool             `json:"fixed"`
-	Cost        int32            `json:"cost"`
-	Duration    int32            `json:"duration"` // minutes
-	CreatedAt   pgtype.Timestamp `json:"created_at"`
-	LastEdited  pgtype.Timestamp `json:"last_edited"`
+	TypeID              int32              `json:"type_id"`
+	Title               string             `json:"title"`
+	Description         string             `json:"description"`
+	Fixed               bool               `json:"fixed"`
+	Cost                int32              `json:"cost"`
+	Duration            int32              `json:"duration"` // minutes
+	UnitMinutes         int32              `json:"unit_minutes"`
+	BufferBeforeMinutes int32              `json:"buffer_before_minutes"`
+	BufferAfterMinutes  int32              `json:"buffer_after_minutes"`
+	MinNoticeMinutes    int32              `json:"min_notice_minutes"`
+	MaxAdvanceDays      int32              `json:"max_advance_days"`
+	CancellationPolicy  CancellationPolicy `json:"cancellation_policy"`
+	Deposit             BookingDeposit     `json:"deposit"`
+	ResourceIDs         []int32            `json:"resource_ids"`
+	CreatedAt           pgtype.Timestamptz `json:"created_at"`
+	LastEdited          pgtype.Timestamptz `json:"last_edited"`
 }
 
-func responseFromDBBookingType(bookingType db.BookingType) GetBookingTypeResponse {
+func responseFromDBBookingType(bookingType db.BookingType, resourceIDs []int32) GetBookingTypeResponse {
+	if resourceIDs == nil {
+		resourceIDs = []int32{}
+	}
 	return GetBookingTypeResponse{
-		TypeID:      bookingType.ID,
-		Title:       bookingType.Title,
-		Description: bookingType.Description,
-		Fixed:       bookingType.Fixed,
-		Cost:        bookingType.Cost,
-		Duration:    bookingType.Duration,
-		CreatedAt:   bookingType.CreatedAt,
-		LastEdited:  bookingType.LastEdited,
+		TypeID:              bookingType.ID,
+		Title:               bookingType.Title,
+		Description:         bookingType.Description,
+		Fixed:               bookingType.Fixed,
+		Cost:                bookingType.Cost,
+		Duration:            bookingType.Duration,
+		UnitMinutes:         bookingType.UnitMinutes,
+		BufferBeforeMinutes: bookingType.BufferBeforeMinutes,
+		BufferAfterMinutes:  bookingType.BufferAfterMinutes,
+		MinNoticeMinutes:    bookingType.MinNoticeMinutes,
+		MaxAdvanceDays:      bookingType.MaxAdvanceDays,
+		CancellationPolicy:  policyFromDBBookingType(bookingType),
+		Deposit:             depositFromDBBookingType(bookingType),
+		ResourceIDs:         resourceIDs,
+		CreatedAt:           bookingType.CreatedAt,
+		LastEdited:          bookingType.LastEdited,
 	}
 }
 
 // const MUST be provided in pennies! i.e. 100 = £1.00
 type PostBookingTypeRequest struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Fixed       bool   `json:"fixed"`
-	Cost        int32  `json:"cost"`
-	Duration    int32  `json:"duration"` // minutes
+	Title               string             `json:"title"`
+	Description         string             `json:"description"`
+	Fixed               bool               `json:"fixed"`
+	Cost                int32              `json:"cost"`
+	Duration            int32              `json:"duration"`     // minutes
+	UnitMinutes         int32              `json:"unit_minutes"` // optional, defaults to the configured unit
+	BufferBeforeMinutes int32              `json:"buffer_before_minutes"`
+	BufferAfterMinutes  int32              `json:"buffer_after_minutes"`
+	MinNoticeMinutes    int32              `json:"min_notice_minutes"`
+	MaxAdvanceDays      int32              `json:"max_advance_days"`    // 0 for no limit
+	CancellationPolicy  CancellationPolicy `json:"cancellation_policy"` // optional, cancelling is free by default
+	Deposit             BookingDeposit     `json:"deposit"`             // optional, no deposit by default
+	ResourceIDs         []int32            `json:"resource_ids"`        // resources every booking of the type needs
 }
 
-func (p PostBookingTypeRequest) ToDBParams() db.CreateBookingTypeParams {
-	return db.CreateBookingTypeParams{
-		Title:       p.Title,
-		Description: p.Description,
-		Fixed:       p.Fixed,
-		Cost:        p.Cost,
-		Duration:    p.Duration / Unit,
+func (p PostBookingTypeRequest) ToDBParams(defaultUnit int32) (db.CreateBookingTypeParams, error) {
+	unit := p.UnitMinutes
+	if unit == 0 {
+		unit = defaultUnit
+	}
+	err := validUnit(unit)
+	if err != nil {
+		return db.CreateBookingTypeParams{}, err
+	}
+	err = validDuration(p.Duration, unit)
+	if err != nil {
+		return db.CreateBookingTypeParams{}, err
+	}
+	err = validBookingLimits(p.BufferBeforeMinutes, p.BufferAfterMinutes, p.MinNoticeMinutes, p.MaxAdvanceDays)
+	if err != nil {
+		return db.CreateBookingTypeParams{}, err
 	}
+	err = p.CancellationPolicy.validate()
+	if err != nil {
+		return db.CreateBookingTypeParams{}, err
+	}
+	err = p.Deposit.validate()
+	if err != nil {
+		return db.CreateBookingTypeParams{}, err
+	}
+	return db.CreateBookingTypeParams{
+		Title:                   p.Title,
+		Description:             p.Description,
+		Fixed:                   p.Fixed,
+		Cost:                    p.Cost,
+		Duration:                p.Duration / unit,
+		UnitMinutes:             unit,
+		BufferBeforeMinutes:     p.BufferBeforeMinutes,
+		BufferAfterMinutes:      p.BufferAfterMinutes,
+		MinNoticeMinutes:        p.MinNoticeMinutes,
+		MaxAdvanceDays:          p.MaxAdvanceDays,
+		FreeCancellationHours:   p.CancellationPolicy.FreeCancellationHours,
+		LateCancellationPercent: p.CancellationPolicy.LateCancellationPercent,
+		NoShowPercent:           p.CancellationPolicy.NoShowPercent,
+		DepositPercent:          p.Deposit.Percent,
+		DepositAmount:           p.Deposit.Amount,
+		DepositWindowHours:      p.Deposit.windowHours(),
+	}, nil
 }
 
 type PostBookingTypeResponse struct {
@@ -63,30 +125,72 @@ type PostBookingTypeResponse struct {
 }
 
 type PutBookingTypeRequest struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Fixed       bool   `json:"fixed"`
-	Cost        int32  `json:"cost"`
-	Duration    int32  `json:"duration"` // minutes
+	Title               string             `json:"title"`
+	Description         string             `json:"description"`
+	Fixed               bool               `json:"fixed"`
+	Cost                int32              `json:"cost"`
+	Duration            int32              `json:"duration"`     // minutes
+	UnitMinutes         int32              `json:"unit_minutes"` // optional, defaults to the current unit
+	BufferBeforeMinutes int32              `json:"buffer_before_minutes"`
+	BufferAfterMinutes  int32              `json:"buffer_after_minutes"`
+	MinNoticeMinutes    int32              `json:"min_notice_minutes"`
+	MaxAdvanceDays      int32              `json:"max_advance_days"`    // 0 for no limit
+	CancellationPolicy  CancellationPolicy `json:"cancellation_policy"` // optional, cancelling is free by default
+	Deposit             BookingDeposit     `json:"deposit"`             // optional, no deposit by default
+	ResourceIDs         []int32            `json:"resource_ids"`        // resources every booking of the type needs
 }
 
 type PutBookingTypeResponse struct {
 	BookingTypeID int32 `json:"booking_type_id"`
 }
 
-func (r PutBookingTypeRequest) ToDBParams(bookingTypeID int32) db.UpdateBookingTypeParams {
-
-	return db.UpdateBookingTypeParams{
-		ID:          bookingTypeID,
-		Title:       r.Title,
-		Description: r.Description,
-		Fixed:       r.Fixed,
-		Cost:        r.Cost,
-		Duration:    r.Duration / Unit,
+func (r PutBookingTypeRequest) ToDBParams(bookingTypeID int32, currentUnit int32) (db.UpdateBookingTypeParams, error) {
+	unit := r.UnitMinutes
+	if unit == 0 {
+		unit = currentUnit
 	}
+	err := validUnit(unit)
+	if err != nil {
+		return db.UpdateBookingTypeParams{}, err
+	}
+	err = validDuration(r.Duration, unit)
+	if err != nil {
+		return db.UpdateBookingTypeParams{}, err
+	}
+	err = validBookingLimits(r.BufferBeforeMinutes, r.BufferAfterMinutes, r.MinNoticeMinutes, r.MaxAdvanceDays)
+	if err != nil {
+		return db.UpdateBookingTypeParams{}, err
+	}
+	err = r.CancellationPolicy.validate()
+	if err != nil {
+		return db.UpdateBookingTypeParams{}, err
+	}
+	err = r.Deposit.validate()
+	if err != nil {
+		return db.UpdateBookingTypeParams{}, err
+	}
+	return db.UpdateBookingTypeParams{
+		ID:                      bookingTypeID,
+		Title:                   r.Title,
+		Description:             r.Description,
+		Fixed:                   r.Fixed,
+		Cost:                    r.Cost,
+		Duration:                r.Duration / unit,
+		UnitMinutes:             unit,
+		BufferBeforeMinutes:     r.BufferBeforeMinutes,
+		BufferAfterMinutes:      r.BufferAfterMinutes,
+		MinNoticeMinutes:        r.MinNoticeMinutes,
+		MaxAdvanceDays:          r.MaxAdvanceDays,
+		FreeCancellationHours:   r.CancellationPolicy.FreeCancellationHours,
+		LateCancellationPercent: r.CancellationPolicy.LateCancellationPercent,
+		NoShowPercent:           r.CancellationPolicy.NoShowPercent,
+		DepositPercent:          r.Deposit.Percent,
+		DepositAmount:           r.Deposit.Amount,
+		DepositWindowHours:      r.Deposit.windowHours(),
+	}, nil
 }
 
-func postBookingType(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+func postBookingType(pool *pgxpool.Pool, ctx context.Context, unit int32) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var bookingTypeRequest PostBookingTypeRequest
 
@@ -97,6 +201,13 @@ func postBookingType(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 			return
 		}
 
+		params, err := bookingTypeRequest.ToDBParams(unit)
+		if err != nil {
+			log.Printf("error creating params in postBookingType: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
 		conn, err := pool.Acquire(ctx)
 		if err != nil {
 			log.Printf("error aquiring pool in postBookingType: %v", err)
@@ -122,7 +233,7 @@ func postBookingType(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 		queries := db.New(conn)
 		qtx := queries.WithTx(tx)
 
-		bookingTypeID, err := qtx.CreateBookingType(ctx, bookingTypeRequest.ToDBParams())
+		bookingTypeID, err := qtx.CreateBookingType(ctx, params)
 		if err != nil {
 			var pgErr *pgconn.PgError
 			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
@@ -138,6 +249,19 @@ func postBookingType(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 			return
 		}
 
+		err = setBookingTypeResources(ctx, qtx, bookingTypeID, bookingTypeRequest.ResourceIDs)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+				log.Printf("resources %v requested in postBookingType dont all exist", bookingTypeRequest.ResourceIDs)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			log.Printf("setting resources in postBookingType failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
 		err = tx.Commit(ctx)
 		if err != nil {
 			log.Printf("error commiting tx in postBookingType: %v", err)
@@ -185,10 +309,21 @@ func getBookingType(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 				return
 			}
 
+			typeIDs := []int32{}
+			for _, b := range bookingTypes {
+				typeIDs = append(typeIDs, b.ID)
+			}
+			resources, err := typeResourceIDs(ctx, queries, typeIDs)
+			if err != nil {
+				log.Printf("error getting booking type resources in getAllBookingTypes: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
 			resp := []GetBookingTypeResponse{}
 
 			for _, b := range bookingTypes {
-				resp = append(resp, responseFromDBBookingType(b))
+				resp = append(resp, responseFromDBBookingType(b, resources[b.ID]))
 			}
 
 			err = json.NewEncoder(w).Encode(resp)
@@ -219,7 +354,14 @@ func getBookingType(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 			return
 		}
 
-		err = json.NewEncoder(w).Encode(responseFromDBBookingType(bookingType))
+		resources, err := typeResourceIDs(ctx, queries, []int32{bookingType.ID})
+		if err != nil {
+			log.Printf("error getting booking type resources in getBookingType: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(responseFromDBBookingType(bookingType, resources[bookingType.ID]))
 		if err != nil {
 			log.Printf("error encoding json in getBookingType: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
@@ -271,7 +413,45 @@ func putBookingType(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 		queries := db.New(conn)
 		qtx := queries.WithTx(tx)
 
-		bookingTypeID, err := qtx.UpdateBookingType(ctx, bookingTypeRequest.ToDBParams(int32(id)))
+		existing, err := qtx.GetBookingTypeById(ctx, int32(id))
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("getting booking type in putBookingType failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf(
+				"bookingType id: %d, which does not exist, was attemped to be updated by putBookingType",
+				id,
+			)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		// the unit is kept unless the request changes it
+		params, err := bookingTypeRequest.ToDBParams(int32(id), existing.UnitMinutes)
+		if err != nil {
+			log.Printf("error creating params in putBookingType: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		// slots and bookings are laid out in the old unit so it cant change under them
+		if params.UnitMinutes != existing.UnitMinutes {
+			uses, err := qtx.CountBookingTypeUses(ctx, existing.ID)
+			if err != nil {
+				log.Printf("counting uses of booking type in putBookingType failed with %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if uses > 0 {
+				log.Printf("unit change requested for booking type %d in putBookingType which has slots or bookings", existing.ID)
+				writePaymentConflict(w, "Booking type has slots or bookings, its unit cant change")
+				return
+			}
+		}
+
+		bookingTypeID, err := qtx.UpdateBookingType(ctx, params)
 		if err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
 				log.Printf(
@@ -297,6 +477,19 @@ func putBookingType(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 			return
 		}
 
+		err = setBookingTypeResources(ctx, qtx, bookingTypeID, bookingTypeRequest.ResourceIDs)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+				log.Printf("resources %v requested in putBookingType dont all exist", bookingTypeRequest.ResourceIDs)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			log.Printf("setting resources in putBookingType failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
 		err = tx.Commit(ctx)
 		if err != nil {
 			log.Printf("error commiting tx in putBookingType: %v", err)
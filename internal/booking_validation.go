@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/jack-cordery/mirai/db"
+)
+
+// FieldError explains why one field of a request was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrorResponse is returned with a 400 when a request is well formed
+// but breaks one or more booking rules
+type ValidationErrorResponse struct {
+	Message string       `json:"message"`
+	Errors  []FieldError `json:"errors"`
+}
+
+func writeValidationErrors(w http.ResponseWriter, errs []FieldError) {
+	w.WriteHeader(http.StatusBadRequest)
+	err := json.NewEncoder(w).Encode(ValidationErrorResponse{
+		Message: "The booking is not valid",
+		Errors:  errs,
+	})
+	if err != nil {
+		log.Printf("encoding validation error response failed with %v", err)
+	}
+}
+
+// validateBookingSlots checks that slots, fetched for slotIDs, can make up one
+// booking of bookingType. They must all exist, belong to one employee, be
+// offered for bookingType according to slotTypes and run back to back. Fixed
+// types with a duration also need exactly that many slots, other types can be
+// booked for any length.
+func validateBookingSlots(bookingType db.BookingType, slotIDs []int32, slots []db.Availability, slotTypes map[int32][]int32) []FieldError {
+	errs := []FieldError{}
+
+	missing := []int32{}
+	for _, id := range slotIDs {
+		if !slices.ContainsFunc(slots, func(s db.Availability) bool { return s.ID == id }) {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		errs = append(errs, FieldError{
+			Field:   "availability_slots",
+			Message: fmt.Sprintf("slots %v do not exist", missing),
+		})
+	}
+	if len(slots) == 0 {
+		return append(errs, FieldError{
+			Field:   "availability_slots",
+			Message: "at least one slot is required",
+		})
+	}
+
+	for _, s := range slots[1:] {
+		if s.EmployeeID != slots[0].EmployeeID {
+			errs = append(errs, FieldError{
+				Field:   "availability_slots",
+				Message: "slots must all be with the same employee",
+			})
+			break
+		}
+	}
+
+	wrongType := []int32{}
+	for _, s := range slots {
+		if !slices.Contains(slotTypes[s.ID], bookingType.ID) {
+			wrongType = append(wrongType, s.ID)
+		}
+	}
+	if len(wrongType) > 0 {
+		errs = append(errs, FieldError{
+			Field:   "type_id",
+			Message: fmt.Sprintf("slots %v are not offered for booking type %d", wrongType, bookingType.ID),
+		})
+	}
+
+	sorted := slices.Clone(slots)
+	slices.SortFunc(sorted, func(a db.Availability, b db.Availability) int {
+		return a.Datetime.Time.Compare(b.Datetime.Time)
+	})
+	if !slotsSequential(sorted) {
+		errs = append(errs, FieldError{
+			Field:   "availability_slots",
+			Message: "slots must run back to back",
+		})
+	}
+
+	if bookingType.Fixed && bookingType.Duration > 0 && int32(len(slotIDs)) != bookingType.Duration {
+		errs = append(errs, FieldError{
+			Field:   "availability_slots",
+			Message: fmt.Sprintf("booking type %d takes exactly %d slots, got %d", bookingType.ID, bookingType.Duration, len(slotIDs)),
+		})
+	}
+
+	return errs
+}
@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateBookingSlots(t *testing.T) {
+	start := time.Date(2025, 7, 26, 9, 0, 0, 0, time.UTC)
+	slot := func(id int32, employeeID int32, offset int) db.Availability {
+		return db.Availability{
+			ID:          id,
+			EmployeeID:  employeeID,
+			Datetime:    pgtype.Timestamptz{Time: start.Add(time.Duration(offset) * 30 * time.Minute), Valid: true},
+			UnitMinutes: 30,
+		}
+	}
+	// slot 2 is offered for two types and slot 3 only for another type
+	slotTypes := map[int32][]int32{1: {1}, 2: {1, 4}, 3: {3}, 4: {1}, 9: {1}}
+	fixed := db.BookingType{ID: 1, Fixed: true, Duration: 2}
+	open := db.BookingType{ID: 1}
+
+	fields := func(errs []FieldError) []string {
+		result := []string{}
+		for _, e := range errs {
+			result = append(result, e.Field)
+		}
+		return result
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+		errs := validateBookingSlots(fixed, []int32{1, 2}, []db.Availability{slot(1, 1, 0), slot(2, 1, 1)}, slotTypes)
+		assert.Empty(t, errs)
+	})
+
+	t.Run("order of slots doesnt matter", func(t *testing.T) {
+		t.Parallel()
+		errs := validateBookingSlots(fixed, []int32{2, 1}, []db.Availability{slot(2, 1, 1), slot(1, 1, 0)}, slotTypes)
+		assert.Empty(t, errs)
+	})
+
+	t.Run("types without a fixed duration take any length", func(t *testing.T) {
+		t.Parallel()
+		errs := validateBookingSlots(open, []int32{1, 2, 4}, []db.Availability{slot(1, 1, 0), slot(2, 1, 1), slot(4, 1, 2)}, slotTypes)
+		assert.Empty(t, errs)
+	})
+
+	t.Run("wrong number of slots for a fixed type", func(t *testing.T) {
+		t.Parallel()
+		errs := validateBookingSlots(fixed, []int32{1}, []db.Availability{slot(1, 1, 0)}, slotTypes)
+		assert.Equal(t, []string{"availability_slots"}, fields(errs))
+		assert.Contains(t, errs[0].Message, "exactly 2 slots")
+	})
+
+	t.Run("different employees", func(t *testing.T) {
+		t.Parallel()
+		errs := validateBookingSlots(fixed, []int32{1, 2}, []db.Availability{slot(1, 1, 0), slot(2, 2, 1)}, slotTypes)
+		assert.Equal(t, []string{"availability_slots"}, fields(errs))
+		assert.Contains(t, errs[0].Message, "same employee")
+	})
+
+	t.Run("slots for another type", func(t *testing.T) {
+		t.Parallel()
+		errs := validateBookingSlots(fixed, []int32{1, 3}, []db.Availability{slot(1, 1, 0), slot(3, 1, 1)}, slotTypes)
+		assert.Equal(t, []string{"type_id"}, fields(errs))
+		assert.Contains(t, errs[0].Message, "[3]")
+	})
+
+	t.Run("gap between slots", func(t *testing.T) {
+		t.Parallel()
+		errs := validateBookingSlots(fixed, []int32{1, 2}, []db.Availability{slot(1, 1, 0), slot(2, 1, 2)}, slotTypes)
+		assert.Equal(t, []string{"availability_slots"}, fields(errs))
+		assert.Contains(t, errs[0].Message, "back to back")
+	})
+
+	t.Run("missing slots", func(t *testing.T) {
+		t.Parallel()
+		errs := validateBookingSlots(fixed, []int32{1, 9}, []db.Availability{slot(1, 1, 0)}, slotTypes)
+		assert.Equal(t, []string{"availability_slots"}, fields(errs))
+		assert.Contains(t, errs[0].Message, "[9]")
+	})
+
+	t.Run("no slots", func(t *testing.T) {
+		t.Parallel()
+		errs := validateBookingSlots(open, []int32{}, []db.Availability{}, slotTypes)
+		assert.Equal(t, []string{"availability_slots"}, fields(errs))
+	})
+
+	t.Run("reports every broken rule", func(t *testing.T) {
+		t.Parallel()
+		errs := validateBookingSlots(fixed, []int32{1, 2, 3}, []db.Availability{slot(1, 1, 0), slot(2, 2, 1), slot(3, 1, 5)}, slotTypes)
+		assert.Equal(t, []string{"availability_slots", "type_id", "availability_slots", "availability_slots"}, fields(errs))
+	})
+}
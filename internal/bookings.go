@@ -4,9 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
-	"slices"
 	"strconv"
 	"time"
 
@@ -18,34 +19,44 @@ import (
 )
 
 type GetBookingResponse struct {
-	BookingID       int32            `json:"booking_id"`
-	UserID          int32            `json:"user_id"`
-	TypeID          int32            `json:"type_id"`
-	Paid            bool             `json:"paid"`
-	Cost            int32            `json:"cost"`
-	Status          db.BookingStatus `json:"status"`
-	StatusUpdatedAt pgtype.Timestamp `json:"status_updated_at"`
-	StatusUpdatedBy string           `json:"status_updated_by"`
-	Notes           pgtype.Text      `json:"notes"`
-	SlotIDs         []int32          `json:"slot_ids"`
-	CreatedAt       pgtype.Timestamp `json:"created_at"`
-	LastEdited      pgtype.Timestamp `json:"last_edited"`
+	BookingID          int32              `json:"booking_id"`
+	UserID             int32              `json:"user_id"`
+	TypeID             int32              `json:"type_id"`
+	Paid               bool               `json:"paid"`
+	Cost               int32              `json:"cost"`
+	Status             db.BookingStatus   `json:"status"`
+	StatusUpdatedAt    pgtype.Timestamptz `json:"status_updated_at"`
+	StatusUpdatedBy    string             `json:"status_updated_by"`
+	Notes              pgtype.Text        `json:"notes"`
+	SlotIDs            []int32            `json:"slot_ids"`
+	SeriesID           pgtype.Int4        `json:"series_id"`
+	CancellationPolicy CancellationPolicy `json:"cancellation_policy"` // the policy of the type when the booking was made
+	Deposit            int32              `json:"deposit"`
+	DepositDueAt       pgtype.Timestamptz `json:"deposit_due_at"`
+	Balance            int32              `json:"balance"` // what is left to pay, negative when the customer is owed money
+	CreatedAt          pgtype.Timestamptz `json:"created_at"`
+	LastEdited         pgtype.Timestamptz `json:"last_edited"`
 }
 
-func responseFromDBBooking(booking db.GetBookingByIdRow) GetBookingResponse {
+func responseFromDBBooking(booking db.GetBookingByIdRow, balance LedgerBalance, loc *time.Location) GetBookingResponse {
 	return GetBookingResponse{
-		BookingID:       booking.ID,
-		UserID:          booking.UserID,
-		TypeID:          booking.TypeID,
-		Paid:            booking.Paid,
-		Cost:            booking.Cost,
-		Status:          booking.Status,
-		StatusUpdatedAt: booking.StatusUpdatedAt,
-		StatusUpdatedBy: booking.StatusUpdatedBy,
-		Notes:           booking.Notes,
-		SlotIDs:         booking.SlotIds,
-		CreatedAt:       booking.CreatedAt,
-		LastEdited:      booking.LastEdited,
+		BookingID:          booking.ID,
+		UserID:             booking.UserID,
+		TypeID:             booking.TypeID,
+		Paid:               booking.Paid,
+		Cost:               booking.Cost,
+		Status:             booking.Status,
+		StatusUpdatedAt:    inLocation(booking.StatusUpdatedAt, loc),
+		StatusUpdatedBy:    booking.StatusUpdatedBy,
+		Notes:              booking.Notes,
+		SlotIDs:            booking.SlotIds,
+		SeriesID:           booking.SeriesID,
+		CancellationPolicy: policyFromDBBooking(booking),
+		Deposit:            booking.Deposit,
+		DepositDueAt:       inLocation(booking.DepositDueAt, loc),
+		Balance:            balance.Outstanding,
+		CreatedAt:          inLocation(booking.CreatedAt, loc),
+		LastEdited:         inLocation(booking.LastEdited, loc),
 	}
 }
 
@@ -54,21 +65,23 @@ type PostBookingRequest struct {
 	AvailabilitySlots []int32     `json:"availability_slots"`
 	TypeID            int32       `json:"type_id"`
 	Notes             pgtype.Text `json:"notes"`
+	HoldID            int32       `json:"hold_id"`
 }
 
 type PostBookingResponse struct {
 	BookingID int32 `json:"booking_id"`
 }
 
-func (r PostBookingRequest) ToDBParams(cost int32, paid bool) db.CreateBookingParams {
+// ToDBParams books the slots for userID. The user comes from the session rather
+// than the body, see bookingUserID.
+func (r PostBookingRequest) ToDBParams(userID int32, cost int32, paid bool) db.CreateBookingParams {
 	return db.CreateBookingParams{
-		UserID:  r.UserID,
+		UserID:  userID,
 		TypeID:  r.TypeID,
 		Notes:   r.Notes,
 		Cost:    cost,
 		Paid:    paid,
 		Column6: r.AvailabilitySlots,
-		Column7: Unit,
 	}
 }
 
@@ -77,7 +90,6 @@ type PutBookingRequest struct {
 	TypeID int32       `json:"type_id"`
 	Notes  pgtype.Text `json:"notes"`
 	Cost   int32       `json:"cost"`
-	Paid   bool        `json:"bool"`
 	Slots  []int32     `json:"availability_slots"`
 }
 
@@ -85,19 +97,132 @@ type PutBookingResponse struct {
 	BookingID int32 `json:"booking_id"`
 }
 
-func (r PutBookingRequest) ToDBParams(bookingID int32) db.UpdateBookingParams {
+func (r PutBookingRequest) ToDBParams(bookingID int32, updatedBy string) db.UpdateBookingParams {
 	return db.UpdateBookingParams{
-		ID:     bookingID,
-		UserID: r.UserID,
-		TypeID: r.TypeID,
-		Notes:  r.Notes,
-		Cost:   r.Cost,
-		Paid:   r.Paid,
+		ID:              bookingID,
+		UserID:          r.UserID,
+		TypeID:          r.TypeID,
+		Notes:           r.Notes,
+		Cost:            r.Cost,
+		StatusUpdatedBy: updatedBy,
 	}
 }
 
+// bookingUserID is the user a new booking is made for. Only admins can book on
+// behalf of someone else, everyone else always books for themselves.
+func bookingUserID(p Principal, requested int32) int32 {
+	if p.IsAdmin() && requested != 0 {
+		return requested
+	}
+	return p.UserID
+}
+
+// restrictEdit limits what p can change on the existing booking. Admins can
+// change anything, an owner can only change the notes as the rest affects
+// the price or who the booking belongs to.
+func (r PutBookingRequest) restrictEdit(p Principal, existing db.GetBookingWithJoinRow) PutBookingRequest {
+	if p.IsAdmin() {
+		return r
+	}
+	r.UserID = existing.UserID
+	r.TypeID = existing.TypeID
+	r.Cost = existing.Cost
+	return r
+}
+
+func bookingHistoryParams(bookingRow db.GetBookingWithJoinRow, status db.BookingStatus, changedBy string) db.CreateBookingHistoryParams {
+	return db.CreateBookingHistoryParams{
+		BookingID:       bookingRow.ID,
+		EmployeeID:      bookingRow.EmployeeID,
+		EmployeeName:    bookingRow.EmployeeName,
+		EmployeeSurname: bookingRow.EmployeeSurname,
+		EmployeeEmail:   bookingRow.EmployeeEmail,
+		StartTime:       bookingRow.StartTime,
+		EndTime:         bookingRow.EndTime,
+		Status:          status,
+		ChangedByEmail:  changedBy,
+	}
+}
+
+// createdBookingHistoryParams records bookingRow, which has just been created
+// by changedBy, in its history
+func createdBookingHistoryParams(bookingRow db.CreateBookingRow, changedBy string) db.CreateBookingHistoryParams {
+	return db.CreateBookingHistoryParams{
+		BookingID:       bookingRow.BookingID,
+		EmployeeID:      bookingRow.EmployeeID,
+		EmployeeName:    bookingRow.EmployeeName,
+		EmployeeSurname: bookingRow.EmployeeSurname,
+		EmployeeEmail:   bookingRow.EmployeeEmail,
+		StartTime:       bookingRow.StartTime,
+		EndTime:         bookingRow.EndTime,
+		Status:          db.BookingStatusCreated,
+		ChangedByEmail:  changedBy,
+	}
+}
+
+// setBookingStatus moves booking id to status and records the change in its
+// history as made by changedBy, with reason if it isnt empty. Cancelling a
+// booking frees its slots. The move must already have passed checkTransition.
+func setBookingStatus(ctx context.Context, queries *db.Queries, id int32, status db.BookingStatus, changedBy string, reason string) error {
+	err := queries.UpdateBookingStatus(ctx, db.UpdateBookingStatusParams{
+		ID:              id,
+		Status:          status,
+		StatusUpdatedBy: changedBy,
+	})
+	if err != nil {
+		return err
+	}
+
+	bookingRow, err := queries.GetBookingWithJoin(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	history := bookingHistoryParams(bookingRow, status, bookingRow.StatusUpdatedBy)
+	history.Reason = pgtype.Text{String: reason, Valid: reason != ""}
+	err = queries.CreateBookingHistory(ctx, history)
+	if err != nil {
+		return err
+	}
+
+	if status == db.BookingStatusCancelled {
+		err = queries.FreeAvailabilitySlot(ctx, id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getOwnedBooking loads booking id for p, writing a 404 if it doesnt exist and
+// a 403 if p isnt an admin or the owner
+func getOwnedBooking(w http.ResponseWriter, ctx context.Context, queries *db.Queries, p Principal, id int32, caller string) (db.GetBookingWithJoinRow, bool) {
+	booking, err := queries.GetBookingWithJoin(ctx, id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("error getting booking in %s: %v", caller, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return booking, false
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("booking id: %d was requested in %s and does not exist", id, caller)
+		w.WriteHeader(http.StatusNotFound)
+		return booking, false
+	}
+	if !p.CanAccessUser(booking.UserID) {
+		log.Printf("user %d requested booking %d in %s and doesnt have permission to", p.UserID, id, caller)
+		writeForbidden(w)
+		return booking, false
+	}
+	return booking, true
+}
+
 func postBooking(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
 		var bookingRequest PostBookingRequest
 
 		err := json.NewDecoder(r.Body).Decode(&bookingRequest)
@@ -131,6 +256,27 @@ func postBooking(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 
 		queries := db.New(conn)
 		qtx := queries.WithTx(tx)
+		userID := bookingUserID(principal, bookingRequest.UserID)
+
+		// a booking made from a hold takes the held slots, the hold is consumed
+		// below once the booking has been created. the hold may be a waitlist
+		// offer, which is then marked as booked.
+		var offer db.WaitlistEntry
+		if bookingRequest.HoldID != 0 {
+			slotIDs, ok := checkSlotHold(w, ctx, qtx, principal, bookingRequest.HoldID, userID, bookingRequest.AvailabilitySlots, "postBooking")
+			if !ok {
+				return
+			}
+			bookingRequest.AvailabilitySlots = slotIDs
+
+			offer, err = qtx.GetWaitlistEntryByHoldId(ctx, pgtype.Int4{Int32: bookingRequest.HoldID, Valid: true})
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				log.Printf("error getting waitlist offer in postBooking: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
 		duration := len(bookingRequest.AvailabilitySlots)
 		if duration < 1 {
 			log.Printf("requested a booking with no slots")
@@ -138,54 +284,84 @@ func postBooking(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 			return
 		}
 
-		sequentialCheckSlots, err := qtx.GetAvailabilitySlotByIds(ctx, bookingRequest.AvailabilitySlots)
+		bookingType, err := qtx.GetBookingTypeById(ctx, bookingRequest.TypeID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("error getting booking type in postBooking: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("booking type id: %d was requested in postBooking and does not exist", bookingRequest.TypeID)
+			writeValidationErrors(w, []FieldError{{
+				Field:   "type_id",
+				Message: fmt.Sprintf("booking type %d does not exist", bookingRequest.TypeID),
+			}})
+			return
+		}
+
+		taken, err := claimSlots(ctx, qtx, bookingRequest.AvailabilitySlots, bookingRequest.HoldID)
+		if errors.Is(err, ErrUnknownSlots) {
+			log.Printf("booking requested for slots %v in postBooking that dont all exist", bookingRequest.AvailabilitySlots)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
 		if err != nil {
-			log.Printf("getting slots for sequential check failed in postBooking: %v", err)
+			log.Printf("claiming slots in postBooking failed with %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
+		}
+		if len(taken) > 0 {
+			log.Printf("user %d requested a booking on taken slots %v", principal.UserID, taken)
+			writeSlotConflict(w, "Some of the requested slots are no longer available", taken)
+			return
+		}
 
+		slots, err := qtx.GetAvailabilitySlotByIds(ctx, bookingRequest.AvailabilitySlots)
+		if err != nil {
+			log.Printf("getting slots for validation failed in postBooking: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
 		}
 
-		sequentialCheckTimes := []time.Time{}
-		for _, s := range sequentialCheckSlots {
-			sequentialCheckTimes = append(sequentialCheckTimes, s.Datetime.Time)
+		slotTypes, err := slotTypeIDs(ctx, qtx, slots)
+		if err != nil {
+			log.Printf("getting slot booking types failed in postBooking: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
 		}
 
-		if !isSequential(sequentialCheckTimes, Unit) {
-			log.Printf("slot request is not sequential in postBooking")
-			w.WriteHeader(http.StatusBadRequest)
+		if errs := validateBookingSlots(bookingType, bookingRequest.AvailabilitySlots, slots, slotTypes); len(errs) > 0 {
+			log.Printf("invalid slots %v for booking type %d in postBooking: %v", bookingRequest.AvailabilitySlots, bookingType.ID, errs)
+			writeValidationErrors(w, errs)
 			return
 		}
 
-		cost, err := getAndCalculateCost(queries, ctx, bookingRequest.TypeID, int32(duration))
-		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-			log.Printf("error getting cost and availability in postBooking: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
+		if !checkBookingLimits(w, ctx, qtx, principal, bookingType, slots, "postBooking") {
 			return
 		}
-		if err != nil && errors.Is(err, pgx.ErrNoRows) {
-			log.Printf("invalid BookingTypeID: %d and/or AvailabilitySlotID: %d",
-				bookingRequest.TypeID,
-				bookingRequest.AvailabilitySlots,
-			)
+
+		if !checkBookingResources(w, ctx, qtx, bookingType, slots, "postBooking") {
+			return
 		}
 
-		bookingRow, err := qtx.CreateBooking(ctx, bookingRequest.ToDBParams(cost, false))
+		cost := bookingCost(bookingType, int32(duration))
+
+		bookingRow, err := qtx.CreateBooking(ctx, bookingRequest.ToDBParams(userID, cost, false))
 		if err != nil {
 			var pgErr *pgconn.PgError
 			if errors.As(err, &pgErr) {
 				if pgErr.Code == "23505" {
 					log.Printf("uniqueness constraint violated in postBooking. userID: %d availabilitySlot: %d",
-						bookingRequest.UserID,
+						userID,
 						bookingRequest.AvailabilitySlots,
 					)
-					w.WriteHeader(http.StatusConflict)
+					writeSlotConflict(w, "Some of the requested slots are no longer available", bookingRequest.AvailabilitySlots)
 					return
 				}
 				if pgErr.Code == "23503" {
 					log.Printf("either the booking type id: %d or user id: %d, or availabilitySlotID %d does not exist",
 						bookingRequest.TypeID,
-						bookingRequest.UserID,
+						userID,
 						bookingRequest.AvailabilitySlots,
 					)
 					w.WriteHeader(http.StatusBadRequest)
@@ -197,30 +373,43 @@ func postBooking(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 			return
 		}
 
-		user, err := qtx.GetUserById(ctx, bookingRequest.UserID)
+		history := createdBookingHistoryParams(bookingRow, principal.Email)
+		history.Reason = pgtype.Text{String: waitlistBookedReason, Valid: offer.ID != 0}
+		err = qtx.CreateBookingHistory(ctx, history)
 		if err != nil {
-			log.Printf("error getting user email in postBooking: %v", err)
+			log.Printf("error creating booking history in postBooking: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		err = qtx.CreateBookingHistory(ctx, db.CreateBookingHistoryParams{
-			BookingID:       bookingRow.BookingID,
-			StartTime:       bookingRow.StartTime,
-			EmployeeID:      bookingRow.EmployeeID,
-			EmployeeName:    bookingRow.EmployeeName,
-			EmployeeSurname: bookingRow.EmployeeSurname,
-			EmployeeEmail:   bookingRow.EmployeeEmail,
-			EndTime:         bookingRow.EndTime,
-			Status:          db.BookingStatusCreated,
-			ChangedByEmail:  user.Email,
-		})
+		err = chargeBooking(ctx, qtx, bookingType, bookingRow, cost, principal.Email)
 		if err != nil {
-			log.Printf("error creating booking history in postBooking: %v", err)
+			log.Printf("error charging booking in postBooking: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
+		if offer.ID != 0 {
+			err = qtx.BookWaitlistEntry(ctx, db.BookWaitlistEntryParams{
+				ID:        offer.ID,
+				BookingID: pgtype.Int4{Int32: bookingRow.BookingID, Valid: true},
+			})
+			if err != nil {
+				log.Printf("error booking waitlist offer in postBooking: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if bookingRequest.HoldID != 0 {
+			_, err = qtx.DeleteSlotHold(ctx, bookingRequest.HoldID)
+			if err != nil {
+				log.Printf("error consuming hold in postBooking: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
 		err = tx.Commit(ctx)
 		if err != nil {
 			log.Printf("error commiting tx in postBooking: %v", err)
@@ -244,6 +433,11 @@ func postBooking(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 
 func getBooking(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		loc, ok := requestLocation(w, r)
+		if !ok {
+			return
+		}
+
 		bookingId := r.PathValue("booking_id")
 		conn, err := pool.Acquire(ctx)
 		if err != nil {
@@ -260,13 +454,22 @@ func getBooking(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 			// - add filtering and pagination through query params
 			// - merge return types to be the same
 
-			bookings, err := queries.GetAllBookingsWithJoin(ctx, Unit)
+			bookings, err := queries.GetAllBookingsWithJoin(ctx)
 			if err != nil {
 				log.Printf("error querying GetAllBookingsWithJoin in getBooking: %v", err)
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
 
+			for i, b := range bookings {
+				bookings[i].UserLastLogin = inLocation(b.UserLastLogin, loc)
+				bookings[i].StatusUpdatedAt = inLocation(b.StatusUpdatedAt, loc)
+				bookings[i].CreatedAt = inLocation(b.CreatedAt, loc)
+				bookings[i].LastEdited = inLocation(b.LastEdited, loc)
+				bookings[i].StartTime = inLocation(b.StartTime, loc)
+				bookings[i].EndTime = inLocation(b.EndTime, loc)
+			}
+
 			err = json.NewEncoder(w).Encode(bookings)
 			if err != nil {
 				log.Printf("error encoding json in all branch of getBooking: %v", err)
@@ -295,7 +498,24 @@ func getBooking(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 			return
 		}
 
-		err = json.NewEncoder(w).Encode(responseFromDBBooking(booking))
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+		if !principal.CanAccessUser(booking.UserID) {
+			log.Printf("user %d requested booking %d in getBooking and doesnt have permission to", principal.UserID, id)
+			writeForbidden(w)
+			return
+		}
+
+		balance, err := bookingBalance(ctx, queries, booking.ID)
+		if err != nil {
+			log.Printf("error getting balance in getBooking: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(responseFromDBBooking(booking, balance, loc))
 		if err != nil {
 			log.Printf("error encoding json in getBooking: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
@@ -304,18 +524,15 @@ func getBooking(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 	}
 }
 
-func getBookingUser(pool *pgxpool.Pool, ctx context.Context, a *AuthParams) http.HandlerFunc {
+func getBookingUser(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		token, err := ReadEncryptedCookie(r, a.CParams.Name, a.SecretKey)
-		if err != nil {
-			log.Printf("The token provided failed in getBookingUser: %v with %v", token, err)
-			w.WriteHeader(http.StatusUnauthorized)
-			err = json.NewEncoder(w).Encode(ErrorResponse{Message: "Invalid session"})
-			if err != nil {
-				log.Printf("encoding response in getBookingUser failed with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
+		loc, ok := requestLocation(w, r)
+		if !ok {
 			return
 		}
 
@@ -329,68 +546,27 @@ func getBookingUser(pool *pgxpool.Pool, ctx context.Context, a *AuthParams) http
 
 		queries := db.New(conn)
 
-		valid, err := VerifySession(ctx, queries, token)
+		bookingData, err := queries.GetAllBookingsWithJoinByID(ctx, principal.UserID)
 		if err != nil {
-			log.Printf("verifying session in getBookingUser failed with %v", err)
+			log.Printf("getting booking data in getBookingUser failed with %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		if !valid {
-			w.WriteHeader(http.StatusUnauthorized)
-			err = json.NewEncoder(w).Encode(ErrorResponse{Message: "Invalid session"})
-			if err != nil {
-				log.Printf("writing json in getBookingUser failed with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-			return
-		} else {
-			session, err := queries.GetSessionByToken(ctx, token)
-			if err != nil {
-				log.Printf("getting session by token in getBookingUser failed with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-
-			approver, err := queries.GetUserById(ctx, session.UserID)
-			if err != nil {
-				log.Printf("getting user by id for user in getBookingUser failed with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-
-			userRoles, err := queries.GetRolesForUser(ctx, approver.ID)
-			if err != nil {
-				log.Printf("getting user roles by id for user in getBookingUser failed with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-
-			isUser := slices.Contains(userRoles, RoleUser)
-
-			if !isUser {
-				log.Printf("user %d has requested to get booking data and doesnt have permission to", approver.ID)
-				w.WriteHeader(http.StatusUnauthorized)
-				return
-			}
-
-			bookingData, err := queries.GetAllBookingsWithJoinByID(ctx, db.GetAllBookingsWithJoinByIDParams{
-				UserID:  approver.ID,
-				Column2: Unit,
-			})
-			if err != nil {
-				log.Printf("getting booking data in getBookingUser failed with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
+		for i, b := range bookingData {
+			bookingData[i].UserLastLogin = inLocation(b.UserLastLogin, loc)
+			bookingData[i].StatusUpdatedAt = inLocation(b.StatusUpdatedAt, loc)
+			bookingData[i].CreatedAt = inLocation(b.CreatedAt, loc)
+			bookingData[i].LastEdited = inLocation(b.LastEdited, loc)
+			bookingData[i].StartTime = inLocation(b.StartTime, loc)
+			bookingData[i].EndTime = inLocation(b.EndTime, loc)
+		}
 
-			err = json.NewEncoder(w).Encode(bookingData)
-			if err != nil {
-				log.Printf("encoding booking data in getBookingUser failed with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
+		err = json.NewEncoder(w).Encode(bookingData)
+		if err != nil {
+			log.Printf("encoding booking data in getBookingUser failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
 		}
 	}
 
@@ -398,11 +574,15 @@ func getBookingUser(pool *pgxpool.Pool, ctx context.Context, a *AuthParams) http
 
 func putBooking(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
 
 		bookingId := r.PathValue("booking_id")
 		id, err := strconv.ParseInt(bookingId, 10, 32)
 		if err != nil {
-			log.Printf("error: %v converting booking id to int in deleteBooking: %s", err, bookingId)
+			log.Printf("error: %v converting booking id to int in putBooking: %s", err, bookingId)
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
@@ -441,25 +621,64 @@ func putBooking(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 		queries := db.New(conn)
 		qtx := queries.WithTx(tx)
 
-		sequentialCheckSlots, err := qtx.GetAvailabilitySlotByIds(ctx, bookingRequest.Slots)
+		existing, ok := getOwnedBooking(w, ctx, qtx, principal, int32(id), "putBooking")
+		if !ok {
+			return
+		}
+		bookingRequest = bookingRequest.restrictEdit(principal, existing)
+
+		bookingType, err := qtx.GetBookingTypeById(ctx, bookingRequest.TypeID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("error getting booking type in putBooking: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("booking type id: %d was requested in putBooking and does not exist", bookingRequest.TypeID)
+			writeValidationErrors(w, []FieldError{{
+				Field:   "type_id",
+				Message: fmt.Sprintf("booking type %d does not exist", bookingRequest.TypeID),
+			}})
+			return
+		}
+
+		// the slots arent changed by an update, they are moved with
+		// POST /booking/{booking_id}/reschedule. The booking's own slots are
+		// checked against the new type.
+		slots, err := qtx.GetBookingAvailabilitySlots(ctx, int32(id))
 		if err != nil {
-			log.Printf("getting slots for sequential check failed in putBooking: %v", err)
+			log.Printf("getting slots for validation failed in putBooking: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		slotIDs := []int32{}
+		for _, s := range slots {
+			slotIDs = append(slotIDs, s.ID)
+		}
+		if len(bookingRequest.Slots) > 0 && !sameSlots(bookingRequest.Slots, slotIDs) {
+			log.Printf("slots of booking %d were attempted to be changed from %v to %v in putBooking", id, slotIDs, bookingRequest.Slots)
+			writeValidationErrors(w, []FieldError{{
+				Field:   "availability_slots",
+				Message: "slots can only be changed by rescheduling the booking",
+			}})
+			return
+		}
+		bookingRequest.Slots = slotIDs
 
-		sequentialCheckTimes := []time.Time{}
-		for _, s := range sequentialCheckSlots {
-			sequentialCheckTimes = append(sequentialCheckTimes, s.Datetime.Time)
+		slotTypes, err := slotTypeIDs(ctx, qtx, slots)
+		if err != nil {
+			log.Printf("getting slot booking types failed in putBooking: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
 		}
 
-		if !isSequential(sequentialCheckTimes, Unit) {
-			log.Printf("slot request is not sequential in putBooking")
-			w.WriteHeader(http.StatusBadRequest)
+		if errs := validateBookingSlots(bookingType, bookingRequest.Slots, slots, slotTypes); len(errs) > 0 {
+			log.Printf("invalid slots %v for booking type %d in putBooking: %v", bookingRequest.Slots, bookingType.ID, errs)
+			writeValidationErrors(w, errs)
 			return
 		}
 
-		bookingID, err := qtx.UpdateBooking(ctx, bookingRequest.ToDBParams(int32(id)))
+		bookingID, err := qtx.UpdateBooking(ctx, bookingRequest.ToDBParams(int32(id), principal.Email))
 		if err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
 				log.Printf("booking id: %d, which does not exist, was attemped to be updated by putBooking", id)
@@ -491,6 +710,20 @@ func putBooking(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 			return
 		}
 
+		err = qtx.CreateBookingHistory(ctx, bookingHistoryParams(existing, existing.Status, principal.Email))
+		if err != nil {
+			log.Printf("error creating booking history in putBooking: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		err = adjustBookingCharge(ctx, qtx, bookingID, existing.Cost, bookingRequest.Cost, principal.Email, "cost changed")
+		if err != nil {
+			log.Printf("error adjusting charge in putBooking: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
 		err = tx.Commit(ctx)
 		if err != nil {
 			log.Printf("error commiting tx in putBooking: %v", err)
@@ -512,8 +745,16 @@ func putBooking(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 	}
 }
 
+// deleteBooking removes a booking outright. It is only for admins and only while
+// no money has moved on the booking, customers cancel through
+// POST /booking/{booking_id}/cancel instead.
 func deleteBooking(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
 		bookingId := r.PathValue("booking_id")
 		id, err := strconv.ParseInt(bookingId, 10, 32)
 		if err != nil {
@@ -547,6 +788,38 @@ func deleteBooking(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 		queries := db.New(conn)
 		qtx := queries.WithTx(tx)
 
+		existing, ok := getOwnedBooking(w, ctx, qtx, principal, int32(id), "deleteBooking")
+		if !ok {
+			return
+		}
+
+		payments, err := qtx.GetBookingPayments(ctx, int32(id))
+		if err != nil {
+			log.Printf("error getting payments in deleteBooking: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		entries, err := qtx.GetBookingLedger(ctx, int32(id))
+		if err != nil {
+			log.Printf("error getting ledger in deleteBooking: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		err = checkBookingDeletable(payments, entries)
+		if err != nil {
+			log.Printf("booking %d with payments was attempted to be deleted by deleteBooking", id)
+			writePaymentConflict(w, "Booking has payments, cancel it instead")
+			return
+		}
+
+		// the history outlives the booking so this is the record of who deleted it
+		err = qtx.CreateBookingHistory(ctx, bookingHistoryParams(existing, db.BookingStatusCancelled, principal.Email))
+		if err != nil {
+			log.Printf("error creating booking history in deleteBooking: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
 		_, err = qtx.DeleteBooking(ctx, int32(id))
 		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 			log.Printf("general error when trying to delete booking in deleteBooking: %v", err)
@@ -571,8 +844,20 @@ func deleteBooking(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 
 }
 
-func postManualPayment(pool *pgxpool.Pool, ctx context.Context, a *AuthParams) http.HandlerFunc {
+// postManualPayment records money taken outside of the app, such as cash at the
+// desk, as a captured manual payment towards what is outstanding on a booking
+func postManualPayment(pool *pgxpool.Pool, ctx context.Context, pp PaymentParams) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
+		loc, ok := requestLocation(w, r)
+		if !ok {
+			return
+		}
+
 		id := r.PathValue("booking_id")
 		if id == "" {
 			log.Printf("booking_id in postManualPayment is empty")
@@ -585,9 +870,19 @@ func postManualPayment(pool *pgxpool.Pool, ctx context.Context, a *AuthParams) h
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
+
+		var paymentRequest PostManualPaymentRequest
+
+		err = json.NewDecoder(r.Body).Decode(&paymentRequest)
+		if err != nil && !errors.Is(err, io.EOF) {
+			log.Printf("error decoding body in postManualPayment: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
 		conn, err := pool.Acquire(ctx)
 		if err != nil {
-			log.Printf("error aquiring pool in deleteBooking: %v", err)
+			log.Printf("error aquiring pool in postManualPayment: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -595,7 +890,7 @@ func postManualPayment(pool *pgxpool.Pool, ctx context.Context, a *AuthParams) h
 
 		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
 		if err != nil {
-			log.Printf("error beginning tx in deleteBooking: %v", err)
+			log.Printf("error beginning tx in postManualPayment: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -610,84 +905,83 @@ func postManualPayment(pool *pgxpool.Pool, ctx context.Context, a *AuthParams) h
 		queries := db.New(conn)
 		qtx := queries.WithTx(tx)
 
-		token, err := ReadEncryptedCookie(r, a.CParams.Name, a.SecretKey)
-		if err != nil {
-			log.Printf("The token provided failed in postManualPayment: %v", token)
-			w.WriteHeader(http.StatusUnauthorized)
-			err = json.NewEncoder(w).Encode(ErrorResponse{Message: "Invalid session"})
-			if err != nil {
-				log.Printf("encoding response in postManualPayment failed with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
+		// the booking is locked so two payments cant both take what is outstanding
+		_, err = qtx.LockBooking(ctx, int32(booking_id))
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("locking booking in postManualPayment failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		booking, ok := getOwnedBooking(w, ctx, qtx, principal, int32(booking_id), "postManualPayment")
+		if !ok {
 			return
 		}
 
-		valid, err := VerifySession(ctx, qtx, token)
+		balance, err := bookingBalance(ctx, qtx, booking.ID)
 		if err != nil {
-			log.Printf("verifying session in postManualPayment failed with %v", err)
+			log.Printf("getting balance in postManualPayment failed with %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-
-		if !valid {
-			w.WriteHeader(http.StatusUnauthorized)
-			err = json.NewEncoder(w).Encode(ErrorResponse{Message: "Invalid session"})
-			if err != nil {
-				log.Printf("writing json in postManualPayment failed with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
+		if balance.Outstanding <= 0 {
+			log.Printf("manual payment requested for booking %d in postManualPayment with nothing outstanding", booking.ID)
+			writePaymentConflict(w, "Booking has nothing outstanding")
 			return
-		} else {
-			session, err := qtx.GetSessionByToken(ctx, token)
-			if err != nil {
-				log.Printf("getting session by token in postManualPayment failed with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-
-			approver, err := qtx.GetUserById(ctx, session.UserID)
-			if err != nil {
-				log.Printf("getting user by id for user in postManualPayment failed with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-
-			userRoles, err := qtx.GetRolesForUser(ctx, approver.ID)
-			if err != nil {
-				log.Printf("getting user roles by id for user in postManualPayment failed with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
+		}
 
-			isAdmin := slices.Contains(userRoles, RoleAdmin)
+		err = paymentRequest.validate(balance.Outstanding)
+		if err != nil {
+			log.Printf("invalid manual payment for booking %d in postManualPayment: %v", booking.ID, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-			if !isAdmin {
-				log.Printf("user %d has requested to post a manual payment and doesnt have permission to", approver.ID)
-				w.WriteHeader(http.StatusUnauthorized)
-				return
-			}
+		payment, err := qtx.CreateManualPayment(ctx, db.CreateManualPaymentParams{
+			BookingID: booking.ID,
+			Amount:    paymentRequest.amountFor(balance.Outstanding),
+			Currency:  pp.Currency,
+			CreatedBy: principal.Email,
+			Method:    pgtype.Text{String: paymentRequest.Method, Valid: true},
+			Reference: pgtype.Text{String: paymentRequest.Reference, Valid: paymentRequest.Reference != ""},
+		})
+		if err != nil {
+			log.Printf("recording manual payment failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 
-			err = qtx.PostManualPayment(ctx, int32(booking_id))
-			if err != nil {
-				log.Printf("posting manual payment failed with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-			err = tx.Commit(ctx)
-			if err != nil {
-				log.Printf("error commiting tx in postManualPayment: %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
+		entry := ledgerEntry(payment.BookingID, db.LedgerEntryKindPayment, payment.Amount, principal.Email, "")
+		entry.PaymentID = pgtype.Int4{Int32: payment.ID, Valid: true}
+		_, err = addLedgerEntries(ctx, qtx, payment.BookingID, entry)
+		if err != nil {
+			log.Printf("posting manual payment failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Printf("error commiting tx in postManualPayment: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 
+		err = json.NewEncoder(w).Encode(responseFromDBPayment(payment, loc))
+		if err != nil {
+			log.Printf("error encoding json in postManualPayment: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 	}
 }
 
-func postManualStatus(pool *pgxpool.Pool, ctx context.Context, a *AuthParams, newStatus db.BookingStatus) http.HandlerFunc {
+// postManualStatus moves a booking to newStatus. Cancelling a booking or marking
+// it a no show keeps the fee its cancellation policy sets and refunds the rest of
+// what was paid, see settleByPolicy. Admins cancel for the business, which keeps
+// no fee, unless by_customer=true in the query string says they are cancelling
+// for the customer. Cancelling also gives its slots to the next customer on the
+// waitlist for them, see promoteWaitlist. A booking that takes a deposit cant be
+// confirmed until the deposit is paid.
+func postManualStatus(pool *pgxpool.Pool, ctx context.Context, newStatus db.BookingStatus, wp WaitlistParams, pp PaymentParams, notifier BookingNotifier) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := r.PathValue("booking_id")
 		if id == "" {
@@ -701,9 +995,25 @@ func postManualStatus(pool *pgxpool.Pool, ctx context.Context, a *AuthParams, ne
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
+
+		approver, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
+		byCustomer := !approver.IsAdmin()
+		if c := r.URL.Query().Get("by_customer"); c != "" && approver.IsAdmin() {
+			byCustomer, err = strconv.ParseBool(c)
+			if err != nil {
+				log.Printf("invalid by_customer %q in postManualStatus", c)
+				http.Error(w, fmt.Sprintf("by_customer %q is not true or false", c), http.StatusBadRequest)
+				return
+			}
+		}
+
 		conn, err := pool.Acquire(ctx)
 		if err != nil {
-			log.Printf("error aquiring pool in deleteBooking: %v", err)
+			log.Printf("error aquiring pool in postManualStatus: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -711,7 +1021,7 @@ func postManualStatus(pool *pgxpool.Pool, ctx context.Context, a *AuthParams, ne
 
 		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
 		if err != nil {
-			log.Printf("error beginning tx in deleteBooking: %v", err)
+			log.Printf("error beginning tx in postManualStatus: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -726,121 +1036,107 @@ func postManualStatus(pool *pgxpool.Pool, ctx context.Context, a *AuthParams, ne
 		queries := db.New(conn)
 		qtx := queries.WithTx(tx)
 
-		token, err := ReadEncryptedCookie(r, a.CParams.Name, a.SecretKey)
-		if err != nil {
-			log.Printf("The token provided failed in postManualStatus: %v", token)
-			w.WriteHeader(http.StatusUnauthorized)
-			err = json.NewEncoder(w).Encode(ErrorResponse{Message: "Invalid session"})
-			if err != nil {
-				log.Printf("encoding response in postManualStatus failed with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
+		booking, err := qtx.GetBookingWithJoin(ctx, int32(booking_id))
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("getting booking by id with join for booking_id in postManualStatus failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("booking id: %d was requested in postManualStatus and does not exist", booking_id)
+			w.WriteHeader(http.StatusNotFound)
 			return
 		}
 
-		valid, err := VerifySession(ctx, qtx, token)
+		err = checkTransition(approver, booking.Status, newStatus, booking.UserID)
 		if err != nil {
-			log.Printf("verifying session in postManualStatus failed with %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
+			log.Printf("user %d requested to move booking %d from %s to %s in postManualStatus: %v", approver.UserID, booking_id, booking.Status, newStatus, err)
+			writeTransitionError(w, err, approver, booking.Status, booking.UserID)
 			return
 		}
 
-		if !valid {
-			w.WriteHeader(http.StatusUnauthorized)
-			err = json.NewEncoder(w).Encode(ErrorResponse{Message: "Invalid session"})
+		if newStatus == db.BookingStatusConfirmed {
+			current, err := qtx.GetBookingById(ctx, int32(booking_id))
 			if err != nil {
-				log.Printf("writing json in postManualStatus failed with %v", err)
+				log.Printf("getting booking deposit in postManualStatus failed with %v", err)
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
-			return
-		} else {
-			session, err := qtx.GetSessionByToken(ctx, token)
+			balance, err := bookingBalance(ctx, qtx, int32(booking_id))
 			if err != nil {
-				log.Printf("getting session by token in postManualStatus failed with %v", err)
+				log.Printf("getting balance in postManualStatus failed with %v", err)
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
-
-			approver, err := qtx.GetUserByIdWithRoles(ctx, session.UserID)
+			err = checkDepositPaid(current.Deposit, balance)
 			if err != nil {
-				log.Printf("getting user by id with roles for user in postManualStatus failed with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
+				log.Printf("booking %d was confirmed before its deposit was paid in postManualStatus", booking_id)
+				writePaymentConflict(w, "Booking deposit has not been paid")
 				return
 			}
+		}
 
-			booking, err := qtx.GetBookingWithJoin(ctx, db.GetBookingWithJoinParams{Column1: Unit, ID: int32(booking_id)})
+		if newStatus == db.BookingStatusNoShow {
+			err = checkNoShow(booking.StartTime.Time, time.Now())
 			if err != nil {
-				log.Printf("getting booking by id with join for booking_id in postManualStatus failed with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-
-			isAdmin := slices.Contains(approver.RoleNames, RoleAdmin)
-			isCorrectUser := slices.Contains(approver.RoleNames, RoleUser) && (approver.ID == booking.UserID)
-
-			if !isAdmin && !isCorrectUser {
-				log.Printf("user %d has requested to post a manual status and doesnt have permission to", approver.ID)
-				w.WriteHeader(http.StatusUnauthorized)
+				log.Printf("booking %d was marked a no show before it started in postManualStatus", booking_id)
+				w.WriteHeader(http.StatusConflict)
+				err = json.NewEncoder(w).Encode(ErrorResponse{Message: "Booking has not started yet"})
+				if err != nil {
+					log.Printf("encoding no show response in postManualStatus failed with %v", err)
+				}
 				return
 			}
+		}
 
-			err = qtx.UpdateBookingStatus(ctx, db.UpdateBookingStatusParams{
-				ID:              int32(booking_id),
-				Status:          newStatus,
-				StatusUpdatedBy: approver.Email,
-			})
+		// the slots have to be read before cancelling frees them
+		var freedSlots []int32
+		if newStatus == db.BookingStatusCancelled {
+			freedSlots, err = qtx.GetBookingSlotIds(ctx, int32(booking_id))
 			if err != nil {
-				log.Printf("updating booking status failed with %v", err)
+				log.Printf("getting booking slots in postManualStatus failed with %v", err)
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
+		}
 
-			bookingRow, err := qtx.GetBookingWithJoin(ctx, db.GetBookingWithJoinParams{
-				Column1: Unit,
-				ID:      int32(booking_id),
-			})
-
-			if err != nil {
-				log.Printf("getting booking data in post manual status with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
+		err = setBookingStatus(ctx, qtx, int32(booking_id), newStatus, approver.Email, "")
+		if err != nil {
+			log.Printf("setting booking status in postManualStatus failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 
-			err = qtx.CreateBookingHistory(ctx, db.CreateBookingHistoryParams{
-				BookingID:       bookingRow.ID,
-				EmployeeID:      bookingRow.EmployeeID,
-				EmployeeName:    bookingRow.EmployeeName,
-				EmployeeSurname: bookingRow.EmployeeSurname,
-				EmployeeEmail:   bookingRow.EmployeeEmail,
-				StartTime:       bookingRow.StartTime,
-				EndTime:         bookingRow.EndTime,
-				Status:          newStatus,
-				ChangedByEmail:  bookingRow.StatusUpdatedBy,
-			})
-			if err != nil {
-				log.Printf("creating booking history in post manual status with %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
+		switch {
+		case newStatus == db.BookingStatusCancelled && !byCustomer:
+			err = settleCancellation(ctx, qtx, pp, int32(booking_id), 0, approver.Email, cancelledReason)
+		case newStatus == db.BookingStatusCancelled || newStatus == db.BookingStatusNoShow:
+			err = settleByPolicy(ctx, qtx, pp, int32(booking_id), newStatus, booking.StartTime.Time, approver.Email)
+		}
+		if err != nil {
+			log.Printf("settling %s booking in postManualStatus failed with %v", newStatus, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 
-			if newStatus == db.BookingStatusCancelled {
-				err = qtx.FreeAvailabilitySlot(ctx, int32(booking_id))
-				if err != nil {
-					log.Printf("freeing availability slots failed with %v", err)
-					w.WriteHeader(http.StatusInternalServerError)
-					return
-				}
-			}
-			err = tx.Commit(ctx)
-			if err != nil {
-				log.Printf("error commiting tx in postManualStatus: %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
+		promotion, err := promoteWaitlist(ctx, qtx, freedSlots, wp, approver.Email)
+		if err != nil {
+			log.Printf("promoting the waitlist in postManualStatus failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Printf("error commiting tx in postManualStatus: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+
+		sendPaymentRefunds(ctx, pool, pp)
+
+		if promotion != nil {
+			notifyWaitlist(ctx, notifier, []WaitlistPromotion{*promotion})
+		}
 	}
 }
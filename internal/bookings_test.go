@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBookingUserID(t *testing.T) {
+	t.Run("user books for themselves", func(t *testing.T) {
+		t.Parallel()
+		p := Principal{UserID: 2, Roles: []string{RoleUser}}
+		assert.Equal(t, int32(2), bookingUserID(p, 3))
+		assert.Equal(t, int32(2), bookingUserID(p, 0))
+	})
+
+	t.Run("admin books on behalf", func(t *testing.T) {
+		t.Parallel()
+		p := Principal{UserID: 1, Roles: []string{RoleAdmin}}
+		assert.Equal(t, int32(3), bookingUserID(p, 3))
+		assert.Equal(t, int32(1), bookingUserID(p, 0))
+	})
+}
+
+func TestRestrictEdit(t *testing.T) {
+	existing := db.GetBookingWithJoinRow{
+		ID:     5,
+		UserID: 2,
+		TypeID: 1,
+		Cost:   2400,
+		Paid:   true,
+	}
+	req := PutBookingRequest{
+		UserID: 3,
+		TypeID: 4,
+		Notes:  pgtype.Text{String: "new notes", Valid: true},
+		Cost:   0,
+	}
+
+	t.Run("owner can only change notes", func(t *testing.T) {
+		t.Parallel()
+		p := Principal{UserID: 2, Roles: []string{RoleUser}}
+		expected := PutBookingRequest{
+			UserID: 2,
+			TypeID: 1,
+			Notes:  pgtype.Text{String: "new notes", Valid: true},
+			Cost:   2400,
+		}
+		assert.Equal(t, expected, req.restrictEdit(p, existing))
+	})
+
+	t.Run("admin can change anything", func(t *testing.T) {
+		t.Parallel()
+		p := Principal{UserID: 1, Roles: []string{RoleAdmin}}
+		assert.Equal(t, req, req.restrictEdit(p, existing))
+	})
+}
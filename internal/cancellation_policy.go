@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+)
+
+var ErrNotStarted = errors.New("booking has not started yet")
+
+// the reasons given in the ledger when a policy keeps a fee
+const (
+	lateCancelledReason = "cancelled late"
+	noShowReason        = "no show"
+)
+
+// CancellationPolicy is what a customer pays when they cancel late or dont turn
+// up, as a percentage of what the booking was charged. Cancelling more than
+// FreeCancellationHours before the start is free.
+type CancellationPolicy struct {
+	FreeCancellationHours   int32 `json:"free_cancellation_hours"`
+	LateCancellationPercent int32 `json:"late_cancellation_percent"`
+	NoShowPercent           int32 `json:"no_show_percent"`
+}
+
+func (p CancellationPolicy) validate() error {
+	if p.FreeCancellationHours < 0 {
+		return errors.New("free cancellation hours can not be negative")
+	}
+	if p.LateCancellationPercent < 0 || p.LateCancellationPercent > 100 || p.NoShowPercent < 0 || p.NoShowPercent > 100 {
+		return errors.New("cancellation fees must be between 0 and 100 percent")
+	}
+	return nil
+}
+
+func policyFromDBBookingType(bookingType db.BookingType) CancellationPolicy {
+	return CancellationPolicy{
+		FreeCancellationHours:   bookingType.FreeCancellationHours,
+		LateCancellationPercent: bookingType.LateCancellationPercent,
+		NoShowPercent:           bookingType.NoShowPercent,
+	}
+}
+
+func policyFromDBBooking(booking db.GetBookingByIdRow) CancellationPolicy {
+	return CancellationPolicy{
+		FreeCancellationHours:   booking.FreeCancellationHours,
+		LateCancellationPercent: booking.LateCancellationPercent,
+		NoShowPercent:           booking.NoShowPercent,
+	}
+}
+
+// feePercent is the percentage of its charge a booking starting at start keeps
+// when it moves to status at now, and the reason to give for it in the ledger
+func (p CancellationPolicy) feePercent(status db.BookingStatus, start time.Time, now time.Time) (int32, string) {
+	switch status {
+	case db.BookingStatusNoShow:
+		return p.NoShowPercent, noShowReason
+	case db.BookingStatusCancelled:
+		late := start.Before(now.Add(time.Duration(p.FreeCancellationHours) * time.Hour))
+		if late && p.LateCancellationPercent > 0 {
+			return p.LateCancellationPercent, lateCancelledReason
+		}
+	}
+	return 0, cancelledReason
+}
+
+// checkNoShow returns ErrNotStarted if a booking starting at start cant be
+// marked as a no show at now
+func checkNoShow(start time.Time, now time.Time) error {
+	if start.After(now) {
+		return ErrNotStarted
+	}
+	return nil
+}
+
+// settleByPolicy settles a booking starting at start that the customer has
+// cancelled or not turned up to, keeping the fee the policy it was booked under
+// sets. Bookings cancelled by the business go through settleCancellation with
+// no fee instead.
+func settleByPolicy(ctx context.Context, queries *db.Queries, pp PaymentParams, bookingID int32, status db.BookingStatus, start time.Time, changedBy string) error {
+	booking, err := queries.GetBookingById(ctx, bookingID)
+	if err != nil {
+		return err
+	}
+	percent, reason := policyFromDBBooking(booking).feePercent(status, start, time.Now())
+	return settleCancellation(ctx, queries, pp, bookingID, percent, changedBy, reason)
+}
@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCancellationPolicyValidate(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+		assert.NoError(t, CancellationPolicy{}.validate())
+		assert.NoError(t, CancellationPolicy{FreeCancellationHours: 24, LateCancellationPercent: 50, NoShowPercent: 100}.validate())
+	})
+
+	t.Run("negative hours", func(t *testing.T) {
+		t.Parallel()
+		assert.Error(t, CancellationPolicy{FreeCancellationHours: -1}.validate())
+	})
+
+	t.Run("percent out of range", func(t *testing.T) {
+		t.Parallel()
+		assert.Error(t, CancellationPolicy{LateCancellationPercent: 101}.validate())
+		assert.Error(t, CancellationPolicy{NoShowPercent: -5}.validate())
+	})
+}
+
+func TestFeePercent(t *testing.T) {
+	policy := CancellationPolicy{FreeCancellationHours: 24, LateCancellationPercent: 50, NoShowPercent: 100}
+	now := time.Date(2025, 9, 8, 12, 0, 0, 0, time.UTC)
+
+	t.Run("cancelled in good time", func(t *testing.T) {
+		t.Parallel()
+		percent, reason := policy.feePercent(db.BookingStatusCancelled, now.Add(48*time.Hour), now)
+		assert.Equal(t, int32(0), percent)
+		assert.Equal(t, cancelledReason, reason)
+	})
+
+	t.Run("cancelled exactly at the cut off", func(t *testing.T) {
+		t.Parallel()
+		percent, _ := policy.feePercent(db.BookingStatusCancelled, now.Add(24*time.Hour), now)
+		assert.Equal(t, int32(0), percent)
+	})
+
+	t.Run("cancelled late", func(t *testing.T) {
+		t.Parallel()
+		percent, reason := policy.feePercent(db.BookingStatusCancelled, now.Add(3*time.Hour), now)
+		assert.Equal(t, int32(50), percent)
+		assert.Equal(t, lateCancelledReason, reason)
+	})
+
+	t.Run("no show", func(t *testing.T) {
+		t.Parallel()
+		percent, reason := policy.feePercent(db.BookingStatusNoShow, now.Add(-time.Hour), now)
+		assert.Equal(t, int32(100), percent)
+		assert.Equal(t, noShowReason, reason)
+	})
+
+	t.Run("free policy", func(t *testing.T) {
+		t.Parallel()
+		percent, reason := CancellationPolicy{}.feePercent(db.BookingStatusCancelled, now.Add(time.Hour), now)
+		assert.Equal(t, int32(0), percent)
+		assert.Equal(t, cancelledReason, reason)
+	})
+}
+
+func TestCheckNoShow(t *testing.T) {
+	now := time.Date(2025, 9, 8, 12, 0, 0, 0, time.UTC)
+
+	t.Run("started", func(t *testing.T) {
+		t.Parallel()
+		assert.NoError(t, checkNoShow(now.Add(-time.Minute), now))
+		assert.NoError(t, checkNoShow(now, now))
+	})
+
+	t.Run("not started", func(t *testing.T) {
+		t.Parallel()
+		assert.ErrorIs(t, checkNoShow(now.Add(time.Minute), now), ErrNotStarted)
+	})
+}
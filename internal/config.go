@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// DefaultUnit is the number of minutes per slot when SLOT_UNIT_MINUTES isnt set.
+// Booking types created without a unit use the configured one and every slot
+// keeps the unit it was created with.
+const DefaultUnit = 30
+
+// validUnit checks that unit splits an hour into whole slots, which keeps slots
+// of one unit lined up on the hour
+func validUnit(unit int32) error {
+	if unit <= 0 || 60%unit != 0 {
+		return fmt.Errorf("unit of %d minutes does not divide an hour", unit)
+	}
+	return nil
+}
+
+// validDuration checks that a duration in minutes is a whole number of units,
+// it is stored as a count of slots so anything else would be cut short
+func validDuration(duration int32, unit int32) error {
+	if duration < 0 {
+		return fmt.Errorf("duration of %d minutes can not be negative", duration)
+	}
+	if duration%unit != 0 {
+		return fmt.Errorf("duration of %d minutes is not a whole number of %d minute slots", duration, unit)
+	}
+	return nil
+}
+
+// parseUnit reads a unit in minutes from configuration, defaulting to DefaultUnit
+func parseUnit(value string) (int32, error) {
+	if value == "" {
+		return DefaultUnit, nil
+	}
+	unit, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("unit %q is not a whole number of minutes", value)
+	}
+	return int32(unit), validUnit(int32(unit))
+}
+
+type GetConfigResponse struct {
+	UnitMinutes int32 `json:"unit_minutes"`
+}
+
+func getConfig(unit int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := json.NewEncoder(w).Encode(GetConfigResponse{UnitMinutes: unit})
+		if err != nil {
+			log.Printf("error encoding json in getConfig: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
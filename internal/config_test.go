@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUnit(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		t.Parallel()
+		unit, err := parseUnit("")
+		assert.NoError(t, err)
+		assert.Equal(t, int32(DefaultUnit), unit)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+		for _, value := range []string{"5", "15", "20", "60"} {
+			_, err := parseUnit(value)
+			assert.NoError(t, err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		for _, value := range []string{"0", "-30", "45", "90", "half an hour"} {
+			_, err := parseUnit(value)
+			assert.Error(t, err)
+		}
+	})
+}
+
+func TestBookingTypeToDBParams(t *testing.T) {
+	t.Run("default unit", func(t *testing.T) {
+		t.Parallel()
+		r := PostBookingTypeRequest{Title: "haircut", Cost: 2400, Duration: 60}
+		params, err := r.ToDBParams(30)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(2), params.Duration)
+		assert.Equal(t, int32(30), params.UnitMinutes)
+	})
+
+	t.Run("own unit", func(t *testing.T) {
+		t.Parallel()
+		r := PostBookingTypeRequest{Title: "haircut", Cost: 2400, Duration: 60, UnitMinutes: 15}
+		params, err := r.ToDBParams(30)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(4), params.Duration)
+		assert.Equal(t, int32(15), params.UnitMinutes)
+	})
+
+	t.Run("keeps the current unit on update", func(t *testing.T) {
+		t.Parallel()
+		r := PutBookingTypeRequest{Title: "haircut", Cost: 2400, Duration: 60}
+		params, err := r.ToDBParams(4, 20)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(3), params.Duration)
+		assert.Equal(t, int32(20), params.UnitMinutes)
+	})
+
+	t.Run("invalid unit", func(t *testing.T) {
+		t.Parallel()
+		r := PostBookingTypeRequest{Title: "haircut", UnitMinutes: 45}
+		_, err := r.ToDBParams(30)
+		assert.Error(t, err)
+	})
+
+	t.Run("duration not a whole number of units", func(t *testing.T) {
+		t.Parallel()
+		_, err := PostBookingTypeRequest{Title: "haircut", Duration: 45}.ToDBParams(30)
+		assert.Error(t, err)
+		_, err = PutBookingTypeRequest{Title: "haircut", Duration: 50}.ToDBParams(4, 20)
+		assert.Error(t, err)
+		_, err = PostBookingTypeRequest{Title: "haircut", Duration: -30}.ToDBParams(30)
+		assert.Error(t, err)
+	})
+
+	t.Run("buffers and notice", func(t *testing.T) {
+		t.Parallel()
+		r := PostBookingTypeRequest{Title: "haircut", Duration: 60, BufferAfterMinutes: 15, MinNoticeMinutes: 120, MaxAdvanceDays: 30}
+		params, err := r.ToDBParams(30)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(15), params.BufferAfterMinutes)
+		assert.Equal(t, int32(120), params.MinNoticeMinutes)
+		assert.Equal(t, int32(30), params.MaxAdvanceDays)
+	})
+
+	t.Run("invalid buffer", func(t *testing.T) {
+		t.Parallel()
+		r := PutBookingTypeRequest{Title: "haircut", Duration: 60, BufferBeforeMinutes: -10}
+		_, err := r.ToDBParams(4, 30)
+		assert.Error(t, err)
+	})
+}
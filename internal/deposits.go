@@ -0,0 +1,204 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrDepositUnpaid = errors.New("booking deposit has not been paid")
+
+// DefaultDepositWindowHours is how long a customer has to pay a deposit when the
+// booking type doesnt say
+const DefaultDepositWindowHours = 24
+
+// depositUnpaidReason is given to customers whose bookings are cancelled for
+// not paying the deposit in time
+const depositUnpaidReason = "deposit not paid"
+
+// BookingDeposit is what a booking type takes up front, either Percent of the
+// cost or a fixed Amount. It has to be paid within WindowHours of booking, or
+// before the booking starts if that is sooner, or the booking is cancelled.
+type BookingDeposit struct {
+	Percent     int32 `json:"percent"`
+	Amount      int32 `json:"amount"`
+	WindowHours int32 `json:"window_hours"` // optional, defaults to DefaultDepositWindowHours
+}
+
+func (d BookingDeposit) validate() error {
+	if d.Percent < 0 || d.Percent > 100 {
+		return errors.New("deposit percent must be between 0 and 100")
+	}
+	if d.Amount < 0 {
+		return errors.New("deposit amount can not be negative")
+	}
+	if d.Percent > 0 && d.Amount > 0 {
+		return errors.New("a deposit is either a percent or an amount, not both")
+	}
+	if d.WindowHours < 0 {
+		return errors.New("deposit window can not be negative")
+	}
+	return nil
+}
+
+// windowHours is WindowHours or the default when it isnt set
+func (d BookingDeposit) windowHours() int32 {
+	if d.WindowHours == 0 {
+		return DefaultDepositWindowHours
+	}
+	return d.WindowHours
+}
+
+func depositFromDBBookingType(bookingType db.BookingType) BookingDeposit {
+	return BookingDeposit{
+		Percent:     bookingType.DepositPercent,
+		Amount:      bookingType.DepositAmount,
+		WindowHours: bookingType.DepositWindowHours,
+	}
+}
+
+// amountFor is the deposit on a booking costing cost, never more than the cost
+func (d BookingDeposit) amountFor(cost int32) int32 {
+	if d.Amount > 0 {
+		return min(d.Amount, cost)
+	}
+	return max(cost, 0) * d.Percent / 100
+}
+
+// dueAt is when the deposit of a booking starting at start made at now has to
+// be paid by
+func (d BookingDeposit) dueAt(start time.Time, now time.Time) time.Time {
+	due := now.Add(time.Duration(d.windowHours()) * time.Hour)
+	if start.Before(due) {
+		return start
+	}
+	return due
+}
+
+// setDeposit sets the deposit the type of a new booking asks for on it, nothing
+// is set when the type takes no deposit
+func setDeposit(ctx context.Context, queries *db.Queries, bookingType db.BookingType, booking db.CreateBookingRow, cost int32, now time.Time) error {
+	deposit := depositFromDBBookingType(bookingType)
+	amount := deposit.amountFor(cost)
+	if amount == 0 {
+		return nil
+	}
+	return queries.SetBookingDeposit(ctx, db.SetBookingDepositParams{
+		ID:           booking.BookingID,
+		Deposit:      amount,
+		DepositDueAt: pgtype.Timestamptz{Time: deposit.dueAt(booking.StartTime.Time, now), Valid: true},
+	})
+}
+
+// checkDepositPaid returns ErrDepositUnpaid if less than deposit has been paid
+// towards balance
+func checkDepositPaid(deposit int32, balance LedgerBalance) error {
+	if balance.Paid < deposit {
+		return ErrDepositUnpaid
+	}
+	return nil
+}
+
+// cancelUnpaidDeposits cancels the bookings whose deposits werent paid in time,
+// freeing their slots for the waitlist and queueing refunds of anything paid
+// towards them. It returns the cancelled bookings as they were and the waitlist
+// promotions made.
+func cancelUnpaidDeposits(ctx context.Context, pool *pgxpool.Pool, wp WaitlistParams, pp PaymentParams) ([]db.GetBookingWithJoinRow, []WaitlistPromotion, error) {
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			panic(err)
+		}
+	}()
+
+	qtx := db.New(pool).WithTx(tx)
+
+	unpaid, err := qtx.GetUnpaidDepositBookings(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cancelled := []db.GetBookingWithJoinRow{}
+	promotions := []WaitlistPromotion{}
+	for _, id := range unpaid {
+		// a deposit captured just before the booking was locked is only seen now
+		current, err := qtx.GetBookingById(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		balance, err := bookingBalance(ctx, qtx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if checkDepositPaid(current.Deposit, balance) == nil {
+			continue
+		}
+
+		booking, err := qtx.GetBookingWithJoin(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		freedSlots, err := qtx.GetBookingSlotIds(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		err = setBookingStatus(ctx, qtx, id, db.BookingStatusCancelled, "system", depositUnpaidReason)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = settleCancellation(ctx, qtx, pp, id, 0, "system", depositUnpaidReason)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		promotion, err := promoteWaitlist(ctx, qtx, freedSlots, wp, "system")
+		if err != nil {
+			return nil, nil, err
+		}
+		cancelled = append(cancelled, booking)
+		if promotion != nil {
+			promotions = append(promotions, *promotion)
+		}
+	}
+
+	return cancelled, promotions, tx.Commit(ctx)
+}
+
+// reapUnpaidDeposits cancels bookings with overdue deposits every interval until
+// ctx is done, sending their refunds and telling the customers and anyone
+// promoted from the waitlist
+func reapUnpaidDeposits(ctx context.Context, pool *pgxpool.Pool, wp WaitlistParams, pp PaymentParams, notifier BookingNotifier, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cancelled, promotions, err := cancelUnpaidDeposits(ctx, pool, wp, pp)
+			if err != nil {
+				log.Printf("cancelling bookings with unpaid deposits failed with %v", err)
+				continue
+			}
+			if len(cancelled) > 0 {
+				log.Printf("cancelled %d bookings with unpaid deposits", len(cancelled))
+			}
+			sendPaymentRefunds(ctx, pool, pp)
+			notifyCancelled(ctx, notifier, cancelled, depositUnpaidReason)
+			notifyWaitlist(ctx, notifier, promotions)
+		}
+	}
+}
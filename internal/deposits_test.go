@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBookingDepositValidate(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+		assert.NoError(t, BookingDeposit{}.validate())
+		assert.NoError(t, BookingDeposit{Percent: 20, WindowHours: 48}.validate())
+		assert.NoError(t, BookingDeposit{Amount: 1000}.validate())
+	})
+
+	t.Run("percent out of range", func(t *testing.T) {
+		t.Parallel()
+		assert.Error(t, BookingDeposit{Percent: 101}.validate())
+		assert.Error(t, BookingDeposit{Percent: -1}.validate())
+	})
+
+	t.Run("negative amount or window", func(t *testing.T) {
+		t.Parallel()
+		assert.Error(t, BookingDeposit{Amount: -100}.validate())
+		assert.Error(t, BookingDeposit{Amount: 100, WindowHours: -1}.validate())
+	})
+
+	t.Run("percent and amount", func(t *testing.T) {
+		t.Parallel()
+		assert.Error(t, BookingDeposit{Percent: 20, Amount: 1000}.validate())
+	})
+}
+
+func TestDepositAmountFor(t *testing.T) {
+	t.Run("none", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, int32(0), BookingDeposit{}.amountFor(5000))
+	})
+
+	t.Run("percent", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, int32(1000), BookingDeposit{Percent: 20}.amountFor(5000))
+		assert.Equal(t, int32(333), BookingDeposit{Percent: 33}.amountFor(1010))
+	})
+
+	t.Run("fixed", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, int32(1500), BookingDeposit{Amount: 1500}.amountFor(5000))
+	})
+
+	t.Run("fixed never more than the cost", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, int32(800), BookingDeposit{Amount: 1500}.amountFor(800))
+	})
+}
+
+func TestDepositDueAt(t *testing.T) {
+	now := time.Date(2025, 9, 8, 12, 0, 0, 0, time.UTC)
+
+	t.Run("default window", func(t *testing.T) {
+		t.Parallel()
+		due := BookingDeposit{Percent: 20}.dueAt(now.AddDate(0, 0, 7), now)
+		assert.Equal(t, now.Add(DefaultDepositWindowHours*time.Hour), due)
+	})
+
+	t.Run("set window", func(t *testing.T) {
+		t.Parallel()
+		due := BookingDeposit{Percent: 20, WindowHours: 2}.dueAt(now.AddDate(0, 0, 7), now)
+		assert.Equal(t, now.Add(2*time.Hour), due)
+	})
+
+	t.Run("booking starts first", func(t *testing.T) {
+		t.Parallel()
+		start := now.Add(3 * time.Hour)
+		assert.Equal(t, start, BookingDeposit{Percent: 20}.dueAt(start, now))
+	})
+}
+
+func TestCheckDepositPaid(t *testing.T) {
+	t.Run("no deposit", func(t *testing.T) {
+		t.Parallel()
+		assert.NoError(t, checkDepositPaid(0, LedgerBalance{Charged: 5000, Outstanding: 5000}))
+	})
+
+	t.Run("paid", func(t *testing.T) {
+		t.Parallel()
+		assert.NoError(t, checkDepositPaid(1000, LedgerBalance{Charged: 5000, Paid: 1000, Outstanding: 4000}))
+	})
+
+	t.Run("unpaid", func(t *testing.T) {
+		t.Parallel()
+		assert.ErrorIs(t, checkDepositPaid(1000, LedgerBalance{Charged: 5000, Paid: 500, Outstanding: 4500}), ErrDepositUnpaid)
+	})
+}
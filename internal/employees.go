@@ -16,14 +16,15 @@ import (
 )
 
 type GetEmployeeResponse struct {
-	EmployeeID  int32            `json:"employee_id"`
-	Name        string           `json:"name"`
-	Surname     string           `json:"surname"`
-	Email       string           `json:"email"`
-	Title       string           `json:"title"`
-	Description string           `json:"description"`
-	CreatedAt   pgtype.Timestamp `json:"created_at"`
-	LastLogin   pgtype.Timestamp `json:"last_login"`
+	EmployeeID  int32              `json:"employee_id"`
+	Name        string             `json:"name"`
+	Surname     string             `json:"surname"`
+	Email       string             `json:"email"`
+	Title       string             `json:"title"`
+	Description string             `json:"description"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	LastLogin   pgtype.Timestamptz `json:"last_login"`
+	TimeZone    string             `json:"time_zone"`
 }
 
 func responseFromDBEmployee(employee db.Employee) GetEmployeeResponse {
@@ -36,6 +37,7 @@ func responseFromDBEmployee(employee db.Employee) GetEmployeeResponse {
 		Description: employee.Description,
 		CreatedAt:   employee.CreatedAt,
 		LastLogin:   employee.LastLogin,
+		TimeZone:    employee.TimeZone,
 	}
 }
 
@@ -45,6 +47,7 @@ type PostEmployeeRequest struct {
 	Email       string `json:"email"`
 	Title       string `json:"title"`
 	Description string `json:"description"`
+	TimeZone    string `json:"time_zone"`
 }
 
 func (p PostEmployeeRequest) ToDBParams() db.CreateEmployeeParams {
@@ -54,6 +57,7 @@ func (p PostEmployeeRequest) ToDBParams() db.CreateEmployeeParams {
 		Email:       p.Email,
 		Title:       p.Title,
 		Description: p.Description,
+		TimeZone:    p.TimeZone,
 	}
 }
 
@@ -67,6 +71,7 @@ type PutEmployeeRequest struct {
 	Email       string `json:"email"`
 	Title       string `json:"title"`
 	Description string `json:"description"`
+	TimeZone    string `json:"time_zone"`
 }
 type PutEmployeeResponse struct {
 	EmployeeID int32 `json:"employee_id"`
@@ -80,6 +85,7 @@ func (r PutEmployeeRequest) ToDBParams(employeeID int32) db.UpdateEmployeeParams
 		Email:       r.Email,
 		Title:       r.Title,
 		Description: r.Description,
+		TimeZone:    r.TimeZone,
 	}
 }
 func postEmployee(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
@@ -97,6 +103,13 @@ func postEmployee(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 			return
 		}
 
+		employeeRequest.TimeZone, err = normaliseTimeZone(employeeRequest.TimeZone)
+		if err != nil {
+			log.Printf("invalid time zone in postEmployee: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
 		conn, err := pool.Acquire(ctx)
 		if err != nil {
 			log.Printf("error aquiring pool in postEmployee: %v", err)
@@ -248,6 +261,13 @@ func putEmployee(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 			return
 		}
 
+		employeeRequest.TimeZone, err = normaliseTimeZone(employeeRequest.TimeZone)
+		if err != nil {
+			log.Printf("invalid time zone in putEmployee: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
 		conn, err := pool.Acquire(ctx)
 		if err != nil {
 			log.Printf("error aquiring pool in putEmployee: %v", err)
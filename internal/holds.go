@@ -0,0 +1,342 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrUnknownSlots = errors.New("one or more availability slots do not exist")
+
+type PostSlotHoldRequest struct {
+	AvailabilitySlots []int32 `json:"availability_slots"`
+}
+
+type PostSlotHoldResponse struct {
+	HoldID            int32     `json:"hold_id"`
+	AvailabilitySlots []int32   `json:"availability_slots"`
+	ExpiresAt         time.Time `json:"expires_at"`
+	TTLSeconds        int       `json:"ttl_seconds"`
+}
+
+// SlotConflictResponse is returned with a 409 when some of the requested slots
+// are already booked or held by somebody else
+type SlotConflictResponse struct {
+	Message      string  `json:"message"`
+	TakenSlotIDs []int32 `json:"taken_slot_ids"`
+}
+
+func writeSlotConflict(w http.ResponseWriter, message string, taken []int32) {
+	w.WriteHeader(http.StatusConflict)
+	err := json.NewEncoder(w).Encode(SlotConflictResponse{
+		Message:      message,
+		TakenSlotIDs: taken,
+	})
+	if err != nil {
+		log.Printf("encoding slot conflict response failed with %v", err)
+	}
+}
+
+// claimSlots locks slotIDs for the rest of the transaction and returns the ones
+// that have no seat left that isnt booked or held by a hold other than holdID,
+// or that fall in time off or a closure. Pass a holdID of 0 to count every live
+// hold.
+// Locking the slots first means that two concurrent requests for the same slot
+// are serialised and the second one sees the booking or hold made by the first.
+// Their employees are locked before them, the order availability edits take
+// the same locks in, so a booking and an edit for the same employee cant
+// deadlock.
+func claimSlots(ctx context.Context, qtx *db.Queries, slotIDs []int32, holdID int32) ([]int32, error) {
+	_, err := qtx.LockSlotEmployees(ctx, slotIDs)
+	if err != nil {
+		return nil, err
+	}
+	locked, err := qtx.LockAvailabilitySlots(ctx, slotIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range slotIDs {
+		if !slices.Contains(locked, id) {
+			return nil, ErrUnknownSlots
+		}
+	}
+
+	return qtx.GetTakenSlotIds(ctx, db.GetTakenSlotIdsParams{
+		Column1: slotIDs,
+		ID:      holdID,
+	})
+}
+
+// sameSlots reports whether a and b contain the same slot ids in any order
+func sameSlots(a []int32, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := slices.Clone(a)
+	sortedB := slices.Clone(b)
+	slices.Sort(sortedA)
+	slices.Sort(sortedB)
+	return slices.Equal(sortedA, sortedB)
+}
+
+// checkSlotHold checks that holdID is a live hold of userID on slotIDs and
+// returns the slots to book, the held ones when slotIDs is empty. It writes
+// the response and returns false when the hold cant be used.
+func checkSlotHold(w http.ResponseWriter, ctx context.Context, qtx *db.Queries, principal Principal, holdID int32, userID int32, slotIDs []int32, handler string) ([]int32, bool) {
+	hold, err := qtx.GetSlotHoldById(ctx, holdID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("error getting hold in %s: %v", handler, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, false
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("hold id: %d was requested in %s and does not exist", holdID, handler)
+		writeSlotConflict(w, "The hold has expired", nil)
+		return nil, false
+	}
+	if hold.UserID != userID {
+		log.Printf("user %d requested to book with hold %d which belongs to user %d", principal.UserID, hold.ID, hold.UserID)
+		writeForbidden(w)
+		return nil, false
+	}
+	isCurr, err := IsTokenCurrent(hold.ExpiresAt)
+	if err != nil {
+		log.Printf("checking hold expiry in %s failed with %v", handler, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, false
+	}
+	if !isCurr {
+		log.Printf("hold id: %d was requested in %s and has expired", hold.ID, handler)
+		writeSlotConflict(w, "The hold has expired", nil)
+		return nil, false
+	}
+
+	heldSlots, err := qtx.GetSlotHoldSlotIds(ctx, hold.ID)
+	if err != nil {
+		log.Printf("error getting held slots in %s: %v", handler, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, false
+	}
+	if len(slotIDs) == 0 {
+		return heldSlots, true
+	}
+	if !sameSlots(slotIDs, heldSlots) {
+		log.Printf("requested slots %v in %s dont match held slots %v", slotIDs, handler, heldSlots)
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, false
+	}
+	return slotIDs, true
+}
+
+func postSlotHold(pool *pgxpool.Pool, ctx context.Context, ttl time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
+		loc, ok := requestLocation(w, r)
+		if !ok {
+			return
+		}
+
+		var holdRequest PostSlotHoldRequest
+
+		err := json.NewDecoder(r.Body).Decode(&holdRequest)
+		if err != nil {
+			log.Printf("error decoding body in postSlotHold: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if len(holdRequest.AvailabilitySlots) < 1 {
+			log.Printf("requested a hold with no slots")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in postSlotHold: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			log.Printf("error beginning tx in postSlotHold: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		defer func() {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				panic(err)
+			}
+		}()
+
+		queries := db.New(conn)
+		qtx := queries.WithTx(tx)
+
+		taken, err := claimSlots(ctx, qtx, holdRequest.AvailabilitySlots, 0)
+		if errors.Is(err, ErrUnknownSlots) {
+			log.Printf("hold requested for slots %v in postSlotHold that dont all exist", holdRequest.AvailabilitySlots)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Printf("claiming slots in postSlotHold failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if len(taken) > 0 {
+			log.Printf("user %d requested a hold on taken slots %v", principal.UserID, taken)
+			writeSlotConflict(w, "Some of the requested slots are no longer available", taken)
+			return
+		}
+
+		sequentialCheckSlots, err := qtx.GetAvailabilitySlotByIds(ctx, holdRequest.AvailabilitySlots)
+		if err != nil {
+			log.Printf("getting slots for sequential check failed in postSlotHold: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if !slotsSequential(sequentialCheckSlots) {
+			log.Printf("slot request is not sequential in postSlotHold")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		expiryTime := time.Now().UTC().Add(ttl)
+		hold, err := qtx.CreateSlotHold(ctx, db.CreateSlotHoldParams{
+			UserID:    principal.UserID,
+			ExpiresAt: pgtype.Timestamptz{Time: expiryTime, Valid: true},
+		})
+		if err != nil {
+			log.Printf("creating hold in postSlotHold failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		err = qtx.CreateSlotHoldSlots(ctx, db.CreateSlotHoldSlotsParams{
+			Column1: hold.ID,
+			Column2: holdRequest.AvailabilitySlots,
+		})
+		if err != nil {
+			log.Printf("creating hold slots in postSlotHold failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Printf("error commiting tx in postSlotHold: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := PostSlotHoldResponse{
+			HoldID:            hold.ID,
+			AvailabilitySlots: holdRequest.AvailabilitySlots,
+			ExpiresAt:         expiryTime.In(loc),
+			TTLSeconds:        int(ttl.Seconds()),
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			log.Printf("error encoding json in postSlotHold: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func deleteSlotHold(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
+		holdId := r.PathValue("hold_id")
+		id, err := strconv.ParseInt(holdId, 10, 32)
+		if err != nil {
+			log.Printf("error: %v converting hold id to int in deleteSlotHold: %s", err, holdId)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in deleteSlotHold: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		queries := db.New(conn)
+
+		hold, err := queries.GetSlotHoldById(ctx, int32(id))
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("getting hold in deleteSlotHold failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("hold id: %d, which does not exist, was attemped to be deleted by deleteSlotHold", id)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if !principal.CanAccessUser(hold.UserID) {
+			log.Printf("user %d requested to release hold %d and doesnt have permission to", principal.UserID, id)
+			writeForbidden(w)
+			return
+		}
+
+		_, err = queries.DeleteSlotHold(ctx, int32(id))
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("general error when trying to delete hold in deleteSlotHold: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// reapSlotHolds releases expired holds every interval until ctx is done. Expired
+// holds are already ignored when checking for conflicts, this just stops them
+// piling up.
+func reapSlotHolds(ctx context.Context, pool *pgxpool.Pool, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reaped, err := db.New(pool).DeleteExpiredSlotHolds(ctx)
+			if err != nil {
+				log.Printf("reaping expired slot holds failed with %v", err)
+				continue
+			}
+			if reaped > 0 {
+				log.Printf("released %d expired slot holds", reaped)
+			}
+		}
+	}
+}
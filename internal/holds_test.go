@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSameSlots(t *testing.T) {
+	t.Run("same order", func(t *testing.T) {
+		t.Parallel()
+		assert.True(t, sameSlots([]int32{1, 2, 3}, []int32{1, 2, 3}))
+	})
+
+	t.Run("different order", func(t *testing.T) {
+		t.Parallel()
+		a := []int32{3, 1, 2}
+		assert.True(t, sameSlots(a, []int32{1, 2, 3}))
+		assert.Equal(t, []int32{3, 1, 2}, a)
+	})
+
+	t.Run("different slots", func(t *testing.T) {
+		t.Parallel()
+		assert.False(t, sameSlots([]int32{1, 2}, []int32{1, 2, 3}))
+		assert.False(t, sameSlots([]int32{1, 2, 4}, []int32{1, 2, 3}))
+	})
+}
+
+func TestWriteSlotConflict(t *testing.T) {
+	t.Run("names taken slots", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		writeSlotConflict(w, "taken", []int32{4, 5})
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		var response SlotConflictResponse
+		err := json.NewDecoder(w.Body).Decode(&response)
+		require.NoError(t, err)
+		assert.Equal(t, SlotConflictResponse{Message: "taken", TakenSlotIDs: []int32{4, 5}}, response)
+	})
+}
@@ -0,0 +1,461 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrBookingHasPayments = errors.New("booking has payments or ledger entries")
+
+// the reasons given in the ledger for changes made by the app
+const (
+	rescheduledReason = "rescheduled"
+	cancelledReason   = "cancelled"
+	paidLateReason    = "paid after the booking was closed"
+)
+
+// LedgerBalance sums up the ledger of a booking. Paid is what has been paid
+// less what has been refunded, Outstanding is negative when the customer is
+// owed money.
+type LedgerBalance struct {
+	Charged     int32 `json:"charged"`
+	Paid        int32 `json:"paid"`
+	Outstanding int32 `json:"outstanding"`
+}
+
+func balanceFromDBRow(row db.GetBookingBalanceRow) LedgerBalance {
+	paid := row.Paid - row.Refunded
+	return LedgerBalance{
+		Charged:     row.Charged,
+		Paid:        paid,
+		Outstanding: row.Charged - paid,
+	}
+}
+
+// isPaid is whether a booking with this balance counts as paid, something has
+// been paid and there is nothing left to pay
+func (b LedgerBalance) isPaid() bool {
+	return b.Paid > 0 && b.Outstanding <= 0
+}
+
+type PostLedgerAdjustmentRequest struct {
+	Amount int32  `json:"amount"` // negative to take money off what is owed
+	Reason string `json:"reason"`
+}
+
+func (r PostLedgerAdjustmentRequest) validate() error {
+	if r.Amount == 0 {
+		return errors.New("amount must not be zero")
+	}
+	if r.Reason == "" {
+		return errors.New("reason is required for an adjustment")
+	}
+	return nil
+}
+
+type LedgerEntryResponse struct {
+	EntryID   int32              `json:"entry_id"`
+	Kind      db.LedgerEntryKind `json:"kind"`
+	Amount    int32              `json:"amount"`
+	PaymentID pgtype.Int4        `json:"payment_id"`
+	Reason    pgtype.Text        `json:"reason"`
+	CreatedBy string             `json:"created_by"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type BookingLedgerResponse struct {
+	BookingID int32                 `json:"booking_id"`
+	Entries   []LedgerEntryResponse `json:"entries"`
+	LedgerBalance
+}
+
+func responseFromDBLedgerEntry(entry db.BookingLedgerEntry, loc *time.Location) LedgerEntryResponse {
+	return LedgerEntryResponse{
+		EntryID:   entry.ID,
+		Kind:      entry.Kind,
+		Amount:    entry.Amount,
+		PaymentID: entry.PaymentID,
+		Reason:    entry.Reason,
+		CreatedBy: entry.CreatedBy,
+		CreatedAt: inLocation(entry.CreatedAt, loc),
+	}
+}
+
+func ledgerEntry(bookingID int32, kind db.LedgerEntryKind, amount int32, changedBy string, reason string) db.CreateLedgerEntryParams {
+	return db.CreateLedgerEntryParams{
+		BookingID: bookingID,
+		Kind:      kind,
+		Amount:    amount,
+		Reason:    pgtype.Text{String: reason, Valid: reason != ""},
+		CreatedBy: changedBy,
+	}
+}
+
+func bookingBalance(ctx context.Context, queries *db.Queries, bookingID int32) (LedgerBalance, error) {
+	row, err := queries.GetBookingBalance(ctx, bookingID)
+	if err != nil {
+		return LedgerBalance{}, err
+	}
+	return balanceFromDBRow(row), nil
+}
+
+// addLedgerEntries writes entries to the ledger of bookingID and keeps
+// bookings.paid in step with the new balance. Entries for nothing are skipped.
+func addLedgerEntries(ctx context.Context, queries *db.Queries, bookingID int32, entries ...db.CreateLedgerEntryParams) (LedgerBalance, error) {
+	for _, entry := range entries {
+		if entry.Amount == 0 {
+			continue
+		}
+		_, err := queries.CreateLedgerEntry(ctx, entry)
+		if err != nil {
+			return LedgerBalance{}, err
+		}
+	}
+
+	balance, err := bookingBalance(ctx, queries, bookingID)
+	if err != nil {
+		return LedgerBalance{}, err
+	}
+	err = queries.SetBookingPaid(ctx, db.SetBookingPaidParams{ID: bookingID, Paid: balance.isPaid()})
+	return balance, err
+}
+
+// chargeBooking charges a new booking its cost and sets the deposit its type
+// takes, see setDeposit
+func chargeBooking(ctx context.Context, queries *db.Queries, bookingType db.BookingType, booking db.CreateBookingRow, cost int32, changedBy string) error {
+	_, err := addLedgerEntries(ctx, queries, booking.BookingID, ledgerEntry(booking.BookingID, db.LedgerEntryKindCharge, cost, changedBy, ""))
+	if err != nil {
+		return err
+	}
+	return setDeposit(ctx, queries, bookingType, booking, cost, time.Now())
+}
+
+// adjustBookingCharge records a change in the cost of a booking from from to to
+func adjustBookingCharge(ctx context.Context, queries *db.Queries, bookingID int32, from int32, to int32, changedBy string, reason string) error {
+	_, err := addLedgerEntries(ctx, queries, bookingID, ledgerEntry(bookingID, db.LedgerEntryKindAdjustment, to-from, changedBy, reason))
+	return err
+}
+
+// recordPaymentStatus saves update to payment and puts what moved into the
+// ledger of its booking, the payment once it is captured and each refund. The
+// booking is locked first so a payment cant land while it is being cancelled.
+func recordPaymentStatus(ctx context.Context, queries *db.Queries, payment db.Payment, update db.UpdatePaymentStatusParams, changedBy string, reason string) error {
+	_, err := queries.LockBooking(ctx, payment.BookingID)
+	if err != nil {
+		return err
+	}
+	err = queries.UpdatePaymentStatus(ctx, update)
+	if err != nil {
+		return err
+	}
+
+	entries := []db.CreateLedgerEntryParams{}
+	paymentID := pgtype.Int4{Int32: payment.ID, Valid: true}
+	if payment.Status == db.PaymentStatusPending && (update.Status == db.PaymentStatusCaptured || update.Status == db.PaymentStatusRefunded) {
+		entry := ledgerEntry(payment.BookingID, db.LedgerEntryKindPayment, payment.Amount, changedBy, "")
+		entry.PaymentID = paymentID
+		entries = append(entries, entry)
+	}
+	if update.RefundedAmount > payment.RefundedAmount {
+		entry := ledgerEntry(payment.BookingID, db.LedgerEntryKindRefund, update.RefundedAmount-payment.RefundedAmount, changedBy, reason)
+		entry.PaymentID = paymentID
+		entries = append(entries, entry)
+	}
+	_, err = addLedgerEntries(ctx, queries, payment.BookingID, entries...)
+	return err
+}
+
+// refundPayment records amount of a captured payment as refunded. Refunds of
+// provider payments are queued to be sent through the provider once the
+// transaction is committed, see sendPaymentRefunds. Manual payments are only
+// recorded as refunded, the money is given back by hand.
+func refundPayment(ctx context.Context, queries *db.Queries, payment db.Payment, amount int32, changedBy string, reason string) (db.Payment, error) {
+	if payment.Provider != manualProvider {
+		_, err := queries.CreatePaymentRefund(ctx, db.CreatePaymentRefundParams{
+			PaymentID: payment.ID,
+			Amount:    amount,
+		})
+		if err != nil {
+			return payment, err
+		}
+	}
+
+	event := PaymentEvent{Status: db.PaymentStatusCaptured, RefundedAmount: payment.RefundedAmount + amount}
+	if event.RefundedAmount == payment.Amount {
+		event.Status = db.PaymentStatusRefunded
+	}
+	update, _ := applyPaymentEvent(payment, event)
+	err := recordPaymentStatus(ctx, queries, payment, update, changedBy, reason)
+	if err != nil {
+		return payment, err
+	}
+	payment.Status = update.Status
+	payment.RefundedAmount = update.RefundedAmount
+	return payment, nil
+}
+
+// refundBooking gives amount back to the customer of bookingID from its
+// payments, newest first. Whatever the payments cant cover, such as payments
+// from before the ledger, is recorded as a refund to be made by hand.
+func refundBooking(ctx context.Context, queries *db.Queries, pp PaymentParams, bookingID int32, amount int32, changedBy string, reason string) error {
+	payments, err := queries.GetBookingPayments(ctx, bookingID)
+	if err != nil {
+		return err
+	}
+	slices.Reverse(payments)
+
+	for _, payment := range payments {
+		if amount <= 0 {
+			break
+		}
+		if payment.Status != db.PaymentStatusCaptured {
+			continue
+		}
+		if payment.Provider != manualProvider && payment.Provider != pp.Provider.Name() {
+			continue
+		}
+		refund := min(amount, payment.Amount-payment.RefundedAmount)
+		_, err = refundPayment(ctx, queries, payment, refund, changedBy, reason)
+		if err != nil {
+			return err
+		}
+		amount -= refund
+	}
+
+	if amount > 0 {
+		log.Printf("%d of the refund for booking %d has to be made by hand", amount, bookingID)
+		_, err = addLedgerEntries(ctx, queries, bookingID, ledgerEntry(bookingID, db.LedgerEntryKindRefund, amount, changedBy, reason+", to be refunded by hand"))
+	}
+	return err
+}
+
+// settleCancellation settles the ledger of a cancelled or no show booking. The
+// booking stays charged feePercent of its charge and everything paid over that
+// is refunded.
+func settleCancellation(ctx context.Context, queries *db.Queries, pp PaymentParams, bookingID int32, feePercent int32, changedBy string, reason string) error {
+	balance, err := bookingBalance(ctx, queries, bookingID)
+	if err != nil {
+		return err
+	}
+	fee := max(balance.Charged, 0) * feePercent / 100
+
+	err = adjustBookingCharge(ctx, queries, bookingID, balance.Charged, fee, changedBy, reason)
+	if err != nil {
+		return err
+	}
+	if balance.Paid > fee {
+		return refundBooking(ctx, queries, pp, bookingID, balance.Paid-fee, changedBy, reason)
+	}
+	return nil
+}
+
+// refundClosedBooking refunds whatever has been paid over the charge of
+// bookingID once it is cancelled or a no show, such as a payment captured
+// after the booking was settled
+func refundClosedBooking(ctx context.Context, queries *db.Queries, pp PaymentParams, bookingID int32, changedBy string) error {
+	booking, err := queries.GetBookingById(ctx, bookingID)
+	if err != nil {
+		return err
+	}
+	if booking.Status != db.BookingStatusCancelled && booking.Status != db.BookingStatusNoShow {
+		return nil
+	}
+	balance, err := bookingBalance(ctx, queries, bookingID)
+	if err != nil {
+		return err
+	}
+	if over := balance.Paid - max(balance.Charged, 0); over > 0 {
+		return refundBooking(ctx, queries, pp, bookingID, over, changedBy, paidLateReason)
+	}
+	return nil
+}
+
+// checkBookingDeletable returns ErrBookingHasPayments if deleting a booking
+// would lose its financial record. Every booking is charged when it is made so
+// only payments and entries other than charges count.
+func checkBookingDeletable(payments []db.Payment, entries []db.BookingLedgerEntry) error {
+	if len(payments) > 0 {
+		return ErrBookingHasPayments
+	}
+	for _, entry := range entries {
+		if entry.Kind != db.LedgerEntryKindCharge {
+			return ErrBookingHasPayments
+		}
+	}
+	return nil
+}
+
+func getBookingLedger(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
+		loc, ok := requestLocation(w, r)
+		if !ok {
+			return
+		}
+
+		bookingId := r.PathValue("booking_id")
+		id, err := strconv.ParseInt(bookingId, 10, 32)
+		if err != nil {
+			log.Printf("error: %v converting booking id to int in getBookingLedger: %s", err, bookingId)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in getBookingLedger: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		queries := db.New(conn)
+
+		booking, ok := getOwnedBooking(w, ctx, queries, principal, int32(id), "getBookingLedger")
+		if !ok {
+			return
+		}
+
+		entries, err := queries.GetBookingLedger(ctx, booking.ID)
+		if err != nil {
+			log.Printf("error querying ledger in getBookingLedger: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		balance, err := bookingBalance(ctx, queries, booking.ID)
+		if err != nil {
+			log.Printf("error querying balance in getBookingLedger: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := BookingLedgerResponse{
+			BookingID:     booking.ID,
+			Entries:       []LedgerEntryResponse{},
+			LedgerBalance: balance,
+		}
+		for _, entry := range entries {
+			response.Entries = append(response.Entries, responseFromDBLedgerEntry(entry, loc))
+		}
+
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			log.Printf("error encoding json in getBookingLedger: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// postLedgerAdjustment lets an admin change what is owed for a booking, such as
+// a discount or a charge for extras
+func postLedgerAdjustment(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
+		loc, ok := requestLocation(w, r)
+		if !ok {
+			return
+		}
+
+		bookingId := r.PathValue("booking_id")
+		id, err := strconv.ParseInt(bookingId, 10, 32)
+		if err != nil {
+			log.Printf("error: %v converting booking id to int in postLedgerAdjustment: %s", err, bookingId)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var adjustmentRequest PostLedgerAdjustmentRequest
+
+		err = json.NewDecoder(r.Body).Decode(&adjustmentRequest)
+		if err != nil {
+			log.Printf("error decoding body in postLedgerAdjustment: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		err = adjustmentRequest.validate()
+		if err != nil {
+			log.Printf("invalid adjustment in postLedgerAdjustment: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in postLedgerAdjustment: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			log.Printf("error beginning tx in postLedgerAdjustment: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		defer func() {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				panic(err)
+			}
+		}()
+
+		queries := db.New(conn)
+		qtx := queries.WithTx(tx)
+
+		booking, ok := getOwnedBooking(w, ctx, qtx, principal, int32(id), "postLedgerAdjustment")
+		if !ok {
+			return
+		}
+
+		entry, err := qtx.CreateLedgerEntry(ctx, ledgerEntry(booking.ID, db.LedgerEntryKindAdjustment, adjustmentRequest.Amount, principal.Email, adjustmentRequest.Reason))
+		if err != nil {
+			log.Printf("creating adjustment in postLedgerAdjustment failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, err = addLedgerEntries(ctx, qtx, booking.ID)
+		if err != nil {
+			log.Printf("updating balance in postLedgerAdjustment failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Printf("error commiting tx in postLedgerAdjustment: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(responseFromDBLedgerEntry(entry, loc))
+		if err != nil {
+			log.Printf("error encoding json in postLedgerAdjustment: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
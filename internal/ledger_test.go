@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalanceFromDBRow(t *testing.T) {
+	t.Run("unpaid", func(t *testing.T) {
+		t.Parallel()
+		balance := balanceFromDBRow(db.GetBookingBalanceRow{Charged: 2400})
+		assert.Equal(t, LedgerBalance{Charged: 2400, Paid: 0, Outstanding: 2400}, balance)
+		assert.False(t, balance.isPaid())
+	})
+
+	t.Run("paid in full", func(t *testing.T) {
+		t.Parallel()
+		balance := balanceFromDBRow(db.GetBookingBalanceRow{Charged: 2400, Paid: 2400})
+		assert.Equal(t, LedgerBalance{Charged: 2400, Paid: 2400, Outstanding: 0}, balance)
+		assert.True(t, balance.isPaid())
+	})
+
+	t.Run("partly refunded", func(t *testing.T) {
+		t.Parallel()
+		balance := balanceFromDBRow(db.GetBookingBalanceRow{Charged: 2400, Paid: 2400, Refunded: 400})
+		assert.Equal(t, LedgerBalance{Charged: 2400, Paid: 2000, Outstanding: 400}, balance)
+		assert.False(t, balance.isPaid())
+	})
+
+	t.Run("cancelled and refunded", func(t *testing.T) {
+		t.Parallel()
+		balance := balanceFromDBRow(db.GetBookingBalanceRow{Charged: 0, Paid: 2400, Refunded: 2400})
+		assert.Equal(t, LedgerBalance{}, balance)
+		assert.False(t, balance.isPaid())
+	})
+
+	t.Run("overpaid is owed money", func(t *testing.T) {
+		t.Parallel()
+		balance := balanceFromDBRow(db.GetBookingBalanceRow{Charged: 2000, Paid: 2400})
+		assert.Equal(t, int32(-400), balance.Outstanding)
+		assert.True(t, balance.isPaid())
+	})
+}
+
+func TestPostLedgerAdjustmentRequestValidate(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+		assert.NoError(t, PostLedgerAdjustmentRequest{Amount: -500, Reason: "goodwill"}.validate())
+		assert.NoError(t, PostLedgerAdjustmentRequest{Amount: 500, Reason: "extra time"}.validate())
+	})
+
+	t.Run("zero amount", func(t *testing.T) {
+		t.Parallel()
+		assert.Error(t, PostLedgerAdjustmentRequest{Reason: "goodwill"}.validate())
+	})
+
+	t.Run("no reason", func(t *testing.T) {
+		t.Parallel()
+		assert.Error(t, PostLedgerAdjustmentRequest{Amount: -500}.validate())
+	})
+}
+
+func TestLedgerEntry(t *testing.T) {
+	t.Run("with a reason", func(t *testing.T) {
+		t.Parallel()
+		entry := ledgerEntry(3, db.LedgerEntryKindRefund, 2400, "admin@example.com", cancelledReason)
+		assert.Equal(t, db.CreateLedgerEntryParams{
+			BookingID: 3,
+			Kind:      db.LedgerEntryKindRefund,
+			Amount:    2400,
+			Reason:    pgtype.Text{String: cancelledReason, Valid: true},
+			CreatedBy: "admin@example.com",
+		}, entry)
+	})
+
+	t.Run("without a reason", func(t *testing.T) {
+		t.Parallel()
+		entry := ledgerEntry(3, db.LedgerEntryKindCharge, 2400, "user@example.com", "")
+		assert.False(t, entry.Reason.Valid)
+	})
+}
+
+func TestCheckBookingDeletable(t *testing.T) {
+	t.Run("only charged", func(t *testing.T) {
+		t.Parallel()
+		entries := []db.BookingLedgerEntry{{Kind: db.LedgerEntryKindCharge, Amount: 2400}}
+		assert.NoError(t, checkBookingDeletable(nil, entries))
+	})
+
+	t.Run("with a payment", func(t *testing.T) {
+		t.Parallel()
+		payments := []db.Payment{{Status: db.PaymentStatusPending, Amount: 2400}}
+		assert.ErrorIs(t, checkBookingDeletable(payments, nil), ErrBookingHasPayments)
+	})
+
+	t.Run("with a refund or adjustment", func(t *testing.T) {
+		t.Parallel()
+		entries := []db.BookingLedgerEntry{
+			{Kind: db.LedgerEntryKindCharge, Amount: 2400},
+			{Kind: db.LedgerEntryKindAdjustment, Amount: -400},
+		}
+		assert.ErrorIs(t, checkBookingDeletable(nil, entries), ErrBookingHasPayments)
+	})
+}
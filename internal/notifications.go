@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+)
+
+// BookingNotifier tells customers about changes to their bookings that they
+// didnt make themselves. It is only called once the change is committed.
+type BookingNotifier interface {
+	NotifyCancelled(ctx context.Context, booking db.GetBookingWithJoinRow, reason string) error
+	NotifyWaitlist(ctx context.Context, promotion WaitlistPromotion) error
+}
+
+// LogBookingNotifier writes notifications to the server log, useful for local development
+type LogBookingNotifier struct{}
+
+func (LogBookingNotifier) NotifyCancelled(ctx context.Context, booking db.GetBookingWithJoinRow, reason string) error {
+	log.Printf("booking %d for %s at %s was cancelled: %s",
+		booking.ID,
+		booking.UserEmail,
+		booking.StartTime.Time.Format(time.RFC3339),
+		reason,
+	)
+	return nil
+}
+
+func (LogBookingNotifier) NotifyWaitlist(ctx context.Context, promotion WaitlistPromotion) error {
+	if promotion.BookingID != 0 {
+		log.Printf("%s was booked from the waitlist into booking %d at %s",
+			promotion.UserEmail,
+			promotion.BookingID,
+			promotion.StartTime.Format(time.RFC3339),
+		)
+		return nil
+	}
+	log.Printf("%s was offered slots %v at %s from the waitlist with hold %d until %s",
+		promotion.UserEmail,
+		promotion.SlotIDs,
+		promotion.StartTime.Format(time.RFC3339),
+		promotion.HoldID,
+		promotion.ExpiresAt.Format(time.RFC3339),
+	)
+	return nil
+}
+
+// notifyCancelled sends a cancellation for each of bookings. A failed
+// notification cant undo the cancellation so it is only logged.
+func notifyCancelled(ctx context.Context, notifier BookingNotifier, bookings []db.GetBookingWithJoinRow, reason string) {
+	for _, booking := range bookings {
+		err := notifier.NotifyCancelled(ctx, booking, reason)
+		if err != nil {
+			log.Printf("notifying %s that booking %d was cancelled failed with %v", booking.UserEmail, booking.ID, err)
+		}
+	}
+}
+
+// notifyWaitlist tells each customer in promotions about their offer or
+// booking, failures are only logged
+func notifyWaitlist(ctx context.Context, notifier BookingNotifier, promotions []WaitlistPromotion) {
+	for _, promotion := range promotions {
+		err := notifier.NotifyWaitlist(ctx, promotion)
+		if err != nil {
+			log.Printf("notifying %s of waitlist entry %d failed with %v", promotion.UserEmail, promotion.EntryID, err)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ForgotPasswordResponse struct {
+	Message string `json:"message"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type ResetPasswordResponse struct {
+	Message string `json:"message"`
+}
+
+// ResetTokenSender delivers a plaintext reset token to the owner of the email address.
+// Only the hash of the token is ever stored, so this is the one place it leaves the server.
+type ResetTokenSender interface {
+	SendResetToken(ctx context.Context, email string, token string) error
+}
+
+// LogResetTokenSender writes reset tokens to the server log, useful for local development
+type LogResetTokenSender struct{}
+
+func (LogResetTokenSender) SendResetToken(ctx context.Context, email string, token string) error {
+	log.Printf("password reset token for %s: %s", email, token)
+	return nil
+}
+
+// FileResetTokenSender appends "email token" lines to the file at Path
+type FileResetTokenSender struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (s *FileResetTokenSender) SendResetToken(ctx context.Context, email string, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "%s %s\n", email, token)
+	return err
+}
+
+// newResetTokenSender returns the sender writing tokens to path, or the log
+// sender when there is no path and allowLog is set. Logged tokens let anyone
+// who can read the log reset any password so they are only for development.
+func newResetTokenSender(path string, allowLog bool) (ResetTokenSender, error) {
+	if path != "" {
+		return &FileResetTokenSender{Path: path}, nil
+	}
+	if allowLog {
+		return LogResetTokenSender{}, nil
+	}
+	return nil, errors.New("reset tokens need somewhere to be sent, set RESET_TOKEN_FILE")
+}
+
+// hashResetToken returns the hex encoded sha256 of a reset token. The tokens are
+// high entropy random values so a fast hash is sufficient here, unlike passwords.
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func HandleForgotPassword(w http.ResponseWriter, ctx context.Context, queries *db.Queries, req ForgotPasswordRequest, a *AuthParams) error {
+	// the response is the same whether or not the email exists so that this
+	// endpoint cant be used to discover which emails have accounts
+	accepted := func() error {
+		w.WriteHeader(http.StatusAccepted)
+		err := json.NewEncoder(w).Encode(ForgotPasswordResponse{
+			Message: "If an account exists for this email a reset token has been sent",
+		})
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return err
+		}
+		return nil
+	}
+
+	if req.Email == "" {
+		http.Error(w, "Invalid body, expects email", http.StatusBadRequest)
+		return errors.New("email is empty")
+	}
+
+	user, err := queries.GetUserByEmail(ctx, req.Email)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("getting user by email in HandleForgotPassword failed with %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return accepted()
+	}
+
+	// only the most recently issued token should be usable
+	err = queries.DeletePasswordResetsByUserID(ctx, user.ID)
+	if err != nil {
+		log.Printf("deleting previous resets in HandleForgotPassword failed with %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+
+	token, err := GenerateSessionToken(a.TokenLength)
+	if err != nil {
+		log.Printf("generating reset token in HandleForgotPassword failed with %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+
+	expiryTime := time.Now().UTC().Add(a.ResetTokenDuration)
+	_, err = queries.CreatePasswordReset(ctx, db.CreatePasswordResetParams{
+		UserID:    user.ID,
+		TokenHash: hashResetToken(token),
+		ExpiresAt: pgtype.Timestamptz{Time: expiryTime, Valid: true},
+	})
+	if err != nil {
+		log.Printf("creating password reset in HandleForgotPassword failed with %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+
+	err = a.ResetSender.SendResetToken(ctx, user.Email, token)
+	if err != nil {
+		log.Printf("sending reset token in HandleForgotPassword failed with %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+
+	return accepted()
+}
+
+func HandleResetPassword(w http.ResponseWriter, ctx context.Context, conn *pgxpool.Conn, req ResetPasswordRequest, a *AuthParams) error {
+	if req.Token == "" || req.Password == "" {
+		http.Error(w, "Invalid body, expects token and password", http.StatusBadRequest)
+		return errors.New("token or password is empty")
+	}
+	err := validatePassword(req.Password)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		log.Printf("error beginning tx in HandleResetPassword: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			panic(err)
+		}
+	}()
+
+	queries := db.New(conn)
+	qtx := queries.WithTx(tx)
+
+	reset, err := qtx.GetPasswordResetByTokenHash(ctx, hashResetToken(req.Token))
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("getting password reset in HandleResetPassword failed with %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return err
+	}
+
+	isCurr, err := IsTokenCurrent(reset.ExpiresAt)
+	if err != nil {
+		log.Printf("checking reset expiry in HandleResetPassword failed with %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+	if !isCurr || reset.UsedAt.Valid {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return errors.New("reset token expired or already used")
+	}
+
+	// marking the token used is conditional on it being unused, so of two
+	// concurrent requests with the same token only one will get a row back
+	userID, err := qtx.UsePasswordReset(ctx, reset.ID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("marking reset as used in HandleResetPassword failed with %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return err
+	}
+
+	hashedPassword, err := HashPassword(req.Password, &a.HParams)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+
+	err = qtx.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+		ID:             userID,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		log.Printf("updating password in HandleResetPassword failed with %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+
+	err = qtx.DeleteSessionsByUserID(ctx, userID)
+	if err != nil {
+		log.Printf("revoking sessions in HandleResetPassword failed with %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Printf("error commiting tx in HandleResetPassword: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+
+	err = InvalidateAuthCookie(w, a)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(ResetPasswordResponse{Message: "Password reset successfully"})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return err
+	}
+	return nil
+}
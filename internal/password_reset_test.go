@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashResetToken(t *testing.T) {
+	t.Run("deterministic", func(t *testing.T) {
+		t.Parallel()
+		token, err := GenerateSessionToken(32)
+		require.NoError(t, err)
+
+		assert.Equal(t, hashResetToken(token), hashResetToken(token))
+		assert.NotEqual(t, token, hashResetToken(token))
+		assert.Len(t, hashResetToken(token), 64)
+	})
+
+	t.Run("different tokens", func(t *testing.T) {
+		t.Parallel()
+		assert.NotEqual(t, hashResetToken("token-a"), hashResetToken("token-b"))
+	})
+}
+
+func TestFileResetTokenSender(t *testing.T) {
+	t.Run("appends", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "tokens.txt")
+		sender := &FileResetTokenSender{Path: path}
+
+		err := sender.SendResetToken(context.Background(), "jim@example.com", "abc")
+		require.NoError(t, err)
+		err = sender.SendResetToken(context.Background(), "jack@example.com", "def")
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "jim@example.com abc\njack@example.com def\n", string(content))
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		t.Parallel()
+		sender := &FileResetTokenSender{Path: filepath.Join(t.TempDir(), "missing", "tokens.txt")}
+
+		err := sender.SendResetToken(context.Background(), "jim@example.com", "abc")
+		assert.Error(t, err)
+	})
+}
+
+func TestNewResetTokenSender(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		t.Parallel()
+		sender, err := newResetTokenSender("/tmp/reset_tokens", false)
+		require.NoError(t, err)
+		assert.IsType(t, &FileResetTokenSender{}, sender)
+	})
+
+	t.Run("log only when allowed", func(t *testing.T) {
+		t.Parallel()
+		sender, err := newResetTokenSender("", true)
+		require.NoError(t, err)
+		assert.IsType(t, LogResetTokenSender{}, sender)
+
+		_, err = newResetTokenSender("", false)
+		assert.Error(t, err)
+	})
+}
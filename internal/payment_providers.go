@@ -0,0 +1,394 @@
+package internal
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+)
+
+var ErrInvalidWebhook = errors.New("webhook signature is not valid")
+
+// stripeWebhookTolerance is how old a signed webhook can be before it is
+// treated as a replay
+const stripeWebhookTolerance = 5 * time.Minute
+
+// PaymentIntent is a providers view of one payment. ClientSecret is passed to
+// the customers browser so it can complete the payment with the provider.
+type PaymentIntent struct {
+	Reference    string
+	Status       db.PaymentStatus
+	ClientSecret string
+	Amount       int32
+	Currency     string
+}
+
+// PaymentEvent is a change to a payment that a provider tells us about through
+// a webhook. Events with no Reference are ones we dont act on.
+type PaymentEvent struct {
+	Reference      string
+	Status         db.PaymentStatus
+	RefundedAmount int32
+}
+
+// PaymentProvider takes payments for bookings. Intents are authorised by the
+// customer with the provider and only taken once they are captured. Intents
+// and refunds made again with the same idempotency key are only made once.
+type PaymentProvider interface {
+	Name() string
+	CreateIntent(ctx context.Context, bookingID int32, amount int32, currency string, idempotencyKey string) (PaymentIntent, error)
+	Capture(ctx context.Context, reference string) (PaymentIntent, error)
+	Refund(ctx context.Context, reference string, amount int32, idempotencyKey string) error
+	VerifyWebhook(payload []byte, header http.Header) (PaymentEvent, error)
+}
+
+// signPayload is the hex HMAC-SHA256 of payload with secret
+func signPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// StripePaymentProvider talks to a Stripe compatible API at BaseURL
+type StripePaymentProvider struct {
+	BaseURL       string
+	SecretKey     string
+	WebhookSecret string
+	Client        *http.Client
+}
+
+type stripePaymentIntent struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	ClientSecret string `json:"client_secret"`
+	Amount       int32  `json:"amount"`
+	Currency     string `json:"currency"`
+}
+
+type stripeError struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type stripeEvent struct {
+	Type string `json:"type"`
+	Data struct {
+		Object struct {
+			ID             string `json:"id"`
+			PaymentIntent  string `json:"payment_intent"`
+			AmountRefunded int32  `json:"amount_refunded"`
+			Refunded       bool   `json:"refunded"`
+		} `json:"object"`
+	} `json:"data"`
+}
+
+func (s *StripePaymentProvider) Name() string {
+	return "stripe"
+}
+
+// post sends form to path and decodes the response into out. Stripe only acts
+// once on requests with the same idempotencyKey, when it is set.
+func (s *StripePaymentProvider) post(ctx context.Context, path string, form url.Values, idempotencyKey string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.BaseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.SecretKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var stripeErr stripeError
+		err = json.NewDecoder(resp.Body).Decode(&stripeErr)
+		if err != nil || stripeErr.Error.Message == "" {
+			return fmt.Errorf("stripe request to %s failed with status %d", path, resp.StatusCode)
+		}
+		return fmt.Errorf("stripe request to %s failed: %s", path, stripeErr.Error.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (i stripePaymentIntent) toPaymentIntent() PaymentIntent {
+	status := db.PaymentStatusPending
+	switch i.Status {
+	case "succeeded":
+		status = db.PaymentStatusCaptured
+	case "canceled":
+		status = db.PaymentStatusFailed
+	}
+	return PaymentIntent{
+		Reference:    i.ID,
+		Status:       status,
+		ClientSecret: i.ClientSecret,
+		Amount:       i.Amount,
+		Currency:     i.Currency,
+	}
+}
+
+func (s *StripePaymentProvider) CreateIntent(ctx context.Context, bookingID int32, amount int32, currency string, idempotencyKey string) (PaymentIntent, error) {
+	form := url.Values{}
+	form.Set("amount", strconv.Itoa(int(amount)))
+	form.Set("currency", currency)
+	form.Set("capture_method", "manual")
+	form.Set("metadata[booking_id]", strconv.Itoa(int(bookingID)))
+
+	var intent stripePaymentIntent
+	err := s.post(ctx, "/v1/payment_intents", form, idempotencyKey, &intent)
+	if err != nil {
+		return PaymentIntent{}, err
+	}
+	return intent.toPaymentIntent(), nil
+}
+
+func (s *StripePaymentProvider) Capture(ctx context.Context, reference string) (PaymentIntent, error) {
+	var intent stripePaymentIntent
+	err := s.post(ctx, "/v1/payment_intents/"+url.PathEscape(reference)+"/capture", url.Values{}, "", &intent)
+	if err != nil {
+		return PaymentIntent{}, err
+	}
+	return intent.toPaymentIntent(), nil
+}
+
+func (s *StripePaymentProvider) Refund(ctx context.Context, reference string, amount int32, idempotencyKey string) error {
+	form := url.Values{}
+	form.Set("payment_intent", reference)
+	form.Set("amount", strconv.Itoa(int(amount)))
+	return s.post(ctx, "/v1/refunds", form, idempotencyKey, nil)
+}
+
+// VerifyWebhook checks the Stripe-Signature header, "t=<unix time>,v1=<hex>",
+// where v1 is signed over "<unix time>.<payload>"
+func (s *StripePaymentProvider) VerifyWebhook(payload []byte, header http.Header) (PaymentEvent, error) {
+	var timestamp string
+	signatures := []string{}
+	for _, part := range strings.Split(header.Get("Stripe-Signature"), ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return PaymentEvent{}, ErrInvalidWebhook
+	}
+	age := time.Since(time.Unix(unix, 0))
+	if age > stripeWebhookTolerance || age < -stripeWebhookTolerance {
+		return PaymentEvent{}, ErrInvalidWebhook
+	}
+
+	expected := signPayload(s.WebhookSecret, append([]byte(timestamp+"."), payload...))
+	valid := false
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			valid = true
+		}
+	}
+	if !valid {
+		return PaymentEvent{}, ErrInvalidWebhook
+	}
+
+	var event stripeEvent
+	err = json.Unmarshal(payload, &event)
+	if err != nil {
+		return PaymentEvent{}, err
+	}
+	object := event.Data.Object
+	switch event.Type {
+	case "payment_intent.succeeded":
+		return PaymentEvent{Reference: object.ID, Status: db.PaymentStatusCaptured}, nil
+	case "payment_intent.payment_failed", "payment_intent.canceled":
+		return PaymentEvent{Reference: object.ID, Status: db.PaymentStatusFailed}, nil
+	case "charge.refunded":
+		status := db.PaymentStatusCaptured
+		if object.Refunded {
+			status = db.PaymentStatusRefunded
+		}
+		return PaymentEvent{
+			Reference:      object.PaymentIntent,
+			Status:         status,
+			RefundedAmount: object.AmountRefunded,
+		}, nil
+	}
+	return PaymentEvent{}, nil
+}
+
+// FakePaymentProvider keeps intents in memory so payments can be taken without a
+// real provider, useful for local development and tests. Its webhooks are the
+// JSON of a PaymentEvent signed in the Fake-Signature header with WebhookSecret.
+type FakePaymentProvider struct {
+	WebhookSecret string
+	mu            sync.Mutex
+	intents       map[string]*fakeIntent
+	intentKeys    map[string]string
+	refunds       map[string]bool
+	next          int
+}
+
+type fakeIntent struct {
+	intent   PaymentIntent
+	refunded int32
+}
+
+type fakeWebhook struct {
+	Reference      string           `json:"reference"`
+	Status         db.PaymentStatus `json:"status"`
+	RefundedAmount int32            `json:"refunded_amount"`
+}
+
+func (f *FakePaymentProvider) Name() string {
+	return "fake"
+}
+
+func (f *FakePaymentProvider) CreateIntent(ctx context.Context, bookingID int32, amount int32, currency string, idempotencyKey string) (PaymentIntent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if reference, ok := f.intentKeys[idempotencyKey]; ok && idempotencyKey != "" {
+		return f.intents[reference].intent, nil
+	}
+
+	if f.intents == nil {
+		f.intents = map[string]*fakeIntent{}
+	}
+	f.next++
+	reference := fmt.Sprintf("fake_pi_%d", f.next)
+	intent := PaymentIntent{
+		Reference:    reference,
+		Status:       db.PaymentStatusPending,
+		ClientSecret: reference + "_secret",
+		Amount:       amount,
+		Currency:     currency,
+	}
+	f.intents[reference] = &fakeIntent{intent: intent}
+	if idempotencyKey != "" {
+		if f.intentKeys == nil {
+			f.intentKeys = map[string]string{}
+		}
+		f.intentKeys[idempotencyKey] = reference
+	}
+	return intent, nil
+}
+
+func (f *FakePaymentProvider) Capture(ctx context.Context, reference string) (PaymentIntent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stored, ok := f.intents[reference]
+	if !ok {
+		return PaymentIntent{}, fmt.Errorf("payment intent %s does not exist", reference)
+	}
+	if stored.intent.Status != db.PaymentStatusPending {
+		return PaymentIntent{}, fmt.Errorf("payment intent %s is %s", reference, stored.intent.Status)
+	}
+	stored.intent.Status = db.PaymentStatusCaptured
+	return stored.intent, nil
+}
+
+func (f *FakePaymentProvider) Refund(ctx context.Context, reference string, amount int32, idempotencyKey string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if idempotencyKey != "" && f.refunds[idempotencyKey] {
+		return nil
+	}
+
+	stored, ok := f.intents[reference]
+	if !ok {
+		return fmt.Errorf("payment intent %s does not exist", reference)
+	}
+	if stored.intent.Status != db.PaymentStatusCaptured {
+		return fmt.Errorf("payment intent %s is %s", reference, stored.intent.Status)
+	}
+	if amount > stored.intent.Amount-stored.refunded {
+		return fmt.Errorf("refund of %d is more than is left on payment intent %s", amount, reference)
+	}
+	stored.refunded += amount
+	if stored.refunded == stored.intent.Amount {
+		stored.intent.Status = db.PaymentStatusRefunded
+	}
+	if idempotencyKey != "" {
+		if f.refunds == nil {
+			f.refunds = map[string]bool{}
+		}
+		f.refunds[idempotencyKey] = true
+	}
+	return nil
+}
+
+func (f *FakePaymentProvider) VerifyWebhook(payload []byte, header http.Header) (PaymentEvent, error) {
+	if f.WebhookSecret == "" {
+		return PaymentEvent{}, ErrInvalidWebhook
+	}
+	if !hmac.Equal([]byte(header.Get("Fake-Signature")), []byte(signPayload(f.WebhookSecret, payload))) {
+		return PaymentEvent{}, ErrInvalidWebhook
+	}
+	var webhook fakeWebhook
+	err := json.Unmarshal(payload, &webhook)
+	if err != nil {
+		return PaymentEvent{}, err
+	}
+	return PaymentEvent(webhook), nil
+}
+
+// newPaymentProvider builds the provider called name. Every provider needs a
+// webhook secret, an empty one would let anyone sign webhooks, and the fake one
+// is only built when allowFake is set for local development.
+func newPaymentProvider(name string, baseURL string, secretKey string, webhookSecret string, allowFake bool) (PaymentProvider, error) {
+	if name == "" {
+		return nil, errors.New("a payment provider has to be set, use fake or stripe")
+	}
+	if webhookSecret == "" {
+		return nil, fmt.Errorf("the %s payment provider needs a webhook secret", name)
+	}
+	switch name {
+	case "fake":
+		if !allowFake {
+			return nil, errors.New("the fake payment provider is only for development and has to be allowed explicitly")
+		}
+		return &FakePaymentProvider{WebhookSecret: webhookSecret}, nil
+	case "stripe":
+		if secretKey == "" {
+			return nil, errors.New("the stripe payment provider needs a secret key")
+		}
+		if baseURL == "" {
+			baseURL = "https://api.stripe.com"
+		}
+		return &StripePaymentProvider{
+			BaseURL:       strings.TrimSuffix(baseURL, "/"),
+			SecretKey:     secretKey,
+			WebhookSecret: webhookSecret,
+			Client:        &http.Client{Timeout: 10 * time.Second},
+		}, nil
+	}
+	return nil, fmt.Errorf("payment provider %q is not supported, use fake or stripe", name)
+}
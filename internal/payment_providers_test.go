@@ -0,0 +1,237 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakePaymentProvider(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("capture and refund", func(t *testing.T) {
+		t.Parallel()
+		f := &FakePaymentProvider{}
+		intent, err := f.CreateIntent(ctx, 3, 2400, "gbp", "")
+		require.NoError(t, err)
+		assert.Equal(t, db.PaymentStatusPending, intent.Status)
+		assert.NotEmpty(t, intent.ClientSecret)
+
+		assert.Error(t, f.Refund(ctx, intent.Reference, 2400, ""))
+
+		captured, err := f.Capture(ctx, intent.Reference)
+		require.NoError(t, err)
+		assert.Equal(t, db.PaymentStatusCaptured, captured.Status)
+
+		_, err = f.Capture(ctx, intent.Reference)
+		assert.Error(t, err)
+
+		assert.NoError(t, f.Refund(ctx, intent.Reference, 400, ""))
+		assert.Error(t, f.Refund(ctx, intent.Reference, 2001, ""))
+		assert.NoError(t, f.Refund(ctx, intent.Reference, 2000, ""))
+	})
+
+	t.Run("refund retried with the same key", func(t *testing.T) {
+		t.Parallel()
+		f := &FakePaymentProvider{}
+		intent, err := f.CreateIntent(ctx, 3, 2400, "gbp", "")
+		require.NoError(t, err)
+		_, err = f.Capture(ctx, intent.Reference)
+		require.NoError(t, err)
+
+		assert.NoError(t, f.Refund(ctx, intent.Reference, 2000, "refund_1"))
+		assert.NoError(t, f.Refund(ctx, intent.Reference, 2000, "refund_1"))
+		assert.Error(t, f.Refund(ctx, intent.Reference, 2000, "refund_2"))
+	})
+
+	t.Run("intent retried with the same key", func(t *testing.T) {
+		t.Parallel()
+		f := &FakePaymentProvider{}
+		first, err := f.CreateIntent(ctx, 3, 2400, "gbp", "payment_3_0")
+		require.NoError(t, err)
+		again, err := f.CreateIntent(ctx, 3, 2400, "gbp", "payment_3_0")
+		require.NoError(t, err)
+		assert.Equal(t, first, again)
+
+		other, err := f.CreateIntent(ctx, 3, 2400, "gbp", "payment_3_1")
+		require.NoError(t, err)
+		assert.NotEqual(t, first.Reference, other.Reference)
+	})
+
+	t.Run("unknown intent", func(t *testing.T) {
+		t.Parallel()
+		f := &FakePaymentProvider{}
+		_, err := f.Capture(ctx, "fake_pi_9")
+		assert.Error(t, err)
+	})
+
+	t.Run("webhook", func(t *testing.T) {
+		t.Parallel()
+		f := &FakePaymentProvider{WebhookSecret: "whsec"}
+		payload := []byte(`{"reference": "fake_pi_1", "status": "captured"}`)
+
+		header := http.Header{}
+		header.Set("Fake-Signature", signPayload("whsec", payload))
+		event, err := f.VerifyWebhook(payload, header)
+		assert.NoError(t, err)
+		assert.Equal(t, PaymentEvent{Reference: "fake_pi_1", Status: db.PaymentStatusCaptured}, event)
+
+		header.Set("Fake-Signature", signPayload("other", payload))
+		_, err = f.VerifyWebhook(payload, header)
+		assert.ErrorIs(t, err, ErrInvalidWebhook)
+	})
+
+	t.Run("webhook without a secret", func(t *testing.T) {
+		t.Parallel()
+		f := &FakePaymentProvider{}
+		payload := []byte(`{"reference": "fake_pi_1", "status": "captured"}`)
+
+		header := http.Header{}
+		header.Set("Fake-Signature", signPayload("", payload))
+		_, err := f.VerifyWebhook(payload, header)
+		assert.ErrorIs(t, err, ErrInvalidWebhook)
+	})
+}
+
+func TestStripePaymentProvider(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("create intent", func(t *testing.T) {
+		t.Parallel()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/payment_intents", r.URL.Path)
+			assert.Equal(t, "Bearer sk_test", r.Header.Get("Authorization"))
+			assert.Equal(t, "payment_3_0", r.Header.Get("Idempotency-Key"))
+			assert.NoError(t, r.ParseForm())
+			assert.Equal(t, "2400", r.PostForm.Get("amount"))
+			assert.Equal(t, "manual", r.PostForm.Get("capture_method"))
+			assert.Equal(t, "3", r.PostForm.Get("metadata[booking_id]"))
+			fmt.Fprint(w, `{"id": "pi_1", "status": "requires_payment_method", "client_secret": "pi_1_secret", "amount": 2400, "currency": "gbp"}`)
+		}))
+		defer server.Close()
+
+		s := &StripePaymentProvider{BaseURL: server.URL, SecretKey: "sk_test"}
+		intent, err := s.CreateIntent(ctx, 3, 2400, "gbp", "payment_3_0")
+		assert.NoError(t, err)
+		assert.Equal(t, PaymentIntent{
+			Reference:    "pi_1",
+			Status:       db.PaymentStatusPending,
+			ClientSecret: "pi_1_secret",
+			Amount:       2400,
+			Currency:     "gbp",
+		}, intent)
+	})
+
+	t.Run("capture", func(t *testing.T) {
+		t.Parallel()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/payment_intents/pi_1/capture", r.URL.Path)
+			fmt.Fprint(w, `{"id": "pi_1", "status": "succeeded", "amount": 2400, "currency": "gbp"}`)
+		}))
+		defer server.Close()
+
+		s := &StripePaymentProvider{BaseURL: server.URL, SecretKey: "sk_test"}
+		intent, err := s.Capture(ctx, "pi_1")
+		assert.NoError(t, err)
+		assert.Equal(t, db.PaymentStatusCaptured, intent.Status)
+	})
+
+	t.Run("api error", func(t *testing.T) {
+		t.Parallel()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusPaymentRequired)
+			fmt.Fprint(w, `{"error": {"message": "card declined"}}`)
+		}))
+		defer server.Close()
+
+		s := &StripePaymentProvider{BaseURL: server.URL, SecretKey: "sk_test"}
+		err := s.Refund(ctx, "pi_1", 400, "")
+		assert.ErrorContains(t, err, "card declined")
+	})
+
+	t.Run("refund", func(t *testing.T) {
+		t.Parallel()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/refunds", r.URL.Path)
+			assert.Equal(t, "refund_7", r.Header.Get("Idempotency-Key"))
+			assert.NoError(t, r.ParseForm())
+			assert.Equal(t, "pi_1", r.PostForm.Get("payment_intent"))
+			assert.Equal(t, "400", r.PostForm.Get("amount"))
+			fmt.Fprint(w, `{"id": "re_1"}`)
+		}))
+		defer server.Close()
+
+		s := &StripePaymentProvider{BaseURL: server.URL, SecretKey: "sk_test"}
+		assert.NoError(t, s.Refund(ctx, "pi_1", 400, "refund_7"))
+	})
+
+	t.Run("webhook", func(t *testing.T) {
+		t.Parallel()
+		s := &StripePaymentProvider{WebhookSecret: "whsec"}
+		payload := []byte(`{"type": "charge.refunded", "data": {"object": {"id": "ch_1", "payment_intent": "pi_1", "amount_refunded": 400, "refunded": false}}}`)
+		sign := func(at time.Time, secret string) http.Header {
+			timestamp := strconv.FormatInt(at.Unix(), 10)
+			header := http.Header{}
+			header.Set("Stripe-Signature", "t="+timestamp+",v1="+signPayload(secret, append([]byte(timestamp+"."), payload...)))
+			return header
+		}
+
+		event, err := s.VerifyWebhook(payload, sign(time.Now(), "whsec"))
+		assert.NoError(t, err)
+		assert.Equal(t, PaymentEvent{Reference: "pi_1", Status: db.PaymentStatusCaptured, RefundedAmount: 400}, event)
+
+		_, err = s.VerifyWebhook(payload, sign(time.Now(), "other"))
+		assert.ErrorIs(t, err, ErrInvalidWebhook)
+
+		_, err = s.VerifyWebhook(payload, sign(time.Now().Add(-time.Hour), "whsec"))
+		assert.ErrorIs(t, err, ErrInvalidWebhook)
+
+		_, err = s.VerifyWebhook(payload, http.Header{})
+		assert.ErrorIs(t, err, ErrInvalidWebhook)
+	})
+}
+
+func TestNewPaymentProvider(t *testing.T) {
+	t.Run("fake only when allowed", func(t *testing.T) {
+		t.Parallel()
+		provider, err := newPaymentProvider("fake", "", "", "whsec", true)
+		assert.NoError(t, err)
+		assert.Equal(t, "fake", provider.Name())
+
+		_, err = newPaymentProvider("fake", "", "", "whsec", false)
+		assert.Error(t, err)
+	})
+
+	t.Run("needs a provider and a webhook secret", func(t *testing.T) {
+		t.Parallel()
+		_, err := newPaymentProvider("", "", "", "whsec", true)
+		assert.Error(t, err)
+
+		_, err = newPaymentProvider("fake", "", "", "", true)
+		assert.Error(t, err)
+	})
+
+	t.Run("stripe", func(t *testing.T) {
+		t.Parallel()
+		provider, err := newPaymentProvider("stripe", "", "sk_test", "whsec", false)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://api.stripe.com", provider.(*StripePaymentProvider).BaseURL)
+
+		_, err = newPaymentProvider("stripe", "", "", "whsec", false)
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		t.Parallel()
+		_, err := newPaymentProvider("paypal", "", "", "whsec", false)
+		assert.Error(t, err)
+	})
+}
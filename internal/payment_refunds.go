@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// refundIdempotencyKey is sent with a queued refund so the provider only takes
+// it once however many times it is retried
+func refundIdempotencyKey(refundID int32) string {
+	return fmt.Sprintf("mirai_refund_%d", refundID)
+}
+
+// sendPaymentRefund sends the queued refund refundID through pp, recording
+// whether the provider took it. Refunds already sent or being sent elsewhere
+// are left alone.
+func sendPaymentRefund(ctx context.Context, pool *pgxpool.Pool, pp PaymentParams, refundID int32) error {
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			panic(err)
+		}
+	}()
+
+	qtx := db.New(pool).WithTx(tx)
+
+	refund, err := qtx.LockUnsentPaymentRefund(ctx, refundID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if refund.Provider != pp.Provider.Name() {
+		err = fmt.Errorf("payment was taken with %s not %s", refund.Provider, pp.Provider.Name())
+	} else {
+		err = pp.Provider.Refund(ctx, refund.ProviderRef.String, refund.Amount, refundIdempotencyKey(refund.ID))
+	}
+	if err != nil {
+		log.Printf("sending refund %d of %d with %s failed with %v", refund.ID, refund.Amount, refund.Provider, err)
+		err = qtx.MarkPaymentRefundFailed(ctx, db.MarkPaymentRefundFailedParams{
+			ID:        refund.ID,
+			LastError: pgtype.Text{String: err.Error(), Valid: true},
+		})
+	} else {
+		err = qtx.MarkPaymentRefundSent(ctx, refund.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// sendPaymentRefunds sends every queued refund through pp, each in its own
+// transaction so one the provider turns down doesnt hold up the rest. It is
+// called once the refunds are committed, those that fail are retried by
+// reapPaymentRefunds.
+func sendPaymentRefunds(ctx context.Context, pool *pgxpool.Pool, pp PaymentParams) {
+	refundIDs, err := db.New(pool).GetUnsentPaymentRefunds(ctx)
+	if err != nil {
+		log.Printf("getting unsent refunds failed with %v", err)
+		return
+	}
+
+	for _, id := range refundIDs {
+		err = sendPaymentRefund(ctx, pool, pp, id)
+		if err != nil {
+			log.Printf("recording refund %d failed with %v", id, err)
+		}
+	}
+}
+
+// reapPaymentRefunds retries unsent refunds every interval until ctx is done
+func reapPaymentRefunds(ctx context.Context, pool *pgxpool.Pool, pp PaymentParams, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sendPaymentRefunds(ctx, pool, pp)
+		}
+	}
+}
@@ -0,0 +1,719 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultCurrency is what payments are taken in when PAYMENT_CURRENCY isnt set
+const DefaultCurrency = "gbp"
+
+// manualProvider is the provider recorded for payments taken outside of the app
+const manualProvider = "manual"
+
+// manualPaymentMethods are the ways a manual payment can be taken
+var manualPaymentMethods = []string{"cash", "card", "bank_transfer", "other"}
+
+// maxWebhookBytes limits how much of a webhook body is read
+const maxWebhookBytes = 64 * 1024
+
+type PaymentParams struct {
+	Provider PaymentProvider
+	Currency string
+}
+
+// parseCurrency reads a three letter ISO currency code from configuration,
+// defaulting to DefaultCurrency
+func parseCurrency(value string) (string, error) {
+	if value == "" {
+		return DefaultCurrency, nil
+	}
+	currency := strings.ToLower(value)
+	if len(currency) != 3 || strings.Trim(currency, "abcdefghijklmnopqrstuvwxyz") != "" {
+		return "", fmt.Errorf("currency %q is not a three letter currency code", value)
+	}
+	return currency, nil
+}
+
+type RefundPaymentRequest struct {
+	Amount int32 `json:"amount"` // what is left on the payment when not set
+}
+
+type PostManualPaymentRequest struct {
+	Amount    int32  `json:"amount"` // what is outstanding on the booking when not set
+	Method    string `json:"method"`
+	Reference string `json:"reference"` // optional, such as a receipt number
+}
+
+// amountFor is the amount paid towards a booking with outstanding left to pay
+func (r PostManualPaymentRequest) amountFor(outstanding int32) int32 {
+	if r.Amount == 0 {
+		return outstanding
+	}
+	return r.Amount
+}
+
+func (r PostManualPaymentRequest) validate(outstanding int32) error {
+	amount := r.amountFor(outstanding)
+	if amount <= 0 {
+		return errors.New("amount must be more than 0")
+	}
+	if amount > outstanding {
+		return fmt.Errorf("amount must be at most %d", outstanding)
+	}
+	if !slices.Contains(manualPaymentMethods, r.Method) {
+		return fmt.Errorf("method must be one of %s", strings.Join(manualPaymentMethods, ", "))
+	}
+	if len(r.Reference) > 255 {
+		return errors.New("reference must be at most 255 characters")
+	}
+	return nil
+}
+
+type PaymentResponse struct {
+	PaymentID      int32              `json:"payment_id"`
+	BookingID      int32              `json:"booking_id"`
+	Provider       string             `json:"provider"`
+	ProviderRef    pgtype.Text        `json:"provider_ref"`
+	Amount         int32              `json:"amount"`
+	RefundedAmount int32              `json:"refunded_amount"`
+	Currency       string             `json:"currency"`
+	Status         db.PaymentStatus   `json:"status"`
+	Method         pgtype.Text        `json:"method"`
+	Reference      pgtype.Text        `json:"reference"`
+	ClientSecret   string             `json:"client_secret,omitempty"`
+	CreatedBy      string             `json:"created_by"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
+}
+
+func responseFromDBPayment(payment db.Payment, loc *time.Location) PaymentResponse {
+	return PaymentResponse{
+		PaymentID:      payment.ID,
+		BookingID:      payment.BookingID,
+		Provider:       payment.Provider,
+		ProviderRef:    payment.ProviderRef,
+		Amount:         payment.Amount,
+		RefundedAmount: payment.RefundedAmount,
+		Currency:       payment.Currency,
+		Status:         payment.Status,
+		Method:         payment.Method,
+		Reference:      payment.Reference,
+		CreatedBy:      payment.CreatedBy,
+		CreatedAt:      inLocation(payment.CreatedAt, loc),
+		UpdatedAt:      inLocation(payment.UpdatedAt, loc),
+	}
+}
+
+func writePaymentConflict(w http.ResponseWriter, message string) {
+	w.WriteHeader(http.StatusConflict)
+	err := json.NewEncoder(w).Encode(ErrorResponse{Message: message})
+	if err != nil {
+		log.Printf("encoding payment conflict response failed with %v", err)
+	}
+}
+
+// applyPaymentEvent works out the change event makes to payment. Payments only
+// move forward, pending to captured or failed and captured to refunded, so
+// events that arrive late or twice change nothing.
+func applyPaymentEvent(payment db.Payment, event PaymentEvent) (db.UpdatePaymentStatusParams, bool) {
+	update := db.UpdatePaymentStatusParams{
+		ID:             payment.ID,
+		Status:         event.Status,
+		RefundedAmount: max(payment.RefundedAmount, min(event.RefundedAmount, payment.Amount)),
+	}
+	if event.Status == db.PaymentStatusRefunded && event.RefundedAmount == 0 {
+		update.RefundedAmount = payment.Amount
+	}
+
+	switch payment.Status {
+	case db.PaymentStatusPending:
+		return update, event.Status != db.PaymentStatusPending
+	case db.PaymentStatusCaptured:
+		if event.Status == db.PaymentStatusRefunded {
+			return update, true
+		}
+		return update, event.Status == db.PaymentStatusCaptured && update.RefundedAmount > payment.RefundedAmount
+	}
+	return update, false
+}
+
+// paymentIdempotencyKey is sent when the attempt'th payment of bookingID is
+// started so a retry after the provider took it gets back the same intent
+func paymentIdempotencyKey(bookingID int32, attempt int) string {
+	return fmt.Sprintf("mirai_payment_%d_%d", bookingID, attempt)
+}
+
+// pendingAmount is how much of payments is waiting to be captured
+func pendingAmount(payments []db.Payment) int32 {
+	var pending int32
+	for _, payment := range payments {
+		if payment.Status == db.PaymentStatusPending {
+			pending += payment.Amount
+		}
+	}
+	return pending
+}
+
+// lockPayment loads payment id for update, writing a 404 if it doesnt exist
+func lockPayment(w http.ResponseWriter, ctx context.Context, queries *db.Queries, id int32, caller string) (db.Payment, bool) {
+	payment, err := queries.LockPayment(ctx, id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("error getting payment in %s: %v", caller, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return payment, false
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("payment id: %d was requested in %s and does not exist", id, caller)
+		w.WriteHeader(http.StatusNotFound)
+		return payment, false
+	}
+	return payment, true
+}
+
+// postPayment starts a payment of what is outstanding on a booking with the
+// provider, or with deposit=true in the query string just what is left of its
+// deposit. The customer completes it with the client secret and it is taken
+// once captured. Payments still pending are left out so a retried request
+// doesnt start a second payment for the same amount.
+func postPayment(pool *pgxpool.Pool, ctx context.Context, pp PaymentParams) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
+		loc, ok := requestLocation(w, r)
+		if !ok {
+			return
+		}
+
+		bookingId := r.PathValue("booking_id")
+		id, err := strconv.ParseInt(bookingId, 10, 32)
+		if err != nil {
+			log.Printf("error: %v converting booking id to int in postPayment: %s", err, bookingId)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		depositOnly := false
+		if d := r.URL.Query().Get("deposit"); d != "" {
+			depositOnly, err = strconv.ParseBool(d)
+			if err != nil {
+				log.Printf("invalid deposit %q in postPayment", d)
+				http.Error(w, fmt.Sprintf("deposit %q is not true or false", d), http.StatusBadRequest)
+				return
+			}
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in postPayment: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			log.Printf("error beginning tx in postPayment: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		defer func() {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				panic(err)
+			}
+		}()
+
+		queries := db.New(conn)
+		qtx := queries.WithTx(tx)
+
+		booking, ok := getOwnedBooking(w, ctx, qtx, principal, int32(id), "postPayment")
+		if !ok {
+			return
+		}
+
+		// payments for the same booking are started one at a time so each sees
+		// those started before it
+		_, err = qtx.LockBooking(ctx, booking.ID)
+		if err != nil {
+			log.Printf("locking booking in postPayment failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		balance, err := bookingBalance(ctx, qtx, booking.ID)
+		if err != nil {
+			log.Printf("getting balance in postPayment failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if balance.Outstanding <= 0 {
+			log.Printf("payment requested for booking %d in postPayment that has nothing to pay", booking.ID)
+			writePaymentConflict(w, "Booking has nothing to pay")
+			return
+		}
+
+		payments, err := qtx.GetBookingPayments(ctx, booking.ID)
+		if err != nil {
+			log.Printf("getting payments in postPayment failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		pending := pendingAmount(payments)
+
+		amount := balance.Outstanding - pending
+		if amount <= 0 {
+			log.Printf("payment requested for booking %d in postPayment with %d already pending", booking.ID, pending)
+			writePaymentConflict(w, "Booking already has a payment in progress")
+			return
+		}
+		if depositOnly {
+			current, err := qtx.GetBookingById(ctx, booking.ID)
+			if err != nil {
+				log.Printf("getting booking deposit in postPayment failed with %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			amount = min(current.Deposit-balance.Paid-pending, amount)
+			if amount <= 0 {
+				log.Printf("deposit payment requested for booking %d in postPayment that has no deposit left to pay", booking.ID)
+				writePaymentConflict(w, "Booking deposit is already paid or in progress")
+				return
+			}
+		}
+
+		intent, err := pp.Provider.CreateIntent(ctx, booking.ID, amount, pp.Currency, paymentIdempotencyKey(booking.ID, len(payments)))
+		if err != nil {
+			log.Printf("creating payment intent with %s in postPayment failed with %v", pp.Provider.Name(), err)
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		payment, err := qtx.CreatePayment(ctx, db.CreatePaymentParams{
+			BookingID:   booking.ID,
+			Provider:    pp.Provider.Name(),
+			ProviderRef: pgtype.Text{String: intent.Reference, Valid: true},
+			Amount:      amount,
+			Currency:    pp.Currency,
+			Status:      intent.Status,
+			CreatedBy:   principal.Email,
+		})
+		if err != nil {
+			log.Printf("creating payment in postPayment failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if intent.Status == db.PaymentStatusCaptured {
+			entry := ledgerEntry(booking.ID, db.LedgerEntryKindPayment, payment.Amount, principal.Email, "")
+			entry.PaymentID = pgtype.Int4{Int32: payment.ID, Valid: true}
+			_, err = addLedgerEntries(ctx, qtx, booking.ID, entry)
+			if err != nil {
+				log.Printf("recording payment in postPayment failed with %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Printf("error commiting tx in postPayment: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := responseFromDBPayment(payment, loc)
+		response.ClientSecret = intent.ClientSecret
+
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			log.Printf("error encoding json in postPayment: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func getBookingPayments(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
+		loc, ok := requestLocation(w, r)
+		if !ok {
+			return
+		}
+
+		bookingId := r.PathValue("booking_id")
+		id, err := strconv.ParseInt(bookingId, 10, 32)
+		if err != nil {
+			log.Printf("error: %v converting booking id to int in getBookingPayments: %s", err, bookingId)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in getBookingPayments: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		queries := db.New(conn)
+
+		booking, ok := getOwnedBooking(w, ctx, queries, principal, int32(id), "getBookingPayments")
+		if !ok {
+			return
+		}
+
+		payments, err := queries.GetBookingPayments(ctx, booking.ID)
+		if err != nil {
+			log.Printf("error querying payments in getBookingPayments: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := []PaymentResponse{}
+		for _, payment := range payments {
+			response = append(response, responseFromDBPayment(payment, loc))
+		}
+
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			log.Printf("error encoding json in getBookingPayments: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// postCapturePayment takes a payment that the customer has authorised
+func postCapturePayment(pool *pgxpool.Pool, ctx context.Context, pp PaymentParams) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
+		loc, ok := requestLocation(w, r)
+		if !ok {
+			return
+		}
+
+		paymentId := r.PathValue("payment_id")
+		id, err := strconv.ParseInt(paymentId, 10, 32)
+		if err != nil {
+			log.Printf("error: %v converting payment id to int in postCapturePayment: %s", err, paymentId)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in postCapturePayment: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			log.Printf("error beginning tx in postCapturePayment: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		defer func() {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				panic(err)
+			}
+		}()
+
+		queries := db.New(conn)
+		qtx := queries.WithTx(tx)
+
+		payment, ok := lockPayment(w, ctx, qtx, int32(id), "postCapturePayment")
+		if !ok {
+			return
+		}
+		if payment.Status != db.PaymentStatusPending {
+			log.Printf("capture requested for payment %d in postCapturePayment that is %s", payment.ID, payment.Status)
+			writePaymentConflict(w, "Payment is "+string(payment.Status))
+			return
+		}
+		if payment.Provider != pp.Provider.Name() {
+			log.Printf("capture requested for payment %d in postCapturePayment taken with %s", payment.ID, payment.Provider)
+			writePaymentConflict(w, "Payment was not taken with "+pp.Provider.Name())
+			return
+		}
+
+		intent, err := pp.Provider.Capture(ctx, payment.ProviderRef.String)
+		if err != nil {
+			log.Printf("capturing payment %d with %s in postCapturePayment failed with %v", payment.ID, payment.Provider, err)
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		update, changed := applyPaymentEvent(payment, PaymentEvent{Reference: intent.Reference, Status: intent.Status})
+		if changed {
+			err = recordPaymentStatus(ctx, qtx, payment, update, principal.Email, "")
+			if err != nil {
+				log.Printf("recording capture of payment %d in postCapturePayment failed with %v", payment.ID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			err = refundClosedBooking(ctx, qtx, pp, payment.BookingID, principal.Email)
+			if err != nil {
+				log.Printf("refunding closed booking %d in postCapturePayment failed with %v", payment.BookingID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			payment, err = qtx.GetPaymentById(ctx, payment.ID)
+			if err != nil {
+				log.Printf("getting captured payment %d in postCapturePayment failed with %v", id, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Printf("error commiting tx in postCapturePayment: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		sendPaymentRefunds(ctx, pool, pp)
+
+		err = json.NewEncoder(w).Encode(responseFromDBPayment(payment, loc))
+		if err != nil {
+			log.Printf("error encoding json in postCapturePayment: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// postRefundPayment gives back some or all of a captured payment, see
+// refundPayment
+func postRefundPayment(pool *pgxpool.Pool, ctx context.Context, pp PaymentParams) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
+		loc, ok := requestLocation(w, r)
+		if !ok {
+			return
+		}
+
+		paymentId := r.PathValue("payment_id")
+		id, err := strconv.ParseInt(paymentId, 10, 32)
+		if err != nil {
+			log.Printf("error: %v converting payment id to int in postRefundPayment: %s", err, paymentId)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var refundRequest RefundPaymentRequest
+
+		err = json.NewDecoder(r.Body).Decode(&refundRequest)
+		if err != nil && !errors.Is(err, io.EOF) {
+			log.Printf("error decoding body in postRefundPayment: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if refundRequest.Amount < 0 {
+			log.Printf("negative refund requested in postRefundPayment")
+			http.Error(w, "amount must not be negative", http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in postRefundPayment: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			log.Printf("error beginning tx in postRefundPayment: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		defer func() {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				panic(err)
+			}
+		}()
+
+		queries := db.New(conn)
+		qtx := queries.WithTx(tx)
+
+		payment, ok := lockPayment(w, ctx, qtx, int32(id), "postRefundPayment")
+		if !ok {
+			return
+		}
+		if payment.Status != db.PaymentStatusCaptured {
+			log.Printf("refund requested for payment %d in postRefundPayment that is %s", payment.ID, payment.Status)
+			writePaymentConflict(w, "Payment is "+string(payment.Status))
+			return
+		}
+		remaining := payment.Amount - payment.RefundedAmount
+		amount := refundRequest.Amount
+		if amount == 0 {
+			amount = remaining
+		}
+		if amount > remaining {
+			log.Printf("refund of %d requested for payment %d in postRefundPayment with %d left", amount, payment.ID, remaining)
+			http.Error(w, fmt.Sprintf("amount must be at most %d", remaining), http.StatusBadRequest)
+			return
+		}
+
+		if payment.Provider != manualProvider && payment.Provider != pp.Provider.Name() {
+			log.Printf("refund requested for payment %d in postRefundPayment taken with %s", payment.ID, payment.Provider)
+			writePaymentConflict(w, "Payment was not taken with "+pp.Provider.Name())
+			return
+		}
+
+		payment, err = refundPayment(ctx, qtx, payment, amount, principal.Email, "")
+		if err != nil {
+			log.Printf("recording refund of payment %d in postRefundPayment failed with %v", payment.ID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Printf("error commiting tx in postRefundPayment: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		sendPaymentRefunds(ctx, pool, pp)
+
+		err = json.NewEncoder(w).Encode(responseFromDBPayment(payment, loc))
+		if err != nil {
+			log.Printf("error encoding json in postRefundPayment: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// postPaymentWebhook takes signed events from the provider. It is not behind
+// auth, the signature is what proves the event came from the provider.
+func postPaymentWebhook(pool *pgxpool.Pool, ctx context.Context, pp PaymentParams) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBytes))
+		if err != nil {
+			log.Printf("error reading body in postPaymentWebhook: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		event, err := pp.Provider.VerifyWebhook(payload, r.Header)
+		if err != nil {
+			log.Printf("rejected webhook in postPaymentWebhook: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if event.Reference == "" {
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in postPaymentWebhook: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			log.Printf("error beginning tx in postPaymentWebhook: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		defer func() {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				panic(err)
+			}
+		}()
+
+		queries := db.New(conn)
+		qtx := queries.WithTx(tx)
+
+		payment, err := qtx.LockPaymentByProviderRef(ctx, db.LockPaymentByProviderRefParams{
+			Provider:    pp.Provider.Name(),
+			ProviderRef: pgtype.Text{String: event.Reference, Valid: true},
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			// the provider may be shared with other apps so unknown payments
+			// are acknowledged and ignored
+			log.Printf("webhook for unknown payment %s in postPaymentWebhook", event.Reference)
+			return
+		}
+		if err != nil {
+			log.Printf("error getting payment in postPaymentWebhook: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		update, changed := applyPaymentEvent(payment, event)
+		if !changed {
+			return
+		}
+		err = recordPaymentStatus(ctx, qtx, payment, update, pp.Provider.Name(), "")
+		if err != nil {
+			log.Printf("recording webhook for payment %d in postPaymentWebhook failed with %v", payment.ID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		err = refundClosedBooking(ctx, qtx, pp, payment.BookingID, pp.Provider.Name())
+		if err != nil {
+			log.Printf("refunding closed booking %d in postPaymentWebhook failed with %v", payment.BookingID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Printf("error commiting tx in postPaymentWebhook: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		sendPaymentRefunds(ctx, pool, pp)
+	}
+}
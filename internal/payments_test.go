@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCurrency(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		t.Parallel()
+		currency, err := parseCurrency("")
+		assert.NoError(t, err)
+		assert.Equal(t, DefaultCurrency, currency)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+		currency, err := parseCurrency("EUR")
+		assert.NoError(t, err)
+		assert.Equal(t, "eur", currency)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		for _, value := range []string{"euro", "e1r", "£"} {
+			_, err := parseCurrency(value)
+			assert.Error(t, err)
+		}
+	})
+}
+
+func TestPendingAmount(t *testing.T) {
+	t.Parallel()
+	payments := []db.Payment{
+		{Amount: 1000, Status: db.PaymentStatusPending},
+		{Amount: 2400, Status: db.PaymentStatusCaptured},
+		{Amount: 500, Status: db.PaymentStatusFailed},
+		{Amount: 400, Status: db.PaymentStatusPending},
+	}
+	assert.Equal(t, int32(1400), pendingAmount(payments))
+	assert.Equal(t, int32(0), pendingAmount(nil))
+}
+
+func TestApplyPaymentEvent(t *testing.T) {
+	pending := db.Payment{ID: 1, Amount: 2400, Status: db.PaymentStatusPending}
+	captured := db.Payment{ID: 1, Amount: 2400, Status: db.PaymentStatusCaptured}
+
+	t.Run("pending is captured", func(t *testing.T) {
+		t.Parallel()
+		update, changed := applyPaymentEvent(pending, PaymentEvent{Status: db.PaymentStatusCaptured})
+		assert.True(t, changed)
+		assert.Equal(t, db.UpdatePaymentStatusParams{ID: 1, Status: db.PaymentStatusCaptured}, update)
+	})
+
+	t.Run("partial refund", func(t *testing.T) {
+		t.Parallel()
+		update, changed := applyPaymentEvent(captured, PaymentEvent{Status: db.PaymentStatusCaptured, RefundedAmount: 400})
+		assert.True(t, changed)
+		assert.Equal(t, db.UpdatePaymentStatusParams{ID: 1, Status: db.PaymentStatusCaptured, RefundedAmount: 400}, update)
+	})
+
+	t.Run("full refund without an amount", func(t *testing.T) {
+		t.Parallel()
+		update, changed := applyPaymentEvent(captured, PaymentEvent{Status: db.PaymentStatusRefunded})
+		assert.True(t, changed)
+		assert.Equal(t, db.UpdatePaymentStatusParams{ID: 1, Status: db.PaymentStatusRefunded, RefundedAmount: 2400}, update)
+	})
+
+	t.Run("late or repeated events change nothing", func(t *testing.T) {
+		t.Parallel()
+		_, changed := applyPaymentEvent(captured, PaymentEvent{Status: db.PaymentStatusCaptured})
+		assert.False(t, changed)
+
+		_, changed = applyPaymentEvent(captured, PaymentEvent{Status: db.PaymentStatusFailed})
+		assert.False(t, changed)
+
+		_, changed = applyPaymentEvent(pending, PaymentEvent{Status: db.PaymentStatusPending})
+		assert.False(t, changed)
+
+		refunded := db.Payment{ID: 1, Amount: 2400, RefundedAmount: 2400, Status: db.PaymentStatusRefunded}
+		_, changed = applyPaymentEvent(refunded, PaymentEvent{Status: db.PaymentStatusCaptured})
+		assert.False(t, changed)
+	})
+}
+
+func TestPostManualPaymentRequestValidate(t *testing.T) {
+	t.Run("what is outstanding", func(t *testing.T) {
+		t.Parallel()
+		r := PostManualPaymentRequest{Method: "cash"}
+		assert.NoError(t, r.validate(2400))
+		assert.Equal(t, int32(2400), r.amountFor(2400))
+	})
+
+	t.Run("part of what is outstanding", func(t *testing.T) {
+		t.Parallel()
+		r := PostManualPaymentRequest{Amount: 1000, Method: "bank_transfer", Reference: "INV-12"}
+		assert.NoError(t, r.validate(2400))
+		assert.Equal(t, int32(1000), r.amountFor(2400))
+	})
+
+	t.Run("more than is outstanding", func(t *testing.T) {
+		t.Parallel()
+		assert.Error(t, PostManualPaymentRequest{Amount: 2401, Method: "cash"}.validate(2400))
+	})
+
+	t.Run("negative amount", func(t *testing.T) {
+		t.Parallel()
+		assert.Error(t, PostManualPaymentRequest{Amount: -100, Method: "cash"}.validate(2400))
+	})
+
+	t.Run("unknown or missing method", func(t *testing.T) {
+		t.Parallel()
+		assert.Error(t, PostManualPaymentRequest{}.validate(2400))
+		assert.Error(t, PostManualPaymentRequest{Method: "cheque"}.validate(2400))
+	})
+}
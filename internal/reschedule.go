@@ -0,0 +1,326 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrTooManyReschedules = errors.New("booking has been rescheduled too many times")
+	ErrRescheduleTooLate  = errors.New("booking is too close to its start time to reschedule")
+)
+
+// DefaultMaxReschedules and DefaultRescheduleNoticeHours limit rescheduling
+// when RESCHEDULE_MAX and RESCHEDULE_NOTICE_HOURS arent set
+const (
+	DefaultMaxReschedules        = 2
+	DefaultRescheduleNoticeHours = 24
+)
+
+// RescheduleParams limits how often and how late a booking can be moved
+type RescheduleParams struct {
+	MaxReschedules int64
+	MinNotice      time.Duration
+}
+
+// parseRescheduleParams reads the most reschedules a booking can have and how
+// many hours before its start it can last be moved from configuration
+func parseRescheduleParams(maxReschedules string, noticeHours string) (RescheduleParams, error) {
+	params := RescheduleParams{
+		MaxReschedules: DefaultMaxReschedules,
+		MinNotice:      DefaultRescheduleNoticeHours * time.Hour,
+	}
+	if maxReschedules != "" {
+		limit, err := strconv.ParseInt(maxReschedules, 10, 32)
+		if err != nil || limit < 0 {
+			return RescheduleParams{}, fmt.Errorf("reschedule limit %q is not a whole number of reschedules", maxReschedules)
+		}
+		params.MaxReschedules = limit
+	}
+	if noticeHours != "" {
+		hours, err := strconv.ParseInt(noticeHours, 10, 32)
+		if err != nil || hours < 0 {
+			return RescheduleParams{}, fmt.Errorf("reschedule notice %q is not a whole number of hours", noticeHours)
+		}
+		params.MinNotice = time.Duration(hours) * time.Hour
+	}
+	return params, nil
+}
+
+// check returns an error if a booking that starts at start and has already been
+// rescheduled reschedules times cant be rescheduled again at now
+func (p RescheduleParams) check(reschedules int64, start time.Time, now time.Time) error {
+	if reschedules >= p.MaxReschedules {
+		return ErrTooManyReschedules
+	}
+	if start.Sub(now) < p.MinNotice {
+		return ErrRescheduleTooLate
+	}
+	return nil
+}
+
+type RescheduleBookingRequest struct {
+	AvailabilitySlots []int32 `json:"availability_slots"`
+	HoldID            int32   `json:"hold_id"` // optional, the held slots are moved to
+}
+
+type RescheduleBookingResponse struct {
+	BookingID int32     `json:"booking_id"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Cost      int32     `json:"cost"`
+}
+
+func postRescheduleBooking(pool *pgxpool.Pool, ctx context.Context, rp RescheduleParams) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
+		loc, ok := requestLocation(w, r)
+		if !ok {
+			return
+		}
+
+		bookingId := r.PathValue("booking_id")
+		id, err := strconv.ParseInt(bookingId, 10, 32)
+		if err != nil {
+			log.Printf("error: %v converting booking id to int in postRescheduleBooking: %s", err, bookingId)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var rescheduleRequest RescheduleBookingRequest
+
+		err = json.NewDecoder(r.Body).Decode(&rescheduleRequest)
+		if err != nil {
+			log.Printf("error decoding body in postRescheduleBooking: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in postRescheduleBooking: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			log.Printf("error beginning tx in postRescheduleBooking: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		defer func() {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				panic(err)
+			}
+		}()
+
+		queries := db.New(conn)
+		qtx := queries.WithTx(tx)
+
+		existing, ok := getOwnedBooking(w, ctx, qtx, principal, int32(id), "postRescheduleBooking")
+		if !ok {
+			return
+		}
+
+		err = checkTransition(principal, existing.Status, db.BookingStatusRescheduled, existing.UserID)
+		if err != nil {
+			log.Printf("user %d cant reschedule booking %d with status %s: %v", principal.UserID, id, existing.Status, err)
+			writeTransitionError(w, err, principal, existing.Status, existing.UserID)
+			return
+		}
+
+		reschedules, err := qtx.CountBookingReschedules(ctx, int32(id))
+		if err != nil {
+			log.Printf("counting reschedules in postRescheduleBooking failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// admins can move bookings regardless of the limits
+		if !principal.IsAdmin() {
+			err = rp.check(reschedules, existing.StartTime.Time, time.Now().UTC())
+			if err != nil {
+				log.Printf("user %d cant reschedule booking %d: %v", principal.UserID, id, err)
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				err = json.NewEncoder(w).Encode(ErrorResponse{Message: err.Error()})
+				if err != nil {
+					log.Printf("error encoding json in postRescheduleBooking: %v", err)
+				}
+				return
+			}
+		}
+
+		// a reschedule made from a hold moves the booking to the held slots, the
+		// hold is consumed below once the booking has moved
+		if rescheduleRequest.HoldID != 0 {
+			slotIDs, ok := checkSlotHold(w, ctx, qtx, principal, rescheduleRequest.HoldID, existing.UserID, rescheduleRequest.AvailabilitySlots, "postRescheduleBooking")
+			if !ok {
+				return
+			}
+			rescheduleRequest.AvailabilitySlots = slotIDs
+		}
+
+		duration := len(rescheduleRequest.AvailabilitySlots)
+		if duration < 1 {
+			log.Printf("requested a reschedule with no slots")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		// the old slots are released first so that the new set can overlap them
+		err = qtx.FreeAvailabilitySlot(ctx, int32(id))
+		if err != nil {
+			log.Printf("releasing old slots in postRescheduleBooking failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		taken, err := claimSlots(ctx, qtx, rescheduleRequest.AvailabilitySlots, rescheduleRequest.HoldID)
+		if errors.Is(err, ErrUnknownSlots) {
+			log.Printf("reschedule requested for slots %v that dont all exist", rescheduleRequest.AvailabilitySlots)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Printf("claiming slots in postRescheduleBooking failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if len(taken) > 0 {
+			log.Printf("user %d requested a reschedule onto taken slots %v", principal.UserID, taken)
+			writeSlotConflict(w, "Some of the requested slots are no longer available", taken)
+			return
+		}
+
+		bookingType, err := qtx.GetBookingTypeById(ctx, existing.TypeID)
+		if err != nil {
+			log.Printf("getting booking type failed in postRescheduleBooking: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		slots, err := qtx.GetAvailabilitySlotByIds(ctx, rescheduleRequest.AvailabilitySlots)
+		if err != nil {
+			log.Printf("getting slots for validation failed in postRescheduleBooking: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		slotTypes, err := slotTypeIDs(ctx, qtx, slots)
+		if err != nil {
+			log.Printf("getting slot booking types failed in postRescheduleBooking: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if errs := validateBookingSlots(bookingType, rescheduleRequest.AvailabilitySlots, slots, slotTypes); len(errs) > 0 {
+			log.Printf("invalid slots %v for booking type %d in postRescheduleBooking: %v", rescheduleRequest.AvailabilitySlots, bookingType.ID, errs)
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		if !checkBookingLimits(w, ctx, qtx, principal, bookingType, slots, "postRescheduleBooking") {
+			return
+		}
+
+		if !checkBookingResources(w, ctx, qtx, bookingType, slots, "postRescheduleBooking") {
+			return
+		}
+
+		for _, slotID := range rescheduleRequest.AvailabilitySlots {
+			err = qtx.CreateBookingSlot(ctx, db.CreateBookingSlotParams{
+				BookingID:          int32(id),
+				AvailabilitySlotID: slotID,
+			})
+			if err != nil {
+				log.Printf("claiming slot %d in postRescheduleBooking failed with %v", slotID, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
+		cost := bookingCost(bookingType, int32(duration))
+
+		err = qtx.RescheduleBooking(ctx, db.RescheduleBookingParams{
+			ID:              int32(id),
+			Cost:            cost,
+			StatusUpdatedBy: principal.Email,
+		})
+		if err != nil {
+			log.Printf("updating booking in postRescheduleBooking failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		err = adjustBookingCharge(ctx, qtx, int32(id), existing.Cost, cost, principal.Email, rescheduledReason)
+		if err != nil {
+			log.Printf("adjusting charge in postRescheduleBooking failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		bookingRow, err := qtx.GetBookingWithJoin(ctx, int32(id))
+		if err != nil {
+			log.Printf("getting rescheduled booking in postRescheduleBooking failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		history := bookingHistoryParams(bookingRow, db.BookingStatusRescheduled, principal.Email)
+		history.PreviousStartTime = existing.StartTime
+		history.PreviousEndTime = existing.EndTime
+		err = qtx.CreateBookingHistory(ctx, history)
+		if err != nil {
+			log.Printf("creating booking history in postRescheduleBooking failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if rescheduleRequest.HoldID != 0 {
+			_, err = qtx.DeleteSlotHold(ctx, rescheduleRequest.HoldID)
+			if err != nil {
+				log.Printf("error consuming hold in postRescheduleBooking: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Printf("error commiting tx in postRescheduleBooking: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := RescheduleBookingResponse{
+			BookingID: bookingRow.ID,
+			StartTime: bookingRow.StartTime.Time.In(loc),
+			EndTime:   bookingRow.EndTime.Time.In(loc),
+			Cost:      bookingRow.Cost,
+		}
+
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			log.Printf("error encoding json in postRescheduleBooking: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
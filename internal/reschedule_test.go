@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRescheduleParamsCheck(t *testing.T) {
+	rp := RescheduleParams{
+		MaxReschedules: 2,
+		MinNotice:      24 * time.Hour,
+	}
+	now, _ := time.Parse(time.RFC3339, "2025-09-08T14:00:00Z")
+
+	t.Run("allowed", func(t *testing.T) {
+		t.Parallel()
+		assert.NoError(t, rp.check(0, now.Add(48*time.Hour), now))
+		assert.NoError(t, rp.check(1, now.Add(24*time.Hour), now))
+	})
+
+	t.Run("too many", func(t *testing.T) {
+		t.Parallel()
+		assert.ErrorIs(t, rp.check(2, now.Add(48*time.Hour), now), ErrTooManyReschedules)
+	})
+
+	t.Run("too late", func(t *testing.T) {
+		t.Parallel()
+		assert.ErrorIs(t, rp.check(0, now.Add(23*time.Hour), now), ErrRescheduleTooLate)
+		assert.ErrorIs(t, rp.check(0, now.Add(-time.Hour), now), ErrRescheduleTooLate)
+	})
+}
+
+func TestParseRescheduleParams(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		t.Parallel()
+		params, err := parseRescheduleParams("", "")
+		assert.NoError(t, err)
+		assert.Equal(t, RescheduleParams{MaxReschedules: DefaultMaxReschedules, MinNotice: DefaultRescheduleNoticeHours * time.Hour}, params)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+		params, err := parseRescheduleParams("0", "48")
+		assert.NoError(t, err)
+		assert.Equal(t, RescheduleParams{MaxReschedules: 0, MinNotice: 48 * time.Hour}, params)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		for _, values := range [][2]string{{"-1", ""}, {"twice", ""}, {"", "-2"}, {"", "a day"}} {
+			_, err := parseRescheduleParams(values[0], values[1])
+			assert.Error(t, err)
+		}
+	})
+}
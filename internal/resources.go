@@ -0,0 +1,441 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// GetResourceResponse is something other than the employee that a booking
+// needs, like a room or a chair. Booking types list the resources they use and
+// a resource can only be used by one booking at a time.
+type GetResourceResponse struct {
+	ResourceID  int32              `json:"resource_id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	LastEdited  pgtype.Timestamptz `json:"last_edited"`
+}
+
+func responseFromDBResource(resource db.Resource) GetResourceResponse {
+	return GetResourceResponse{
+		ResourceID:  resource.ID,
+		Name:        resource.Name,
+		Description: resource.Description,
+		CreatedAt:   resource.CreatedAt,
+		LastEdited:  resource.LastEdited,
+	}
+}
+
+type PostResourceRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (r PostResourceRequest) ToDBParams() (db.CreateResourceParams, error) {
+	name := strings.TrimSpace(r.Name)
+	if name == "" {
+		return db.CreateResourceParams{}, errors.New("name is required")
+	}
+	return db.CreateResourceParams{
+		Name:        name,
+		Description: r.Description,
+	}, nil
+}
+
+type PostResourceResponse struct {
+	ResourceID int32 `json:"resource_id"`
+}
+
+type PutResourceRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (r PutResourceRequest) ToDBParams(resourceID int32) (db.UpdateResourceParams, error) {
+	name := strings.TrimSpace(r.Name)
+	if name == "" {
+		return db.UpdateResourceParams{}, errors.New("name is required")
+	}
+	return db.UpdateResourceParams{
+		ID:          resourceID,
+		Name:        name,
+		Description: r.Description,
+	}, nil
+}
+
+type PutResourceResponse struct {
+	ResourceID int32 `json:"resource_id"`
+}
+
+// resourceBooking is the time a booking uses one of its resources for
+type resourceBooking struct {
+	ResourceID int32
+	EmployeeID int32
+	TypeID     int32
+	Start      time.Time
+	End        time.Time
+}
+
+// getResourceBookings returns the bookings that use any of resourceIDs between
+// from and to
+func getResourceBookings(ctx context.Context, queries *db.Queries, resourceIDs []int32, from time.Time, to time.Time) ([]resourceBooking, error) {
+	rows, err := queries.GetResourceBookedTimes(ctx, db.GetResourceBookedTimesParams{
+		ResourceIds: resourceIDs,
+		FromTime:    pgtype.Timestamptz{Time: from, Valid: true},
+		ToTime:      pgtype.Timestamptz{Time: to, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	booked := []resourceBooking{}
+	for _, row := range rows {
+		booked = append(booked, resourceBooking{
+			ResourceID: row.ResourceID,
+			EmployeeID: row.EmployeeID,
+			TypeID:     row.TypeID,
+			Start:      row.StartTime.Time,
+			End:        row.EndTime.Time,
+		})
+	}
+	return booked, nil
+}
+
+// busyResources returns the resources of resourceIDs that are used by one of
+// booked at some point between start and end, other than by the same class of
+// bookingType with employeeID
+func busyResources(resourceIDs []int32, bookingType db.BookingType, employeeID int32, start time.Time, end time.Time, booked []resourceBooking) []int32 {
+	busy := []int32{}
+	for _, b := range booked {
+		if !slices.Contains(resourceIDs, b.ResourceID) || slices.Contains(busy, b.ResourceID) {
+			continue
+		}
+		if b.EmployeeID == employeeID && sameClass(b.TypeID, b.Start, b.End, bookingType.ID, start, end) {
+			continue
+		}
+		if b.Start.Before(end) && b.End.After(start) {
+			busy = append(busy, b.ResourceID)
+		}
+	}
+	return busy
+}
+
+// typeResourceIDs returns the resources each of typeIDs uses, types without any
+// resources are left out
+func typeResourceIDs(ctx context.Context, queries *db.Queries, typeIDs []int32) (map[int32][]int32, error) {
+	rows, err := queries.GetBookingTypeResources(ctx, typeIDs)
+	if err != nil {
+		return nil, err
+	}
+	resources := map[int32][]int32{}
+	for _, row := range rows {
+		resources[row.TypeID] = append(resources[row.TypeID], row.ResourceID)
+	}
+	return resources, nil
+}
+
+// setBookingTypeResources replaces the resources that typeID uses
+func setBookingTypeResources(ctx context.Context, queries *db.Queries, typeID int32, resourceIDs []int32) error {
+	err := queries.DeleteBookingTypeResources(ctx, typeID)
+	if err != nil {
+		return err
+	}
+	if len(resourceIDs) == 0 {
+		return nil
+	}
+	return queries.CreateBookingTypeResources(ctx, db.CreateBookingTypeResourcesParams{
+		TypeID:  typeID,
+		Column2: resourceIDs,
+	})
+}
+
+// ResourcesBusyError is returned when resources a booking needs are in use
+type ResourcesBusyError struct {
+	ResourceIDs []int32
+}
+
+func (e *ResourcesBusyError) Error() string {
+	return fmt.Sprintf("Resources %v are not free at the requested time", e.ResourceIDs)
+}
+
+// bookingResourcesError checks that every resource bookingType uses is free for
+// the whole of slots, returning a *ResourcesBusyError if any arent or the error
+// from the database
+func bookingResourcesError(ctx context.Context, queries *db.Queries, bookingType db.BookingType, slots []db.Availability) error {
+	resources, err := typeResourceIDs(ctx, queries, []int32{bookingType.ID})
+	if err != nil {
+		return err
+	}
+	resourceIDs := resources[bookingType.ID]
+	if len(resourceIDs) == 0 {
+		return nil
+	}
+
+	// locking the resources stops two bookings with different employees both
+	// taking the same room
+	_, err = queries.LockResources(ctx, resourceIDs)
+	if err != nil {
+		return err
+	}
+	start, end := slotsSpan(slots)
+	booked, err := getResourceBookings(ctx, queries, resourceIDs, start, end)
+	if err != nil {
+		return err
+	}
+	if busy := busyResources(resourceIDs, bookingType, slots[0].EmployeeID, start, end, booked); len(busy) > 0 {
+		return &ResourcesBusyError{ResourceIDs: busy}
+	}
+	return nil
+}
+
+// checkBookingResources checks the resources of a booking of bookingType on
+// slots with bookingResourcesError. It writes the response and returns false if
+// they arent free.
+func checkBookingResources(w http.ResponseWriter, ctx context.Context, queries *db.Queries, bookingType db.BookingType, slots []db.Availability, caller string) bool {
+	err := bookingResourcesError(ctx, queries, bookingType, slots)
+	if err == nil {
+		return true
+	}
+	var busyErr *ResourcesBusyError
+	if errors.As(err, &busyErr) {
+		log.Printf("booking of type %d at %s in %s needs resources %v which are in use", bookingType.ID, slots[0].Datetime.Time, caller, busyErr.ResourceIDs)
+		writeSlotConflict(w, busyErr.Error(), nil)
+		return false
+	}
+	log.Printf("checking booking resources in %s failed with %v", caller, err)
+	w.WriteHeader(http.StatusInternalServerError)
+	return false
+}
+
+func postResource(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var resourceRequest PostResourceRequest
+
+		err := json.NewDecoder(r.Body).Decode(&resourceRequest)
+		if err != nil {
+			log.Printf("error decoding body in postResource: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		params, err := resourceRequest.ToDBParams()
+		if err != nil {
+			log.Printf("invalid resource in postResource: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in postResource: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		queries := db.New(conn)
+
+		resourceID, err := queries.CreateResource(ctx, params)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+				log.Printf("uniqueness constraint violated in postResource. name: %s", params.Name)
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			log.Printf("general error when trying to create resource in postResource: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(PostResourceResponse{ResourceID: resourceID})
+		if err != nil {
+			log.Printf("error encoding json in postResource: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func getResource(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in getResource: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		queries := db.New(conn)
+
+		resourceID := r.PathValue("resource_id")
+		if resourceID == "" {
+			resources, err := queries.GetAllResources(ctx)
+			if err != nil {
+				log.Printf("error querying resources in getResource: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			resp := []GetResourceResponse{}
+			for _, resource := range resources {
+				resp = append(resp, responseFromDBResource(resource))
+			}
+
+			err = json.NewEncoder(w).Encode(resp)
+			if err != nil {
+				log.Printf("error encoding json in getResource: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			return
+		}
+
+		id, err := strconv.ParseInt(resourceID, 10, 32)
+		if err != nil {
+			log.Printf("error: %v converting resource id to int in getResource: %s", err, resourceID)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		resource, err := queries.GetResourceById(ctx, int32(id))
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("error querying resources in getResource: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("resource id: %d was requested in getResource and does not exist", id)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(responseFromDBResource(resource))
+		if err != nil {
+			log.Printf("error encoding json in getResource: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func putResource(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resourceID := r.PathValue("resource_id")
+		id, err := strconv.ParseInt(resourceID, 10, 32)
+		if err != nil {
+			log.Printf("error: %v converting resource id to int in putResource: %s", err, resourceID)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var resourceRequest PutResourceRequest
+
+		err = json.NewDecoder(r.Body).Decode(&resourceRequest)
+		if err != nil {
+			log.Printf("error decoding body in putResource: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		params, err := resourceRequest.ToDBParams(int32(id))
+		if err != nil {
+			log.Printf("invalid resource in putResource: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in putResource: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		queries := db.New(conn)
+
+		_, err = queries.UpdateResource(ctx, params)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				log.Printf("resource id: %d, which does not exist, was attemped to be updated by putResource", id)
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+				log.Printf("uniqueness constraint violated in putResource. name: %s", params.Name)
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			log.Printf("general error when trying to update resource in putResource: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(PutResourceResponse{ResourceID: int32(id)})
+		if err != nil {
+			log.Printf("error encoding json in putResource: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func deleteResource(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resourceID := r.PathValue("resource_id")
+		id, err := strconv.ParseInt(resourceID, 10, 32)
+		if err != nil {
+			log.Printf("error: %v converting resource id to int in deleteResource: %s", err, resourceID)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in deleteResource: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		queries := db.New(conn)
+
+		_, err = queries.DeleteResource(ctx, int32(id))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				log.Printf("resource id: %d, which does not exist, was attemped to be deleted by deleteResource", id)
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			// a resource cant be removed while a booking type still uses it
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+				log.Printf("resource id: %d is still used by a booking type in deleteResource", id)
+				http.Error(w, "resource is used by a booking type", http.StatusConflict)
+				return
+			}
+			log.Printf("general error when trying to delete resource in deleteResource: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceToDBParams(t *testing.T) {
+	t.Run("base", func(t *testing.T) {
+		t.Parallel()
+		r := PostResourceRequest{Name: " Room 1 ", Description: "the room at the back"}
+		params, err := r.ToDBParams()
+		assert.NoError(t, err)
+		assert.Equal(t, db.CreateResourceParams{Name: "Room 1", Description: "the room at the back"}, params)
+	})
+
+	t.Run("missing name", func(t *testing.T) {
+		t.Parallel()
+		_, err := PostResourceRequest{Name: "  "}.ToDBParams()
+		assert.Error(t, err)
+		_, err = PutResourceRequest{}.ToDBParams(1)
+		assert.Error(t, err)
+	})
+}
+
+func TestBusyResources(t *testing.T) {
+	at := func(hour int, minute int) time.Time {
+		return time.Date(2025, 7, 26, hour, minute, 0, 0, time.UTC)
+	}
+	yoga := db.BookingType{ID: 4}
+	// room 1 is used by a yoga class 10:00 to 11:00 and room 2 from 11:00 to 12:00
+	booked := []resourceBooking{
+		{ResourceID: 1, EmployeeID: 1, TypeID: 4, Start: at(10, 0), End: at(11, 0)},
+		{ResourceID: 2, EmployeeID: 2, TypeID: 5, Start: at(11, 0), End: at(12, 0)},
+	}
+
+	t.Run("free", func(t *testing.T) {
+		t.Parallel()
+		assert.Empty(t, busyResources([]int32{1}, yoga, 3, at(11, 0), at(11, 30), booked))
+		assert.Empty(t, busyResources([]int32{2}, yoga, 3, at(10, 0), at(11, 0), booked))
+	})
+
+	t.Run("overlapping", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, []int32{1}, busyResources([]int32{1, 2}, yoga, 3, at(10, 30), at(11, 0), booked))
+		assert.Equal(t, []int32{1, 2}, busyResources([]int32{1, 2}, yoga, 3, at(9, 0), at(13, 0), booked))
+	})
+
+	t.Run("another seat in the same class", func(t *testing.T) {
+		t.Parallel()
+		assert.Empty(t, busyResources([]int32{1}, yoga, 1, at(10, 0), at(11, 0), booked))
+		assert.Equal(t, []int32{1}, busyResources([]int32{1}, yoga, 3, at(10, 0), at(11, 0), booked))
+		assert.Equal(t, []int32{1}, busyResources([]int32{1}, db.BookingType{ID: 5}, 1, at(10, 0), at(11, 0), booked))
+	})
+
+	t.Run("resources not needed", func(t *testing.T) {
+		t.Parallel()
+		assert.Empty(t, busyResources([]int32{3}, yoga, 3, at(9, 0), at(13, 0), booked))
+		assert.Empty(t, busyResources(nil, yoga, 3, at(9, 0), at(13, 0), booked))
+	})
+}
@@ -8,6 +8,8 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/jack-cordery/mirai/db"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -91,14 +93,37 @@ func SetupServer() {
 		Secure:   true,
 		SameSite: http.SameSiteLaxMode,
 	}
+	// reset tokens are written to RESET_TOKEN_FILE, logging them instead is only
+	// for local development and has to be allowed with RESET_TOKEN_LOG
+	allowLog := false
+	if value := os.Getenv("RESET_TOKEN_LOG"); value != "" {
+		allowLog, err = strconv.ParseBool(value)
+		if err != nil {
+			log.Fatalf("RESET_TOKEN_LOG %q is not true or false", value)
+			return
+		}
+	}
+	sender, err := newResetTokenSender(os.Getenv("RESET_TOKEN_FILE"), allowLog)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
 	a := &AuthParams{
-		SessionDuration: 1,
-		TokenLength:     64,
-		SecretKey:       secretKey,
-		RedirectPath:    "/home",
-		LoginPath:       "/login",
-		CParams:         c,
-		HParams:         p,
+		SessionDuration:    1,
+		TokenLength:        64,
+		SecretKey:          secretKey,
+		RedirectPath:       "/home",
+		LoginPath:          "/login",
+		ResetTokenDuration: 30 * time.Minute,
+		ResetSender:        sender,
+		CParams:            c,
+		HParams:            p,
+	}
+	// minutes per slot for booking types that dont set their own
+	unit, err := parseUnit(os.Getenv("SLOT_UNIT_MINUTES"))
+	if err != nil {
+		log.Fatal(err)
+		return
 	}
 	appUrl := os.Getenv("APP_URL")
 	ctx := context.Background()
@@ -121,58 +146,178 @@ func SetupServer() {
 	}
 	defer baseConn.Release()
 
+	// customers can only move a booking so many times and not too close to
+	// its start
+	rp, err := parseRescheduleParams(os.Getenv("RESCHEDULE_MAX"), os.Getenv("RESCHEDULE_NOTICE_HOURS"))
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+
+	// recurring availability rules are kept materialised this far ahead
+	ruleHorizon := 8 * 7 * 24 * time.Hour
+	go materialiseAvailabilityRules(ctx, pool, ruleHorizon, time.Hour)
+
+	// customers are told when a booking is cancelled by someone else
+	var notifier BookingNotifier = LogBookingNotifier{}
+
+	// holds keep slots aside while a customer finishes booking them
+	holdDuration := 5 * time.Minute
+	go reapSlotHolds(ctx, pool, time.Minute)
+
+	// customers waiting for a taken slot are offered it, or booked into it,
+	// when it frees up
+	wp, err := parseWaitlistParams(os.Getenv("WAITLIST_MODE"), os.Getenv("WAITLIST_OFFER_MINUTES"))
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	go reapWaitlistOffers(ctx, pool, wp, notifier, time.Minute)
+
+	// payments for bookings are taken through the provider, the fake one
+	// keeps everything in memory and has to be allowed with PAYMENT_ALLOW_FAKE
+	// for local development
+	allowFake := false
+	if value := os.Getenv("PAYMENT_ALLOW_FAKE"); value != "" {
+		allowFake, err = strconv.ParseBool(value)
+		if err != nil {
+			log.Fatalf("PAYMENT_ALLOW_FAKE %q is not true or false", value)
+			return
+		}
+	}
+	provider, err := newPaymentProvider(
+		os.Getenv("PAYMENT_PROVIDER"),
+		os.Getenv("STRIPE_API_URL"),
+		os.Getenv("STRIPE_SECRET_KEY"),
+		os.Getenv("PAYMENT_WEBHOOK_SECRET"),
+		allowFake,
+	)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	currency, err := parseCurrency(os.Getenv("PAYMENT_CURRENCY"))
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	pp := PaymentParams{
+		Provider: provider,
+		Currency: currency,
+	}
+
+	// bookings whose deposits arent paid in time are cancelled
+	go reapUnpaidDeposits(ctx, pool, wp, pp, notifier, time.Minute)
+
+	// refunds the provider couldnt take when they were made are tried again
+	go reapPaymentRefunds(ctx, pool, pp, time.Minute)
+
+	// auth wraps a handler so that it requires a logged in user with one of roles
+	auth := func(next http.HandlerFunc, roles ...string) http.HandlerFunc {
+		return withAuth(pool, ctx, a, next, roles...)
+	}
+
 	mux.HandleFunc("GET /readyz", readyHandler(baseConn, ctx))
 	mux.HandleFunc("GET /livez", liveHandler)
+	mux.HandleFunc("GET /config", getConfig(unit))
+
+	mux.HandleFunc("POST /booking", auth(postBooking(pool, ctx), RoleAdmin, RoleUser))
+	mux.HandleFunc("GET /booking", auth(getBooking(pool, ctx), RoleAdmin))
+	mux.HandleFunc("GET /booking/user", auth(getBookingUser(pool, ctx), RoleUser))
+	mux.HandleFunc("GET /booking/{booking_id}", auth(getBooking(pool, ctx), RoleAdmin, RoleUser))
+	mux.HandleFunc("POST /hold", auth(postSlotHold(pool, ctx, holdDuration), RoleAdmin, RoleUser))
+	mux.HandleFunc("DELETE /hold/{hold_id}", auth(deleteSlotHold(pool, ctx), RoleAdmin, RoleUser))
+	mux.HandleFunc("PUT /booking/{booking_id}", auth(putBooking(pool, ctx), RoleAdmin, RoleUser))
+	mux.HandleFunc("DELETE /booking/{booking_id}", auth(deleteBooking(pool, ctx), RoleAdmin))
+	mux.HandleFunc("POST /booking/{booking_id}/payment/manual", auth(postManualPayment(pool, ctx, pp), RoleAdmin))
+	mux.HandleFunc("POST /booking/{booking_id}/payment", auth(postPayment(pool, ctx, pp), RoleAdmin, RoleUser))
+	mux.HandleFunc("GET /booking/{booking_id}/payment", auth(getBookingPayments(pool, ctx), RoleAdmin, RoleUser))
+	mux.HandleFunc("GET /booking/{booking_id}/ledger", auth(getBookingLedger(pool, ctx), RoleAdmin, RoleUser))
+	mux.HandleFunc("POST /booking/{booking_id}/ledger/adjustment", auth(postLedgerAdjustment(pool, ctx), RoleAdmin))
+	mux.HandleFunc("POST /booking/{booking_id}/cancel", auth(postManualStatus(pool, ctx, db.BookingStatusCancelled, wp, pp, notifier), RoleAdmin, RoleUser))
+	mux.HandleFunc("POST /booking/{booking_id}/confirm", auth(postManualStatus(pool, ctx, db.BookingStatusConfirmed, wp, pp, notifier), RoleAdmin, RoleUser))
+	mux.HandleFunc("POST /booking/{booking_id}/complete", auth(postManualStatus(pool, ctx, db.BookingStatusCompleted, wp, pp, notifier), RoleAdmin, RoleUser))
+	mux.HandleFunc("POST /booking/{booking_id}/no_show", auth(postManualStatus(pool, ctx, db.BookingStatusNoShow, wp, pp, notifier), RoleAdmin, RoleUser))
+	mux.HandleFunc("POST /booking/{booking_id}/reschedule", auth(postRescheduleBooking(pool, ctx, rp), RoleAdmin, RoleUser))
+
+	mux.HandleFunc("POST /payment/{payment_id}/capture", auth(postCapturePayment(pool, ctx, pp), RoleAdmin))
+	mux.HandleFunc("POST /payment/{payment_id}/refund", auth(postRefundPayment(pool, ctx, pp), RoleAdmin))
+	mux.HandleFunc("POST /payment/webhook", postPaymentWebhook(pool, ctx, pp))
+
+	mux.HandleFunc("POST /booking_series", auth(postBookingSeries(pool, ctx), RoleAdmin, RoleUser))
+	mux.HandleFunc("GET /booking_series/{series_id}", auth(getBookingSeries(pool, ctx), RoleAdmin, RoleUser))
+	mux.HandleFunc("POST /booking_series/{series_id}/cancel", auth(postCancelBookingSeries(pool, ctx, wp, pp, notifier), RoleAdmin, RoleUser))
+	mux.HandleFunc("POST /booking_series/{series_id}/reschedule", auth(postRescheduleBookingSeries(pool, ctx, rp), RoleAdmin, RoleUser))
 
-	mux.HandleFunc("POST /booking", postBooking(pool, ctx))
-	mux.HandleFunc("GET /booking", getBooking(pool, ctx))
-	mux.HandleFunc("GET /booking/user", getBookingUser(pool, ctx, a))
-	mux.HandleFunc("GET /booking/{booking_id}", getBooking(pool, ctx))
-	mux.HandleFunc("PUT /booking/{booking_id}", putBooking(pool, ctx))
-	mux.HandleFunc("DELETE /booking/{booking_id}", deleteBooking(pool, ctx))
-	mux.HandleFunc("POST /booking/{booking_id}/payment/manual", postManualPayment(pool, ctx, a))
-	mux.HandleFunc("POST /booking/{booking_id}/cancel", postManualStatus(pool, ctx, a, db.BookingStatusCancelled))
-	mux.HandleFunc("POST /booking/{booking_id}/confirm", postManualStatus(pool, ctx, a, db.BookingStatusConfirmed))
-	mux.HandleFunc("POST /booking/{booking_id}/complete", postManualStatus(pool, ctx, a, db.BookingStatusCompleted))
-
-	mux.HandleFunc("POST /user", postUser(pool, ctx))
-	mux.HandleFunc("GET /user/{user_id}", getUser(pool, ctx))
-	mux.HandleFunc("PUT /user/{user_id}", putUser(pool, ctx))
-	mux.HandleFunc("DELETE /user/{user_id}", deleteUser(pool, ctx))
-	mux.HandleFunc("GET /userByEmail", getUserByEmail(pool, ctx))
+	mux.HandleFunc("POST /waitlist", auth(postWaitlistEntry(pool, ctx), RoleAdmin, RoleUser))
+	mux.HandleFunc("GET /waitlist", auth(getWaitlistEntry(pool, ctx), RoleAdmin, RoleUser))
+	mux.HandleFunc("GET /waitlist/{waitlist_id}", auth(getWaitlistEntry(pool, ctx), RoleAdmin, RoleUser))
+	mux.HandleFunc("DELETE /waitlist/{waitlist_id}", auth(deleteWaitlistEntry(pool, ctx, wp, notifier), RoleAdmin, RoleUser))
+
+	mux.HandleFunc("POST /user", auth(postUser(pool, ctx), RoleAdmin))
+	mux.HandleFunc("GET /user/{user_id}", auth(getUser(pool, ctx)))
+	mux.HandleFunc("PUT /user/{user_id}", auth(putUser(pool, ctx)))
+	mux.HandleFunc("DELETE /user/{user_id}", auth(deleteUser(pool, ctx)))
+	mux.HandleFunc("GET /userByEmail", auth(getUserByEmail(pool, ctx), RoleAdmin))
 
 	mux.HandleFunc("POST /auth/login", postLogin(pool, ctx, a))
 	mux.HandleFunc("POST /auth/logout", postLogout(pool, ctx, a))
 	mux.HandleFunc("POST /auth/register", postRegister(pool, ctx, a))
 	mux.HandleFunc("GET /auth/session/status", getSessionStatus(pool, ctx, a))
 	mux.HandleFunc("POST /auth/session/refresh", postSessionRefresh(pool, ctx, a))
-	mux.HandleFunc("POST /auth/raise", postRaise(pool, ctx, a))
-	mux.HandleFunc("GET /auth/requests", getAllRequests(pool, ctx, a))
-	mux.HandleFunc("POST /auth/request/approve/{request_id}", postApproveRequest(pool, ctx, a))
-	mux.HandleFunc("POST /auth/request/reject/{request_id}", postRejectRequest(pool, ctx, a))
-	// mux.HandleFunc("POST /auth/change-password", postChangePassword())
-	// mux.HandleFunc("POST /auth/reset-password", postResetPassword())
-	// mux.HandleFunc("POST /auth/forgot-password", postForgotPassword())
-
-	mux.HandleFunc("POST /employee", postEmployee(pool, ctx))
+	mux.HandleFunc("POST /auth/raise", auth(postRaise(pool, ctx)))
+	mux.HandleFunc("GET /auth/requests", auth(getAllRequests(pool, ctx), RoleAdmin))
+	mux.HandleFunc("POST /auth/request/approve/{request_id}", auth(postApproveRequest(pool, ctx), RoleAdmin))
+	mux.HandleFunc("POST /auth/request/reject/{request_id}", auth(postRejectRequest(pool, ctx), RoleAdmin))
+	mux.HandleFunc("POST /auth/change-password", postChangePassword(pool, ctx, a))
+	mux.HandleFunc("POST /auth/reset-password", postResetPassword(pool, ctx, a))
+	mux.HandleFunc("POST /auth/forgot-password", postForgotPassword(pool, ctx, a))
+
+	mux.HandleFunc("POST /employee", auth(postEmployee(pool, ctx), RoleAdmin))
 	mux.HandleFunc("GET /employee/{employee_id}", getEmployee(pool, ctx))
 	mux.HandleFunc("GET /employee/", getEmployee(pool, ctx))
-	mux.HandleFunc("PUT /employee/{employee_id}", putEmployee(pool, ctx))
-	mux.HandleFunc("DELETE /employee/{employee_id}", deleteEmployee(pool, ctx))
+	mux.HandleFunc("PUT /employee/{employee_id}", auth(putEmployee(pool, ctx), RoleAdmin))
+	mux.HandleFunc("DELETE /employee/{employee_id}", auth(deleteEmployee(pool, ctx), RoleAdmin))
 
-	mux.HandleFunc("POST /booking_type", postBookingType(pool, ctx))
+	mux.HandleFunc("POST /booking_type", auth(postBookingType(pool, ctx, unit), RoleAdmin))
 	mux.HandleFunc("GET /booking_type/{type_id}", getBookingType(pool, ctx))
 	mux.HandleFunc("GET /booking_type/", getBookingType(pool, ctx))
-	mux.HandleFunc("PUT /booking_type/{type_id}", putBookingType(pool, ctx))
-	mux.HandleFunc("DELETE /booking_type/{type_id}", deleteBookingType(pool, ctx))
+	mux.HandleFunc("PUT /booking_type/{type_id}", auth(putBookingType(pool, ctx), RoleAdmin))
+	mux.HandleFunc("DELETE /booking_type/{type_id}", auth(deleteBookingType(pool, ctx), RoleAdmin))
+
+	mux.HandleFunc("POST /resource", auth(postResource(pool, ctx), RoleAdmin))
+	mux.HandleFunc("GET /resource/{resource_id}", getResource(pool, ctx))
+	mux.HandleFunc("GET /resource/", getResource(pool, ctx))
+	mux.HandleFunc("PUT /resource/{resource_id}", auth(putResource(pool, ctx), RoleAdmin))
+	mux.HandleFunc("DELETE /resource/{resource_id}", auth(deleteResource(pool, ctx), RoleAdmin))
 
-	mux.HandleFunc("POST /availability", postAvailabilitySlot(pool, ctx))
+	mux.HandleFunc("POST /availability", auth(postAvailabilitySlot(pool, ctx), RoleAdmin))
 	mux.HandleFunc("GET /availability/{availability_slot_id}", getAvailabilitySlot(pool, ctx))
-	mux.HandleFunc("GET /availability/free", getFreeAvailabilitySlots(pool, ctx, a))
+	mux.HandleFunc("GET /availability/free", auth(getFreeAvailabilitySlots(pool, ctx), RoleUser))
+	mux.HandleFunc("GET /availability/search", auth(getSearchAvailability(pool, ctx), RoleAdmin, RoleUser))
 	mux.HandleFunc("GET /availability/", getAvailabilitySlot(pool, ctx))
-	mux.HandleFunc("PUT /availability/", putAvailabilitySlot(pool, ctx))
-	mux.HandleFunc("DELETE /availability/{availability_slot_id}", deleteAvailabilitySlot(pool, ctx, a, false))
-	mux.HandleFunc("DELETE /availability/", deleteAvailabilitySlot(pool, ctx, a, false))
+	mux.HandleFunc("PUT /availability/", auth(putAvailabilitySlot(pool, ctx), RoleAdmin))
+	mux.HandleFunc("DELETE /availability/{availability_slot_id}", auth(deleteAvailabilitySlot(pool, ctx, pp, notifier), RoleAdmin))
+	mux.HandleFunc("DELETE /availability/", auth(deleteAvailabilitySlot(pool, ctx, pp, notifier), RoleAdmin))
+
+	mux.HandleFunc("POST /availability_rule", auth(postAvailabilityRule(pool, ctx, ruleHorizon), RoleAdmin))
+	mux.HandleFunc("GET /availability_rule", auth(getAvailabilityRule(pool, ctx), RoleAdmin))
+	mux.HandleFunc("GET /availability_rule/{rule_id}", auth(getAvailabilityRule(pool, ctx), RoleAdmin))
+	mux.HandleFunc("PUT /availability_rule/{rule_id}", auth(putAvailabilityRule(pool, ctx, ruleHorizon), RoleAdmin))
+	mux.HandleFunc("DELETE /availability_rule/{rule_id}", auth(deleteAvailabilityRule(pool, ctx), RoleAdmin))
+	mux.HandleFunc("POST /availability_rule/{rule_id}/exception", auth(postAvailabilityRuleException(pool, ctx), RoleAdmin))
+	mux.HandleFunc("DELETE /availability_rule/{rule_id}/exception/{date}", auth(deleteAvailabilityRuleException(pool, ctx, ruleHorizon), RoleAdmin))
+	mux.HandleFunc("POST /availability_rule/{rule_id}/materialise", auth(postMaterialiseAvailabilityRule(pool, ctx), RoleAdmin))
+
+	mux.HandleFunc("POST /time_off", auth(postTimeOff(pool, ctx, pp, notifier), RoleAdmin))
+	mux.HandleFunc("GET /time_off", auth(getTimeOff(pool, ctx), RoleAdmin))
+	mux.HandleFunc("GET /time_off/{time_off_id}", auth(getTimeOff(pool, ctx), RoleAdmin))
+	mux.HandleFunc("DELETE /time_off/{time_off_id}", auth(deleteTimeOff(pool, ctx), RoleAdmin))
+
+	mux.HandleFunc("POST /closure", auth(postClosure(pool, ctx, pp, notifier), RoleAdmin))
+	mux.HandleFunc("GET /closure", auth(getClosure(pool, ctx), RoleAdmin))
+	mux.HandleFunc("GET /closure/{closure_id}", auth(getClosure(pool, ctx), RoleAdmin))
+	mux.HandleFunc("DELETE /closure/{closure_id}", auth(deleteClosure(pool, ctx), RoleAdmin))
 
 	err = http.ListenAndServe(":8000", corsMiddleware(jsonContentTypeMiddleware(mux), appUrl))
 	if err != nil {
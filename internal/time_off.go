@@ -0,0 +1,626 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostTimeOffRequest takes an employee off between StartTime and EndTime. Their
+// slots in that time stop being offered, and bookings already made in it are
+// cancelled if CancelBookings is set or otherwise just reported.
+type PostTimeOffRequest struct {
+	EmployeeID     int32     `json:"employee_id"`
+	StartTime      time.Time `json:"start_time"` // this expects RFC 3339 format
+	EndTime        time.Time `json:"end_time"`   // this expects RFC 3339 format
+	Reason         string    `json:"reason"`
+	CancelBookings bool      `json:"cancel_bookings"`
+}
+
+type TimeOffResponse struct {
+	TimeOffID  int32              `json:"time_off_id"`
+	EmployeeID int32              `json:"employee_id"`
+	StartTime  pgtype.Timestamptz `json:"start_time"`
+	EndTime    pgtype.Timestamptz `json:"end_time"`
+	Reason     string             `json:"reason"`
+	CreatedBy  string             `json:"created_by"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type PostTimeOffResponse struct {
+	TimeOffID           int32                `json:"time_off_id"`
+	ConflictingBookings []ConflictingBooking `json:"conflicting_bookings"`
+}
+
+// PostClosureRequest shuts the business for every employee from StartDate to
+// EndDate inclusive, with the dates taken in each employee's time zone.
+type PostClosureRequest struct {
+	StartDate      string `json:"start_date"` // 2006-01-02
+	EndDate        string `json:"end_date"`   // 2006-01-02, defaults to start_date
+	Reason         string `json:"reason"`
+	CancelBookings bool   `json:"cancel_bookings"`
+}
+
+type ClosureResponse struct {
+	ClosureID int32              `json:"closure_id"`
+	StartDate string             `json:"start_date"`
+	EndDate   string             `json:"end_date"`
+	Reason    string             `json:"reason"`
+	CreatedBy string             `json:"created_by"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type PostClosureResponse struct {
+	ClosureID           int32                `json:"closure_id"`
+	ConflictingBookings []ConflictingBooking `json:"conflicting_bookings"`
+}
+
+// ConflictingBooking is a booking that falls in new time off or a closure. Its
+// status is cancelled if the request asked for conflicts to be cancelled.
+type ConflictingBooking struct {
+	BookingID  int32              `json:"booking_id"`
+	UserID     int32              `json:"user_id"`
+	EmployeeID int32              `json:"employee_id"`
+	Status     db.BookingStatus   `json:"status"`
+	StartTime  pgtype.Timestamptz `json:"start_time"`
+	EndTime    pgtype.Timestamptz `json:"end_time"`
+}
+
+func (r PostTimeOffRequest) ToDBParams(createdBy string) (db.CreateEmployeeTimeOffParams, error) {
+	if r.StartTime.IsZero() || r.EndTime.IsZero() {
+		return db.CreateEmployeeTimeOffParams{}, errors.New("start_time and end_time are required")
+	}
+	if !r.StartTime.Before(r.EndTime) {
+		return db.CreateEmployeeTimeOffParams{}, errors.New("start_time must be before end_time")
+	}
+	return db.CreateEmployeeTimeOffParams{
+		EmployeeID: r.EmployeeID,
+		StartTime:  pgtype.Timestamptz{Time: r.StartTime, Valid: true},
+		EndTime:    pgtype.Timestamptz{Time: r.EndTime, Valid: true},
+		Reason:     r.Reason,
+		CreatedBy:  createdBy,
+	}, nil
+}
+
+func (r PostClosureRequest) ToDBParams(createdBy string) (db.CreateClosureParams, error) {
+	startDate, err := parseDate(r.StartDate)
+	if err != nil {
+		return db.CreateClosureParams{}, err
+	}
+	if !startDate.Valid {
+		return db.CreateClosureParams{}, errors.New("start_date is required")
+	}
+	endDate, err := parseDate(r.EndDate)
+	if err != nil {
+		return db.CreateClosureParams{}, err
+	}
+	if !endDate.Valid {
+		endDate = startDate
+	}
+	if endDate.Time.Before(startDate.Time) {
+		return db.CreateClosureParams{}, errors.New("end_date must not be before start_date")
+	}
+	return db.CreateClosureParams{
+		StartDate: startDate,
+		EndDate:   endDate,
+		Reason:    r.Reason,
+		CreatedBy: createdBy,
+	}, nil
+}
+
+func responseFromDBTimeOff(timeOff db.EmployeeTimeOff, loc *time.Location) TimeOffResponse {
+	return TimeOffResponse{
+		TimeOffID:  timeOff.ID,
+		EmployeeID: timeOff.EmployeeID,
+		StartTime:  inLocation(timeOff.StartTime, loc),
+		EndTime:    inLocation(timeOff.EndTime, loc),
+		Reason:     timeOff.Reason,
+		CreatedBy:  timeOff.CreatedBy,
+		CreatedAt:  inLocation(timeOff.CreatedAt, loc),
+	}
+}
+
+func responseFromDBClosure(closure db.Closure, loc *time.Location) ClosureResponse {
+	return ClosureResponse{
+		ClosureID: closure.ID,
+		StartDate: formatDate(closure.StartDate),
+		EndDate:   formatDate(closure.EndDate),
+		Reason:    closure.Reason,
+		CreatedBy: closure.CreatedBy,
+		CreatedAt: inLocation(closure.CreatedAt, loc),
+	}
+}
+
+func conflictFromDBBooking(booking db.GetBookingWithJoinRow, cancelled bool, loc *time.Location) ConflictingBooking {
+	conflict := ConflictingBooking{
+		BookingID:  booking.ID,
+		UserID:     booking.UserID,
+		EmployeeID: booking.EmployeeID,
+		Status:     booking.Status,
+		StartTime:  inLocation(booking.StartTime, loc),
+		EndTime:    inLocation(booking.EndTime, loc),
+	}
+	if cancelled {
+		conflict.Status = db.BookingStatusCancelled
+	}
+	return conflict
+}
+
+// resolveConflicts loads the bookings in bookingIDs and, if cancel is set,
+// cancels them as changedBy the same way POST /booking/{booking_id}/cancel does,
+// refunding what was paid. The bookings are returned as they were before being
+// cancelled. Refunds are only queued, they are sent once the caller commits.
+func resolveConflicts(ctx context.Context, queries *db.Queries, pp PaymentParams, bookingIDs []int32, cancel bool, changedBy string, reason string) ([]db.GetBookingWithJoinRow, error) {
+	bookings := []db.GetBookingWithJoinRow{}
+	for _, id := range bookingIDs {
+		booking, err := queries.GetBookingWithJoin(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if cancel {
+			err = setBookingStatus(ctx, queries, id, db.BookingStatusCancelled, changedBy, reason)
+			if err != nil {
+				return nil, err
+			}
+			err = settleCancellation(ctx, queries, pp, id, 0, changedBy, cancelledReason)
+			if err != nil {
+				return nil, err
+			}
+		}
+		bookings = append(bookings, booking)
+	}
+	return bookings, nil
+}
+
+func postTimeOff(pool *pgxpool.Pool, ctx context.Context, pp PaymentParams, notifier BookingNotifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
+		loc, ok := requestLocation(w, r)
+		if !ok {
+			return
+		}
+
+		var timeOffRequest PostTimeOffRequest
+
+		err := json.NewDecoder(r.Body).Decode(&timeOffRequest)
+		if err != nil {
+			log.Printf("error decoding body in postTimeOff: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		params, err := timeOffRequest.ToDBParams(principal.Email)
+		if err != nil {
+			log.Printf("invalid time off in postTimeOff: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in postTimeOff: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			log.Printf("error beginning tx in postTimeOff: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		defer func() {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				panic(err)
+			}
+		}()
+
+		queries := db.New(conn)
+		qtx := queries.WithTx(tx)
+
+		timeOff, err := qtx.CreateEmployeeTimeOff(ctx, params)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+				log.Printf("employee id: %d does not exist in postTimeOff", params.EmployeeID)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			log.Printf("general error when trying to create time off in postTimeOff: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		bookingIDs, err := qtx.GetTimeOffConflicts(ctx, db.GetTimeOffConflictsParams{
+			EmployeeID: timeOff.EmployeeID,
+			StartTime:  timeOff.StartTime,
+			EndTime:    timeOff.EndTime,
+		})
+		if err != nil {
+			log.Printf("getting conflicting bookings in postTimeOff failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		bookings, err := resolveConflicts(ctx, qtx, pp, bookingIDs, timeOffRequest.CancelBookings, principal.Email, timeOffRequest.Reason)
+		if err != nil {
+			log.Printf("resolving conflicting bookings in postTimeOff failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Printf("error commiting tx in postTimeOff: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		sendPaymentRefunds(ctx, pool, pp)
+
+		conflicts := []ConflictingBooking{}
+		for _, booking := range bookings {
+			conflicts = append(conflicts, conflictFromDBBooking(booking, timeOffRequest.CancelBookings, loc))
+		}
+		if timeOffRequest.CancelBookings {
+			notifyCancelled(ctx, notifier, bookings, timeOffRequest.Reason)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(PostTimeOffResponse{
+			TimeOffID:           timeOff.ID,
+			ConflictingBookings: conflicts,
+		})
+		if err != nil {
+			log.Printf("error encoding json in postTimeOff: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// getTimeOff returns one period of time off, or all of them optionally
+// filtered with the employee_id query parameter
+func getTimeOff(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loc, ok := requestLocation(w, r)
+		if !ok {
+			return
+		}
+
+		timeOffId := r.PathValue("time_off_id")
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in getTimeOff: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		queries := db.New(conn)
+
+		if timeOffId != "" {
+			id, err := strconv.ParseInt(timeOffId, 10, 32)
+			if err != nil {
+				log.Printf("error: %v converting time off id to int in getTimeOff: %s", err, timeOffId)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			timeOff, err := queries.GetEmployeeTimeOffById(ctx, int32(id))
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				log.Printf("error querying time off in getTimeOff: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if errors.Is(err, pgx.ErrNoRows) {
+				log.Printf("time off id: %d was requested in getTimeOff and does not exist", id)
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			err = json.NewEncoder(w).Encode(responseFromDBTimeOff(timeOff, loc))
+			if err != nil {
+				log.Printf("error encoding json in getTimeOff: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		var employeeID pgtype.Int4
+		if employeeId := r.URL.Query().Get("employee_id"); employeeId != "" {
+			id, err := strconv.ParseInt(employeeId, 10, 32)
+			if err != nil {
+				log.Printf("error: %v converting employee id to int in getTimeOff: %s", err, employeeId)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			employeeID = pgtype.Int4{Int32: int32(id), Valid: true}
+		}
+
+		timeOffs, err := queries.GetAllEmployeeTimeOff(ctx, employeeID)
+		if err != nil {
+			log.Printf("error querying time off in getTimeOff: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		resp := []TimeOffResponse{}
+		for _, timeOff := range timeOffs {
+			resp = append(resp, responseFromDBTimeOff(timeOff, loc))
+		}
+
+		err = json.NewEncoder(w).Encode(resp)
+		if err != nil {
+			log.Printf("error encoding json in getTimeOff: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// deleteTimeOff ends a period of time off early or removes it, its slots are
+// offered again but bookings cancelled because of it stay cancelled
+func deleteTimeOff(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		timeOffId := r.PathValue("time_off_id")
+		id, err := strconv.ParseInt(timeOffId, 10, 32)
+		if err != nil {
+			log.Printf("error: %v converting time off id to int in deleteTimeOff: %s", err, timeOffId)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in deleteTimeOff: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		queries := db.New(conn)
+
+		_, err = queries.DeleteEmployeeTimeOff(ctx, int32(id))
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("general error when trying to delete time off id: %d in deleteTimeOff: %v", id, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("time off id: %d, which does not exist, was attemped to be deleted by deleteTimeOff", id)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func postClosure(pool *pgxpool.Pool, ctx context.Context, pp PaymentParams, notifier BookingNotifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
+		loc, ok := requestLocation(w, r)
+		if !ok {
+			return
+		}
+
+		var closureRequest PostClosureRequest
+
+		err := json.NewDecoder(r.Body).Decode(&closureRequest)
+		if err != nil {
+			log.Printf("error decoding body in postClosure: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		params, err := closureRequest.ToDBParams(principal.Email)
+		if err != nil {
+			log.Printf("invalid closure in postClosure: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in postClosure: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			log.Printf("error beginning tx in postClosure: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		defer func() {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				panic(err)
+			}
+		}()
+
+		queries := db.New(conn)
+		qtx := queries.WithTx(tx)
+
+		closure, err := qtx.CreateClosure(ctx, params)
+		if err != nil {
+			log.Printf("general error when trying to create closure in postClosure: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		bookingIDs, err := qtx.GetClosureConflicts(ctx, db.GetClosureConflictsParams{
+			StartDate: closure.StartDate,
+			EndDate:   closure.EndDate,
+		})
+		if err != nil {
+			log.Printf("getting conflicting bookings in postClosure failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		bookings, err := resolveConflicts(ctx, qtx, pp, bookingIDs, closureRequest.CancelBookings, principal.Email, closureRequest.Reason)
+		if err != nil {
+			log.Printf("resolving conflicting bookings in postClosure failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Printf("error commiting tx in postClosure: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		sendPaymentRefunds(ctx, pool, pp)
+
+		conflicts := []ConflictingBooking{}
+		for _, booking := range bookings {
+			conflicts = append(conflicts, conflictFromDBBooking(booking, closureRequest.CancelBookings, loc))
+		}
+		if closureRequest.CancelBookings {
+			notifyCancelled(ctx, notifier, bookings, closureRequest.Reason)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(PostClosureResponse{
+			ClosureID:           closure.ID,
+			ConflictingBookings: conflicts,
+		})
+		if err != nil {
+			log.Printf("error encoding json in postClosure: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func getClosure(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		loc, ok := requestLocation(w, r)
+		if !ok {
+			return
+		}
+
+		closureId := r.PathValue("closure_id")
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in getClosure: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		queries := db.New(conn)
+
+		closures := []db.Closure{}
+		if closureId == "" {
+			closures, err = queries.GetAllClosures(ctx)
+			if err != nil {
+				log.Printf("error querying closures in getClosure: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		} else {
+			id, err := strconv.ParseInt(closureId, 10, 32)
+			if err != nil {
+				log.Printf("error: %v converting closure id to int in getClosure: %s", err, closureId)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			closure, err := queries.GetClosureById(ctx, int32(id))
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				log.Printf("error querying closures in getClosure: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if errors.Is(err, pgx.ErrNoRows) {
+				log.Printf("closure id: %d was requested in getClosure and does not exist", id)
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			closures = append(closures, closure)
+		}
+
+		resp := []ClosureResponse{}
+		for _, closure := range closures {
+			resp = append(resp, responseFromDBClosure(closure, loc))
+		}
+
+		if closureId != "" {
+			err = json.NewEncoder(w).Encode(resp[0])
+		} else {
+			err = json.NewEncoder(w).Encode(resp)
+		}
+		if err != nil {
+			log.Printf("error encoding json in getClosure: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// deleteClosure reopens the business on the closure's dates, bookings cancelled
+// because of it stay cancelled
+func deleteClosure(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		closureId := r.PathValue("closure_id")
+		id, err := strconv.ParseInt(closureId, 10, 32)
+		if err != nil {
+			log.Printf("error: %v converting closure id to int in deleteClosure: %s", err, closureId)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in deleteClosure: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		queries := db.New(conn)
+
+		_, err = queries.DeleteClosure(ctx, int32(id))
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("general error when trying to delete closure id: %d in deleteClosure: %v", id, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("closure id: %d, which does not exist, was attemped to be deleted by deleteClosure", id)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeOffToDBParams(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2025-12-22T09:00:00Z")
+	end, _ := time.Parse(time.RFC3339, "2025-12-24T17:00:00Z")
+
+	t.Run("base", func(t *testing.T) {
+		t.Parallel()
+		r := PostTimeOffRequest{EmployeeID: 3, StartTime: start, EndTime: end, Reason: "christmas"}
+		expected := db.CreateEmployeeTimeOffParams{
+			EmployeeID: 3,
+			StartTime:  pgtype.Timestamptz{Time: start, Valid: true},
+			EndTime:    pgtype.Timestamptz{Time: end, Valid: true},
+			Reason:     "christmas",
+			CreatedBy:  "admin@mirai.com",
+		}
+
+		params, err := r.ToDBParams("admin@mirai.com")
+		assert.NoError(t, err)
+		assert.Equal(t, expected, params)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		missing := PostTimeOffRequest{EmployeeID: 3, StartTime: start}
+		backwards := PostTimeOffRequest{EmployeeID: 3, StartTime: end, EndTime: start}
+		empty := PostTimeOffRequest{EmployeeID: 3, StartTime: start, EndTime: start}
+
+		for _, r := range []PostTimeOffRequest{missing, backwards, empty} {
+			_, err := r.ToDBParams("admin@mirai.com")
+			assert.Error(t, err)
+		}
+	})
+}
+
+func TestClosureToDBParams(t *testing.T) {
+	t.Run("range", func(t *testing.T) {
+		t.Parallel()
+		r := PostClosureRequest{StartDate: "2025-12-25", EndDate: "2025-12-26", Reason: "christmas"}
+		expected := db.CreateClosureParams{
+			StartDate: mustDate(t, "2025-12-25"),
+			EndDate:   mustDate(t, "2025-12-26"),
+			Reason:    "christmas",
+			CreatedBy: "admin@mirai.com",
+		}
+
+		params, err := r.ToDBParams("admin@mirai.com")
+		assert.NoError(t, err)
+		assert.Equal(t, expected, params)
+	})
+
+	t.Run("single day", func(t *testing.T) {
+		t.Parallel()
+		r := PostClosureRequest{StartDate: "2026-05-04"}
+		params, err := r.ToDBParams("admin@mirai.com")
+		assert.NoError(t, err)
+		assert.Equal(t, params.StartDate, params.EndDate)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		missing := PostClosureRequest{EndDate: "2025-12-26"}
+		badDate := PostClosureRequest{StartDate: "25/12/2025"}
+		backwards := PostClosureRequest{StartDate: "2025-12-26", EndDate: "2025-12-25"}
+
+		for _, r := range []PostClosureRequest{missing, badDate, backwards} {
+			_, err := r.ToDBParams("admin@mirai.com")
+			assert.Error(t, err)
+		}
+	})
+}
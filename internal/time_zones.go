@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	// embed the zone database so time.LoadLocation doesnt depend on the host
+	_ "time/tzdata"
+)
+
+// normaliseTimeZone checks that name is an IANA time zone, defaulting an empty
+// name to UTC
+func normaliseTimeZone(name string) (string, error) {
+	if name == "" {
+		return "UTC", nil
+	}
+	_, err := time.LoadLocation(name)
+	if err != nil {
+		return "", fmt.Errorf("unknown time zone %q", name)
+	}
+	return name, nil
+}
+
+// viewerLocation returns the time zone asked for in the tz query parameter,
+// defaulting to UTC. Times are stored as instants so this only changes how
+// they are rendered.
+func viewerLocation(r *http.Request) (*time.Location, error) {
+	name, err := normaliseTimeZone(r.URL.Query().Get("tz"))
+	if err != nil {
+		return nil, err
+	}
+	return time.LoadLocation(name)
+}
+
+// inLocation returns t with its instant unchanged but rendered in loc
+func inLocation(t pgtype.Timestamptz, loc *time.Location) pgtype.Timestamptz {
+	if t.Valid {
+		t.Time = t.Time.In(loc)
+	}
+	return t
+}
+
+// requestLocation returns the viewerLocation for r, writing a 400 if the tz
+// parameter isnt a known time zone
+func requestLocation(w http.ResponseWriter, r *http.Request) (*time.Location, bool) {
+	loc, err := viewerLocation(r)
+	if err != nil {
+		log.Printf("bad tz parameter on %s %s: %v", r.Method, r.URL.Path, err)
+		w.WriteHeader(http.StatusBadRequest)
+		err = json.NewEncoder(w).Encode(ErrorResponse{Message: err.Error()})
+		if err != nil {
+			log.Printf("encoding time zone error response failed with %v", err)
+		}
+		return nil, false
+	}
+	return loc, true
+}
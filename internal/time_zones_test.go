@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormaliseTimeZone(t *testing.T) {
+	t.Run("defaults to UTC", func(t *testing.T) {
+		t.Parallel()
+		actual, err := normaliseTimeZone("")
+		assert.NoError(t, err)
+		assert.Equal(t, "UTC", actual)
+	})
+
+	t.Run("known zone", func(t *testing.T) {
+		t.Parallel()
+		actual, err := normaliseTimeZone("Europe/London")
+		assert.NoError(t, err)
+		assert.Equal(t, "Europe/London", actual)
+	})
+
+	t.Run("unknown zone", func(t *testing.T) {
+		t.Parallel()
+		_, err := normaliseTimeZone("Europe/Atlantis")
+		assert.Error(t, err)
+	})
+}
+
+func TestViewerLocation(t *testing.T) {
+	t.Run("defaults to UTC", func(t *testing.T) {
+		t.Parallel()
+		loc, err := viewerLocation(httptest.NewRequest("GET", "/booking", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, time.UTC, loc)
+	})
+
+	t.Run("renders in the requested zone", func(t *testing.T) {
+		t.Parallel()
+		loc, err := viewerLocation(httptest.NewRequest("GET", "/booking?tz=Europe/London", nil))
+		require.NoError(t, err)
+
+		// 09:00 UTC is 10:00 in London during BST and 09:00 after the clocks go back
+		summer := inLocation(pgtype.Timestamptz{Time: time.Date(2025, 10, 25, 9, 0, 0, 0, time.UTC), Valid: true}, loc)
+		winter := inLocation(pgtype.Timestamptz{Time: time.Date(2025, 10, 27, 9, 0, 0, 0, time.UTC), Valid: true}, loc)
+		assert.Equal(t, "2025-10-25T10:00:00+01:00", summer.Time.Format(time.RFC3339))
+		assert.Equal(t, "2025-10-27T09:00:00Z", winter.Time.Format(time.RFC3339))
+	})
+
+	t.Run("unknown zone", func(t *testing.T) {
+		t.Parallel()
+		_, err := viewerLocation(httptest.NewRequest("GET", "/booking?tz=Mars/Olympus", nil))
+		assert.Error(t, err)
+	})
+}
@@ -16,12 +16,12 @@ import (
 )
 
 type GetUserResponse struct {
-	UserID    int32            `json:"user_id"`
-	Name      string           `json:"name"`
-	Surname   string           `json:"surname"`
-	Email     string           `json:"email"`
-	CreatedAt pgtype.Timestamp `json:"created_at"`
-	LastLogin pgtype.Timestamp `json:"last_login"`
+	UserID    int32              `json:"user_id"`
+	Name      string             `json:"name"`
+	Surname   string             `json:"surname"`
+	Email     string             `json:"email"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	LastLogin pgtype.Timestamptz `json:"last_login"`
 }
 
 func responseFromDBUser(user db.User) GetUserResponse {
@@ -155,6 +155,16 @@ func getUser(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 			return
 		}
 
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+		if !principal.CanAccessUser(int32(id)) {
+			log.Printf("user %d requested user %d in getUser and doesnt have permission to", principal.UserID, id)
+			writeForbidden(w)
+			return
+		}
+
 		conn, err := pool.Acquire(ctx)
 		if err != nil {
 			log.Printf("error aquiring pool in getUser: %v", err)
@@ -235,6 +245,16 @@ func putUser(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 			return
 		}
 
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+		if !principal.CanAccessUser(int32(id)) {
+			log.Printf("user %d requested user %d in putUser and doesnt have permission to", principal.UserID, id)
+			writeForbidden(w)
+			return
+		}
+
 		var userRequest PutUserRequest
 
 		err = json.NewDecoder(r.Body).Decode(&userRequest)
@@ -325,6 +345,16 @@ func deleteUser(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
 			return
 		}
 
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+		if !principal.CanAccessUser(int32(id)) {
+			log.Printf("user %d requested user %d in deleteUser and doesnt have permission to", principal.UserID, id)
+			writeForbidden(w)
+			return
+		}
+
 		conn, err := pool.Acquire(ctx)
 		if err != nil {
 			log.Printf("error aquiring pool in deleteUser: %v", err)
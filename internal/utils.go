@@ -12,11 +12,11 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-func timeToTimeStamp(t time.Time) (pgtype.Timestamp, error) {
-	var result pgtype.Timestamp
+func timeToTimeStamp(t time.Time) (pgtype.Timestamptz, error) {
+	var result pgtype.Timestamptz
 	err := result.Scan(t)
 	if err != nil {
-		return pgtype.Timestamp{}, err
+		return pgtype.Timestamptz{}, err
 	}
 	return result, nil
 }
@@ -50,20 +50,13 @@ func checkUserExists(queries *db.Queries, ctx context.Context, userID int32) (bo
 	return true, nil
 }
 
-func getAndCalculateCost(queries *db.Queries, ctx context.Context, typeID int32, duration int32) (int32, error) {
-	bookingType, err := queries.GetBookingTypeById(ctx, typeID)
-	if err != nil {
-		return 0, err
-	}
-
+// bookingCost is the price of booking duration units of bookingType
+func bookingCost(bookingType db.BookingType, duration int32) int32 {
 	if bookingType.Fixed {
-		return bookingType.Cost, nil
+		return bookingType.Cost
 	}
 
-	totalCost := calculateCost(bookingType.Cost, duration)
-
-	return totalCost, nil
-
+	return calculateCost(bookingType.Cost, duration)
 }
 
 func spanToSlots(startTime time.Time, endTime time.Time, unit int) ([]time.Time, error) {
@@ -113,10 +106,10 @@ func spanToSlots(startTime time.Time, endTime time.Time, unit int) ([]time.Time,
 }
 
 // slots_to_keep takes two sets and calculates the items to keep and delete
-func slotsToKeepDelete(current, next []pgtype.Timestamp) ([]pgtype.Timestamp, []pgtype.Timestamp) {
+func slotsToKeepDelete(current, next []pgtype.Timestamptz) ([]pgtype.Timestamptz, []pgtype.Timestamptz) {
 
-	toKeep := []pgtype.Timestamp{}
-	toDelete := []pgtype.Timestamp{}
+	toKeep := []pgtype.Timestamptz{}
+	toDelete := []pgtype.Timestamptz{}
 
 	nextNorm := []time.Time{}
 	for _, n := range next {
@@ -137,8 +130,8 @@ func slotsToKeepDelete(current, next []pgtype.Timestamp) ([]pgtype.Timestamp, []
 }
 
 // slots_to_keep takes two sets and calculates whats in next that isnt in next
-func slotsToCreate(current, new []pgtype.Timestamp) []pgtype.Timestamp {
-	result := []pgtype.Timestamp{}
+func slotsToCreate(current, new []pgtype.Timestamptz) []pgtype.Timestamptz {
+	result := []pgtype.Timestamptz{}
 	currentNorm := []time.Time{}
 	for _, c := range current {
 		currentNorm = append(currentNorm, c.Time.UTC())
@@ -156,9 +149,25 @@ func isSequential(times []time.Time, unit int32) bool {
 	prev := times[0]
 	for _, t := range times[1:] {
 		prev = prev.Add(time.Minute * time.Duration(unit))
-		if prev.UTC() != t.UTC() {
+		if !prev.Equal(t) {
 			return false
 		}
 	}
 	return true
 }
+
+// slotsSequential reports whether slots are all the same length and each one
+// starts when the one before it ends
+func slotsSequential(slots []db.Availability) bool {
+	if len(slots) == 0 {
+		return false
+	}
+	times := []time.Time{}
+	for _, s := range slots {
+		if s.UnitMinutes != slots[0].UnitMinutes {
+			return false
+		}
+		times = append(times, s.Datetime.Time)
+	}
+	return isSequential(times, slots[0].UnitMinutes)
+}
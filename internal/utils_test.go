@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jack-cordery/mirai/db"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/assert"
 )
@@ -13,7 +14,7 @@ func TestTimeToTimeStamp(t *testing.T) {
 	t.Run("base", func(t *testing.T) {
 		t.Parallel()
 		in := time.Now()
-		expected := pgtype.Timestamp{
+		expected := pgtype.Timestamptz{
 			Time:  in,
 			Valid: true,
 		}
@@ -210,6 +211,28 @@ func TestSpanToSlots(t *testing.T) {
 		_, err := spanToSlots(startTime, endTime, unit)
 		assert.Error(t, err)
 	})
+
+	t.Run("across DST changes in London", func(t *testing.T) {
+		t.Parallel()
+		london, _ := time.LoadLocation("Europe/London")
+		// clocks go forward at 01:00 on 2025-03-30, so 00:30 to 02:30 local is an hour
+		startTime := time.Date(2025, 3, 30, 0, 30, 0, 0, london)
+		endTime := time.Date(2025, 3, 30, 2, 30, 0, 0, london)
+
+		actual, err := spanToSlots(startTime, endTime, 30)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(actual))
+		assert.True(t, actual[1].Equal(time.Date(2025, 3, 30, 2, 0, 0, 0, london)))
+
+		// clocks go back at 02:00 on 2025-10-26, so 00:30 to 02:30 local is three hours
+		startTime = time.Date(2025, 10, 26, 0, 30, 0, 0, london)
+		endTime = time.Date(2025, 10, 26, 2, 30, 0, 0, london)
+
+		actual, err = spanToSlots(startTime, endTime, 30)
+		assert.NoError(t, err)
+		assert.Equal(t, 6, len(actual))
+		assert.True(t, actual[5].Equal(time.Date(2025, 10, 26, 2, 0, 0, 0, london)))
+	})
 }
 
 func TestSlotsToKeepDelete(t *testing.T) {
@@ -222,14 +245,14 @@ func TestSlotsToKeepDelete(t *testing.T) {
 		unit := 30
 
 		currentSlots, _ := spanToSlots(currentStartTime, currentEndTime, unit)
-		currentTimestamps := []pgtype.Timestamp{}
+		currentTimestamps := []pgtype.Timestamptz{}
 		for _, s := range currentSlots {
 			t, _ := timeToTimeStamp(s)
 			currentTimestamps = append(currentTimestamps, t)
 		}
 
 		newSlots, _ := spanToSlots(newStartTime, newEndTime, unit)
-		newTimestamps := []pgtype.Timestamp{}
+		newTimestamps := []pgtype.Timestamptz{}
 		for _, s := range newSlots {
 			t, _ := timeToTimeStamp(s)
 			newTimestamps = append(newTimestamps, t)
@@ -239,13 +262,13 @@ func TestSlotsToKeepDelete(t *testing.T) {
 
 		expectedDelFirst, _ := timeToTimeStamp(currentStartTime)
 		expectedDelSecond, _ := timeToTimeStamp(newEndTime)
-		expectedDel := []pgtype.Timestamp{expectedDelFirst, expectedDelSecond}
+		expectedDel := []pgtype.Timestamptz{expectedDelFirst, expectedDelSecond}
 
 		expectedKeepStartTime, _ := time.Parse(time.RFC3339, "2025-09-08T14:30:00Z")
 		expectedKeepEndTime, _ := time.Parse(time.RFC3339, "2025-09-08T15:30:00Z")
 
 		expectedKeepSlots, _ := spanToSlots(expectedKeepStartTime, expectedKeepEndTime, unit)
-		expectedKeepTimestamps := []pgtype.Timestamp{}
+		expectedKeepTimestamps := []pgtype.Timestamptz{}
 		for _, s := range expectedKeepSlots {
 			t, _ := timeToTimeStamp(s)
 			expectedKeepTimestamps = append(expectedKeepTimestamps, t)
@@ -266,14 +289,14 @@ func TestSlotsToCreate(t *testing.T) {
 		unit := 30
 
 		currentSlots, _ := spanToSlots(currentStartTime, currentEndTime, unit)
-		currentTimestamps := []pgtype.Timestamp{}
+		currentTimestamps := []pgtype.Timestamptz{}
 		for _, s := range currentSlots {
 			t, _ := timeToTimeStamp(s)
 			currentTimestamps = append(currentTimestamps, t)
 		}
 
 		newSlots, _ := spanToSlots(newStartTime, newEndTime, unit)
-		newTimestamps := []pgtype.Timestamp{}
+		newTimestamps := []pgtype.Timestamptz{}
 		for _, s := range newSlots {
 			t, _ := timeToTimeStamp(s)
 			newTimestamps = append(newTimestamps, t)
@@ -292,14 +315,14 @@ func TestSlotsToCreate(t *testing.T) {
 		unit := 30
 
 		currentSlots, _ := spanToSlots(currentStartTime, currentEndTime, unit)
-		currentTimestamps := []pgtype.Timestamp{}
+		currentTimestamps := []pgtype.Timestamptz{}
 		for _, s := range currentSlots {
 			t, _ := timeToTimeStamp(s)
 			currentTimestamps = append(currentTimestamps, t)
 		}
 
 		newSlots, _ := spanToSlots(newStartTime, newEndTime, unit)
-		newTimestamps := []pgtype.Timestamp{}
+		newTimestamps := []pgtype.Timestamptz{}
 		for _, s := range newSlots {
 			t, _ := timeToTimeStamp(s)
 			newTimestamps = append(newTimestamps, t)
@@ -309,7 +332,7 @@ func TestSlotsToCreate(t *testing.T) {
 
 		expectedCreateTime, _ := time.Parse(time.RFC3339, "2025-09-08T16:00:00Z")
 		expectedTimestamp, _ := timeToTimeStamp(expectedCreateTime)
-		expected := []pgtype.Timestamp{expectedTimestamp}
+		expected := []pgtype.Timestamptz{expectedTimestamp}
 
 		assert.Equal(t, expected, toCreate)
 	})
@@ -348,4 +371,51 @@ func TestIsSequential(t *testing.T) {
 		expected := false
 		assert.Equal(t, expected, actual)
 	})
+	t.Run("across DST in London", func(t *testing.T) {
+		t.Parallel()
+		london, _ := time.LoadLocation("Europe/London")
+		// 00:30, 02:00 and 02:30 local are sequential on 2025-03-30 as 01:00 to
+		// 02:00 is skipped
+		forward := []time.Time{
+			time.Date(2025, 3, 30, 0, 30, 0, 0, london),
+			time.Date(2025, 3, 30, 2, 0, 0, 0, london),
+			time.Date(2025, 3, 30, 2, 30, 0, 0, london),
+		}
+		assert.True(t, isSequential(forward, 30))
+
+		// 01:00 to 02:00 happens twice on 2025-10-26 so the local times go
+		// 01:30 BST, 01:00 GMT, 01:30 GMT
+		back := []time.Time{
+			time.Date(2025, 10, 26, 0, 30, 0, 0, time.UTC).In(london),
+			time.Date(2025, 10, 26, 1, 0, 0, 0, time.UTC).In(london),
+			time.Date(2025, 10, 26, 1, 30, 0, 0, time.UTC).In(london),
+		}
+		assert.True(t, isSequential(back, 30))
+	})
+}
+
+func TestSlotsSequential(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2025-09-08T14:00:00Z")
+	slot := func(offset time.Duration, unit int32) db.Availability {
+		return db.Availability{
+			Datetime:    pgtype.Timestamptz{Time: start.Add(offset), Valid: true},
+			UnitMinutes: unit,
+		}
+	}
+
+	t.Run("uses the slot unit", func(t *testing.T) {
+		t.Parallel()
+		assert.True(t, slotsSequential([]db.Availability{slot(0, 15), slot(15*time.Minute, 15)}))
+		assert.False(t, slotsSequential([]db.Availability{slot(0, 15), slot(30*time.Minute, 15)}))
+	})
+
+	t.Run("mixed units", func(t *testing.T) {
+		t.Parallel()
+		assert.False(t, slotsSequential([]db.Availability{slot(0, 30), slot(30*time.Minute, 15)}))
+	})
+
+	t.Run("no slots", func(t *testing.T) {
+		t.Parallel()
+		assert.False(t, slotsSequential([]db.Availability{}))
+	})
 }
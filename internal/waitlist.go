@@ -0,0 +1,613 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WaitlistMode is what happens to the first customer on the waitlist when the
+// slots they are waiting for free up
+type WaitlistMode string
+
+const (
+	// WaitlistModeOffer holds the slots for the customer for the offer ttl
+	WaitlistModeOffer WaitlistMode = "offer"
+	// WaitlistModeBook books the customer straight into the slots
+	WaitlistModeBook WaitlistMode = "book"
+)
+
+// DefaultWaitlistOfferMinutes is how long a waitlist offer lasts when
+// WAITLIST_OFFER_MINUTES isnt set
+const DefaultWaitlistOfferMinutes = 60
+
+// waitlistBookedReason is recorded in the history of bookings made from the
+// waitlist
+const waitlistBookedReason = "booked from the waitlist"
+
+type WaitlistParams struct {
+	Mode     WaitlistMode
+	OfferTTL time.Duration
+}
+
+// parseWaitlistParams reads the waitlist mode and offer length in minutes from
+// configuration, defaulting to offers of DefaultWaitlistOfferMinutes
+func parseWaitlistParams(mode string, offerMinutes string) (WaitlistParams, error) {
+	params := WaitlistParams{
+		Mode:     WaitlistMode(mode),
+		OfferTTL: DefaultWaitlistOfferMinutes * time.Minute,
+	}
+	if params.Mode == "" {
+		params.Mode = WaitlistModeOffer
+	}
+	if params.Mode != WaitlistModeOffer && params.Mode != WaitlistModeBook {
+		return WaitlistParams{}, fmt.Errorf("waitlist mode %q must be %q or %q", mode, WaitlistModeOffer, WaitlistModeBook)
+	}
+	if offerMinutes != "" {
+		minutes, err := strconv.ParseInt(offerMinutes, 10, 32)
+		if err != nil || minutes <= 0 {
+			return WaitlistParams{}, fmt.Errorf("waitlist offer %q is not a positive whole number of minutes", offerMinutes)
+		}
+		params.OfferTTL = time.Duration(minutes) * time.Minute
+	}
+	return params, nil
+}
+
+// PostWaitlistRequest puts a customer on the waitlist for a booking of TypeID
+// anywhere between StartTime and EndTime, with EmployeeID or with anyone if it
+// isnt set
+type PostWaitlistRequest struct {
+	UserID     int32     `json:"user_id"`
+	TypeID     int32     `json:"type_id"`
+	EmployeeID int32     `json:"employee_id"`
+	StartTime  time.Time `json:"start_time"` // this expects RFC 3339 format
+	EndTime    time.Time `json:"end_time"`   // this expects RFC 3339 format
+}
+
+type WaitlistResponse struct {
+	WaitlistID     int32              `json:"waitlist_id"`
+	UserID         int32              `json:"user_id"`
+	TypeID         int32              `json:"type_id"`
+	EmployeeID     pgtype.Int4        `json:"employee_id"`
+	StartTime      pgtype.Timestamptz `json:"start_time"`
+	EndTime        pgtype.Timestamptz `json:"end_time"`
+	Status         db.WaitlistStatus  `json:"status"`
+	HoldID         pgtype.Int4        `json:"hold_id"`
+	OfferedSlotIDs []int32            `json:"offered_slot_ids"`
+	OfferExpiresAt pgtype.Timestamptz `json:"offer_expires_at"`
+	BookingID      pgtype.Int4        `json:"booking_id"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+}
+
+// ToDBParams puts userID on the waitlist, see bookingUserID
+func (r PostWaitlistRequest) ToDBParams(userID int32) (db.CreateWaitlistEntryParams, error) {
+	if r.TypeID == 0 {
+		return db.CreateWaitlistEntryParams{}, errors.New("type_id is required")
+	}
+	if r.StartTime.IsZero() || r.EndTime.IsZero() {
+		return db.CreateWaitlistEntryParams{}, errors.New("start_time and end_time are required")
+	}
+	if !r.StartTime.Before(r.EndTime) {
+		return db.CreateWaitlistEntryParams{}, errors.New("start_time must be before end_time")
+	}
+	return db.CreateWaitlistEntryParams{
+		UserID:     userID,
+		TypeID:     r.TypeID,
+		EmployeeID: pgtype.Int4{Int32: r.EmployeeID, Valid: r.EmployeeID != 0},
+		StartTime:  pgtype.Timestamptz{Time: r.StartTime, Valid: true},
+		EndTime:    pgtype.Timestamptz{Time: r.EndTime, Valid: true},
+	}, nil
+}
+
+func responseFromDBWaitlistEntry(entry db.WaitlistEntry, loc *time.Location) WaitlistResponse {
+	return WaitlistResponse{
+		WaitlistID:     entry.ID,
+		UserID:         entry.UserID,
+		TypeID:         entry.TypeID,
+		EmployeeID:     entry.EmployeeID,
+		StartTime:      inLocation(entry.StartTime, loc),
+		EndTime:        inLocation(entry.EndTime, loc),
+		Status:         entry.Status,
+		HoldID:         entry.HoldID,
+		OfferedSlotIDs: entry.OfferedSlotIds,
+		OfferExpiresAt: inLocation(entry.OfferExpiresAt, loc),
+		BookingID:      entry.BookingID,
+		CreatedAt:      inLocation(entry.CreatedAt, loc),
+	}
+}
+
+// WaitlistPromotion is a customer taken off the waitlist for freed slots,
+// either offered them through HoldID until ExpiresAt or booked into BookingID
+type WaitlistPromotion struct {
+	EntryID   int32
+	UserEmail string
+	SlotIDs   []int32
+	StartTime time.Time
+	HoldID    int32
+	ExpiresAt time.Time
+	BookingID int32
+}
+
+// waitlistFits reports whether a booking of bookingType could be made on slots,
+// which must be claimed and in time order, by a customer booking them at now
+func waitlistFits(ctx context.Context, queries *db.Queries, bookingType db.BookingType, slotIDs []int32, slots []db.Availability, slotTypes map[int32][]int32, now time.Time) (bool, error) {
+	if errs := validateBookingSlots(bookingType, slotIDs, slots, slotTypes); len(errs) > 0 {
+		return false, nil
+	}
+	problem, err := bookingProblem(bookingLimitsError(ctx, queries, bookingType, slots, true, now))
+	if err != nil || problem != "" {
+		return false, err
+	}
+	problem, err = bookingProblem(bookingResourcesError(ctx, queries, bookingType, slots))
+	return problem == "", err
+}
+
+// promoteWaitlist gives slotIDs, which have just been freed, to the oldest
+// waitlist entry that a booking of the slots as a whole would suit. Depending
+// on params the customer is offered the slots through a hold or booked straight
+// into them, recorded in the booking history as changed by changedBy. It returns
+// nil if the slots are still taken or nobody is waiting for them.
+func promoteWaitlist(ctx context.Context, queries *db.Queries, slotIDs []int32, params WaitlistParams, changedBy string) (*WaitlistPromotion, error) {
+	if len(slotIDs) == 0 {
+		return nil, nil
+	}
+	taken, err := claimSlots(ctx, queries, slotIDs, 0)
+	if errors.Is(err, ErrUnknownSlots) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(taken) > 0 {
+		return nil, nil
+	}
+
+	slots, err := queries.GetAvailabilitySlotByIds(ctx, slotIDs)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	start, end := slotsSpan(slots)
+	if !start.After(now) {
+		return nil, nil
+	}
+
+	entries, err := queries.GetWaitingWaitlistEntries(ctx, db.GetWaitingWaitlistEntriesParams{
+		EmployeeID: slots[0].EmployeeID,
+		StartTime:  pgtype.Timestamptz{Time: start, Valid: true},
+		EndTime:    pgtype.Timestamptz{Time: end, Valid: true},
+	})
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+
+	slotTypes, err := slotTypeIDs(ctx, queries, slots)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		bookingType, err := queries.GetBookingTypeById(ctx, entry.TypeID)
+		if err != nil {
+			return nil, err
+		}
+		fits, err := waitlistFits(ctx, queries, bookingType, slotIDs, slots, slotTypes, now)
+		if err != nil {
+			return nil, err
+		}
+		if !fits {
+			continue
+		}
+
+		user, err := queries.GetUserById(ctx, entry.UserID)
+		if err != nil {
+			return nil, err
+		}
+		promotion := &WaitlistPromotion{
+			EntryID:   entry.ID,
+			UserEmail: user.Email,
+			SlotIDs:   slotIDs,
+			StartTime: start,
+		}
+		if params.Mode == WaitlistModeBook {
+			promotion.BookingID, err = bookFromWaitlist(ctx, queries, entry, bookingType, slotIDs, changedBy)
+		} else {
+			promotion.ExpiresAt = now.Add(params.OfferTTL)
+			promotion.HoldID, err = offerFromWaitlist(ctx, queries, entry, slotIDs, promotion.ExpiresAt)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return promotion, nil
+	}
+	return nil, nil
+}
+
+// bookFromWaitlist books entry into slotIDs and returns the new booking
+func bookFromWaitlist(ctx context.Context, queries *db.Queries, entry db.WaitlistEntry, bookingType db.BookingType, slotIDs []int32, changedBy string) (int32, error) {
+	cost := bookingCost(bookingType, int32(len(slotIDs)))
+	bookingRow, err := queries.CreateBooking(ctx, db.CreateBookingParams{
+		UserID:  entry.UserID,
+		TypeID:  entry.TypeID,
+		Cost:    cost,
+		Column6: slotIDs,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	history := createdBookingHistoryParams(bookingRow, changedBy)
+	history.Reason = pgtype.Text{String: waitlistBookedReason, Valid: true}
+	err = queries.CreateBookingHistory(ctx, history)
+	if err != nil {
+		return 0, err
+	}
+
+	err = chargeBooking(ctx, queries, bookingType, bookingRow, cost, changedBy)
+	if err != nil {
+		return 0, err
+	}
+
+	err = queries.BookWaitlistEntry(ctx, db.BookWaitlistEntryParams{
+		ID:        entry.ID,
+		BookingID: pgtype.Int4{Int32: bookingRow.BookingID, Valid: true},
+	})
+	return bookingRow.BookingID, err
+}
+
+// offerFromWaitlist holds slotIDs for entry until expiresAt and returns the hold.
+// Booking with the hold takes the customer off the waitlist, see postBooking.
+func offerFromWaitlist(ctx context.Context, queries *db.Queries, entry db.WaitlistEntry, slotIDs []int32, expiresAt time.Time) (int32, error) {
+	hold, err := queries.CreateSlotHold(ctx, db.CreateSlotHoldParams{
+		UserID:    entry.UserID,
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	err = queries.CreateSlotHoldSlots(ctx, db.CreateSlotHoldSlotsParams{
+		Column1: hold.ID,
+		Column2: slotIDs,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	err = queries.OfferWaitlistEntry(ctx, db.OfferWaitlistEntryParams{
+		ID:             entry.ID,
+		HoldID:         pgtype.Int4{Int32: hold.ID, Valid: true},
+		OfferedSlotIds: slotIDs,
+		OfferExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
+	return hold.ID, err
+}
+
+// passOnLapsedOffers expires every waitlist offer whose hold has run out or been
+// released and passes its slots on to the next customer waiting for them
+func passOnLapsedOffers(ctx context.Context, pool *pgxpool.Pool, params WaitlistParams) ([]WaitlistPromotion, error) {
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != nil && err != pgx.ErrTxClosed {
+			panic(err)
+		}
+	}()
+
+	qtx := db.New(pool).WithTx(tx)
+
+	lapsed, err := qtx.GetLapsedWaitlistOffers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	promotions := []WaitlistPromotion{}
+	for _, entry := range lapsed {
+		err = qtx.ExpireWaitlistEntry(ctx, entry.ID)
+		if err != nil {
+			return nil, err
+		}
+		promotion, err := promoteWaitlist(ctx, qtx, entry.OfferedSlotIds, params, "waitlist")
+		if err != nil {
+			return nil, err
+		}
+		if promotion != nil {
+			promotions = append(promotions, *promotion)
+		}
+	}
+
+	return promotions, tx.Commit(ctx)
+}
+
+// reapWaitlistOffers passes lapsed waitlist offers on every interval until ctx
+// is done
+func reapWaitlistOffers(ctx context.Context, pool *pgxpool.Pool, params WaitlistParams, notifier BookingNotifier, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			promotions, err := passOnLapsedOffers(ctx, pool, params)
+			if err != nil {
+				log.Printf("passing on lapsed waitlist offers failed with %v", err)
+				continue
+			}
+			notifyWaitlist(ctx, notifier, promotions)
+		}
+	}
+}
+
+func postWaitlistEntry(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
+		loc, ok := requestLocation(w, r)
+		if !ok {
+			return
+		}
+
+		var waitlistRequest PostWaitlistRequest
+
+		err := json.NewDecoder(r.Body).Decode(&waitlistRequest)
+		if err != nil {
+			log.Printf("error decoding body in postWaitlistEntry: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		params, err := waitlistRequest.ToDBParams(bookingUserID(principal, waitlistRequest.UserID))
+		if err != nil {
+			log.Printf("invalid waitlist entry in postWaitlistEntry: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in postWaitlistEntry: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		queries := db.New(conn)
+
+		entry, err := queries.CreateWaitlistEntry(ctx, params)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+				log.Printf("either the user id: %d, booking type id: %d or employee id: %d does not exist in postWaitlistEntry",
+					params.UserID,
+					params.TypeID,
+					params.EmployeeID.Int32,
+				)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			log.Printf("general error when trying to create waitlist entry in postWaitlistEntry: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(responseFromDBWaitlistEntry(entry, loc))
+		if err != nil {
+			log.Printf("error encoding json in postWaitlistEntry: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// getWaitlistEntry returns one waitlist entry, or every entry the caller can
+// see. Admins see everyone's, optionally filtered with the user_id query
+// parameter, and everyone else only sees their own.
+func getWaitlistEntry(pool *pgxpool.Pool, ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
+		loc, ok := requestLocation(w, r)
+		if !ok {
+			return
+		}
+
+		waitlistId := r.PathValue("waitlist_id")
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in getWaitlistEntry: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		queries := db.New(conn)
+
+		if waitlistId != "" {
+			id, err := strconv.ParseInt(waitlistId, 10, 32)
+			if err != nil {
+				log.Printf("error: %v converting waitlist id to int in getWaitlistEntry: %s", err, waitlistId)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			entry, err := queries.GetWaitlistEntryById(ctx, int32(id))
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				log.Printf("error querying waitlist entry in getWaitlistEntry: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if errors.Is(err, pgx.ErrNoRows) {
+				log.Printf("waitlist id: %d was requested in getWaitlistEntry and does not exist", id)
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			if !principal.CanAccessUser(entry.UserID) {
+				log.Printf("user %d requested waitlist entry %d and doesnt have permission to", principal.UserID, id)
+				writeForbidden(w)
+				return
+			}
+
+			err = json.NewEncoder(w).Encode(responseFromDBWaitlistEntry(entry, loc))
+			if err != nil {
+				log.Printf("error encoding json in getWaitlistEntry: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		userID := pgtype.Int4{Int32: principal.UserID, Valid: true}
+		if principal.IsAdmin() {
+			userID = pgtype.Int4{}
+			if userId := r.URL.Query().Get("user_id"); userId != "" {
+				id, err := strconv.ParseInt(userId, 10, 32)
+				if err != nil {
+					log.Printf("error: %v converting user id to int in getWaitlistEntry: %s", err, userId)
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				userID = pgtype.Int4{Int32: int32(id), Valid: true}
+			}
+		}
+
+		entries, err := queries.GetAllWaitlistEntries(ctx, userID)
+		if err != nil {
+			log.Printf("error querying waitlist entries in getWaitlistEntry: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		resp := []WaitlistResponse{}
+		for _, entry := range entries {
+			resp = append(resp, responseFromDBWaitlistEntry(entry, loc))
+		}
+
+		err = json.NewEncoder(w).Encode(resp)
+		if err != nil {
+			log.Printf("error encoding json in getWaitlistEntry: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// deleteWaitlistEntry takes a customer off the waitlist. An outstanding offer
+// is released and passed on to the next customer waiting for the slots.
+func deleteWaitlistEntry(pool *pgxpool.Pool, ctx context.Context, params WaitlistParams, notifier BookingNotifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := requestPrincipal(w, r)
+		if !ok {
+			return
+		}
+
+		waitlistId := r.PathValue("waitlist_id")
+		id, err := strconv.ParseInt(waitlistId, 10, 32)
+		if err != nil {
+			log.Printf("error: %v converting waitlist id to int in deleteWaitlistEntry: %s", err, waitlistId)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			log.Printf("error aquiring pool in deleteWaitlistEntry: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer conn.Release()
+
+		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			log.Printf("error beginning tx in deleteWaitlistEntry: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		defer func() {
+			err := tx.Rollback(ctx)
+			if err != nil && err != pgx.ErrTxClosed {
+				panic(err)
+			}
+		}()
+
+		queries := db.New(conn)
+		qtx := queries.WithTx(tx)
+
+		entry, err := qtx.GetWaitlistEntryById(ctx, int32(id))
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("getting waitlist entry in deleteWaitlistEntry failed with %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("waitlist id: %d, which does not exist, was attemped to be deleted by deleteWaitlistEntry", id)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if !principal.CanAccessUser(entry.UserID) {
+			log.Printf("user %d requested to delete waitlist entry %d and doesnt have permission to", principal.UserID, id)
+			writeForbidden(w)
+			return
+		}
+
+		_, err = qtx.DeleteWaitlistEntry(ctx, entry.ID)
+		if err != nil {
+			log.Printf("general error when trying to delete waitlist entry in deleteWaitlistEntry: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var promotion *WaitlistPromotion
+		if entry.Status == db.WaitlistStatusOffered {
+			if entry.HoldID.Valid {
+				_, err = qtx.DeleteSlotHold(ctx, entry.HoldID.Int32)
+				if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+					log.Printf("releasing waitlist offer hold in deleteWaitlistEntry failed with %v", err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+			}
+			promotion, err = promoteWaitlist(ctx, qtx, entry.OfferedSlotIds, params, principal.Email)
+			if err != nil {
+				log.Printf("passing on waitlist offer in deleteWaitlistEntry failed with %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Printf("error commiting tx in deleteWaitlistEntry: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if promotion != nil {
+			notifyWaitlist(ctx, notifier, []WaitlistPromotion{*promotion})
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jack-cordery/mirai/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWaitlistParams(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		t.Parallel()
+		params, err := parseWaitlistParams("", "")
+		assert.NoError(t, err)
+		assert.Equal(t, WaitlistParams{Mode: WaitlistModeOffer, OfferTTL: DefaultWaitlistOfferMinutes * time.Minute}, params)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+		params, err := parseWaitlistParams("book", "15")
+		assert.NoError(t, err)
+		assert.Equal(t, WaitlistParams{Mode: WaitlistModeBook, OfferTTL: 15 * time.Minute}, params)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		for _, values := range [][2]string{{"first come", ""}, {"offer", "0"}, {"offer", "-5"}, {"offer", "an hour"}} {
+			_, err := parseWaitlistParams(values[0], values[1])
+			assert.Error(t, err)
+		}
+	})
+}
+
+func TestWaitlistToDBParams(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2025-12-22T09:00:00Z")
+	end, _ := time.Parse(time.RFC3339, "2025-12-22T17:00:00Z")
+
+	t.Run("any employee", func(t *testing.T) {
+		t.Parallel()
+		r := PostWaitlistRequest{TypeID: 2, StartTime: start, EndTime: end}
+		expected := db.CreateWaitlistEntryParams{
+			UserID:    7,
+			TypeID:    2,
+			StartTime: pgtype.Timestamptz{Time: start, Valid: true},
+			EndTime:   pgtype.Timestamptz{Time: end, Valid: true},
+		}
+
+		params, err := r.ToDBParams(7)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, params)
+	})
+
+	t.Run("one employee", func(t *testing.T) {
+		t.Parallel()
+		r := PostWaitlistRequest{TypeID: 2, EmployeeID: 3, StartTime: start, EndTime: end}
+
+		params, err := r.ToDBParams(7)
+		assert.NoError(t, err)
+		assert.Equal(t, pgtype.Int4{Int32: 3, Valid: true}, params.EmployeeID)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		noType := PostWaitlistRequest{StartTime: start, EndTime: end}
+		missing := PostWaitlistRequest{TypeID: 2, StartTime: start}
+		backwards := PostWaitlistRequest{TypeID: 2, StartTime: end, EndTime: start}
+
+		for _, r := range []PostWaitlistRequest{noType, missing, backwards} {
+			_, err := r.ToDBParams(7)
+			assert.Error(t, err)
+		}
+	})
+}
@@ -0,0 +1,24 @@
+#!/bin/bash
+
+
+# test_get_config : test the config end point
+#
+#
+set -eou pipefail
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+source "$SCRIPT_DIR/test_helpers.sh"
+SERVER="$1"
+
+# test GET, no login is needed
+response=$(curl -sS -w "\n%{http_code}" "$SERVER/config")
+
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+assert_status "GET" "/config" "$status" "200"
+
+unit_minutes=$(echo "$body" | jq -r '.unit_minutes')
+if [[ "$unit_minutes" == "null" || $((60 % unit_minutes)) -ne 0 ]]; then
+	echo "GET /config failed with unit_minutes $unit_minutes"
+	exit 1
+fi
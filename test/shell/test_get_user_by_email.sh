@@ -8,8 +8,9 @@ set -eou pipefail
 SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
 source "$SCRIPT_DIR/test_helpers.sh"
 SERVER="$1"
+admin_login
 # test POST
-response=$(curl -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
 		-d '{
         "name": "Jim",
         "surname": "Email",
@@ -23,7 +24,7 @@ user_id=$(echo "$body" | jq -r '.user_id')
 
 # test GET on first POST
 
-response=$(curl -s -w "\n%{http_code}" "$SERVER/userByEmail?email=Jim.email@company.com")
+response=$(curl -b "$COOKIE_JAR" -s -w "\n%{http_code}" "$SERVER/userByEmail?email=Jim.email@company.com")
 body=$(echo "$response" | sed '$d')
 status=$(echo "$response" | tail -n1)
 
@@ -6,6 +6,32 @@
 #
 set -eou pipefail
 
+COOKIE_JAR="$(mktemp)"
+trap 'rm -f "$COOKIE_JAR"' EXIT
+
+# admin_login registers and logs in as INITIAL_ADMIN_EMAIL and raises it to
+# admin, leaving the session cookie in COOKIE_JAR for the rest of the test
+function admin_login() {
+	local email="${INITIAL_ADMIN_EMAIL:?INITIAL_ADMIN_EMAIL must be set}"
+	local password="${ADMIN_PASSWORD:?ADMIN_PASSWORD must be set}"
+
+	curl -sS -o /dev/null -H 'Content-Type: application/json' \
+		-d "{
+	  \"name\": \"Admin\",
+	  \"surname\": \"User\",
+	  \"email\": \"$email\",
+	  \"password\": \"$password\"
+	}" "$SERVER/auth/register"
+
+	curl -sS -o /dev/null -c "$COOKIE_JAR" -H 'Content-Type: application/json' \
+		-d "{
+	  \"email\": \"$email\",
+	  \"password\": \"$password\"
+	}" "$SERVER/auth/login"
+
+	curl -sS -o /dev/null -b "$COOKIE_JAR" -X POST "$SERVER/auth/raise"
+}
+
 
 function assert_status() {
 	local test_method="$1"
@@ -34,7 +60,7 @@ function assert_status_with_cleanup() {
 	
 	if [[ "$actual" != "$expected" ]]; then
 			echo "$test_method $test_name failed with $actual and expected $expected"
-			curl -sS -X DELETE "$SERVER/$test_name/$clean_id"
+			curl -sS -b "$COOKIE_JAR" -X DELETE "$SERVER/$test_name/$clean_id"
 			echo "cleaning db "$SERVER" complete..."
 			exit 1 
 	else 
@@ -51,7 +77,7 @@ function assert_body_contains_with_cleanup() {
 	if [[ "$body" != *"$substring"* ]]; then 
                 echo "$test_method $test_name failed to return the correctly edited data, PUT or GET has failed with body: $body"
                 echo "cleaning db..."
-                curl -sS -X DELETE "$SERVER/$test_name/$clean_id"
+                curl -sS -b "$COOKIE_JAR" -X DELETE "$SERVER/$test_name/$clean_id"
                 exit 1
 	else 
 		echo "$test_method $test_name passed contains condition!"
@@ -0,0 +1,179 @@
+#!/bin/bash
+
+
+# test_post_booking_cancellation_policy : test that a booking keeps the
+# cancellation policy of its type from when it was made, that cancelling late
+# for the customer keeps the late fee, that the business cancelling keeps no fee
+# and that a booking cant be a no show before it starts
+#
+#
+set -eou pipefail
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+source "$SCRIPT_DIR/test_helpers.sh"
+SERVER="$1"
+admin_login
+
+# set-up - create employee and a booking type with a policy and book two slots
+# starting within the free cancellation window and one well after it
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{
+	  "name": "Polly",
+	  "surname": "Policy",
+	  "email": "polly.policy@company.com",
+	  "title": "Stylist",
+	  "description": "good worker"
+	}' "$SERVER/employee")
+
+body=$(echo "$response" | sed '$d')
+employee_id=$(echo "$body" | jq -r '.employee_id')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{
+	  "title": "policy haircut",
+	  "description": "cutting of hair",
+	  "fixed": true,
+	  "cost": 3000,
+	  "cancellation_policy": {
+	    "free_cancellation_hours": 24,
+	    "late_cancellation_percent": 50,
+	    "no_show_percent": 100
+	  }
+	}' "$SERVER/booking_type")
+
+body=$(echo "$response" | sed '$d')
+booking_type_id=$(echo "$body" | jq -r '.booking_type_id')
+
+slots=()
+booking_ids=()
+soon=$(date -u -d "+3 hours" +%Y-%m-%dT%H:00:00Z)
+later=$(date -u -d "+4 hours" +%Y-%m-%dT%H:00:00Z)
+for start in "$soon" "2027-05-05T10:00:00Z" "$later"; do
+	end=$(date -u -d "$start + 30 minutes" +%Y-%m-%dT%H:%M:%SZ)
+	response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+		-d "{
+		  \"employee_id\": $employee_id,
+		  \"start_time\": \"$start\",
+		  \"end_time\": \"$end\",
+		  \"type_id\": $booking_type_id
+		}" "$SERVER/availability")
+
+	body=$(echo "$response" | sed '$d')
+	slot=$(echo "$body" | jq -r '.availability_slot_ids[0]')
+	slots+=("$slot")
+
+	response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+		-d "{
+		  \"availability_slots\": [$slot],
+		  \"type_id\": $booking_type_id
+		}" "$SERVER/booking")
+
+	body=$(echo "$response" | sed '$d')
+	booking_ids+=("$(echo "$body" | jq -r '.booking_id')")
+done
+
+function cleanup() {
+	for booking_id in "${booking_ids[@]}"; do
+		curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/booking/$booking_id"
+	done
+	for slot in "${slots[@]}"; do
+		curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/availability/$slot"
+	done
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/booking_type/$booking_type_id"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/employee/$employee_id"
+}
+
+# test the booking keeps its policy when the type is changed
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-X PUT \
+	-d '{
+	  "title": "policy haircut",
+	  "description": "cutting of hair",
+	  "fixed": true,
+	  "cost": 3000
+	}' "$SERVER/booking_type/$booking_type_id")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "201" ]]; then cleanup; fi
+assert_status "PUT" "/booking_type" "$status" "201"
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" "$SERVER/booking/${booking_ids[0]}")
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "GET" "/booking" "$status" "200"
+if [[ "$(echo "$body" | jq -c '.cancellation_policy')" != '{"free_cancellation_hours":24,"late_cancellation_percent":50,"no_show_percent":100}' ]]; then
+	echo "GET /booking did not keep the policy the booking was made under: $body"
+	cleanup
+	exit 1
+fi
+
+# test cancelling a paid booking late for the customer keeps the late fee and
+# refunds the rest
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{"method": "card"}' "$SERVER/booking/${booking_ids[0]}/payment/manual")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "POST" "/booking/payment/manual" "$status" "200"
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X POST "$SERVER/booking/${booking_ids[0]}/cancel?by_customer=true")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "POST" "/booking/cancel" "$status" "200"
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" "$SERVER/booking/${booking_ids[0]}/ledger")
+body=$(echo "$response" | sed '$d')
+
+if [[ "$(echo "$body" | jq -c '[.charged, .paid, .outstanding]')" != '[1500,1500,0]' ]]; then
+	echo "GET /booking/ledger did not keep half of the booking as a late fee: $body"
+	cleanup
+	exit 1
+fi
+if [[ "$(echo "$body" | jq -r '[.entries[] | select(.kind == "adjustment") | .reason] | first')" != "cancelled late" ]]; then
+	echo "GET /booking/ledger did not give the late fee as the reason: $body"
+	cleanup
+	exit 1
+fi
+
+# test the business cancelling a paid booking late keeps no fee
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{"method": "card"}' "$SERVER/booking/${booking_ids[2]}/payment/manual")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "POST" "/booking/payment/manual" "$status" "200"
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X POST "$SERVER/booking/${booking_ids[2]}/cancel")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "POST" "/booking/cancel" "$status" "200"
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" "$SERVER/booking/${booking_ids[2]}/ledger")
+body=$(echo "$response" | sed '$d')
+
+if [[ "$(echo "$body" | jq -c '[.charged, .paid, .outstanding]')" != '[0,0,0]' ]]; then
+	echo "GET /booking/ledger did not refund all of a booking the business cancelled: $body"
+	cleanup
+	exit 1
+fi
+
+# test a booking cant be marked a no show before it starts
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X POST "$SERVER/booking/${booking_ids[1]}/confirm")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "POST" "/booking/confirm" "$status" "200"
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X POST "$SERVER/booking/${booking_ids[1]}/no_show")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "409" ]]; then cleanup; fi
+assert_status "POST" "/booking/no_show" "$status" "409"
+
+# clean-up
+echo "cleaning up test..."
+
+cleanup
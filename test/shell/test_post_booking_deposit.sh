@@ -0,0 +1,138 @@
+#!/bin/bash
+
+
+# test_post_booking_deposit : test that a booking of a type that takes a deposit
+# cant be confirmed until the deposit is paid and shows what is left to pay
+#
+#
+set -eou pipefail
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+source "$SCRIPT_DIR/test_helpers.sh"
+SERVER="$1"
+admin_login
+
+# set-up - create employee and a booking type with a deposit and book a slot
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{
+	  "name": "Debbie",
+	  "surname": "Deposit",
+	  "email": "debbie.deposit@company.com",
+	  "title": "Stylist",
+	  "description": "good worker"
+	}' "$SERVER/employee")
+
+body=$(echo "$response" | sed '$d')
+employee_id=$(echo "$body" | jq -r '.employee_id')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{
+	  "title": "deposit colour",
+	  "description": "colouring of hair",
+	  "fixed": true,
+	  "cost": 5000,
+	  "deposit": {
+	    "percent": 20
+	  }
+	}' "$SERVER/booking_type")
+
+body=$(echo "$response" | sed '$d')
+booking_type_id=$(echo "$body" | jq -r '.booking_type_id')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"employee_id\": $employee_id,
+	  \"start_time\": \"2027-05-06T10:00:00Z\",
+	  \"end_time\": \"2027-05-06T10:30:00Z\",
+	  \"type_id\": $booking_type_id
+	}" "$SERVER/availability")
+
+body=$(echo "$response" | sed '$d')
+slot=$(echo "$body" | jq -r '.availability_slot_ids[0]')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"availability_slots\": [$slot],
+	  \"type_id\": $booking_type_id
+	}" "$SERVER/booking")
+
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+booking_id=$(echo "$body" | jq -r '.booking_id')
+
+function cleanup() {
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/booking/$booking_id"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/availability/$slot"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/booking_type/$booking_type_id"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/employee/$employee_id"
+}
+
+if [[ "$status" != "201" ]]; then cleanup; fi
+assert_status "POST" "/booking" "$status" "201"
+
+# test the booking shows its deposit and balance
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" "$SERVER/booking/$booking_id")
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "GET" "/booking" "$status" "200"
+if [[ "$(echo "$body" | jq -c '[.status, .deposit, .balance, .deposit_due_at != null]')" != '["created",1000,5000,true]' ]]; then
+	echo "GET /booking did not show the deposit and balance: $body"
+	cleanup
+	exit 1
+fi
+
+# test the booking cant be confirmed before the deposit is paid
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X POST "$SERVER/booking/$booking_id/confirm")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "409" ]]; then cleanup; fi
+assert_status "POST" "/booking/confirm" "$status" "409"
+
+# test paying just the deposit
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X POST "$SERVER/booking/$booking_id/payment?deposit=true")
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "201" ]]; then cleanup; fi
+assert_status "POST" "/booking/payment" "$status" "201"
+if [[ "$(echo "$body" | jq -r '.amount')" != "1000" ]]; then
+	echo "POST /booking/payment did not start a payment of the deposit: $body"
+	cleanup
+	exit 1
+fi
+payment_id=$(echo "$body" | jq -r '.payment_id')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X POST "$SERVER/payment/$payment_id/capture")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "POST" "/payment/capture" "$status" "200"
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X POST "$SERVER/booking/$booking_id/payment?deposit=true")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "409" ]]; then cleanup; fi
+assert_status "POST" "/booking/payment" "$status" "409"
+
+# test the booking can be confirmed once the deposit is paid, leaving the rest
+# to pay
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X POST "$SERVER/booking/$booking_id/confirm")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "POST" "/booking/confirm" "$status" "200"
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" "$SERVER/booking/$booking_id")
+body=$(echo "$response" | sed '$d')
+
+if [[ "$(echo "$body" | jq -c '[.status, .balance, .paid]')" != '["confirmed",4000,false]' ]]; then
+	echo "GET /booking did not show the rest of the balance to pay: $body"
+	cleanup
+	exit 1
+fi
+
+# clean-up
+echo "cleaning up test..."
+
+cleanup
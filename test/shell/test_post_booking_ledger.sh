@@ -0,0 +1,160 @@
+#!/bin/bash
+
+
+# test_post_booking_ledger : test that a booking is charged in its ledger, that
+# payments and adjustments show up against it and that cancelling a paid
+# booking refunds it
+#
+#
+set -eou pipefail
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+source "$SCRIPT_DIR/test_helpers.sh"
+SERVER="$1"
+admin_login
+
+# set-up - create employee and booking type and book a slot
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{
+	  "name": "Lenny",
+	  "surname": "Ledger",
+	  "email": "lenny.ledger@company.com",
+	  "title": "Stylist",
+	  "description": "good worker"
+	}' "$SERVER/employee")
+
+body=$(echo "$response" | sed '$d')
+employee_id=$(echo "$body" | jq -r '.employee_id')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{
+	  "title": "ledger haircut",
+	  "description": "cutting of hair",
+	  "fixed": true,
+	  "cost": 3000
+	}' "$SERVER/booking_type")
+
+body=$(echo "$response" | sed '$d')
+booking_type_id=$(echo "$body" | jq -r '.booking_type_id')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"employee_id\": $employee_id,
+	  \"start_time\": \"2027-05-04T10:00:00Z\",
+	  \"end_time\": \"2027-05-04T10:30:00Z\",
+	  \"type_id\": $booking_type_id
+	}" "$SERVER/availability")
+
+body=$(echo "$response" | sed '$d')
+slot=$(echo "$body" | jq -r '.availability_slot_ids[0]')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"availability_slots\": [$slot],
+	  \"type_id\": $booking_type_id
+	}" "$SERVER/booking")
+
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+booking_id=$(echo "$body" | jq -r '.booking_id')
+
+function cleanup() {
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/booking/$booking_id"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/availability/$slot"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/booking_type/$booking_type_id"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/employee/$employee_id"
+}
+
+if [[ "$status" != "201" ]]; then cleanup; fi
+assert_status "POST" "/booking" "$status" "201"
+
+# test a new booking is charged its cost
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" "$SERVER/booking/$booking_id/ledger")
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "GET" "/booking/ledger" "$status" "200"
+if [[ "$(echo "$body" | jq -c '[.charged, .paid, .outstanding, [.entries[].kind]]')" != '[3000,0,3000,["charge"]]' ]]; then
+	echo "GET /booking/ledger did not show the booking charged its cost: $body"
+	cleanup
+	exit 1
+fi
+
+# test an adjustment takes money off what is owed
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{"amount": -500, "reason": "loyalty discount"}' "$SERVER/booking/$booking_id/ledger/adjustment")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "201" ]]; then cleanup; fi
+assert_status "POST" "/booking/ledger/adjustment" "$status" "201"
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{"amount": -500}' "$SERVER/booking/$booking_id/ledger/adjustment")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "400" ]]; then cleanup; fi
+assert_status "POST" "/booking/ledger/adjustment" "$status" "400"
+
+# test a manual payment pays what is outstanding and marks the booking paid
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{"method": "cash", "reference": "receipt 12"}' "$SERVER/booking/$booking_id/payment/manual")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "POST" "/booking/payment/manual" "$status" "200"
+
+# test a second manual payment is refused with nothing outstanding
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{"method": "cash"}' "$SERVER/booking/$booking_id/payment/manual")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "409" ]]; then cleanup; fi
+assert_status "POST" "/booking/payment/manual" "$status" "409"
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" "$SERVER/booking/$booking_id/ledger")
+body=$(echo "$response" | sed '$d')
+
+if [[ "$(echo "$body" | jq -c '[.charged, .paid, .outstanding]')" != '[2500,2500,0]' ]]; then
+	echo "GET /booking/ledger did not show the manual payment: $body"
+	cleanup
+	exit 1
+fi
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" "$SERVER/booking/$booking_id")
+body=$(echo "$response" | sed '$d')
+
+if [[ "$(echo "$body" | jq -r '.paid')" != "true" ]]; then
+	echo "GET /booking did not show the booking as paid: $body"
+	cleanup
+	exit 1
+fi
+
+# test cancelling the paid booking refunds it
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X POST "$SERVER/booking/$booking_id/cancel")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "POST" "/booking/cancel" "$status" "200"
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" "$SERVER/booking/$booking_id/ledger")
+body=$(echo "$response" | sed '$d')
+
+if [[ "$(echo "$body" | jq -c '[.charged, .paid, .outstanding, [.entries[].kind]]')" != '[0,0,0,["charge","adjustment","payment","adjustment","refund"]]' ]]; then
+	echo "GET /booking/ledger did not show the cancelled booking refunded: $body"
+	cleanup
+	exit 1
+fi
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" "$SERVER/booking/$booking_id")
+body=$(echo "$response" | sed '$d')
+
+if [[ "$(echo "$body" | jq -r '.paid')" != "false" ]]; then
+	echo "GET /booking still showed the refunded booking as paid: $body"
+	cleanup
+	exit 1
+fi
+
+# clean-up
+echo "cleaning up test..."
+
+cleanup
@@ -0,0 +1,148 @@
+#!/bin/bash
+
+
+# test_post_booking_payment : test that a booking can be paid, captured and
+# refunded through the payment provider, run against the fake provider
+#
+#
+set -eou pipefail
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+source "$SCRIPT_DIR/test_helpers.sh"
+SERVER="$1"
+admin_login
+
+# set-up - create employee and booking type and book a slot
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{
+	  "name": "Penny",
+	  "surname": "Payment",
+	  "email": "penny.payment@company.com",
+	  "title": "Stylist",
+	  "description": "good worker"
+	}' "$SERVER/employee")
+
+body=$(echo "$response" | sed '$d')
+employee_id=$(echo "$body" | jq -r '.employee_id')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{
+	  "title": "paid haircut",
+	  "description": "cutting of hair",
+	  "fixed": true,
+	  "cost": 2400
+	}' "$SERVER/booking_type")
+
+body=$(echo "$response" | sed '$d')
+booking_type_id=$(echo "$body" | jq -r '.booking_type_id')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"employee_id\": $employee_id,
+	  \"start_time\": \"2027-05-03T10:00:00Z\",
+	  \"end_time\": \"2027-05-03T10:30:00Z\",
+	  \"type_id\": $booking_type_id
+	}" "$SERVER/availability")
+
+body=$(echo "$response" | sed '$d')
+slot=$(echo "$body" | jq -r '.availability_slot_ids[0]')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"availability_slots\": [$slot],
+	  \"type_id\": $booking_type_id
+	}" "$SERVER/booking")
+
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+booking_id=$(echo "$body" | jq -r '.booking_id')
+
+function cleanup() {
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/booking/$booking_id"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/availability/$slot"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/booking_type/$booking_type_id"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/employee/$employee_id"
+}
+
+if [[ "$status" != "201" ]]; then cleanup; fi
+assert_status "POST" "/booking" "$status" "201"
+
+# test starting a payment for the booking cost
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X POST "$SERVER/booking/$booking_id/payment")
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "201" ]]; then cleanup; fi
+assert_status "POST" "/booking/payment" "$status" "201"
+if [[ "$(echo "$body" | jq -c '[.amount, .status]')" != '[2400,"pending"]' ]]; then
+	echo "POST /booking/payment did not start a pending payment of the cost: $body"
+	cleanup
+	exit 1
+fi
+payment_id=$(echo "$body" | jq -r '.payment_id')
+
+# test a second payment isnt started while the first is pending
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X POST "$SERVER/booking/$booking_id/payment")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "409" ]]; then cleanup; fi
+assert_status "POST" "/booking/payment" "$status" "409"
+
+# test capturing the payment marks the booking paid
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X POST "$SERVER/payment/$payment_id/capture")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "POST" "/payment/capture" "$status" "200"
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" "$SERVER/booking/$booking_id")
+body=$(echo "$response" | sed '$d')
+
+if [[ "$(echo "$body" | jq -r '.paid')" != "true" ]]; then
+	echo "GET /booking did not show the booking as paid: $body"
+	cleanup
+	exit 1
+fi
+
+# test a paid booking cant be paid again
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X POST "$SERVER/booking/$booking_id/payment")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "409" ]]; then cleanup; fi
+assert_status "POST" "/booking/payment" "$status" "409"
+
+# test refunding part and then the rest of the payment
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{"amount": 400}' "$SERVER/payment/$payment_id/refund")
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "POST" "/payment/refund" "$status" "200"
+if [[ "$(echo "$body" | jq -c '[.refunded_amount, .status]')" != '[400,"captured"]' ]]; then
+	echo "POST /payment/refund did not record the partial refund: $body"
+	cleanup
+	exit 1
+fi
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X POST "$SERVER/payment/$payment_id/refund")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "POST" "/payment/refund" "$status" "200"
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" "$SERVER/booking/$booking_id/payment")
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "GET" "/booking/payment" "$status" "200"
+if [[ "$(echo "$body" | jq -c '[.[] | [.refunded_amount, .status]]')" != '[[2400,"refunded"]]' ]]; then
+	echo "GET /booking/payment did not show the payment as refunded: $body"
+	cleanup
+	exit 1
+fi
+
+# clean-up
+echo "cleaning up test..."
+
+cleanup
@@ -0,0 +1,145 @@
+#!/bin/bash
+
+
+# test_post_booking_series : test that a weekly series books the weeks that have
+# a slot, reports the week that doesnt, and can be moved and cancelled together
+#
+#
+set -eou pipefail
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+source "$SCRIPT_DIR/test_helpers.sh"
+SERVER="$1"
+admin_login
+
+# set-up - create employee and booking type with slots in two of three weeks
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{
+	  "name": "Sally",
+	  "surname": "Series",
+	  "email": "sally.series@company.com",
+	  "title": "Stylist",
+	  "description": "good worker"
+	}' "$SERVER/employee")
+
+body=$(echo "$response" | sed '$d')
+employee_id=$(echo "$body" | jq -r '.employee_id')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{
+	  "title": "weekly haircut",
+	  "description": "cutting of hair",
+	  "fixed": true,
+	  "cost": 2400
+	}' "$SERVER/booking_type")
+
+body=$(echo "$response" | sed '$d')
+booking_type_id=$(echo "$body" | jq -r '.booking_type_id')
+
+slots=()
+for start in "2027-04-05T10:00:00Z" "2027-04-12T10:00:00Z" "2027-04-06T11:00:00Z" "2027-04-13T11:00:00Z"; do
+	end=$(date -u -d "$start + 30 minutes" +%Y-%m-%dT%H:%M:%SZ)
+	response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+		-d "{
+		  \"employee_id\": $employee_id,
+		  \"start_time\": \"$start\",
+		  \"end_time\": \"$end\",
+		  \"type_id\": $booking_type_id
+		}" "$SERVER/availability")
+
+	body=$(echo "$response" | sed '$d')
+	slots+=("$(echo "$body" | jq -r '.availability_slot_ids[0]')")
+done
+booking_ids=()
+
+function cleanup() {
+	for id in "${booking_ids[@]}"; do
+		curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/booking/$id"
+	done
+	for id in "${slots[@]}"; do
+		curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/availability/$id"
+	done
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/booking_type/$booking_type_id"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/employee/$employee_id"
+}
+
+# test POST books the first two weeks and skips the third
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"type_id\": $booking_type_id,
+	  \"availability_slots\": [${slots[0]}],
+	  \"interval_weeks\": 1,
+	  \"occurrences\": 3
+	}" "$SERVER/booking_series")
+
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "201" ]]; then cleanup; fi
+assert_status "POST" "/booking_series" "$status" "201"
+series_id=$(echo "$body" | jq -r '.series_id')
+mapfile -t booking_ids < <(echo "$body" | jq -r '.bookings[].booking_id')
+
+if [[ "$(echo "$body" | jq -c '[[.bookings[].occurrence], [.skipped[].occurrence]]')" != "[[1,2],[3]]" ]]; then
+	echo "POST /booking_series did not place the expected occurrences: $body"
+	cleanup
+	exit 1
+fi
+
+# test an unsupported interval is rejected
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"type_id\": $booking_type_id,
+	  \"availability_slots\": [${slots[0]}],
+	  \"interval_weeks\": 3,
+	  \"occurrences\": 3
+	}" "$SERVER/booking_series")
+
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "400" ]]; then cleanup; fi
+assert_status "POST" "/booking_series" "$status" "400"
+
+# test rescheduling all occurrences moves each to the next day at 11:00
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"scope\": \"all\",
+	  \"availability_slots\": [${slots[2]}]
+	}" "$SERVER/booking_series/$series_id/reschedule")
+
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "POST" "/booking_series/reschedule" "$status" "200"
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" "$SERVER/booking_series/$series_id")
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "GET" "/booking_series" "$status" "200"
+if [[ "$(echo "$body" | jq -c '[.bookings[] | .start_time | fromdateiso8601 | todate]')" != '["2027-04-06T11:00:00Z","2027-04-13T11:00:00Z"]' ]]; then
+	echo "GET /booking_series did not show the moved occurrences: $body"
+	cleanup
+	exit 1
+fi
+
+# test cancelling the whole series
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{"scope": "all"}' "$SERVER/booking_series/$series_id/cancel")
+
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "POST" "/booking_series/cancel" "$status" "200"
+if [[ "$(echo "$body" | jq '.booking_ids | length')" != "2" ]]; then
+	echo "POST /booking_series/cancel did not cancel both occurrences: $body"
+	cleanup
+	exit 1
+fi
+
+# clean-up
+echo "cleaning up test..."
+
+cleanup
@@ -0,0 +1,121 @@
+#!/bin/bash
+
+
+# test_post_class_capacity : test that a slot with a capacity takes that many
+# bookings and no more
+#
+#
+set -eou pipefail
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+source "$SCRIPT_DIR/test_helpers.sh"
+SERVER="$1"
+admin_login
+
+# set-up - create employee and booking type and a class with two seats
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{
+	  "name": "Cass",
+	  "surname": "Class",
+	  "email": "cass.class@company.com",
+	  "title": "Instructor",
+	  "description": "good worker"
+	}' "$SERVER/employee")
+
+body=$(echo "$response" | sed '$d')
+employee_id=$(echo "$body" | jq -r '.employee_id')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{
+	  "title": "yoga class",
+	  "description": "half an hour of yoga",
+	  "fixed": true,
+	  "cost": 1200
+	}' "$SERVER/booking_type")
+
+body=$(echo "$response" | sed '$d')
+booking_type_id=$(echo "$body" | jq -r '.booking_type_id')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"employee_id\": $employee_id,
+	  \"start_time\": \"2026-02-03T18:00:00Z\",
+	  \"end_time\": \"2026-02-03T18:30:00Z\",
+	  \"type_id\": $booking_type_id,
+	  \"capacity\": 2
+	}" "$SERVER/availability")
+
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+slot=$(echo "$body" | jq -r '.availability_slot_ids[0]')
+
+booking_ids=()
+
+function cleanup() {
+	for booking_id in "${booking_ids[@]}"; do
+		curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/booking/$booking_id"
+	done
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/availability/$slot"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/booking_type/$booking_type_id"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/employee/$employee_id"
+}
+
+if [[ "$status" != "201" ]]; then cleanup; fi
+assert_status "POST" "/availability" "$status" "201"
+
+# test both seats can be booked
+for seat in 1 2; do
+	response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+		-d "{
+	  \"availability_slots\": [$slot],
+	  \"type_id\": $booking_type_id
+	}" "$SERVER/booking")
+
+	body=$(echo "$response" | sed '$d')
+	status=$(echo "$response" | tail -n1)
+
+	if [[ "$status" != "201" ]]; then cleanup; fi
+	assert_status "POST" "/booking" "$status" "201"
+	booking_ids+=("$(echo "$body" | jq -r '.booking_id')")
+done
+
+# test the full class reports no seats and cant be booked again
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" "$SERVER/availability/$slot")
+body=$(echo "$response" | sed '$d')
+
+if [[ "$(echo "$body" | jq -c '[.capacity, .seats_remaining]')" != "[2,0]" ]]; then
+	echo "GET /availability did not report the class as full: $body"
+	cleanup
+	exit 1
+fi
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"availability_slots\": [$slot],
+	  \"type_id\": $booking_type_id
+	}" "$SERVER/booking")
+
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "409" ]]; then cleanup; fi
+assert_status "POST" "/booking" "$status" "409"
+
+# test cancelling a booking gives its seat back
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X POST "$SERVER/booking/${booking_ids[0]}/cancel")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "POST" "/booking/cancel" "$status" "200"
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" "$SERVER/availability/$slot")
+body=$(echo "$response" | sed '$d')
+
+if [[ "$(echo "$body" | jq -r '.seats_remaining')" != "1" ]]; then
+	echo "GET /availability did not give the cancelled seat back: $body"
+	cleanup
+	exit 1
+fi
+
+# clean-up
+echo "cleaning up test..."
+
+cleanup
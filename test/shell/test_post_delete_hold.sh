@@ -0,0 +1,121 @@
+#!/bin/bash
+
+
+# test_post_delete_hold : test holding slots and booking from a hold
+#
+#
+set -eou pipefail
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+source "$SCRIPT_DIR/test_helpers.sh"
+SERVER="$1"
+admin_login
+
+# set-up - create employee and booking type, and an hour of availability
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+		-d '{
+        "name": "Holly",
+        "surname": "Hold",
+        "email": "Holly.hold@company.com",
+        "title": "Manager",
+	"description": "good worker"
+      }' "$SERVER/employee")
+
+body=$(echo "$response" | sed '$d')
+employee_id=$(echo "$body" | jq -r '.employee_id')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+		-d '{
+        "title": "held haircut",
+        "description": "cutting of hair",
+        "fixed": false,
+        "cost": 2400
+      }' "$SERVER/booking_type")
+
+body=$(echo "$response" | sed '$d')
+booking_type_id=$(echo "$body" | jq -r '.booking_type_id')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"employee_id\": $employee_id,
+	  \"start_time\": \"2025-07-27T18:00:00Z\",
+	  \"end_time\": \"2025-07-27T19:00:00Z\",
+	  \"type_id\": $booking_type_id
+	}" "$SERVER/availability")
+
+body=$(echo "$response" | sed '$d')
+first_slot=$(echo "$body" | jq -r '.availability_slot_ids[0]')
+second_slot=$(echo "$body" | jq -r '.availability_slot_ids[1]')
+
+function cleanup() {
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/availability/$first_slot"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/availability/$second_slot"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/booking_type/$booking_type_id"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/employee/$employee_id"
+}
+
+# test POST
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{\"availability_slots\": [$first_slot, $second_slot]}" "$SERVER/hold")
+
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "201" ]]; then cleanup; fi
+assert_status "POST" "/hold" "$status" "201"
+
+hold_id=$(echo "$body" | jq -r '.hold_id')
+
+# test POST on a held slot is a conflict naming the slot
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{\"availability_slots\": [$second_slot]}" "$SERVER/hold")
+
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "409" ]]; then cleanup; fi
+assert_status "POST" "/hold" "$status" "409"
+if [[ "$(echo "$body" | jq -c '.taken_slot_ids')" != "[$second_slot]" ]]; then
+	echo "POST /hold conflict did not name the taken slot: $body"
+	cleanup
+	exit 1
+fi
+
+# test DELETE then hold again
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X DELETE "$SERVER/hold/$hold_id")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "204" ]]; then cleanup; fi
+assert_status "DELETE" "/hold" "$status" "204"
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{\"availability_slots\": [$first_slot, $second_slot]}" "$SERVER/hold")
+
+body=$(echo "$response" | sed '$d')
+hold_id=$(echo "$body" | jq -r '.hold_id')
+
+# test POST /booking consumes the hold
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"hold_id\": $hold_id,
+	  \"type_id\": $booking_type_id,
+	  \"notes\": \"held booking\"
+	}" "$SERVER/booking")
+
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "201" ]]; then cleanup; fi
+assert_status "POST" "/booking" "$status" "201"
+
+booking_id=$(echo "$body" | jq -r '.booking_id')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X DELETE "$SERVER/hold/$hold_id")
+status=$(echo "$response" | tail -n1)
+
+assert_status_with_cleanup "DELETE" "/booking" "$status" "404" "$booking_id"
+
+# clean-up
+echo "cleaning up test..."
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X DELETE "$SERVER/booking/$booking_id")
+cleanup
@@ -0,0 +1,160 @@
+#!/bin/bash
+
+
+# test_post_get_delete_time_off : test employee time off and business closures
+#
+#
+set -eou pipefail
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+source "$SCRIPT_DIR/test_helpers.sh"
+SERVER="$1"
+admin_login
+
+# set-up - create employee and booking type, an hour of availability and a
+# booking on the first half of it
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+		-d '{
+        "name": "Tim",
+        "surname": "Off",
+        "email": "tim.off@company.com",
+        "title": "Barber",
+	"description": "good worker"
+      }' "$SERVER/employee")
+
+body=$(echo "$response" | sed '$d')
+employee_id=$(echo "$body" | jq -r '.employee_id')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+		-d '{
+        "title": "time off haircut",
+        "description": "cutting of hair",
+        "fixed": false,
+        "cost": 2400
+      }' "$SERVER/booking_type")
+
+body=$(echo "$response" | sed '$d')
+booking_type_id=$(echo "$body" | jq -r '.booking_type_id')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"employee_id\": $employee_id,
+	  \"start_time\": \"2025-12-24T10:00:00Z\",
+	  \"end_time\": \"2025-12-24T11:00:00Z\",
+	  \"type_id\": $booking_type_id
+	}" "$SERVER/availability")
+
+body=$(echo "$response" | sed '$d')
+first_slot=$(echo "$body" | jq -r '.availability_slot_ids[0]')
+second_slot=$(echo "$body" | jq -r '.availability_slot_ids[1]')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"availability_slots\": [$first_slot],
+	  \"type_id\": $booking_type_id
+	}" "$SERVER/booking")
+
+body=$(echo "$response" | sed '$d')
+booking_id=$(echo "$body" | jq -r '.booking_id')
+
+function cleanup() {
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/booking/$booking_id"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/availability/$first_slot"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/availability/$second_slot"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/booking_type/$booking_type_id"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/employee/$employee_id"
+}
+
+# test POST time off over the booking reports it without cancelling
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"employee_id\": $employee_id,
+	  \"start_time\": \"2025-12-24T09:00:00Z\",
+	  \"end_time\": \"2025-12-24T17:00:00Z\",
+	  \"reason\": \"christmas eve\"
+	}" "$SERVER/time_off")
+
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "201" ]]; then cleanup; fi
+assert_status "POST" "/time_off" "$status" "201"
+
+time_off_id=$(echo "$body" | jq -r '.time_off_id')
+if [[ "$(echo "$body" | jq -c '[.conflicting_bookings[] | [.booking_id, .status]]')" != "[[$booking_id,\"created\"]]" ]]; then
+	echo "POST /time_off did not report the conflicting booking: $body"
+	cleanup
+	exit 1
+fi
+
+# test the free slot in the time off can no longer be held
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{\"availability_slots\": [$second_slot]}" "$SERVER/hold")
+
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "409" ]]; then cleanup; fi
+assert_status "POST" "/hold" "$status" "409"
+
+# test GET
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" "$SERVER/time_off/$time_off_id")
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "GET" "/time_off" "$status" "200"
+if [[ "$body" != *"christmas eve"* ]]; then
+	echo "GET /time_off did not return the reason: $body"
+	cleanup
+	exit 1
+fi
+
+# test DELETE
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X DELETE "$SERVER/time_off/$time_off_id")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "204" ]]; then cleanup; fi
+assert_status "DELETE" "/time_off" "$status" "204"
+
+# test POST closure cancelling the booking
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{
+	  "start_date": "2025-12-24",
+	  "reason": "closed for christmas",
+	  "cancel_bookings": true
+	}' "$SERVER/closure")
+
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "201" ]]; then cleanup; fi
+assert_status "POST" "/closure" "$status" "201"
+
+closure_id=$(echo "$body" | jq -r '.closure_id')
+if [[ "$(echo "$body" | jq -c '[.conflicting_bookings[] | [.booking_id, .status]]')" != "[[$booking_id,\"cancelled\"]]" ]]; then
+	echo "POST /closure did not cancel the conflicting booking: $body"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/closure/$closure_id"
+	cleanup
+	exit 1
+fi
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" "$SERVER/booking/$booking_id")
+body=$(echo "$response" | sed '$d')
+
+if [[ "$(echo "$body" | jq -r '.status')" != "cancelled" ]]; then
+	echo "booking $booking_id was not cancelled by the closure: $body"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/closure/$closure_id"
+	cleanup
+	exit 1
+fi
+
+# test DELETE closure
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X DELETE "$SERVER/closure/$closure_id")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "204" ]]; then cleanup; fi
+assert_status "DELETE" "/closure" "$status" "204"
+
+# clean-up
+echo "cleaning up test..."
+
+cleanup
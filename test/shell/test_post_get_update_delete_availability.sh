@@ -8,10 +8,11 @@ set -eou pipefail
 SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
 source "$SCRIPT_DIR/test_helpers.sh"
 SERVER="$1"
+admin_login
 
 
 # set-up - create employee and get id, and setup booking type and get id
-response=$(curl -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
 		-d '{
         "name": "Jimmy",
         "surname": "Smith",
@@ -27,7 +28,7 @@ employee_id=$(echo "$body" | jq -r '.employee_id')
 
 echo $employee_id
 
-response=$(curl -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
 		-d '{
         "title": "not a haircut",
         "description": "cutting of hair",
@@ -42,7 +43,7 @@ booking_type_id=$(echo "$body" | jq -r '.booking_type_id')
 echo $booking_type_id
 
 # test POST
-response=$(curl -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
 	-d "{
 	  \"employee_id\": $employee_id,
 	  \"start_time\": \"2025-07-26T18:30:00Z\",
@@ -60,7 +61,7 @@ availability_id_2=$(echo "$body" | jq -r '.availability_slot_ids[1]')
 
 # test GET on first POST
 
-response=$(curl -s -w "\n%{http_code}" "$SERVER/availability/$availability_id_1")
+response=$(curl -b "$COOKIE_JAR" -s -w "\n%{http_code}" "$SERVER/availability/$availability_id_1")
 body=$(echo "$response" | sed '$d')
 status=$(echo "$response" | tail -n1)
 
@@ -70,7 +71,7 @@ assert_body_contains_with_cleanup "GET" "/availability" "$body" "2025-07-26T18:3
 assert_body_contains_with_cleanup "GET" "/availability" "$body" "2" "$availability_id_1"
 assert_body_contains_with_cleanup "GET" "/availability" "$body" "$booking_type_id" "$availability_id_1"
 
-response=$(curl -s -w "\n%{http_code}" "$SERVER/availability/$availability_id_2")
+response=$(curl -b "$COOKIE_JAR" -s -w "\n%{http_code}" "$SERVER/availability/$availability_id_2")
 body=$(echo "$response" | sed '$d')
 status=$(echo "$response" | tail -n1)
 
@@ -79,8 +80,35 @@ assert_body_contains_with_cleanup "GET" "/availability" "$body" "$employee_id" "
 assert_body_contains_with_cleanup "GET" "/availability" "$body" "2025-07-26T19:00:00" "$availability_id_2"
 assert_body_contains_with_cleanup "GET" "/availability" "$body" "2" "$availability_id_2"
 assert_body_contains_with_cleanup "GET" "/availability" "$body" "$booking_type_id" "$availability_id_2"
+
+# test POST overlapping the time already offered
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"employee_id\": $employee_id,
+	  \"start_time\": \"2025-07-26T19:00:00Z\",
+	  \"end_time\": \"2025-07-26T20:00:00Z\",
+	  \"type_id\": $booking_type_id
+	}" "$SERVER/availability")
+
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+assert_status_with_cleanup "POST" "/availability" "$status" "409" "$availability_id_1"
+assert_body_contains_with_cleanup "POST" "/availability" "$body" "$availability_id_2" "$availability_id_1"
+
+# test search, the slots above are in the past so only the status is checked
+response=$(curl -b "$COOKIE_JAR" -s -w "\n%{http_code}" "$SERVER/availability/search?type_id=$booking_type_id&employee_id=$employee_id")
+status=$(echo "$response" | tail -n1)
+
+assert_status_with_cleanup "GET" "/availability/search" "$status" "200" "$availability_id_1"
+
+response=$(curl -b "$COOKIE_JAR" -s -w "\n%{http_code}" "$SERVER/availability/search")
+status=$(echo "$response" | tail -n1)
+
+assert_status_with_cleanup "GET" "/availability/search" "$status" "400" "$availability_id_1"
+
 # test PUT
-response=$(curl -s -w "\n%{http_code}" -H 'Content-Type: application/json' \
+response=$(curl -b "$COOKIE_JAR" -s -w "\n%{http_code}" -H 'Content-Type: application/json' \
 		-X PUT \
 	-d "{
           \"availability_slot_ids\": [$availability_id_1, $availability_id_2],
@@ -101,7 +129,7 @@ echo $body
 # test PUT
 assert_status_with_cleanup "PUT" "/availability" "$status" "201" "$availability_id_1"
 
-response=$(curl -s -w "\n%{http_code}" "$SERVER/availability/$availability_id_1")
+response=$(curl -b "$COOKIE_JAR" -s -w "\n%{http_code}" "$SERVER/availability/$availability_id_1")
 body=$(echo "$response" | sed '$d')
 status=$(echo "$response" | tail -n1)
 
@@ -112,7 +140,7 @@ assert_body_contains_with_cleanup "GET" "/availability" "$body" "2024-07-26T19:3
 assert_body_contains_with_cleanup "GET" "/availability" "$body" "$booking_type_id" "$availability_id_1"
 
 # test DELETE
-response=$(curl -sS -w "\n%{http_code}" -X DELETE \
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X DELETE \
 		-H "Content-Type: application/json" \
 		-d "{
 		\"availability_slot_ids\": [$availability_id_1]
@@ -123,19 +151,43 @@ status=$(echo "$response" | tail -n1)
 
 assert_status "DELETE" "/availability" "$status" "204" 
 
-response=$(curl -sS -w "\n%{http_code}" -X DELETE \
+# test DELETE on booked availability needs force and a reason, and cancels the booking
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"availability_slots\": [$availability_id_2],
+	  \"type_id\": $booking_type_id
+	}" "$SERVER/booking")
+
+body=$(echo "$response" | sed '$d')
+booking_id=$(echo "$body" | jq -r '.booking_id')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X DELETE "$SERVER/availability/$availability_id_2")
+status=$(echo "$response" | tail -n1)
+
+assert_status_with_cleanup "DELETE" "/availability" "$status" "400" "$availability_id_2"
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X DELETE "$SERVER/availability/$availability_id_2?force=true")
+status=$(echo "$response" | tail -n1)
+
+assert_status_with_cleanup "DELETE" "/availability" "$status" "400" "$availability_id_2"
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X DELETE \
 		-H "Content-Type: application/json" \
 		-d "{
 		\"availability_slot_ids\": [$availability_id_2] 
-		}" "$SERVER/availability/")
+		}" "$SERVER/availability/?force=true&reason=staff%20sickness")
 
 body=$(echo "$response" | sed '$d')
 status=$(echo "$response" | tail -n1)
 
-assert_status "DELETE" "/availability" "$status" "204" 
+assert_status "DELETE" "/availability" "$status" "200" 
+if [[ "$(echo "$body" | jq -c '.cancelled_booking_ids')" != "[$booking_id]" ]]; then
+	echo "DELETE /availability did not list the cancelled booking: $body"
+	exit 1
+fi
 # clean-up
 echo "cleaning up test..."
-response=$(curl -sS -w "\n%{http_code}" -X DELETE "$SERVER/employee/$employee_id")
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X DELETE "$SERVER/employee/$employee_id")
 echo $response
-response=$(curl -sS -w "\n%{http_code}" -X DELETE "$SERVER/booking_type/$booking_type_id")
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X DELETE "$SERVER/booking_type/$booking_type_id")
 echo $response
@@ -8,9 +8,10 @@ set -eou pipefail
 SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
 source "$SCRIPT_DIR/test_helpers.sh"
 SERVER="$1"
+admin_login
 
 # test POST
-response=$(curl -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
 		-d '{
         "title": "haircut",
         "description": "cutting of hair",
@@ -27,7 +28,7 @@ booking_type_id=$(echo "$body" | jq -r '.booking_type_id')
 
 # test GET on first POST
 
-response=$(curl -s -w "\n%{http_code}" "$SERVER/booking_type/$booking_type_id")
+response=$(curl -b "$COOKIE_JAR" -s -w "\n%{http_code}" "$SERVER/booking_type/$booking_type_id")
 body=$(echo "$response" | sed '$d')
 status=$(echo "$response" | tail -n1)
 
@@ -38,13 +39,15 @@ assert_body_contains_with_cleanup "GET" "/booking_type" "$body" "2400" "$booking
 assert_body_contains_with_cleanup "GET" "/booking_type" "$body" "false" "$booking_type_id"
 
 # test PUT
-response=$(curl -s -w "\n%{http_code}" -H 'Content-Type: application/json' \
+response=$(curl -b "$COOKIE_JAR" -s -w "\n%{http_code}" -H 'Content-Type: application/json' \
 		-X PUT \
 		-d '{
 		"title": "masssage",
 		"description": "sports massage",
 		"fixed": true,
-		"cost": 5800
+		"cost": 5800,
+		"buffer_after_minutes": 15,
+		"min_notice_minutes": 120
 	      }' "$SERVER/booking_type/$booking_type_id")
 
 body=$(echo "$response" | sed '$d')
@@ -53,7 +56,7 @@ status=$(echo "$response" | tail -n1)
 # test PUT
 assert_status_with_cleanup "PUT" "/booking_type" "$status" "201" "$booking_type_id"
 
-response=$(curl -s -w "\n%{http_code}" "$SERVER/booking_type/$booking_type_id")
+response=$(curl -b "$COOKIE_JAR" -s -w "\n%{http_code}" "$SERVER/booking_type/$booking_type_id")
 body=$(echo "$response" | sed '$d')
 status=$(echo "$response" | tail -n1)
 
@@ -63,9 +66,11 @@ assert_body_contains_with_cleanup "GET" "/booking_type" "$body" "massage" "$book
 assert_body_contains_with_cleanup "GET" "/booking_type" "$body" "sports" "$booking_type_id"
 assert_body_contains_with_cleanup "GET" "/booking_type" "$body" "5800" "$booking_type_id"
 assert_body_contains_with_cleanup "GET" "/booking_type" "$body" "true" "$booking_type_id"
+assert_body_contains_with_cleanup "GET" "/booking_type" "$body" '"buffer_after_minutes":15' "$booking_type_id"
+assert_body_contains_with_cleanup "GET" "/booking_type" "$body" '"min_notice_minutes":120' "$booking_type_id"
 
 # test DELETE
-response=$(curl -sS -w "\n%{http_code}" -X DELETE "$SERVER/booking_type/$booking_type_id")
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X DELETE "$SERVER/booking_type/$booking_type_id")
 
 body=$(echo "$response" | sed '$d')
 status=$(echo "$response" | tail -n1)
@@ -8,9 +8,10 @@ set -eou pipefail
 SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
 source "$SCRIPT_DIR/test_helpers.sh"
 SERVER="$1"
+admin_login
 
 # test POST
-response=$(curl -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
 		-d '{
         "name": "Jim",
         "surname": "Smith",
@@ -28,7 +29,7 @@ employee_id=$(echo "$body" | jq -r '.employee_id')
 #
 # test GET on first POST
 
-response=$(curl -s -w "\n%{http_code}" "$SERVER/employee/$employee_id")
+response=$(curl -b "$COOKIE_JAR" -s -w "\n%{http_code}" "$SERVER/employee/$employee_id")
 body=$(echo "$response" | sed '$d')
 status=$(echo "$response" | tail -n1)
 
@@ -38,7 +39,7 @@ assert_body_contains_with_cleanup "GET" "/employee" "$body" "Smith" "$employee_i
 assert_body_contains_with_cleanup "GET" "/employee" "$body" "Jim.smith@company.com" "$employee_id"
 
 # test PUT
-response=$(curl -s -w "\n%{http_code}" -H 'Content-Type: application/json' \
+response=$(curl -b "$COOKIE_JAR" -s -w "\n%{http_code}" -H 'Content-Type: application/json' \
 		-X PUT \
 		-d '{
         "name": "Jamie",
@@ -55,7 +56,7 @@ status=$(echo "$response" | tail -n1)
 # test PUT
 assert_status_with_cleanup "PUT" "/employee" "$status" "201" "$employee_id"
 
-response=$(curl -s -w "\n%{http_code}" "$SERVER/employee/$employee_id")
+response=$(curl -b "$COOKIE_JAR" -s -w "\n%{http_code}" "$SERVER/employee/$employee_id")
 body=$(echo "$response" | sed '$d')
 status=$(echo "$response" | tail -n1)
 
@@ -68,7 +69,7 @@ assert_body_contains_with_cleanup "GET" "/employee" "$body" "Director" "$employe
 assert_body_contains_with_cleanup "GET" "/employee" "$body" "bad worker" "$employee_id"
 
 # test DELETE
-response=$(curl -sS -w "\n%{http_code}" -X DELETE "$SERVER/employee/$employee_id")
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X DELETE "$SERVER/employee/$employee_id")
 
 body=$(echo "$response" | sed '$d')
 status=$(echo "$response" | tail -n1)
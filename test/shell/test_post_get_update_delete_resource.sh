@@ -0,0 +1,157 @@
+#!/bin/bash
+
+
+# test_post_get_update_delete_resource : test resources and that two bookings
+# cant use the same resource at once
+#
+#
+set -eou pipefail
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+source "$SCRIPT_DIR/test_helpers.sh"
+SERVER="$1"
+admin_login
+
+# test POST
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{
+	  "name": "Treatment room",
+	  "description": "the room at the back"
+	}' "$SERVER/resource")
+
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+assert_status "POST" "/resource" "$status" "201"
+resource_id=$(echo "$body" | jq -r '.resource_id')
+
+# set-up - two employees each with the same hour free and a booking type that
+# needs the room
+employee_ids=()
+for name in Rhys Rosa; do
+	response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+		-d "{
+	  \"name\": \"$name\",
+	  \"surname\": \"Room\",
+	  \"email\": \"$name.room@company.com\",
+	  \"title\": \"Therapist\",
+	  \"description\": \"good worker\"
+	}" "$SERVER/employee")
+
+	body=$(echo "$response" | sed '$d')
+	employee_ids+=("$(echo "$body" | jq -r '.employee_id')")
+done
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"title\": \"room massage\",
+	  \"description\": \"massage in the treatment room\",
+	  \"fixed\": false,
+	  \"cost\": 4000,
+	  \"resource_ids\": [$resource_id]
+	}" "$SERVER/booking_type")
+
+body=$(echo "$response" | sed '$d')
+booking_type_id=$(echo "$body" | jq -r '.booking_type_id')
+
+slots=()
+for employee_id in "${employee_ids[@]}"; do
+	response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+		-d "{
+	  \"employee_id\": $employee_id,
+	  \"start_time\": \"2026-01-20T10:00:00Z\",
+	  \"end_time\": \"2026-01-20T10:30:00Z\",
+	  \"type_id\": $booking_type_id
+	}" "$SERVER/availability")
+
+	body=$(echo "$response" | sed '$d')
+	slots+=("$(echo "$body" | jq -r '.availability_slot_ids[0]')")
+done
+
+booking_id=""
+
+function cleanup() {
+	if [[ -n "$booking_id" ]]; then
+		curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/booking/$booking_id"
+	fi
+	for slot in "${slots[@]}"; do
+		curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/availability/$slot"
+	done
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/booking_type/$booking_type_id"
+	for employee_id in "${employee_ids[@]}"; do
+		curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/employee/$employee_id"
+	done
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/resource/$resource_id"
+}
+
+# test GET booking type lists the resource
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" "$SERVER/booking_type/$booking_type_id")
+body=$(echo "$response" | sed '$d')
+
+if [[ "$(echo "$body" | jq -c '.resource_ids')" != "[$resource_id]" ]]; then
+	echo "GET /booking_type did not return the resource: $body"
+	cleanup
+	exit 1
+fi
+
+# test booking the first employee takes the room
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"availability_slots\": [${slots[0]}],
+	  \"type_id\": $booking_type_id
+	}" "$SERVER/booking")
+
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "201" ]]; then cleanup; fi
+assert_status "POST" "/booking" "$status" "201"
+booking_id=$(echo "$body" | jq -r '.booking_id')
+
+# test the second employee cant be booked at the same time
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"availability_slots\": [${slots[1]}],
+	  \"type_id\": $booking_type_id
+	}" "$SERVER/booking")
+
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "409" ]]; then cleanup; fi
+assert_status "POST" "/booking" "$status" "409"
+
+# test PUT
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X PUT -H 'Content-Type: application/json' \
+	-d '{
+	  "name": "Treatment room 1",
+	  "description": "the room at the back"
+	}' "$SERVER/resource/$resource_id")
+
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "PUT" "/resource" "$status" "200"
+
+# test GET
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" "$SERVER/resource/$resource_id")
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "GET" "/resource" "$status" "200"
+if [[ "$(echo "$body" | jq -r '.name')" != "Treatment room 1" ]]; then
+	echo "GET /resource did not return the new name: $body"
+	cleanup
+	exit 1
+fi
+
+# test DELETE is refused while the booking type uses the resource
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X DELETE "$SERVER/resource/$resource_id")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "409" ]]; then cleanup; fi
+assert_status "DELETE" "/resource" "$status" "409"
+
+# clean-up
+echo "cleaning up test..."
+
+cleanup
@@ -8,9 +8,10 @@ set -eou pipefail
 SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
 source "$SCRIPT_DIR/test_helpers.sh"
 SERVER="$1"
+admin_login
 
 # test POST
-response=$(curl -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
 		-d '{
         "name": "Jim",
         "surname": "Smith",
@@ -26,7 +27,7 @@ user_id=$(echo "$body" | jq -r '.user_id')
 
 # test GET on first POST
 
-response=$(curl -s -w "\n%{http_code}" "$SERVER/user/$user_id")
+response=$(curl -b "$COOKIE_JAR" -s -w "\n%{http_code}" "$SERVER/user/$user_id")
 body=$(echo "$response" | sed '$d')
 status=$(echo "$response" | tail -n1)
 
@@ -36,7 +37,7 @@ assert_body_contains_with_cleanup "GET" "/user" "$body" "Smith" "$user_id"
 assert_body_contains_with_cleanup "GET" "/user" "$body" "Jim.smith@company.com" "$user_id"
 
 # test PUT
-response=$(curl -s -w "\n%{http_code}" -H 'Content-Type: application/json' \
+response=$(curl -b "$COOKIE_JAR" -s -w "\n%{http_code}" -H 'Content-Type: application/json' \
 		-X PUT \
 		-d '{
         "name": "Jamie",
@@ -50,7 +51,7 @@ status=$(echo "$response" | tail -n1)
 # test PUT
 assert_status_with_cleanup "PUT" "/user" "$status" "201" "$user_id"
 
-response=$(curl -s -w "\n%{http_code}" "$SERVER/user/$user_id")
+response=$(curl -b "$COOKIE_JAR" -s -w "\n%{http_code}" "$SERVER/user/$user_id")
 body=$(echo "$response" | sed '$d')
 status=$(echo "$response" | tail -n1)
 
@@ -61,7 +62,7 @@ assert_body_contains_with_cleanup "GET" "/user" "$body" "Smith" "$user_id"
 assert_body_contains_with_cleanup "GET" "/user" "$body" "jamie.smith@company.com" "$user_id"
 
 # test DELETE
-response=$(curl -sS -w "\n%{http_code}" -X DELETE "$SERVER/user/$user_id")
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X DELETE "$SERVER/user/$user_id")
 
 body=$(echo "$response" | sed '$d')
 status=$(echo "$response" | tail -n1)
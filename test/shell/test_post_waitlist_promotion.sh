@@ -0,0 +1,160 @@
+#!/bin/bash
+
+
+# test_post_waitlist_promotion : test that cancelling a booking offers its slot
+# to the first customer on the waitlist, who can then book it with the offer
+#
+#
+set -eou pipefail
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+source "$SCRIPT_DIR/test_helpers.sh"
+SERVER="$1"
+admin_login
+
+# set-up - create employee and booking type and book their only slot
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{
+	  "name": "Wade",
+	  "surname": "Waitlist",
+	  "email": "wade.waitlist@company.com",
+	  "title": "Stylist",
+	  "description": "good worker"
+	}' "$SERVER/employee")
+
+body=$(echo "$response" | sed '$d')
+employee_id=$(echo "$body" | jq -r '.employee_id')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d '{
+	  "title": "waitlisted haircut",
+	  "description": "cutting of hair",
+	  "fixed": true,
+	  "cost": 2400
+	}' "$SERVER/booking_type")
+
+body=$(echo "$response" | sed '$d')
+booking_type_id=$(echo "$body" | jq -r '.booking_type_id')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"employee_id\": $employee_id,
+	  \"start_time\": \"2027-03-01T10:00:00Z\",
+	  \"end_time\": \"2027-03-01T10:30:00Z\",
+	  \"type_id\": $booking_type_id
+	}" "$SERVER/availability")
+
+body=$(echo "$response" | sed '$d')
+slot=$(echo "$body" | jq -r '.availability_slot_ids[0]')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"availability_slots\": [$slot],
+	  \"type_id\": $booking_type_id
+	}" "$SERVER/booking")
+
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+booking_id=$(echo "$body" | jq -r '.booking_id')
+waitlist_id=""
+offered_booking_id=""
+
+function cleanup() {
+	if [[ -n "$waitlist_id" ]]; then
+		curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/waitlist/$waitlist_id"
+	fi
+	if [[ -n "$offered_booking_id" ]]; then
+		curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/booking/$offered_booking_id"
+	fi
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/booking/$booking_id"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/availability/$slot"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/booking_type/$booking_type_id"
+	curl -b "$COOKIE_JAR" -sS -o /dev/null -X DELETE "$SERVER/employee/$employee_id"
+}
+
+if [[ "$status" != "201" ]]; then cleanup; fi
+assert_status "POST" "/booking" "$status" "201"
+
+# test joining the waitlist for the morning
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"type_id\": $booking_type_id,
+	  \"employee_id\": $employee_id,
+	  \"start_time\": \"2027-03-01T09:00:00Z\",
+	  \"end_time\": \"2027-03-01T12:00:00Z\"
+	}" "$SERVER/waitlist")
+
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "201" ]]; then cleanup; fi
+assert_status "POST" "/waitlist" "$status" "201"
+waitlist_id=$(echo "$body" | jq -r '.waitlist_id')
+
+# test a backwards window is rejected
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"type_id\": $booking_type_id,
+	  \"start_time\": \"2027-03-01T12:00:00Z\",
+	  \"end_time\": \"2027-03-01T09:00:00Z\"
+	}" "$SERVER/waitlist")
+
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "400" ]]; then cleanup; fi
+assert_status "POST" "/waitlist" "$status" "400"
+
+# test cancelling the booking offers the slot to the waitlist
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X POST "$SERVER/booking/$booking_id/cancel")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "POST" "/booking/cancel" "$status" "200"
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" "$SERVER/waitlist/$waitlist_id")
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "200" ]]; then cleanup; fi
+assert_status "GET" "/waitlist" "$status" "200"
+if [[ "$(echo "$body" | jq -c '[.status, .offered_slot_ids]')" != "[\"offered\",[$slot]]" ]]; then
+	echo "GET /waitlist did not show the freed slot as offered: $body"
+	cleanup
+	exit 1
+fi
+hold_id=$(echo "$body" | jq -r '.hold_id')
+
+# test booking with the offer takes the entry off the waitlist
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -H 'Content-Type: application/json' \
+	-d "{
+	  \"hold_id\": $hold_id,
+	  \"type_id\": $booking_type_id
+	}" "$SERVER/booking")
+
+body=$(echo "$response" | sed '$d')
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "201" ]]; then cleanup; fi
+assert_status "POST" "/booking" "$status" "201"
+offered_booking_id=$(echo "$body" | jq -r '.booking_id')
+
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" "$SERVER/waitlist/$waitlist_id")
+body=$(echo "$response" | sed '$d')
+
+if [[ "$(echo "$body" | jq -c '[.status, .booking_id]')" != "[\"booked\",$offered_booking_id]" ]]; then
+	echo "GET /waitlist did not show the entry as booked: $body"
+	cleanup
+	exit 1
+fi
+
+# test DELETE
+response=$(curl -b "$COOKIE_JAR" -sS -w "\n%{http_code}" -X DELETE "$SERVER/waitlist/$waitlist_id")
+status=$(echo "$response" | tail -n1)
+
+if [[ "$status" != "204" ]]; then cleanup; fi
+assert_status "DELETE" "/waitlist" "$status" "204"
+waitlist_id=""
+
+# clean-up
+echo "cleaning up test..."
+
+cleanup